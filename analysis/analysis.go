@@ -0,0 +1,132 @@
+// Package analysis statically checks Human Resource Machine programs for
+// mistakes, such as loops that can never end, without running them
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/levels"
+)
+
+// The severity of a finding
+type Severity int
+
+const (
+	SEVERITY_INFO Severity = iota
+	SEVERITY_WARNING
+	SEVERITY_ERROR
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SEVERITY_INFO:
+		return "info"
+	case SEVERITY_WARNING:
+		return "warning"
+	case SEVERITY_ERROR:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// A problem found in a program
+type Finding struct {
+	// The index of the instruction in the disassembled program
+	Index int
+	// The line number of the instruction, as shown in the game
+	Line     int
+	Severity Severity
+	// The name of the check reporting the finding
+	Check   string
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: %s: %s [%s]", f.Line, f.Severity, f.Message, f.Check)
+}
+
+// A program being analyzed
+type Pass struct {
+	Program instructions.Disassembled
+	Graph   *Graph
+	// The level the program solves, nil if unknown
+	Level    *levels.Level
+	findings []Finding
+}
+
+// Report a finding for the instruction at index
+func (p *Pass) Report(index int, severity Severity, check, format string, args ...interface{}) {
+	line := 0
+	if inst, ok := p.Program[index].(instructions.LineNumbered); ok {
+		line = inst.Line()
+	}
+	p.findings = append(p.findings, Finding{index, line, severity, check, fmt.Sprintf(format, args...)})
+}
+
+// A check run by Analyze
+type Check struct {
+	Name string
+	// A one line description of what the check reports
+	Doc string
+	// Optional checks only run when enabled with Enable
+	Optional bool
+	Run      func(pass *Pass)
+}
+
+var checks []Check
+
+// Register a check run by Analyze
+func Register(check Check) {
+	checks = append(checks, check)
+}
+
+// Return the registered checks
+func Checks() []Check {
+	return append([]Check(nil), checks...)
+}
+
+type options struct {
+	level   *levels.Level
+	enabled map[string]bool
+}
+
+// An Analyze option
+type Option func(*options)
+
+// Analyze the program as a solution of level, allowing checks which
+// depend on the level's floor tiles and inbox
+func ForLevel(level levels.Level) Option {
+	return func(o *options) {
+		o.level = &level
+	}
+}
+
+// Enable the optional checks named
+func Enable(names ...string) Option {
+	return func(o *options) {
+		for _, name := range names {
+			o.enabled[name] = true
+		}
+	}
+}
+
+// Run the registered checks on program, returning the findings ordered by
+// instruction
+func Analyze(program instructions.Disassembled, opts ...Option) []Finding {
+	options := options{enabled: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	pass := &Pass{Program: program, Graph: NewGraph(program), Level: options.level}
+	for _, check := range checks {
+		if !check.Optional || options.enabled[check.Name] {
+			check.Run(pass)
+		}
+	}
+	sort.SliceStable(pass.findings, func(i, j int) bool {
+		return pass.findings[i].Index < pass.findings[j].Index
+	})
+	return pass.findings
+}
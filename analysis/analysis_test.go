@@ -0,0 +1,244 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/levels"
+)
+
+func disassembled(t *testing.T, text string) instructions.Disassembled {
+	t.Helper()
+	program, _, err := instructions.Assemble(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := instructions.Disassemble(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+var checkTests = []struct {
+	name     string
+	check    string
+	program  string
+	floor    int // The level to analyze the program for, 0 for none
+	findings []string
+}{
+	{"jump to self", "jump-to-self", `
+    INBOX
+a:
+    JUMP     a
+`, 0, []string{"line 2: error: JUMP a jumps to itself and loops forever [jump-to-self]"}},
+	{"conditional jump to self", "jump-to-self", `
+    INBOX
+a:
+    JUMPZ    a
+    OUTBOX
+`, 0, []string{"line 2: warning: JUMPZ a jumps to itself and loops forever when the jump is taken [jump-to-self]"}},
+	{"no jump to self", "jump-to-self", `
+a:
+    INBOX
+    OUTBOX
+    JUMP     a
+`, 0, nil},
+
+	{"infinite loop", "infinite-loop", `
+    INBOX
+    COPYTO   0
+a:
+    BUMPUP   0
+    COPYTO   1
+    JUMP     a
+`, 0, []string{"line 3: error: the loop at lines 3-5 never takes from the inbox, puts in the outbox or leaves the loop [infinite-loop]"}},
+	{"loop ending with the inbox", "infinite-loop", `
+a:
+    INBOX
+    COPYTO   0
+    JUMP     a
+`, 0, nil},
+	{"loop putting in the outbox", "infinite-loop", `
+    INBOX
+a:
+    OUTBOX
+    JUMP     a
+`, 0, nil},
+	{"loop with an exit", "infinite-loop", `
+    INBOX
+    COPYTO   0
+a:
+    BUMPDN   0
+    JUMPZ    b
+    JUMP     a
+b:
+    OUTBOX
+`, 0, nil},
+	{"jump to self is not an infinite loop", "infinite-loop", `
+a:
+    JUMP     a
+`, 0, nil},
+
+	{"stateless loop", "stateless-loop", `
+    INBOX
+a:
+    JUMPZ    b
+    JUMP     a
+b:
+    OUTBOX
+`, 0, []string{"line 2: warning: the loop at lines 2-3 only jumps, so it either leaves on the first pass or loops forever [stateless-loop]"}},
+	{"loop changing state", "stateless-loop", `
+    INBOX
+    COPYTO   0
+a:
+    BUMPDN   0
+    JUMPZ    b
+    JUMP     a
+b:
+    OUTBOX
+`, 0, nil},
+
+	{"dead store", "dead-store", `
+    INBOX
+    COPYTO   0
+    COPYTO   1
+    INBOX
+    COPYTO   0
+    ADD      0
+    OUTBOX
+`, 0, []string{
+		"line 2: warning: the value copied to tile 0 is never read [dead-store]",
+		"line 3: warning: the value copied to tile 1 is never read [dead-store]",
+	}},
+	{"store read in a loop", "dead-store", `
+    INBOX
+    COPYTO   0
+a:
+    INBOX
+    ADD      0
+    COPYTO   0
+    OUTBOX
+    JUMP     a
+`, 0, nil},
+	{"store read through a pointer", "dead-store", `
+    INBOX
+    COPYTO   1
+    COPYFROM [0]
+    OUTBOX
+`, 0, nil},
+	{"unreachable store", "dead-store", `
+    INBOX
+    JUMP     a
+    COPYTO   0
+a:
+    OUTBOX
+`, 0, nil},
+
+	{"never puts in the outbox", "no-outbox-path", `
+a:
+    INBOX
+    COPYTO   0
+    JUMP     a
+`, 0, []string{"line 1: warning: the program never puts anything in the outbox [no-outbox-path]"}},
+	{"path without outbox", "no-outbox-path", `
+    INBOX
+    JUMPZ    a
+    OUTBOX
+a:
+    INBOX
+    COPYTO   0
+`, 0, []string{"line 4: warning: no OUTBOX can be performed from here on [no-outbox-path]"}},
+	{"every path puts in the outbox", "no-outbox-path", `
+a:
+    INBOX
+    JUMPZ    a
+    OUTBOX
+    JUMP     a
+`, 0, nil},
+	{"empty program", "no-outbox-path", "", 0, nil},
+
+	{"jump never taken", "value-ranges", `
+a:
+    INBOX
+    JUMPN    b
+    OUTBOX
+    JUMP     a
+b:
+    BUMPUP   0
+    JUMP     a
+`, 22, []string{"line 2: warning: JUMPN b is never taken [value-ranges]"}},
+	{"jump always taken", "value-ranges", `
+a:
+    INBOX
+    OUTBOX
+    COPYFROM 5
+    JUMPZ    a
+    OUTBOX
+`, 25, []string{"line 4: warning: JUMPZ a is always taken [value-ranges]"}},
+	{"tile outside the floor", "value-ranges", `
+    INBOX
+    COPYTO   3
+    OUTBOX
+`, 8, []string{"line 2: error: COPYTO accesses a tile outside the floor (3 tiles) [value-ranges]"}},
+	{"jumps depending on the inbox", "value-ranges", `
+a:
+    INBOX
+    JUMPN    a
+    JUMPZ    a
+    OUTBOX
+    JUMP     a
+`, 8, nil},
+	{"ranges without a level", "value-ranges", `
+    INBOX
+    COPYTO   99
+`, 0, nil},
+}
+
+func TestChecks(t *testing.T) {
+	for _, test := range checkTests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := []Option{Enable(test.check)}
+			if test.floor != 0 {
+				opts = append(opts, ForLevel(levels.Floor(test.floor)))
+			}
+			var findings []string
+			for _, finding := range Analyze(disassembled(t, test.program), opts...) {
+				if finding.Check == test.check {
+					findings = append(findings, finding.String())
+				}
+			}
+			if !reflect.DeepEqual(findings, test.findings) {
+				t.Errorf("got %q, expected %q", findings, test.findings)
+			}
+		})
+	}
+}
+
+func TestOptionalChecks(t *testing.T) {
+	program := disassembled(t, "INBOX\nCOPYTO 3\nOUTBOX\n")
+	for _, finding := range Analyze(program, ForLevel(levels.Floor(8))) {
+		if finding.Check == "value-ranges" {
+			t.Errorf("the optional check reported %s without being enabled", finding)
+		}
+	}
+	for _, check := range Checks() {
+		if check.Name == "value-ranges" && !check.Optional {
+			t.Error("value-ranges is not optional")
+		}
+	}
+}
+
+func TestAnalyzeOrder(t *testing.T) {
+	program := disassembled(t, "INBOX\nCOPYTO 0\na:\nJUMP a\n")
+	findings := Analyze(program)
+	if len(findings) < 2 {
+		t.Fatalf("got findings %v, expected at least 2", findings)
+	}
+	for i := 1; i < len(findings); i++ {
+		if findings[i].Index < findings[i-1].Index {
+			t.Errorf("the findings are not ordered by instruction: %v", findings)
+		}
+	}
+}
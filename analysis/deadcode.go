@@ -0,0 +1,166 @@
+package analysis
+
+import (
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+func init() {
+	Register(Check{
+		Name: "dead-store",
+		Doc:  "values copied to a tile which are never read afterwards",
+		Run:  checkDeadStores,
+	})
+	Register(Check{
+		Name: "no-outbox-path",
+		Doc:  "instructions after which the program can never put anything in the outbox",
+		Run:  checkNoOutboxPaths,
+	})
+}
+
+// A set of tiles. All is set when any tile may be in the set, e.g. because
+// of a read through a pointer
+type tileSet struct {
+	all   bool
+	tiles map[uint32]bool
+}
+
+func (s tileSet) has(tile uint32) bool {
+	return s.all || s.tiles[tile]
+}
+
+// Add the tiles of other to s, reporting whether s changed
+func (s *tileSet) union(other tileSet) bool {
+	changed := false
+	if other.all && !s.all {
+		s.all, changed = true, true
+	}
+	for tile := range other.tiles {
+		if !s.tiles[tile] {
+			if s.tiles == nil {
+				s.tiles = make(map[uint32]bool)
+			}
+			s.tiles[tile] = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Return the tiles read by an instruction, and the tile it certainly
+// writes, if any
+func tileAccess(diss instructions.DisassembleInterface) (reads tileSet, writes uint32, overwrites bool) {
+	inst, ok := diss.(instructions.DisassembleArgInstruction)
+	if !ok {
+		return
+	}
+	reads.tiles = map[uint32]bool{}
+	if inst.Indirect {
+		// The pointer is read, and any tile may be accessed through it
+		reads.tiles[inst.Arg] = true
+		if inst.Op != instructions.OP_COPY_TO {
+			reads.all = true
+		}
+		return
+	}
+	if inst.Op == instructions.OP_COPY_TO {
+		return reads, inst.Arg, true
+	}
+	reads.tiles[inst.Arg] = true
+	return
+}
+
+// Return the tiles which may be read after each instruction before they
+// are overwritten (the tiles live after the instruction)
+func liveTiles(pass *Pass) map[int]tileSet {
+	liveOut := make(map[int]tileSet)
+	liveIn := make(map[int]tileSet)
+	for changed := true; changed; {
+		changed = false
+		for i := len(pass.Graph.Nodes) - 1; i >= 0; i-- {
+			node := pass.Graph.Nodes[i]
+			out := liveOut[node]
+			for _, s := range pass.Graph.Successors[node] {
+				if s != END && out.union(liveIn[s]) {
+					changed = true
+				}
+			}
+			liveOut[node] = out
+
+			reads, writes, overwrites := tileAccess(pass.Program[node])
+			in := tileSet{all: out.all, tiles: make(map[uint32]bool)}
+			for tile := range out.tiles {
+				if !overwrites || tile != writes {
+					in.tiles[tile] = true
+				}
+			}
+			in.union(reads)
+			current := liveIn[node]
+			if current.union(in) {
+				changed = true
+			}
+			liveIn[node] = current
+		}
+	}
+	return liveOut
+}
+
+func checkDeadStores(pass *Pass) {
+	liveOut := liveTiles(pass)
+	reachable := pass.Graph.Reachable()
+	for _, node := range pass.Graph.Nodes {
+		_, writes, overwrites := tileAccess(pass.Program[node])
+		if overwrites && reachable[node] && !liveOut[node].has(writes) {
+			pass.Report(node, SEVERITY_WARNING, "dead-store", "the value copied to tile %d is never read", writes)
+		}
+	}
+}
+
+func checkNoOutboxPaths(pass *Pass) {
+	// The instructions after which an OUTBOX can be performed
+	leadsToOutbox := make(map[int]bool)
+	predecessors := make(map[int][]int)
+	var queue []int
+	for _, node := range pass.Graph.Nodes {
+		for _, s := range pass.Graph.Successors[node] {
+			predecessors[s] = append(predecessors[s], node)
+		}
+		if inst, ok := pass.Program[node].(instructions.DisassembleInstruction); ok && inst.Op == instructions.OP_OUTBOX {
+			leadsToOutbox[node] = true
+			queue = append(queue, node)
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, p := range predecessors[node] {
+			if !leadsToOutbox[p] {
+				leadsToOutbox[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+
+	reachable := pass.Graph.Reachable()
+	if len(leadsToOutbox) == 0 {
+		if entry := pass.Graph.Entry(); entry != END {
+			pass.Report(entry, SEVERITY_WARNING, "no-outbox-path", "the program never puts anything in the outbox")
+		}
+		return
+	}
+	// Report where the program enters code which can never lead to an
+	// OUTBOX
+	for _, node := range pass.Graph.Nodes {
+		if !reachable[node] || leadsToOutbox[node] {
+			continue
+		}
+		entered := node == pass.Graph.Entry()
+		for _, p := range predecessors[node] {
+			if leadsToOutbox[p] {
+				entered = true
+			}
+		}
+		if entered {
+			pass.Report(node, SEVERITY_WARNING, "no-outbox-path", "no OUTBOX can be performed from here on")
+		}
+	}
+}
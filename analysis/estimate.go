@@ -0,0 +1,133 @@
+package analysis
+
+import "github.com/clj/hrm-profile-tool/instructions"
+
+// Bounds of the number of steps a program takes, as counted by the speed
+// challenge
+type StepBounds struct {
+	// The number of values in the inbox the bounds are for
+	InboxLength int
+	Best, Worst int
+	// Set if the worst case is unbounded, because of loops which may
+	// repeat without taking from the inbox (e.g. counting down a value) or
+	// never end
+	Unbounded bool
+	// The first instruction of each loop making the worst case unbounded
+	UnboundedLoops []int
+	// Set if the program cannot end, e.g. because every path loops forever
+	NeverEnds bool
+}
+
+// A state of the step count search: the next instruction and the number
+// of values taken from the inbox
+type stepState struct {
+	node, taken int
+}
+
+// Return the states following state, each taking one step, and whether
+// the program can end after state (taking steps steps)
+func (g *Graph) stepSuccessors(program instructions.Disassembled, state stepState, inboxLength int) (next []stepState, ends bool, endSteps int) {
+	if inst, ok := program[state.node].(instructions.DisassembleInstruction); ok && inst.Op == instructions.OP_INBOX {
+		if state.taken == inboxLength {
+			// Taking from the empty inbox ends the program, it is not a step
+			return nil, true, 0
+		}
+		for _, s := range g.Successors[state.node] {
+			if s != END {
+				next = append(next, stepState{s, state.taken + 1})
+			} else if nextInstruction(program, state.node+1) == END {
+				ends, endSteps = true, 1
+			}
+		}
+		return next, ends, endSteps
+	}
+	for _, s := range g.Successors[state.node] {
+		if s == END {
+			ends, endSteps = true, 1
+		} else {
+			next = append(next, stepState{s, state.taken})
+		}
+	}
+	return next, ends, endSteps
+}
+
+// Estimate the best and worst case number of steps the program takes with
+// inboxLength values in the inbox, without running it. Every path through
+// the program is assumed possible, so the bounds may be wider than the
+// steps actually taken
+func EstimateSteps(program instructions.Disassembled, inboxLength int) StepBounds {
+	g := NewGraph(program)
+	bounds := StepBounds{InboxLength: inboxLength}
+	entry := g.Entry()
+	if entry == END {
+		return bounds
+	}
+	start := stepState{entry, 0}
+
+	// Best case: breadth first search, every step costs one
+	distance := map[stepState]int{start: 0}
+	queue := []stepState{start}
+	best := -1
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		next, ends, endSteps := g.stepSuccessors(program, state, inboxLength)
+		if ends && (best < 0 || distance[state]+endSteps < best) {
+			best = distance[state] + endSteps
+		}
+		for _, s := range next {
+			if _, seen := distance[s]; !seen {
+				distance[s] = distance[state] + 1
+				queue = append(queue, s)
+			}
+		}
+	}
+	if best < 0 {
+		bounds.NeverEnds, bounds.Unbounded = true, true
+		return bounds
+	}
+	bounds.Best = best
+
+	// Worst case: longest path, unbounded if a loop can repeat without
+	// taking from the inbox
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	color := make(map[stepState]int)
+	longest := make(map[stepState]int)
+	loops := make(map[int]bool)
+	var visit func(state stepState)
+	visit = func(state stepState) {
+		color[state] = visiting
+		next, ends, endSteps := g.stepSuccessors(program, state, inboxLength)
+		worst := -1
+		if ends {
+			worst = endSteps
+		}
+		for _, s := range next {
+			switch color[s] {
+			case visiting:
+				loops[s.node] = true
+				continue
+			case unvisited:
+				visit(s)
+			}
+			if longest[s] >= 0 && longest[s]+1 > worst {
+				worst = longest[s] + 1
+			}
+		}
+		longest[state] = worst
+		color[state] = visited
+	}
+	visit(start)
+	bounds.Worst = longest[start]
+	for _, node := range g.Nodes {
+		if loops[node] {
+			bounds.Unbounded = true
+			bounds.UnboundedLoops = append(bounds.UnboundedLoops, node)
+		}
+	}
+	return bounds
+}
@@ -0,0 +1,59 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEstimateSteps(t *testing.T) {
+	tests := []struct {
+		name        string
+		program     string
+		inboxLength int
+		bounds      StepBounds
+	}{
+		{"mail room", `
+a:
+    INBOX
+    OUTBOX
+    JUMP     a
+`, 3, StepBounds{InboxLength: 3, Best: 9, Worst: 9}},
+		{"running past the end", `
+    INBOX
+    OUTBOX
+`, 2, StepBounds{InboxLength: 2, Best: 2, Worst: 2}},
+		{"empty inbox", `
+    INBOX
+    OUTBOX
+`, 0, StepBounds{}},
+		{"branches", `
+a:
+    INBOX
+    JUMPZ    a
+    OUTBOX
+    JUMP     a
+`, 2, StepBounds{InboxLength: 2, Best: 4, Worst: 8}},
+		{"countdown", `
+    INBOX
+    COPYTO   0
+a:
+    BUMPDN   0
+    JUMPZ    b
+    JUMP     a
+b:
+`, 1, StepBounds{InboxLength: 1, Best: 4, Worst: 4, Unbounded: true, UnboundedLoops: []int{3}}},
+		{"never ends", `
+a:
+    JUMP     a
+`, 1, StepBounds{InboxLength: 1, Unbounded: true, NeverEnds: true}},
+		{"empty program", "", 1, StepBounds{InboxLength: 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bounds := EstimateSteps(disassembled(t, test.program), test.inboxLength)
+			if !reflect.DeepEqual(bounds, test.bounds) {
+				t.Errorf("got %+v, expected %+v", bounds, test.bounds)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+module github.com/clj/hrm-profile-tool/analysis
+
+require (
+	github.com/clj/hrm-profile-tool/instructions v0.0.0
+	github.com/clj/hrm-profile-tool/levels v0.0.0
+	github.com/clj/hrm-profile-tool/utils/logging v0.0.0 // indirect
+)
+
+replace github.com/clj/hrm-profile-tool/instructions => ../instructions
+
+replace github.com/clj/hrm-profile-tool/levels => ../levels
+
+replace github.com/clj/hrm-profile-tool/utils/logging => ../utils/logging
@@ -0,0 +1,172 @@
+package analysis
+
+import "github.com/clj/hrm-profile-tool/instructions"
+
+// The successor representing the end of the program
+const END = -1
+
+// The control flow graph of a program. The nodes are the indexes of the
+// instructions the worker performs, i.e. not comments and jump targets
+type Graph struct {
+	// The nodes in program order
+	Nodes []int
+	// The successors of each node, END if the program can end after it
+	Successors map[int][]int
+}
+
+// Return the index of the first instruction performed at or after index,
+// or END
+func nextInstruction(program instructions.Disassembled, index int) int {
+	for ; index >= 0 && index < len(program); index++ {
+		if _, ok := program[index].(instructions.LineNumbered); ok {
+			return index
+		}
+	}
+	return END
+}
+
+// Return the control flow graph of program
+func NewGraph(program instructions.Disassembled) *Graph {
+	g := &Graph{Successors: make(map[int][]int)}
+	for i, diss := range program {
+		var successors []int
+		switch diss := diss.(type) {
+		case instructions.DisassembleJumpInstruction:
+			target := END
+			if diss.Target >= 0 {
+				target = nextInstruction(program, diss.Target)
+			}
+			successors = append(successors, target)
+			if diss.Op != instructions.OP_JUMP {
+				successors = append(successors, nextInstruction(program, i+1))
+			}
+		case instructions.DisassembleArgInstruction:
+			successors = append(successors, nextInstruction(program, i+1))
+		case instructions.DisassembleInstruction:
+			successors = append(successors, nextInstruction(program, i+1))
+			if diss.Op == instructions.OP_INBOX {
+				// The program ends when the inbox is empty
+				successors = append(successors, END)
+			}
+		default:
+			continue
+		}
+		g.Nodes = append(g.Nodes, i)
+		g.Successors[i] = uniqueSuccessors(successors)
+	}
+	return g
+}
+
+func uniqueSuccessors(successors []int) []int {
+	var unique []int
+	seen := make(map[int]bool)
+	for _, s := range successors {
+		if !seen[s] {
+			seen[s] = true
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
+
+// Return the entry node of the program, or END for an empty program
+func (g *Graph) Entry() int {
+	if len(g.Nodes) == 0 {
+		return END
+	}
+	return g.Nodes[0]
+}
+
+// Return the strongly connected components of the graph which contain a
+// cycle, i.e. the loops of the program, each in program order
+func (g *Graph) Loops() [][]int {
+	// Tarjan's algorithm
+	index := make(map[int]int)
+	lowlink := make(map[int]int)
+	onStack := make(map[int]bool)
+	var stack []int
+	var loops [][]int
+	next := 0
+	var connect func(node int)
+	connect = func(node int) {
+		index[node], lowlink[node] = next, next
+		next++
+		stack = append(stack, node)
+		onStack[node] = true
+		for _, s := range g.Successors[node] {
+			if s == END {
+				continue
+			}
+			if _, visited := index[s]; !visited {
+				connect(s)
+				if lowlink[s] < lowlink[node] {
+					lowlink[node] = lowlink[s]
+				}
+			} else if onStack[s] && index[s] < lowlink[node] {
+				lowlink[node] = index[s]
+			}
+		}
+		if lowlink[node] != index[node] {
+			return
+		}
+		var component []int
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == node {
+				break
+			}
+		}
+		if len(component) > 1 || g.hasEdge(node, node) {
+			loops = append(loops, g.inProgramOrder(component))
+		}
+	}
+	for _, node := range g.Nodes {
+		if _, visited := index[node]; !visited {
+			connect(node)
+		}
+	}
+	return loops
+}
+
+func (g *Graph) hasEdge(from, to int) bool {
+	for _, s := range g.Successors[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Graph) inProgramOrder(nodes []int) []int {
+	member := make(map[int]bool)
+	for _, node := range nodes {
+		member[node] = true
+	}
+	ordered := make([]int, 0, len(nodes))
+	for _, node := range g.Nodes {
+		if member[node] {
+			ordered = append(ordered, node)
+		}
+	}
+	return ordered
+}
+
+// Return the nodes reachable from the entry of the program
+func (g *Graph) Reachable() map[int]bool {
+	reachable := make(map[int]bool)
+	var visit func(node int)
+	visit = func(node int) {
+		if node == END || reachable[node] {
+			return
+		}
+		reachable[node] = true
+		for _, s := range g.Successors[node] {
+			visit(s)
+		}
+	}
+	visit(g.Entry())
+	return reachable
+}
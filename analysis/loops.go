@@ -0,0 +1,100 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+func init() {
+	Register(Check{
+		Name: "jump-to-self",
+		Doc:  "jumps to the jump itself, which loop forever once taken",
+		Run:  checkJumpToSelf,
+	})
+	Register(Check{
+		Name: "infinite-loop",
+		Doc:  "loops without a way out which never use the inbox or outbox",
+		Run:  checkInfiniteLoops,
+	})
+	Register(Check{
+		Name: "stateless-loop",
+		Doc:  "loops of jumps only, which change nothing and so leave on the first pass or never",
+		Run:  checkStatelessLoops,
+	})
+}
+
+// Return the lines spanned by the nodes of a loop, for messages
+func loopLines(program instructions.Disassembled, loop []int) string {
+	first := program[loop[0]].(instructions.LineNumbered).Line()
+	last := program[loop[len(loop)-1]].(instructions.LineNumbered).Line()
+	if first == last {
+		return fmt.Sprintf("line %d", first)
+	}
+	return fmt.Sprintf("lines %d-%d", first, last)
+}
+
+// Report whether the loop is a single jump to itself
+func isJumpToSelf(g *Graph, loop []int) bool {
+	return len(loop) == 1 && g.hasEdge(loop[0], loop[0])
+}
+
+func checkJumpToSelf(pass *Pass) {
+	for _, node := range pass.Graph.Nodes {
+		jump, ok := pass.Program[node].(instructions.DisassembleJumpInstruction)
+		if !ok || !pass.Graph.hasEdge(node, node) {
+			continue
+		}
+		if jump.Op == instructions.OP_JUMP {
+			pass.Report(node, SEVERITY_ERROR, "jump-to-self", "%s %s jumps to itself and loops forever", jump.Op, jump.TargetLabel)
+		} else {
+			pass.Report(node, SEVERITY_WARNING, "jump-to-self", "%s %s jumps to itself and loops forever when the jump is taken", jump.Op, jump.TargetLabel)
+		}
+	}
+}
+
+// Report whether the program can leave the loop: by jumping or running
+// out of it, by ending when the inbox is empty, or by failing when putting
+// nothing in the outbox
+func loopExits(pass *Pass, loop []int) bool {
+	member := make(map[int]bool)
+	for _, node := range loop {
+		member[node] = true
+	}
+	for _, node := range loop {
+		for _, s := range pass.Graph.Successors[node] {
+			if !member[s] {
+				return true
+			}
+		}
+		if inst, ok := pass.Program[node].(instructions.DisassembleInstruction); ok && inst.Op == instructions.OP_OUTBOX {
+			return true
+		}
+	}
+	return false
+}
+
+func checkInfiniteLoops(pass *Pass) {
+	for _, loop := range pass.Graph.Loops() {
+		if !isJumpToSelf(pass.Graph, loop) && !loopExits(pass, loop) {
+			pass.Report(loop[0], SEVERITY_ERROR, "infinite-loop", "the loop at %s never takes from the inbox, puts in the outbox or leaves the loop", loopLines(pass.Program, loop))
+		}
+	}
+}
+
+func checkStatelessLoops(pass *Pass) {
+	for _, loop := range pass.Graph.Loops() {
+		if isJumpToSelf(pass.Graph, loop) || !loopExits(pass, loop) {
+			continue
+		}
+		stateless := true
+		for _, node := range loop {
+			if _, ok := pass.Program[node].(instructions.DisassembleJumpInstruction); !ok {
+				stateless = false
+			}
+		}
+		if stateless {
+			pass.Report(loop[0], SEVERITY_WARNING, "stateless-loop", "the loop at %s only jumps, so it either leaves on the first pass or loops forever", loopLines(pass.Program, loop))
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package analysis
+
+import (
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/levels"
+)
+
+func init() {
+	Register(Check{
+		Name:     "value-ranges",
+		Doc:      "jumps that are never or always taken and tile accesses outside the floor, from the ranges of values the level allows (requires the level)",
+		Optional: true,
+		Run:      checkValueRanges,
+	})
+}
+
+// The range of numbers the game allows
+const (
+	minNumber = -999
+	maxNumber = 999
+)
+
+// The number of times the state of an instruction may grow before its
+// ranges are widened to the full range of numbers, ensuring that loops
+// counting up or down are analyzed quickly
+const wideningThreshold = 4
+
+// The values the worker's hand or a tile may hold
+type valueRange struct {
+	// May hold nothing
+	empty bool
+	// May hold a number from min to max
+	numbers  bool
+	min, max int
+	// May hold a letter
+	letters bool
+}
+
+func clamp(n int) int {
+	switch {
+	case n < minNumber:
+		return minNumber
+	case n > maxNumber:
+		return maxNumber
+	}
+	return n
+}
+
+// Return a range holding only numbers from min to max
+func numberRange(min, max int) valueRange {
+	return valueRange{numbers: true, min: clamp(min), max: clamp(max)}
+}
+
+// Return a range holding exactly value
+func exactRange(value levels.Value) valueRange {
+	if value.IsLetter() {
+		return valueRange{letters: true}
+	}
+	return numberRange(value.Number, value.Number)
+}
+
+// Return the range holding the values of both ranges
+func (v valueRange) join(other valueRange) valueRange {
+	switch {
+	case !other.numbers:
+	case !v.numbers:
+		v.min, v.max = other.min, other.max
+	default:
+		if other.min < v.min {
+			v.min = other.min
+		}
+		if other.max > v.max {
+			v.max = other.max
+		}
+	}
+	v.empty = v.empty || other.empty
+	v.numbers = v.numbers || other.numbers
+	v.letters = v.letters || other.letters
+	return v
+}
+
+// Return next, widened to the full range of numbers where it grew from v
+func (v valueRange) widen(next valueRange) valueRange {
+	if v.numbers && next.numbers {
+		if next.min < v.min {
+			next.min = minNumber
+		}
+		if next.max > v.max {
+			next.max = maxNumber
+		}
+	}
+	return next
+}
+
+// Report whether the range holds no value at all
+func (v valueRange) impossible() bool {
+	return !v.empty && !v.numbers && !v.letters
+}
+
+// Restrict the numbers of the range to min to max
+func (v valueRange) restrict(min, max int) valueRange {
+	if v.numbers {
+		if min > v.min {
+			v.min = min
+		}
+		if max < v.max {
+			v.max = max
+		}
+		v.numbers = v.min <= v.max
+	}
+	return v
+}
+
+// Return the part of the range for which JUMPZ (zero) or JUMPN (!zero)
+// jumps, or does not jump
+func (v valueRange) branch(zero, taken bool) valueRange {
+	v.empty = false
+	switch {
+	case zero && taken:
+		v.letters = false
+		return v.restrict(0, 0)
+	case zero:
+		switch {
+		case v.numbers && v.min == 0 && v.max == 0:
+			v.numbers = false
+		case v.numbers && v.min == 0:
+			v.min = 1
+		case v.numbers && v.max == 0:
+			v.max = -1
+		}
+		return v
+	case taken:
+		v.letters = false
+		return v.restrict(minNumber, -1)
+	default:
+		return v.restrict(0, maxNumber)
+	}
+}
+
+// The state of the worker and the floor before an instruction
+type rangeState struct {
+	hand  valueRange
+	tiles []valueRange
+}
+
+func (s rangeState) copy() rangeState {
+	return rangeState{s.hand, append([]valueRange(nil), s.tiles...)}
+}
+
+func (s rangeState) equal(other rangeState) bool {
+	if s.hand != other.hand {
+		return false
+	}
+	for i := range s.tiles {
+		if s.tiles[i] != other.tiles[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// The range analysis of a program
+type rangeAnalysis struct {
+	pass  *Pass
+	inbox valueRange
+	// The state before each instruction reached
+	states map[int]rangeState
+}
+
+// Return the tiles an instruction accesses in state, reporting whether it
+// certainly accesses a tile outside the floor
+func (a *rangeAnalysis) tiles(inst instructions.DisassembleArgInstruction, state rangeState) (first, last int, outside bool) {
+	count := len(state.tiles)
+	if !inst.Indirect {
+		if int(inst.Arg) >= count {
+			return 0, -1, true
+		}
+		return int(inst.Arg), int(inst.Arg), false
+	}
+	if int(inst.Arg) >= count {
+		return 0, -1, true
+	}
+	pointer := state.tiles[inst.Arg].restrict(0, count-1)
+	if !pointer.numbers {
+		return 0, -1, true
+	}
+	return pointer.min, pointer.max, false
+}
+
+// Return the state after inst is performed in state
+func (a *rangeAnalysis) transfer(diss instructions.DisassembleInterface, state rangeState) rangeState {
+	state = state.copy()
+	switch inst := diss.(type) {
+	case instructions.DisassembleArgInstruction:
+		first, last, outside := a.tiles(inst, state)
+		if outside {
+			// The worker stops with an error
+			return state
+		}
+		var tile valueRange
+		for i := first; i <= last; i++ {
+			tile = tile.join(state.tiles[i])
+		}
+		switch inst.Op {
+		case instructions.OP_COPY_FROM:
+			state.hand = tile
+		case instructions.OP_COPY_TO:
+			for i := first; i <= last; i++ {
+				if first == last {
+					state.tiles[i] = state.hand
+				} else {
+					state.tiles[i] = state.tiles[i].join(state.hand)
+				}
+			}
+		case instructions.OP_ADD:
+			hand := valueRange{}
+			if state.hand.numbers && tile.numbers {
+				hand = numberRange(state.hand.min+tile.min, state.hand.max+tile.max)
+			}
+			state.hand = hand
+		case instructions.OP_SUB:
+			hand := valueRange{}
+			if state.hand.numbers && tile.numbers {
+				hand = numberRange(state.hand.min-tile.max, state.hand.max-tile.min)
+			}
+			if state.hand.letters && tile.letters {
+				hand = hand.join(numberRange(-25, 25))
+			}
+			state.hand = hand
+		case instructions.OP_BUMP_PLUS, instructions.OP_BUMP_MINUS:
+			delta := 1
+			if inst.Op == instructions.OP_BUMP_MINUS {
+				delta = -1
+			}
+			bumped := valueRange{}
+			if tile.numbers {
+				bumped = numberRange(tile.min+delta, tile.max+delta)
+			}
+			for i := first; i <= last; i++ {
+				if first == last {
+					state.tiles[i] = bumped
+				} else {
+					state.tiles[i] = state.tiles[i].join(bumped)
+				}
+			}
+			state.hand = bumped
+		}
+	case instructions.DisassembleInstruction:
+		switch inst.Op {
+		case instructions.OP_INBOX:
+			state.hand = a.inbox
+		case instructions.OP_OUTBOX:
+			state.hand = valueRange{empty: true}
+		}
+	}
+	return state
+}
+
+// Propagate the ranges through the program until they no longer change
+func (a *rangeAnalysis) run() {
+	g := a.pass.Graph
+	entry := g.Entry()
+	if entry == END {
+		return
+	}
+	initial := rangeState{hand: valueRange{empty: true}}
+	for _, tile := range a.pass.Level.Tiles() {
+		if tile.Filled {
+			initial.tiles = append(initial.tiles, exactRange(tile.Value))
+		} else {
+			initial.tiles = append(initial.tiles, valueRange{empty: true})
+		}
+	}
+	a.states[entry] = initial
+	updates := make(map[int]int)
+	queue := []int{entry}
+	queued := map[int]bool{entry: true}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		queued[node] = false
+
+		state := a.states[node]
+		successors := make(map[int]rangeState)
+		switch jump := a.pass.Program[node].(type) {
+		case instructions.DisassembleJumpInstruction:
+			target := END
+			if jump.Target >= 0 {
+				target = nextInstruction(a.pass.Program, jump.Target)
+			}
+			if jump.Op == instructions.OP_JUMP {
+				successors[target] = state
+				break
+			}
+			zero := jump.Op == instructions.OP_JUMP_ZERO
+			taken, notTaken := state.copy(), state.copy()
+			taken.hand, notTaken.hand = state.hand.branch(zero, true), state.hand.branch(zero, false)
+			next := nextInstruction(a.pass.Program, node+1)
+			if !taken.hand.impossible() {
+				successors[target] = taken
+			}
+			if !notTaken.hand.impossible() {
+				if other, found := successors[next]; found {
+					notTaken = joinStates(other, notTaken)
+				}
+				successors[next] = notTaken
+			}
+		default:
+			after := a.transfer(a.pass.Program[node], state)
+			for _, s := range g.Successors[node] {
+				successors[s] = after
+			}
+		}
+
+		for s, next := range successors {
+			if s == END {
+				continue
+			}
+			current, reached := a.states[s]
+			if reached {
+				joined := joinStates(current, next)
+				if joined.equal(current) {
+					continue
+				}
+				updates[s]++
+				if updates[s] > wideningThreshold {
+					joined.hand = current.hand.widen(joined.hand)
+					for i := range joined.tiles {
+						joined.tiles[i] = current.tiles[i].widen(joined.tiles[i])
+					}
+				}
+				next = joined
+			}
+			a.states[s] = next
+			if !queued[s] {
+				queued[s] = true
+				queue = append(queue, s)
+			}
+		}
+	}
+}
+
+func joinStates(a, b rangeState) rangeState {
+	joined := a.copy()
+	joined.hand = joined.hand.join(b.hand)
+	for i := range joined.tiles {
+		joined.tiles[i] = joined.tiles[i].join(b.tiles[i])
+	}
+	return joined
+}
+
+func checkValueRanges(pass *Pass) {
+	if pass.Level == nil {
+		return
+	}
+	constraints := pass.Level.Inbox()
+	inbox := valueRange{letters: constraints.Letters}
+	if constraints.Numbers {
+		inbox = inbox.join(numberRange(constraints.Min, constraints.Max))
+	}
+	a := &rangeAnalysis{pass: pass, inbox: inbox, states: make(map[int]rangeState)}
+	a.run()
+
+	for _, node := range pass.Graph.Nodes {
+		state, reached := a.states[node]
+		if !reached {
+			continue
+		}
+		switch inst := pass.Program[node].(type) {
+		case instructions.DisassembleJumpInstruction:
+			if inst.Op == instructions.OP_JUMP || state.hand.impossible() || (state.hand.empty && !state.hand.numbers && !state.hand.letters) {
+				continue
+			}
+			zero := inst.Op == instructions.OP_JUMP_ZERO
+			switch {
+			case state.hand.branch(zero, true).impossible():
+				pass.Report(node, SEVERITY_WARNING, "value-ranges", "%s %s is never taken", inst.Op, inst.TargetLabel)
+			case state.hand.branch(zero, false).impossible():
+				pass.Report(node, SEVERITY_WARNING, "value-ranges", "%s %s is always taken", inst.Op, inst.TargetLabel)
+			}
+		case instructions.DisassembleArgInstruction:
+			if _, _, outside := a.tiles(inst, state); outside {
+				pass.Report(node, SEVERITY_ERROR, "value-ranges", "%s accesses a tile outside the floor (%d tiles)", inst.Op, pass.Level.TileCount())
+			}
+		}
+	}
+}
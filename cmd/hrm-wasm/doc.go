@@ -0,0 +1,8 @@
+// Command hrm-wasm exposes profile decoding, rendering and the VM to
+// JavaScript, for the playground served by hrm serve. Build with:
+//
+//	GOOS=js GOARCH=wasm go build -o hrm.wasm
+//
+// The functions are registered as globals taking and returning strings
+// (JSON for structured results), errors are returned as {"error": ...}
+package main
@@ -0,0 +1,27 @@
+module github.com/clj/hrm-profile-tool/cmd/hrm-wasm
+
+require (
+	github.com/ajstarks/svgo v0.0.0-20180830174826-7338bd80e790 // indirect
+	github.com/clj/hrm-profile-tool/instructions v0.0.0
+	github.com/clj/hrm-profile-tool/levels v0.0.0
+	github.com/clj/hrm-profile-tool/schema v0.0.0
+	github.com/clj/hrm-profile-tool/profile v0.0.0
+	github.com/clj/hrm-profile-tool/render v0.0.0
+	github.com/clj/hrm-profile-tool/vm v0.0.0
+)
+
+replace github.com/clj/hrm-profile-tool/instructions => ../../instructions
+
+replace github.com/clj/hrm-profile-tool/profile => ../../profile
+
+replace github.com/clj/hrm-profile-tool/render => ../../render
+
+replace github.com/clj/hrm-profile-tool/vm => ../../vm
+
+replace github.com/clj/hrm-profile-tool/utils/text => ../../utils/text
+
+replace github.com/clj/hrm-profile-tool/utils/logging => ../../utils/logging
+
+replace github.com/clj/hrm-profile-tool/schema => ../../schema
+
+replace github.com/clj/hrm-profile-tool/levels => ../../levels
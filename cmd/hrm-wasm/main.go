@@ -0,0 +1,126 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"syscall/js"
+
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/clj/hrm-profile-tool/vm"
+)
+
+// The profile loaded by hrmLoadProfile
+var loaded *profile.Profile
+
+func jsonResult(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return jsonError(err)
+	}
+	return string(data)
+}
+
+func jsonError(err error) interface{} {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(data)
+}
+
+// hrmLoadProfile(bytes: Uint8Array): JSON profile (see the schema package)
+func loadProfile(this js.Value, args []js.Value) interface{} {
+	data := make([]byte, args[0].Get("length").Int())
+	js.CopyBytesToGo(data, args[0])
+	layout, found := profile.DetectLayout(int64(len(data)))
+	if !found {
+		layout = profile.DefaultLayout
+	}
+	p, err := profile.Decode(bytes.NewReader(data), profile.DecodeLayout(layout))
+	if err != nil {
+		return jsonError(err)
+	}
+	loaded = &p
+	return jsonResult(p.Schema(1))
+}
+
+// Return the tab selected by the floor and tab (starting at 1) arguments
+func selectedTab(args []js.Value) (profile.Tab, error) {
+	if loaded == nil {
+		return profile.Tab{}, fmt.Errorf("no profile loaded")
+	}
+	if len(args) < 2 {
+		return profile.Tab{}, fmt.Errorf("expected floor and tab arguments")
+	}
+	floor, tab := args[0].Int(), args[1].Int()
+	f, err := loaded.GetFloor(floor)
+	if err != nil || tab < 1 || tab > 3 {
+		return profile.Tab{}, fmt.Errorf("no floor %d tab %d", floor, tab)
+	}
+	return f.Tabs[tab-1], nil
+}
+
+// hrmRenderSVG(floor, tab): SVG string
+func renderSVG(this js.Value, args []js.Value) interface{} {
+	tab, err := selectedTab(args)
+	if err != nil {
+		return jsonError(err)
+	}
+	return render.RenderSVG(tab.Code, tab.Comments)
+}
+
+// hrmRenderText(floor, tab): program text
+func renderText(this js.Value, args []js.Value) interface{} {
+	tab, err := selectedTab(args)
+	if err != nil {
+		return jsonError(err)
+	}
+	return render.RenderText(render.Program{Disassembled: tab.Code, RawComments: tab.RawComments, Comments: tab.Comments})
+}
+
+// hrmRun(floor, tab, inbox): JSON {outbox, steps} of running the program
+// with the comma separated inbox values
+func run(this js.Value, args []js.Value) interface{} {
+	tab, err := selectedTab(args)
+	if err != nil {
+		return jsonError(err)
+	}
+	var inbox []levels.Value
+	if len(args) > 2 {
+		for _, field := range strings.FieldsFunc(args[2].String(), func(r rune) bool { return r == ',' || r == ' ' }) {
+			value, err := vm.ParseValue(field)
+			if err != nil {
+				return jsonError(err)
+			}
+			inbox = append(inbox, value)
+		}
+	}
+	level := levels.Floor(args[0].Int())
+	m := vm.NewForLevel(tab.Code, inbox, level)
+	result := struct {
+		Outbox []string `json:"outbox"`
+		Steps  int      `json:"steps"`
+		Error  string   `json:"error,omitempty"`
+	}{Outbox: []string{}}
+	if err := m.Run(context.Background(), 0); err != nil {
+		result.Error = err.Error()
+	}
+	for _, value := range m.Outbox {
+		result.Outbox = append(result.Outbox, value.String())
+	}
+	result.Steps = m.Steps
+	return jsonResult(result)
+}
+
+func main() {
+	js.Global().Set("hrmLoadProfile", js.FuncOf(loadProfile))
+	js.Global().Set("hrmRenderSVG", js.FuncOf(renderSVG))
+	js.Global().Set("hrmRenderText", js.FuncOf(renderText))
+	js.Global().Set("hrmRun", js.FuncOf(run))
+	js.Global().Call("dispatchEvent", js.Global().Get("Event").New("hrmready"))
+	select {}
+}
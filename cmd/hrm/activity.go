@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	activityLogPath string
+	activityDisable bool
+)
+
+// A challenge result which changed, -1 for no result
+type activityDelta struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// The changes of a floor between two saves of a profile
+type activityFloor struct {
+	Floor int `json:"floor"`
+	// The tabs whose program changed
+	Tabs      []int          `json:"tabs,omitempty"`
+	Completed bool           `json:"completed,omitempty"`
+	Size      *activityDelta `json:"size,omitempty"`
+	Steps     *activityDelta `json:"steps,omitempty"`
+}
+
+// A record of the activity log: a save of a profile seen by a watching
+// command, and the floors which changed since the previous save
+type activityRecord struct {
+	Time    time.Time       `json:"time"`
+	Profile string          `json:"profile"`
+	Slot    int             `json:"slot"`
+	Floors  []activityFloor `json:"floors,omitempty"`
+}
+
+// Return the path of the activity log: --activity-log, or else
+// activity.jsonl in the user's configuration directory
+func activityLogFile() (string, error) {
+	if activityLogPath != "" {
+		return activityLogPath, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hrm-profile-tool", "activity.jsonl"), nil
+}
+
+// Return the floors which changed between older and newer
+func activityFloors(older, newer profile.Profile) []activityFloor {
+	var floors []activityFloor
+	for floorIndex := range newer.Floors {
+		o, n := older.Floors[floorIndex], newer.Floors[floorIndex]
+		floor := activityFloor{Floor: floorNumber(floorIndex), Completed: n.Completed && !o.Completed}
+		for tab := range n.Tabs {
+			if n.Tabs[tab].Hash() != o.Tabs[tab].Hash() {
+				floor.Tabs = append(floor.Tabs, tab+1)
+			}
+		}
+		if o.SizeChallenge != n.SizeChallenge {
+			floor.Size = &activityDelta{o.SizeChallenge, n.SizeChallenge}
+		}
+		if o.SpeedChallenge != n.SpeedChallenge {
+			floor.Steps = &activityDelta{o.SpeedChallenge, n.SpeedChallenge}
+		}
+		if len(floor.Tabs) > 0 || floor.Completed || floor.Size != nil || floor.Steps != nil {
+			floors = append(floors, floor)
+		}
+	}
+	return floors
+}
+
+// Records the saves of a profile in the activity log, as seen by a
+// watching command
+type activityTracker struct {
+	path string
+	slot int
+	last *profile.Profile
+}
+
+// Return a tracker of the save slot of the profile at path, nil with
+// --no-activity
+func newActivityTracker(path string, slot int) *activityTracker {
+	if activityDisable {
+		return nil
+	}
+	return &activityTracker{path: activityProfilePath(path), slot: slot}
+}
+
+// Return the path of a profile as recorded in the activity log, i.e.
+// absolute
+func activityProfilePath(path string) string {
+	if absolute, err := filepath.Abs(path); err == nil {
+		return absolute
+	}
+	return path
+}
+
+// Record the save p of the profile, unless it is the first one seen
+func (t *activityTracker) observe(p profile.Profile) {
+	if t == nil {
+		return
+	}
+	last := t.last
+	t.last = &p
+	if last == nil {
+		return
+	}
+	record := activityRecord{Time: time.Now(), Profile: t.path, Slot: t.slot, Floors: activityFloors(*last, p)}
+	if err := appendActivity(record); err != nil {
+		logger.Warn("cannot record the activity", "error", err)
+	}
+}
+
+// Decode the tracked save slot of the profile and record it
+func (t *activityTracker) observeFile() {
+	if t == nil {
+		return
+	}
+	reader, err := openProfileAt(t.path)
+	if err != nil {
+		logger.Warn("cannot read the profile for the activity log", "path", t.path, "error", err)
+		return
+	}
+	defer reader.Close()
+	p, err := decodeSlot(reader, t.slot)
+	if err != nil {
+		logger.Warn("cannot read the profile for the activity log", "path", t.path, "error", err)
+		return
+	}
+	t.observe(p)
+}
+
+// Append a record to the activity log
+func appendActivity(record activityRecord) error {
+	path, err := activityLogFile()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	logger.Debug("recorded activity", "log", path, "floors", len(record.Floors))
+	return file.Close()
+}
+
+// Read the records of the activity log, in the order recorded
+func readActivity() ([]activityRecord, error) {
+	path, err := activityLogFile()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var records []activityRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record activityRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", path, line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// Add the flags of the activity log to a watching command
+func addActivityFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&activityLogPath, "activity-log", "", "Record the saves of the profile in the activity log `FILE` (default activity.jsonl in the configuration directory)")
+	cmd.Flags().BoolVar(&activityDisable, "no-activity", false, "Do not record the saves of the profile in the activity log")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/journal"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importAnnotationsForce bool
+)
+
+func exportAnnotations(cmd *cobra.Command, args []string) {
+	floorIndex, tab := floorTabArgs(args)
+	reader := openProfile()
+	defer reader.Close()
+	program := decodeTab(reader, slotNumber, floorIndex, tab)
+	if len(program.RawComments) == 0 {
+		fatalf("floor %d tab %d has no comments", floorNumber(floorIndex), tab+1)
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Fprintf(output, "-- COMMENTS OF FLOOR %d TAB %d --\n\n", floorNumber(floorIndex), tab+1)
+	if _, err := output.Write([]byte(render.RenderCommentsText(program.RawComments, render.GameIdenticalComments()))); err != nil {
+		fatal(err)
+	}
+	if err := output.Close(); err != nil {
+		fatal(err)
+	}
+}
+
+func importAnnotations(cmd *cobra.Command, args []string) {
+	floorIndex, tab := floorTabArgs(args[1:])
+	floor := floorNumber(floorIndex)
+	path := editProfilePath()
+
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		fatal(err)
+	}
+	// Only the comment definitions are imported, instructions in the file
+	// (e.g. a whole program copied from the game) are left out
+	var opts []instructions.AssembleOption
+	if mnemonics := textMnemonics(); mnemonics != nil {
+		opts = append(opts, instructions.AssembleMnemonics(mnemonics))
+	}
+	_, comments, err := instructions.Assemble(string(data), opts...)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", args[0], err))
+	}
+	if len(comments) == 0 {
+		fatalf("%s: no comment definitions (DEFINE COMMENT) found", args[0])
+	}
+	block, err := instructions.EncodeComments(comments)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", args[0], err))
+	}
+
+	reader, err := openProfileAt(path)
+	if err != nil {
+		fatal(err)
+	}
+	program := decodeTab(reader, slotNumber, floorIndex, tab)
+	layout := profileLayout(reader)
+	reader.Close()
+
+	// The program places the comments by their number, which the file
+	// must define
+	missing := 0
+	for _, diss := range program.Disassembled {
+		if comment, ok := diss.(instructions.DisassembleComment); ok && int(comment.Index) >= len(comments) {
+			missing++
+			logger.Warn("the program shows a comment the file does not define", "comment", comment.Index)
+		}
+	}
+	if missing > 0 && !importAnnotationsForce {
+		fatalf("the program of floor %d tab %d shows %d comment(s) not defined in %s, use --force to import anyway", floor, tab+1, missing, args[0])
+	}
+
+	offset := layout.TabStartAddr(slotNumber, floorIndex, tab) + instructions.INSTRUCTIONS_BLOCK_SIZE
+	description := fmt.Sprintf("floor %d tab %d: import %d comment(s) from %s", floor, tab+1, len(comments), args[0])
+	applyEdit("import-annotations", path, description, []journal.Change{{Offset: offset, Modified: block}})
+}
+
+func exportAnnotationsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-annotations FLOOR TAB",
+		Short: "Export the comments of a tab",
+		Long: `Export the comment drawings of a tab, without its instructions, as the
+DEFINE COMMENT sections of the game's text format. Import them into a tab
+with hrm import-annotations, e.g. to keep the comments of a solution that
+is being redone.`,
+		Args: cobra.ExactArgs(2),
+		Run:  exportAnnotations,
+	}
+	addSlotFlag(cmd, "read")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the comments to")
+	return cmd
+}
+
+func importAnnotationsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import-annotations FILE FLOOR TAB",
+		Short: "Replace the comments of a tab",
+		Long: `Replace the comment drawings of a tab with those defined in FILE (- for
+stdin), as written by hrm export-annotations or copied from the game,
+leaving its instructions as they are. The COMMENT instructions of the
+program show the drawings by number, so the file must define every
+number the program uses, unless --force is given.
+
+The profile is backed up first and the change is recorded in the edit
+journal, revert it with hrm undo`,
+		Args: cobra.ExactArgs(3),
+		Run:  importAnnotations,
+	}
+	cmd.Flags().BoolVar(&importAnnotationsForce, "force", false, "Import the comments even if the program uses comments the file does not define")
+	addEditFlags(cmd)
+	return cmd
+}
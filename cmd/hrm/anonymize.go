@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	anonymizeOutput        string
+	anonymizeForce         bool
+	anonymizeStripComments bool
+)
+
+func anonymize(cmd *cobra.Command, args []string) {
+	if anonymizeOutput == "" {
+		usageFatalf("--output is required")
+	}
+	path, err := profileFilePath()
+	if err != nil {
+		fatal(err)
+	}
+	if !isProfileURL(path) {
+		if in, err := filepath.Abs(path); err == nil {
+			if out, err := filepath.Abs(anonymizeOutput); err == nil && in == out {
+				usageFatalf("--output must not be the profile itself")
+			}
+		}
+	}
+	reader, err := openProfileAt(path)
+	if err != nil {
+		fatal(err)
+	}
+	defer reader.Close()
+	size, err := profileSize(reader)
+	if err != nil {
+		fatal(err)
+	}
+	data, err := ioutil.ReadAll(io.NewSectionReader(reader, 0, size))
+	if err != nil {
+		fatal(err)
+	}
+	var opts []profile.AnonymizeOption
+	if anonymizeStripComments {
+		opts = append(opts, profile.StripComments())
+	}
+	data, stats, err := profileLayout(reader).Anonymize(data, opts...)
+	if err != nil {
+		fatal(err)
+	}
+
+	verb := "Wrote"
+	if dryRun {
+		if _, err := os.Stat(anonymizeOutput); err == nil && !anonymizeForce {
+			usageFatalf("%s already exists, use --force to overwrite it", anonymizeOutput)
+		}
+		verb = "Would write"
+	} else {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+		if anonymizeForce {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		}
+		file, err := os.OpenFile(anonymizeOutput, flags, 0644)
+		if os.IsExist(err) {
+			usageFatalf("%s already exists, use --force to overwrite it", anonymizeOutput)
+		} else if err != nil {
+			fatal(err)
+		}
+		if _, err := file.Write(data); err != nil {
+			file.Close()
+			fatal(err)
+		}
+		if err := file.Close(); err != nil {
+			fatal(err)
+		}
+	}
+	fmt.Printf("%s anonymized profile %s (%d bytes cleared", verb, anonymizeOutput, stats.ChangedBytes)
+	if anonymizeStripComments {
+		fmt.Printf(", %d comment drawing(s) removed", stats.StrippedComments)
+	}
+	if stats.TrailingBytes > 0 {
+		fmt.Printf(", %d trailing byte(s) dropped", stats.TrailingBytes)
+	}
+	fmt.Println(")")
+	if dryRun {
+		fmt.Println("Dry run, nothing was changed")
+	}
+}
+
+func anonymizeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "anonymize -o OUTPUT",
+		Short: "Write a copy of the profile safe for sharing",
+		Long: `Write a copy of the profile to OUTPUT for attaching to bug reports, with
+the programs and scores unchanged.
+
+The game is not known to store a name or any other identifying data in
+the profile, so the copy clears what could hold it unnoticed: the
+undecoded header of every save slot, and the stale data left after the
+instructions and comments of every tab (e.g. the drawings of deleted
+comments). With --strip-comments the drawings of the comments are
+removed as well; the comments remain, empty, in the programs.`,
+		Args: cobra.NoArgs,
+		Run:  anonymize,
+	}
+	cmd.Flags().StringVarP(&anonymizeOutput, "output", "o", "", "Write the anonymized profile to `PATH`")
+	cmd.Flags().BoolVarP(&anonymizeForce, "force", "f", false, "Overwrite the output if it exists")
+	cmd.Flags().BoolVar(&anonymizeStripComments, "strip-comments", false, "Remove the drawings of the comments")
+	return cmd
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/analysis"
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/vm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditReplays string
+)
+
+// A recorded result of a floor which none of its programs can have
+// achieved
+type auditMismatch struct {
+	floor   int
+	result  string
+	message string
+}
+
+func writeAuditText(w io.Writer, mismatches []auditMismatch) error {
+	for _, mismatch := range mismatches {
+		level := levels.Floor(mismatch.floor)
+		if _, err := fmt.Fprintf(w, "floor %d %s: %s: %s\n", mismatch.floor, levelName(level), mismatch.result, mismatch.message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeAuditCSV(w io.Writer, mismatches []auditMismatch) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"floor", "name", "result", "message"})
+	for _, mismatch := range mismatches {
+		cw.Write([]string{strconv.Itoa(mismatch.floor), levels.Floor(mismatch.floor).Name, mismatch.result, mismatch.message})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeAuditJSON(w io.Writer, mismatches []auditMismatch) error {
+	type jsonMismatch struct {
+		Floor   int    `json:"floor"`
+		Name    string `json:"name"`
+		Result  string `json:"result"`
+		Message string `json:"message"`
+	}
+	view := []jsonMismatch{}
+	for _, mismatch := range mismatches {
+		view = append(view, jsonMismatch{mismatch.floor, levels.Floor(mismatch.floor).Name, mismatch.result, mismatch.message})
+	}
+	data, err := json.MarshalIndent(view, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// Read the replays in dir, by floor
+func readReplayDir(dir string) (map[int][]replay, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	replays := make(map[int][]replay)
+	for _, path := range paths {
+		r, err := readReplay(path)
+		if err != nil {
+			return nil, err
+		}
+		replays[r.Floor] = append(replays[r.Floor], r)
+	}
+	return replays, nil
+}
+
+// Check the recorded size of a floor against the sizes of its programs, by
+// tab (0 for empty tabs)
+func auditSize(recorded int, sizes [3]int) string {
+	smallest, smallestTab := 0, 0
+	for tab, size := range sizes {
+		if size == recorded {
+			return ""
+		}
+		if size > 0 && (smallest == 0 || size < smallest) {
+			smallest, smallestTab = size, tab+1
+		}
+	}
+	if smallest == 0 {
+		return fmt.Sprintf("recorded %d instructions, but no tab has a program", recorded)
+	}
+	if recorded < smallest {
+		return fmt.Sprintf("recorded %d instructions, the smallest program (tab %d) has %d", recorded, smallestTab, smallest)
+	}
+	return fmt.Sprintf("recorded %d instructions, no program has that size, the smallest (tab %d) has %d", recorded, smallestTab, smallest)
+}
+
+// Check the recorded steps of a floor by running its programs on the
+// inboxes of the replays, returning the measured step counts if none match
+func auditStepsMeasured(recorded int, floor profile.Floor, level levels.Level, replays []replay) string {
+	var measured []string
+	for tab, t := range floor.Tabs {
+		if t.Code.Size() == 0 {
+			continue
+		}
+		for _, r := range replays {
+			inbox, err := r.inbox()
+			if err != nil {
+				logger.Warn("invalid replay inbox", "floor", level.Floor, "error", err)
+				continue
+			}
+			m := vm.NewForLevel(t.Code, inbox, level)
+			if err := m.Run(appContext, 0); err != nil {
+				logger.Debug("run failed", "floor", level.Floor, "tab", tab+1, "error", err)
+				continue
+			}
+			if m.Steps == recorded {
+				return ""
+			}
+			measured = append(measured, fmt.Sprintf("%d (tab %d)", m.Steps, tab+1))
+		}
+	}
+	if len(measured) == 0 {
+		return fmt.Sprintf("recorded %d steps, but no program runs on the inboxes of the replays", recorded)
+	}
+	return fmt.Sprintf("recorded %d steps, measured %s", recorded, strings.Join(measured, ", "))
+}
+
+// Check the recorded steps of a floor against the step bounds of its
+// programs, estimated for the level's inbox length
+func auditStepsEstimated(recorded int, floor profile.Floor, level levels.Level) string {
+	inboxLength := level.Inbox().MaxLength
+	if inboxLength == 0 {
+		return ""
+	}
+	var bounds []string
+	for tab, t := range floor.Tabs {
+		if t.Code.Size() == 0 {
+			continue
+		}
+		b := analysis.EstimateSteps(t.Code, inboxLength)
+		switch {
+		case b.NeverEnds:
+			bounds = append(bounds, fmt.Sprintf("never ends (tab %d)", tab+1))
+			continue
+		case recorded >= b.Best && (b.Unbounded || recorded <= b.Worst):
+			return ""
+		case b.Unbounded:
+			bounds = append(bounds, fmt.Sprintf("%d or more (tab %d)", b.Best, tab+1))
+		default:
+			bounds = append(bounds, fmt.Sprintf("%d-%d (tab %d)", b.Best, b.Worst, tab+1))
+		}
+	}
+	if len(bounds) == 0 {
+		return fmt.Sprintf("recorded %d steps, but no tab has a program", recorded)
+	}
+	return fmt.Sprintf("recorded %d steps, estimated %s", recorded, strings.Join(bounds, ", "))
+}
+
+func audit(cmd *cobra.Command, args []string) {
+	var mismatches []auditMismatch
+	write := reportWriters{
+		"text": func(w io.Writer) error { return writeAuditText(w, mismatches) },
+		"csv":  func(w io.Writer) error { return writeAuditCSV(w, mismatches) },
+		"json": func(w io.Writer) error { return writeAuditJSON(w, mismatches) },
+	}.selected()
+
+	var replays map[int][]replay
+	if auditReplays != "" {
+		var err error
+		if replays, err = readReplayDir(auditReplays); err != nil {
+			fatal(err)
+		}
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+	checkSlot(reader, slotNumber)
+	p, err := decodeSlot(reader, slotNumber)
+	if err != nil {
+		fatal(err)
+	}
+
+	checked := 0
+	for floorIndex, floor := range p.Floors {
+		number := floorNumber(floorIndex)
+		level, found := levels.Lookup(number)
+		if !found || (floor.SizeChallenge < 0 && floor.SpeedChallenge < 0) {
+			continue
+		}
+		checked++
+		var sizes [3]int
+		for tab, t := range floor.Tabs {
+			sizes[tab] = t.Code.Size()
+		}
+		if floor.SizeChallenge >= 0 {
+			if message := auditSize(floor.SizeChallenge, sizes); message != "" {
+				mismatches = append(mismatches, auditMismatch{number, "size", message})
+			}
+		}
+		if floor.SpeedChallenge >= 0 {
+			message := ""
+			if len(replays[number]) > 0 {
+				message = auditStepsMeasured(floor.SpeedChallenge, floor, level, replays[number])
+			} else {
+				message = auditStepsEstimated(floor.SpeedChallenge, floor, level)
+			}
+			if message != "" {
+				mismatches = append(mismatches, auditMismatch{number, "steps", message})
+			}
+		}
+	}
+
+	sort.SliceStable(mismatches, func(i, j int) bool { return mismatches[i].floor < mismatches[j].floor })
+	writeReport(write)
+	if !logQuiet {
+		fmt.Fprintf(os.Stderr, "%d floor(s) with recorded results checked, %d mismatch(es)\n", checked, len(mismatches))
+	}
+	if len(mismatches) > 0 {
+		os.Exit(1)
+	}
+}
+
+func auditCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Check the recorded sizes and steps against the programs",
+		Long: `Check the size and steps recorded for each floor against the programs of its
+tabs, reporting results which none of the programs can have achieved, as
+happens when cloud sync mixes the results of one computer with the
+programs of another. The exit status is 1 if a mismatch was found.
+
+A recorded size matches if a tab's program has that many instructions. The
+steps are measured by running the programs on the inboxes of the replays
+in --replays (written by hrm run --record, e.g. with the inbox shown in the
+game); for floors without a replay they match if they lie within the
+bounds of hrm estimate for the level's inbox length.
+
+The mismatches are written as text, or as CSV or JSON with --format or an
+output file name ending in .csv or .json`,
+		Args: cobra.NoArgs,
+		Run:  audit,
+	}
+	addSlotFlag(cmd, "check")
+	cmd.Flags().StringVar(&auditReplays, "replays", "", "Measure the steps on the inboxes of the replays in `DIR`")
+	addReportFlags(cmd, "mismatches", "text", "csv", "json")
+	return cmd
+}
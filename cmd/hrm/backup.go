@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Return the backup copies of the profile at path, most recently modified
+// first. Backups are siblings of the profile named like it with a backup
+// suffix (profiles.bin.bak, profiles.bak, profiles.bin~, the backups made
+// before modifying the profile, ...) and the copy Steam keeps in its
+// remote cache (../remote/profiles.bin)
+func profileBackups(path string) ([]string, error) {
+	dir, name := filepath.Split(path)
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	patterns := []string{
+		name + ".bak", name + ".bak*", name + ".*.bak", name + ".old", name + "~",
+		base + ".bak", base + ".bak*",
+		filepath.Join("..", "remote", name),
+	}
+	seen := map[string]bool{filepath.Clean(path): true}
+	type backup struct {
+		path string
+		info os.FileInfo
+	}
+	var backups []backup
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			match = filepath.Clean(match)
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
+			info, err := os.Stat(match)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			backups = append(backups, backup{match, info})
+		}
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].info.ModTime().After(backups[j].info.ModTime())
+	})
+	paths := make([]string, len(backups))
+	for i, backup := range backups {
+		paths[i] = backup.path
+	}
+	return paths, nil
+}
+
+// Return the most recent backup of the profile at path
+func latestProfileBackup(path string) (string, error) {
+	if isProfileURL(path) {
+		return "", fmt.Errorf("cannot look for backups of a downloaded profile")
+	}
+	backups, err := profileBackups(path)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) == 0 {
+		return "", fmt.Errorf("no backup found next to %s", path)
+	}
+	return backups[0], nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	badgeMetric string
+	badgeLabel  string
+)
+
+// The metrics a badge can show: the label and a function returning the
+// number of floors meeting the metric from the profile summary
+var badgeMetrics = map[string]struct {
+	label string
+	count func(summary profile.ProfileSummary) int
+}{
+	"completion": {"completion", func(summary profile.ProfileSummary) int {
+		return summary.Solved
+	}},
+	"size-challenges": {"size challenges", func(summary profile.ProfileSummary) int {
+		return summary.SizeChallengesMet
+	}},
+	"speed-challenges": {"speed challenges", func(summary profile.ProfileSummary) int {
+		return summary.SpeedChallengesMet
+	}},
+}
+
+func badge(cmd *cobra.Command, args []string) {
+	metric, found := badgeMetrics[badgeMetric]
+	if !found {
+		usageFatalf("unknown metric %q (available: completion, size-challenges, speed-challenges)", badgeMetric)
+	}
+	summary := profile.Summary(decodeProfile())
+	met, total := metric.count(summary), summary.Floors
+	label := metric.label
+	if badgeLabel != "" {
+		label = badgeLabel
+	}
+	colour := render.BadgeColour(float64(met) / float64(total))
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+	if _, err := io.WriteString(output, render.RenderBadge(label, fmt.Sprintf("%d/%d", met, total), colour)); err != nil {
+		fatal(err)
+	}
+}
+
+func badgeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "badge",
+		Short: "Render a progress badge",
+		Long: `Render a small shields.io style SVG badge showing the progress of a
+profile, e.g. for embedding in the README of a solutions repository`,
+		Args: cobra.NoArgs,
+		Run:  badge,
+	}
+	addSlotFlag(cmd, "read")
+	cmd.Flags().StringVar(&badgeMetric, "metric", "completion", "`METRIC` to show (completion, size-challenges, speed-challenges)")
+	cmd.Flags().StringVar(&badgeLabel, "label", "", "`TEXT` of the badge label (defaults to the metric)")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the SVG to")
+	return cmd
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+// A version of the profile: a backup or the profile itself
+type profileSnapshot struct {
+	path    string
+	modTime time.Time
+}
+
+// Return the backups of the profile at path and the profile itself, oldest
+// first
+func profileSnapshots(path string) ([]profileSnapshot, error) {
+	if isProfileURL(path) {
+		return nil, fmt.Errorf("cannot look for backups of a downloaded profile")
+	}
+	backups, err := profileBackups(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []profileSnapshot
+	for i := len(backups) - 1; i >= 0; i-- {
+		info, err := os.Stat(backups[i])
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, profileSnapshot{backups[i], info.ModTime()})
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return append(snapshots, profileSnapshot{path, info.ModTime()}), nil
+}
+
+// Decode a tab of a snapshot, reporting whether the snapshot holds the
+// slot and the tab could be decoded
+func decodeSnapshotTab(snapshot profileSnapshot, slot, floorIndex, tab int) (render.Program, bool) {
+	reader, err := openProfileAt(snapshot.path)
+	if err != nil {
+		logger.Warn("skipping snapshot", "path", snapshot.path, "error", err)
+		return render.Program{}, false
+	}
+	defer reader.Close()
+	if size, err := profileSize(reader); err != nil || profileLayout(reader).SlotCount(size) < slot {
+		logger.Warn("skipping snapshot without the slot", "path", snapshot.path, "slot", slot)
+		return render.Program{}, false
+	}
+	program, err := render.DecodeProgramAt(appContext, reader, profileLayout(reader).TabStartAddr(slot, floorIndex, tab), instructions.Logger(logger))
+	if err != nil {
+		logger.Warn("skipping snapshot", "path", snapshot.path, "error", err)
+		return render.Program{}, false
+	}
+	return program, true
+}
+
+func blame(cmd *cobra.Command, args []string) {
+	floor := parseInt(args[0])
+	tab := parseInt(args[1]) - 1
+	checkSlotNumber(slotNumber)
+	path, err := profileFilePath()
+	if err != nil {
+		fatal(err)
+	}
+	snapshots, err := profileSnapshots(path)
+	if err != nil {
+		fatal(err)
+	}
+
+	floorIndex := floorIndexArg(floor)
+
+	var versions []instructions.Disassembled
+	var versionSnapshots []profileSnapshot
+	var current render.Program
+	for _, snapshot := range snapshots {
+		program, ok := decodeSnapshotTab(snapshot, slotNumber, floorIndex, tab)
+		if !ok {
+			continue
+		}
+		versions = append(versions, program.Disassembled)
+		versionSnapshots = append(versionSnapshots, snapshot)
+		current = program
+	}
+	if len(versions) == 0 || versionSnapshots[len(versionSnapshots)-1].path != path {
+		fatal(fmt.Errorf("cannot decode floor %d tab %d of %s", floor, tab+1, path))
+	}
+	if len(current.Disassembled) == 0 {
+		fmt.Printf("floor %d tab %d is empty\n", floor, tab+1)
+		return
+	}
+
+	var opts []render.RenderInstructionsTextOption
+	if mnemonics := textMnemonics(); mnemonics != nil {
+		opts = append(opts, render.UseMnemonics(mnemonics))
+	}
+	lines := strings.Split(render.RenderInstructionsText(current.Disassembled, opts...), "\n")
+	width := 0
+	for _, snapshot := range versionSnapshots {
+		if len(filepath.Base(snapshot.path)) > width {
+			width = len(filepath.Base(snapshot.path))
+		}
+	}
+	for i, version := range instructions.Blame(versions) {
+		switch {
+		case current.Disassembled[i] == nil:
+			continue
+		case version < 0:
+			fmt.Printf("%-17s %-*s %s\n", "", width, "", lines[i])
+		default:
+			snapshot := versionSnapshots[version]
+			// Instructions present in the oldest snapshot may be older
+			boundary := " "
+			if version == 0 {
+				boundary = "^"
+			}
+			fmt.Printf("%s%s %-*s %s\n", boundary, snapshot.modTime.Format("2006-01-02 15:04"), width, filepath.Base(snapshot.path), lines[i])
+		}
+	}
+}
+
+func blameCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "blame FLOOR TAB",
+		Short: "Show when each instruction of a tab last changed",
+		Long: `Annotate each instruction of the program of a tab with the snapshot in which it
+last changed. The snapshots are the backups of the profile (see hrm diff
+--against-backup) and the profile itself, dated by their modification time.
+
+Instructions are compared as by hrm diff-tab, so relabeling does not count as
+a change. Instructions marked with ^ are unchanged since the oldest snapshot
+and may be older.`,
+		Args: cobra.ExactArgs(2),
+		Run:  blame,
+	}
+	addSlotFlag(cmd, "annotate")
+	return cmd
+}
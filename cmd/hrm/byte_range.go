@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	renderOffset string
+	renderLength int
+)
+
+// Report whether a byte range (--offset) is rendered rather than a tab
+func renderingByteRange() bool {
+	return renderOffset != ""
+}
+
+// Validate the arguments of the render commands: FLOOR TAB (or the
+// deprecated PROFILE PROGRAM TAB), or none when rendering a byte range
+func tabOrByteRangeArgs(cmd *cobra.Command, args []string) error {
+	if renderingByteRange() {
+		if len(args) > 0 {
+			return fmt.Errorf("FLOOR TAB cannot be combined with --offset")
+		}
+		return nil
+	}
+	return cobra.RangeArgs(2, 3)(cmd, args)
+}
+
+// Decode the program at --offset in the profile file, reading at most
+// --length bytes. A range too short to hold the comment block is decoded
+// without comments
+func decodeByteRange(reader profileReader) render.Program {
+	offset, err := strconv.ParseInt(renderOffset, 0, 64)
+	if err != nil || offset < 0 {
+		usageFatalf("invalid --offset %q, expected a decimal or 0x prefixed hexadecimal byte offset", renderOffset)
+	}
+	if renderLength < 0 {
+		usageFatalf("--length must not be negative")
+	}
+	if withMetadata || textBanner {
+		usageFatalf("--metadata and --banner require a tab, they cannot be used with --offset")
+	}
+	size, err := profileSize(reader)
+	if err != nil {
+		fatal(err)
+	}
+	if offset >= size {
+		usageFatalf("--offset %s is beyond the end of the file (%d bytes)", renderOffset, size)
+	}
+	length := size - offset
+	if renderLength > 0 && int64(renderLength) < length {
+		length = int64(renderLength)
+	}
+
+	logger.Debug("decoding byte range", "offset", offset, "length", length)
+	section := io.NewSectionReader(reader, offset, length)
+	program, err := render.DecodeProgramAt(appContext, section, 0, programDecodeOptions()...)
+	if err != nil && program.Disassembled != nil && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+		logger.Warn("the byte range ends before the comment block, decoded without comments", "offset", offset, "length", length)
+		program.RawComments, program.Comments = nil, nil
+		err = nil
+	}
+	if err != nil {
+		fatal(fmt.Errorf("offset 0x%X: %w", offset, err))
+	}
+	return program
+}
+
+// Add the flags selecting a byte range to render instead of a tab
+func addByteRangeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&renderOffset, "offset", "", "Decode the program at byte `OFFSET` (e.g. 0x4c) of the --profile file instead of a tab")
+	cmd.Flags().IntVar(&renderLength, "length", 0, "Read at most `N` bytes from --offset (default to the end of the file)")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+// Return the social card of a program: the level name and the size and
+// steps results of the floor
+func programCard(reader profileReader, profileId, floorIndex int, program render.Program) (render.Card, error) {
+	floor := floorNumber(floorIndex)
+	m, err := programMetadata(reader, profileId, floorIndex, program)
+	if err != nil {
+		return render.Card{}, err
+	}
+
+	c := render.Card{Title: fmt.Sprintf("Floor %d", floor)}
+	level, found := levels.Lookup(floor)
+	if found {
+		c.Title = levelName(level)
+		c.Subtitle = fmt.Sprintf("Floor %d", floor)
+	}
+	c.Results = append(c.Results, render.CardResult{
+		Label: "size", Value: m.Size, Goal: level.SizeChallenge, Met: level.SizeChallengeMet(m.Size)})
+	if m.Steps > 0 {
+		c.Results = append(c.Results, render.CardResult{
+			Label: "steps", Value: m.Steps, Goal: level.SpeedChallenge, Met: level.SpeedChallengeMet(m.Steps)})
+	}
+	return c, nil
+}
+
+func card(cmd *cobra.Command, args []string) {
+	if outputFileName == "" && isTerminal(os.Stdout) {
+		usageFatalf("refusing to write png output to a terminal, use --output or redirect stdout")
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+
+	floorIndex, tab := floorTabArgs(args)
+	checkSlot(reader, slotNumber)
+	floor := floorNumber(floorIndex)
+	tabStart := profileLayout(reader).TabStartAddr(slotNumber, floorIndex, tab)
+	program, err := render.DecodeProgramAt(appContext, reader, tabStart, programDecodeOptions()...)
+	if err != nil {
+		fatal(&profile.DecodeError{Offset: tabStart, Floor: floor, Tab: tab + 1, Err: err})
+	}
+	c, err := programCard(reader, slotNumber, floorIndex, program)
+	if err != nil {
+		fatal(err)
+	}
+
+	img, err := render.RenderCardContext(appContext, c, program.Disassembled, program.Comments, render.CardLogger(logger))
+	if err != nil {
+		fatal(err)
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+	if err := png.Encode(output, img); err != nil {
+		fatal(err)
+	}
+}
+
+func cardCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "card FLOOR TAB",
+		Short: "Render a social card",
+		Long: `Render a 1200x630 PNG share image of a program, showing the level name,
+the program, and the size and steps results with challenge checkmarks`,
+		Args: cobra.ExactArgs(2),
+		Run:  card,
+	}
+	addSlotFlag(cmd, "read")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the PNG to")
+	addCommentSpaceFlags(cmd)
+	return cmd
+}
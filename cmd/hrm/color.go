@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// When to color terminal output (--color)
+var colorMode = "auto"
+
+// The values of --color
+var colorModes = []string{"auto", "always", "never"}
+
+// ANSI escape sequences used for terminal output
+const (
+	ansiClear   = "\x1b[H\x1b[2J"
+	ansiReverse = "\x1b[7m"
+	ansiReset   = "\x1b[0m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+)
+
+// Check the value of --color
+func checkColorMode() {
+	for _, mode := range colorModes {
+		if colorMode == mode {
+			return
+		}
+	}
+	usageFatalf("unknown color mode %q (available: %s)", colorMode, strings.Join(colorModes, ", "))
+}
+
+// Report whether output written to f is colored: always with --color
+// always, never with --color never, and otherwise if f is a terminal and
+// the NO_COLOR environment variable is not set (see https://no-color.org)
+func useColor(f *os.File) bool {
+	switch colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && isTerminal(f)
+}
+
+// Return str in the color if color is set
+func paint(color bool, code, str string) string {
+	if !color {
+		return str
+	}
+	return code + str + ansiReset
+}
+
+// Add the --color flag to cmd
+func addColorFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&colorMode, "color", colorMode, "Color terminal output: `WHEN` ("+strings.Join(colorModes, ", ")+"), auto honors NO_COLOR")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/spf13/cobra"
+)
+
+var (
+	commentOrigin    string
+	commentNormalize bool
+)
+
+// Add the flags selecting the coordinate space of the decoded comments,
+// used by the image formats and the formats exporting comment points
+func addCommentSpaceFlags(cmd *cobra.Command) {
+	names := []string{
+		instructions.COMMENT_ORIGIN_TOP_LEFT.String(), instructions.COMMENT_ORIGIN_TOP_RIGHT.String(),
+		instructions.COMMENT_ORIGIN_BOTTOM_LEFT.String(), instructions.COMMENT_ORIGIN_BOTTOM_RIGHT.String(),
+	}
+	cmd.Flags().StringVar(&commentOrigin, "comment-origin", instructions.COMMENT_ORIGIN_TOP_LEFT.String(),
+		"`CORNER` of the comment canvas at which comment points are (0, 0) ("+strings.Join(names, ", ")+")")
+	cmd.Flags().BoolVar(&commentNormalize, "comment-normalize", false, "Scale each comment drawing to fill its canvas")
+}
+
+// Return the options decoding a program with the logger and the comment
+// coordinate space selected on the command line
+func programDecodeOptions() []instructions.DecodeOption {
+	opts := []instructions.DecodeOption{instructions.Logger(logger)}
+	if commentOrigin != "" {
+		origin, found := instructions.ParseCommentOrigin(commentOrigin)
+		if !found {
+			usageFatalf("unknown --comment-origin %q", commentOrigin)
+		}
+		opts = append(opts, instructions.CommentCoordinates(origin))
+	}
+	if commentNormalize {
+		opts = append(opts, instructions.NormalizeComments())
+	}
+	return opts
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonAddr     string
+	daemonInterval time.Duration
+	daemonWasmDir  string
+)
+
+// A daemon config: the profiles watched by hrm daemon
+type daemonConfig struct {
+	// The directory holding the snapshots and exports, in a directory
+	// per target
+	Dir string
+	// The number of snapshots kept per target
+	Keep    int
+	Targets []daemonTarget
+}
+
+// A profile watched by the daemon
+type daemonTarget struct {
+	// The line of the config the target is defined on
+	line    int
+	Name    string
+	Profile string
+	Slot    int
+	// The format of the exports
+	Format string
+}
+
+// Set a field of the config or of a target from a key: value line
+func (c *daemonConfig) set(target *daemonTarget, key, value string) error {
+	value, err := pipelineScalar(value)
+	if err != nil {
+		return fmt.Errorf("invalid quoted value %s", value)
+	}
+	number := func(field *int) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", key, value)
+		}
+		*field = n
+		return nil
+	}
+	if target == nil {
+		switch key {
+		case "dir":
+			c.Dir = value
+		case "keep":
+			return number(&c.Keep)
+		default:
+			return fmt.Errorf("unknown key %q (dir, keep or targets)", key)
+		}
+		return nil
+	}
+	switch key {
+	case "name":
+		target.Name = value
+	case "profile":
+		target.Profile = value
+	case "slot":
+		return number(&target.Slot)
+	case "format":
+		target.Format = value
+	default:
+		return fmt.Errorf("unknown target key %q (name, profile, slot or format)", key)
+	}
+	return nil
+}
+
+// Read the daemon config at path, in the subset of YAML of pipeline specs
+// (see parseSpec). Relative paths are relative to the directory of the
+// config
+func readDaemonConfig(path string) (daemonConfig, error) {
+	config := daemonConfig{Keep: 20}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	err = parseSpec(data, "targets", func(line int) {
+		config.Targets = append(config.Targets, daemonTarget{line: line, Slot: 1, Format: "text"})
+	}, func(item bool, key, value string) error {
+		var target *daemonTarget
+		if item {
+			target = &config.Targets[len(config.Targets)-1]
+		}
+		return config.set(target, key, value)
+	})
+	if err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+
+	relative := func(p string) (string, error) {
+		p, err := expandProfilePath(p)
+		if err != nil || filepath.IsAbs(p) {
+			return p, err
+		}
+		return filepath.Join(filepath.Dir(path), p), nil
+	}
+	if config.Dir == "" {
+		return config, fmt.Errorf("%s: dir is required", path)
+	}
+	if config.Dir, err = relative(config.Dir); err != nil {
+		return config, err
+	}
+	if config.Keep < 1 {
+		return config, fmt.Errorf("%s: keep must be at least 1, got %d", path, config.Keep)
+	}
+	if len(config.Targets) == 0 {
+		return config, fmt.Errorf("%s: no targets", path)
+	}
+	names := make(map[string]bool)
+	for i := range config.Targets {
+		target := &config.Targets[i]
+		switch {
+		case target.Name == "" || strings.ContainsAny(target.Name, `/\`) || strings.HasPrefix(target.Name, "."):
+			return config, fmt.Errorf("%s: line %d: every target needs a name usable as a directory name", path, target.line)
+		case names[target.Name]:
+			return config, fmt.Errorf("%s: line %d: target %s is defined twice", path, target.line, target.Name)
+		case target.Profile == "" || isProfileURL(target.Profile):
+			return config, fmt.Errorf("%s: line %d: target %s needs the path of a local profile", path, target.line, target.Name)
+		case target.Slot < 1:
+			return config, fmt.Errorf("%s: line %d: profile slots are numbered from 1, got %d", path, target.line, target.Slot)
+		}
+		if _, found := lookupFormat(target.Format); !found {
+			return config, fmt.Errorf("%s: line %d: unknown format %q", path, target.line, target.Format)
+		}
+		names[target.Name] = true
+		if target.Profile, err = relative(target.Profile); err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
+
+// The directories holding the snapshots and the exports of a target
+func (c daemonConfig) snapshotDir(target daemonTarget) string {
+	return filepath.Join(c.Dir, target.Name, "snapshots")
+}
+
+func (c daemonConfig) exportDir(target daemonTarget) string {
+	return filepath.Join(c.Dir, target.Name, "exports")
+}
+
+// The state of a target, as shown by the web UI
+type daemonStatus struct {
+	Target daemonTarget
+	// When the target was last updated, and the error if it failed
+	Updated time.Time
+	Err     error
+	Summary profile.ProfileSummary
+	// The number of snapshots kept and the exported files
+	Snapshots int
+	Exports   []string
+}
+
+// Snapshot the profile of target, unless it is unchanged since the last
+// snapshot, and remove the oldest snapshots beyond keep. Returns the
+// number of snapshots
+func (c daemonConfig) snapshot(target daemonTarget, data []byte) (int, error) {
+	dir := c.snapshotDir(target)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	// Snapshots are named by time, so sort in the order taken
+	snapshots, err := filepath.Glob(filepath.Join(dir, "profiles-*.bin"))
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(snapshots)
+	if len(snapshots) > 0 {
+		if last, err := ioutil.ReadFile(snapshots[len(snapshots)-1]); err == nil && bytes.Equal(last, data) {
+			return len(snapshots), nil
+		}
+	}
+	name := filepath.Join(dir, "profiles-"+time.Now().Format("20060102-150405")+".bin")
+	if err := ioutil.WriteFile(name, data, 0644); err != nil {
+		return len(snapshots), err
+	}
+	logger.Info("snapshot", "target", target.Name, "file", name)
+	snapshots = append(snapshots, name)
+	for len(snapshots) > c.Keep {
+		if err := os.Remove(snapshots[0]); err != nil {
+			return len(snapshots), err
+		}
+		snapshots = snapshots[1:]
+	}
+	return len(snapshots), nil
+}
+
+// Snapshot and export the profile of target, recording it in the
+// activity log, and return its status
+func (c daemonConfig) update(target daemonTarget, activity *activityTracker) daemonStatus {
+	status := daemonStatus{Target: target, Updated: time.Now()}
+	data, err := ioutil.ReadFile(target.Profile)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	if status.Snapshots, status.Err = c.snapshot(target, data); status.Err != nil {
+		return status
+	}
+	reader := memoryProfile{bytes.NewReader(data)}
+	if count := profileLayout(reader).SlotCount(int64(len(data))); target.Slot > count {
+		status.Err = fmt.Errorf("profile slot %d does not exist, the profile has %d slot(s)", target.Slot, count)
+		return status
+	}
+	p, err := decodeSlot(reader, target.Slot)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	status.Summary = profile.Summary(p)
+	activity.observe(p)
+
+	// Export the tabs with a program, as a pipeline with an output per tab
+	spec := pipelineSpec{Slot: target.Slot}
+	for floorIndex, floor := range p.Floors {
+		for tab, t := range floor.Tabs {
+			if len(t.Code) > 0 {
+				spec.Outputs = append(spec.Outputs, pipelineOutput{Floor: floorNumber(floorIndex), Tab: tab + 1, Format: target.Format, Dir: c.exportDir(target)})
+			}
+		}
+	}
+	for _, output := range spec.Outputs {
+		fileName, changed, err := spec.render(reader, output)
+		if err != nil {
+			logger.Error("export failed", "target", target.Name, "floor", output.Floor, "tab", output.Tab, "error", err)
+			status.Err = err
+			continue
+		}
+		if changed {
+			logger.Info("exported", "target", target.Name, "file", fileName)
+		}
+		rel, _ := filepath.Rel(c.exportDir(target), fileName)
+		status.Exports = append(status.Exports, filepath.ToSlash(rel))
+	}
+	return status
+}
+
+var daemonIndex = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Human Resource Machine solutions</title>
+<style>
+body { font-family: sans-serif; margin: 2em; background: #f4efe6; color: #333; }
+section { background: #fff; border-radius: 6px; padding: 1em 1.5em; margin-bottom: 1.5em; }
+.error { color: #b00; }
+ul.exports { columns: 3; }
+</style>
+</head>
+<body>
+<h1>Human Resource Machine solutions</h1>
+{{if .Playground}}<p><a href="/playground/">Playground</a></p>{{end}}
+{{range .Targets}}
+<section>
+<h2>{{.Target.Name}}</h2>
+<p>{{.Target.Profile}}, slot {{.Target.Slot}}, updated {{.Updated.Format "2006-01-02 15:04:05"}}</p>
+{{if .Err}}<p class="error">{{.Err}}</p>{{end}}
+<p>{{.Summary.Solved}} of {{.Summary.Floors}} floors solved,
+{{.Summary.SizeChallengesMet}} size and {{.Summary.SpeedChallengesMet}} speed challenges met.
+<a href="/targets/{{.Target.Name}}/snapshots/">{{.Snapshots}} snapshot(s)</a>,
+<a href="/targets/{{.Target.Name}}/metrics">metrics</a></p>
+<ul class="exports">
+{{$name := .Target.Name}}{{range .Exports}}<li><a href="/targets/{{$name}}/exports/{{.}}">{{.}}</a></li>
+{{end}}</ul>
+</section>
+{{end}}
+</body>
+</html>
+`))
+
+func daemon(cmd *cobra.Command, args []string) {
+	if daemonInterval <= 0 {
+		usageFatalf("--interval must be positive")
+	}
+	checkHTTPFlags(daemonAddr)
+	config, err := readDaemonConfig(args[0])
+	if err != nil {
+		fatal(err)
+	}
+
+	var mutex sync.Mutex
+	statuses := make([]daemonStatus, len(config.Targets))
+	trackers := make([]*activityTracker, len(config.Targets))
+	for i, target := range config.Targets {
+		trackers[i] = newActivityTracker(target.Profile, target.Slot)
+	}
+	update := func(i int) {
+		status := config.update(config.Targets[i], trackers[i])
+		if status.Err != nil {
+			logger.Error("update failed", "target", status.Target.Name, "error", status.Err)
+		}
+		mutex.Lock()
+		statuses[i] = status
+		mutex.Unlock()
+	}
+
+	mux := http.NewServeMux()
+	playground := true
+	for _, name := range []string{"hrm.wasm", "wasm_exec.js"} {
+		if _, err := os.Stat(filepath.Join(daemonWasmDir, name)); err != nil {
+			logger.Debug("not serving the playground", "error", err)
+			playground = false
+		}
+	}
+	if playground {
+		mux.Handle("/playground", playgroundHandler(daemonWasmDir))
+		mux.Handle("/playground/", playgroundHandler(daemonWasmDir))
+	}
+	for _, target := range config.Targets {
+		prefix := "/targets/" + target.Name
+		mux.Handle(prefix+"/exports/", http.StripPrefix(prefix+"/exports/", http.FileServer(http.Dir(config.exportDir(target)))))
+		mux.Handle(prefix+"/snapshots/", http.StripPrefix(prefix+"/snapshots/", http.FileServer(http.Dir(config.snapshotDir(target)))))
+		mux.Handle(prefix+"/metrics", metricsHandler(target.Profile))
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		mutex.Lock()
+		data := struct {
+			Playground bool
+			Targets    []daemonStatus
+		}{playground, append([]daemonStatus(nil), statuses...)}
+		mutex.Unlock()
+		var buffer bytes.Buffer
+		if err := daemonIndex.Execute(&buffer, data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(buffer.Bytes())
+	})
+
+	for i := range config.Targets {
+		update(i)
+	}
+	for i, target := range config.Targets {
+		i := i
+		go watchFiles([]string{target.Profile}, daemonInterval, func() { update(i) })
+	}
+
+	server := &http.Server{Addr: daemonAddr, Handler: guardHandler(mux), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-appContext.Done()
+		server.Close()
+	}()
+	if !logQuiet {
+		fmt.Fprintf(os.Stderr, "Watching %d profile(s), serving http://%s/\n", len(config.Targets), daemonAddr)
+	}
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatal(err)
+	}
+}
+
+func daemonCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "daemon CONFIG",
+		Short: "Watch several profiles and serve their solutions",
+		Long: `Watch the profiles listed in CONFIG, e.g. those of several Steam accounts
+sharing a computer, keeping snapshots and exports of each, and serve them
+all on one web page. CONFIG is a YAML file such as:
+
+  # Relative paths are relative to the directory of the config
+  dir: ~/hrm-hub      # holds a directory per target
+  keep: 20            # snapshots kept per target (default 20)
+  targets:
+    - name: alice
+      profile: ~/.local/share/Steam/userdata/1111/375820/remote/profiles.bin
+    - name: bob
+      profile: ~/.local/share/Steam/userdata/2222/375820/remote/profiles.bin
+      slot: 2
+      format: svg     # the format of the exports (default text)
+
+Whenever a profile changes, a copy is kept in DIR/NAME/snapshots (unless
+identical to the last one) and every tab with a program is exported to
+DIR/NAME/exports, named as by hrm export-all. The page at / shows the
+progress and the exports of every target; the snapshots, exports and
+Prometheus metrics of each are served below /targets/NAME/, and the
+playground at /playground/ if hrm.wasm and wasm_exec.js are found in
+--wasm-dir. Every save of the profiles is recorded in the activity log
+listed by hrm sessions. Runs until interrupted.
+
+The server is protected as by hrm serve with --auth-token, --rate-limit
+and --read-only.`,
+		Args: cobra.ExactArgs(1),
+		Run:  daemon,
+	}
+	cmd.Flags().StringVar(&daemonAddr, "addr", "localhost:8080", "`ADDRESS` to listen on")
+	cmd.Flags().DurationVar(&daemonInterval, "interval", 2*time.Second, "How often to check the profiles for changes")
+	cmd.Flags().StringVar(&daemonWasmDir, "wasm-dir", ".", "`DIR` containing hrm.wasm and wasm_exec.js")
+	addHTTPFlags(cmd)
+	addActivityFlags(cmd)
+	addTextFlags(cmd)
+	addSVGFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+var dedupShowHash bool
+
+// A tab identified by its save slot, floor number and tab number (as
+// shown in the game)
+type tabRef struct {
+	Slot  int `json:"slot"`
+	Floor int `json:"floor"`
+	Tab   int `json:"tab"`
+	size  int
+}
+
+// A group of tabs holding the same program
+type dedupGroup struct {
+	Hash string   `json:"hash"`
+	Size int      `json:"size"`
+	Tabs []tabRef `json:"tabs"`
+}
+
+// Decode the save slot of --slot
+func decodeProfile() profile.Profile {
+	reader := openProfile()
+	defer reader.Close()
+
+	checkSlot(reader, slotNumber)
+	start := time.Now()
+	p, err := decodeSlot(reader, slotNumber)
+	if err != nil {
+		fatal(err)
+	}
+	logger.Debug("decoded profile", "slot", slotNumber, "floors", len(p.Floors), "duration", time.Since(start))
+	return p
+}
+
+func writeDedupText(w io.Writer, groups []dedupGroup, slotCount int) error {
+	for _, group := range groups {
+		hash := group.Hash
+		if !dedupShowHash {
+			hash = hash[:12]
+		}
+		fmt.Fprintf(w, "%s (size %d)\n", hash, group.Size)
+		for _, ref := range group.Tabs {
+			if slotCount > 1 {
+				fmt.Fprintf(w, "    slot %d floor %2d tab %d\n", ref.Slot, ref.Floor, ref.Tab)
+			} else {
+				fmt.Fprintf(w, "    floor %2d tab %d\n", ref.Floor, ref.Tab)
+			}
+		}
+	}
+	return nil
+}
+
+func writeDedupJSON(w io.Writer, groups []dedupGroup) error {
+	data, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+func dedup(cmd *cobra.Command, args []string) {
+	duplicates := []dedupGroup{}
+	slotCount := 0
+	write := reportWriters{
+		"text": func(w io.Writer) error { return writeDedupText(w, duplicates, slotCount) },
+		"json": func(w io.Writer) error { return writeDedupJSON(w, duplicates) },
+	}.selected()
+
+	reader := openProfile()
+	defer reader.Close()
+	size, err := profileSize(reader)
+	if err != nil {
+		fatal(err)
+	}
+	slotCount = profileLayout(reader).SlotCount(size)
+	if slotCount == 0 {
+		fatalf("the profile is too small (%d bytes) to contain a save slot", size)
+	}
+
+	groups := make(map[string][]tabRef)
+	var hashes []string
+	for slot := 1; slot <= slotCount; slot++ {
+		p, err := decodeSlot(reader, slot)
+		if err != nil {
+			fatal(err)
+		}
+		for floorIndex, floor := range p.Floors {
+			for tabIndex, tab := range floor.Tabs {
+				size := tab.Code.Size()
+				if size == 0 {
+					continue
+				}
+				hash := tab.Hash()
+				if _, found := groups[hash]; !found {
+					hashes = append(hashes, hash)
+				}
+				groups[hash] = append(groups[hash], tabRef{slot, floorNumber(floorIndex), tabIndex + 1, size})
+			}
+		}
+	}
+	sort.SliceStable(hashes, func(i, j int) bool {
+		gi, gj := groups[hashes[i]], groups[hashes[j]]
+		if gi[0].Floor != gj[0].Floor {
+			return gi[0].Floor < gj[0].Floor
+		}
+		return gi[0].Tab < gj[0].Tab
+	})
+
+	for _, hash := range hashes {
+		if refs := groups[hash]; len(refs) > 1 {
+			duplicates = append(duplicates, dedupGroup{hash, refs[0].size, refs})
+		}
+	}
+	writeReport(write)
+}
+
+func dedupCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dedup",
+		Short: "List duplicate programs",
+		Long: `List the tabs across all floors and save slots that contain identical
+programs (ignoring comments and label names).
+
+The duplicates are written as text, or as JSON with --format or an output
+file name ending in .json`,
+		Args: cobra.NoArgs,
+		Run:  dedup,
+	}
+	cmd.Flags().BoolVar(&dedupShowHash, "full-hash", false, "Show the full program hash")
+	addReportFlags(cmd, "duplicates", "text", "json")
+	return cmd
+}
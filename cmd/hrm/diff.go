@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+var diffAgainstBackup bool
+
+// Decode the save slot of --slot of the profile at path
+func decodeProfileAt(path string) profile.Profile {
+	reader, err := openProfileAt(path)
+	if err != nil {
+		fatal(err)
+	}
+	defer reader.Close()
+	checkSlot(reader, slotNumber)
+	p, err := decodeSlot(reader, slotNumber)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", path, err))
+	}
+	return p
+}
+
+// Format a challenge result, -1 meaning not met
+func challengeResult(n int) string {
+	if n < 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
+}
+
+// Write the differences between the profiles older and newer, returning the
+// number of differences
+func diffProfiles(w io.Writer, older, newer profile.Profile) int {
+	changes := 0
+	report := func(floor int, format string, args ...interface{}) {
+		changes++
+		fmt.Fprintf(w, "floor %2d: %s\n", floor, fmt.Sprintf(format, args...))
+	}
+	for floorIndex := range newer.Floors {
+		floor := floorNumber(floorIndex)
+		o, n := older.Floors[floorIndex], newer.Floors[floorIndex]
+		if o.Completed != n.Completed {
+			if n.Completed {
+				report(floor, "completed")
+			} else {
+				report(floor, "no longer completed")
+			}
+		}
+		if o.SizeChallenge != n.SizeChallenge {
+			report(floor, "size %s -> %s", challengeResult(o.SizeChallenge), challengeResult(n.SizeChallenge))
+		}
+		if o.SpeedChallenge != n.SpeedChallenge {
+			report(floor, "steps %s -> %s", challengeResult(o.SpeedChallenge), challengeResult(n.SpeedChallenge))
+		}
+		for tab := range n.Tabs {
+			ot, nt := o.Tabs[tab], n.Tabs[tab]
+			oldSize, newSize := ot.Code.Size(), nt.Code.Size()
+			switch {
+			case len(ot.Code) == 0 && len(nt.Code) > 0:
+				report(floor, "tab %d added (size %d)", tab+1, newSize)
+			case len(ot.Code) > 0 && len(nt.Code) == 0:
+				report(floor, "tab %d cleared (was size %d)", tab+1, oldSize)
+			case ot.Hash() != nt.Hash():
+				report(floor, "tab %d changed (size %d -> %d)", tab+1, oldSize, newSize)
+			case !reflect.DeepEqual(ot.RawComments, nt.RawComments) || !reflect.DeepEqual(ot.Code, nt.Code):
+				report(floor, "tab %d comments or labels changed", tab+1)
+			}
+		}
+	}
+	return changes
+}
+
+func diff(cmd *cobra.Command, args []string) {
+	current, err := profileFilePath()
+	if err != nil {
+		fatal(err)
+	}
+	var other string
+	switch {
+	case diffAgainstBackup && len(args) > 0:
+		usageFatalf("OTHER cannot be combined with --against-backup")
+	case diffAgainstBackup:
+		if other, err = latestProfileBackup(current); err != nil {
+			fatal(err)
+		}
+	case len(args) == 1:
+		other = args[0]
+	default:
+		usageFatalf("requires the OTHER profile or --against-backup")
+	}
+	logger.Debug("comparing profiles", "old", other, "new", current)
+
+	older, newer := decodeProfileAt(other), decodeProfileAt(current)
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+
+	fmt.Fprintf(output, "--- %s\n+++ %s\n", other, current)
+	if diffProfiles(output, older, newer) == 0 {
+		fmt.Fprintln(output, "no differences")
+	}
+}
+
+func diffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff [OTHER]",
+		Short: "Show what changed between two profiles",
+		Long: `Show the floors completed, the challenge results and the programs changed
+between the OTHER (older) profile and the selected profile.
+
+With --against-backup OTHER is the most recent backup next to the selected
+profile (profiles.bin.bak and similar, or Steam's remote copy), showing what
+changed in the most recent play session.`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  diff,
+	}
+	addSlotFlag(cmd, "compare")
+	cmd.Flags().BoolVar(&diffAgainstBackup, "against-backup", false, "Compare against the most recent backup of the profile")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the differences to")
+	return cmd
+}
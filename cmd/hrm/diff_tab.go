@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var diffTabAgainstBackup bool
+
+// Decode the tab of the profile opened as reader
+func decodeTab(reader profileReader, profileId, floorIndex, tab int) render.Program {
+	checkSlot(reader, profileId)
+	tabStart := profileLayout(reader).TabStartAddr(profileId, floorIndex, tab)
+	program, err := render.DecodeProgramAt(appContext, reader, tabStart, instructions.Logger(logger))
+	if err != nil {
+		fatal(&profile.DecodeError{Offset: tabStart, Floor: floorNumber(floorIndex), Tab: tab + 1, Err: err})
+	}
+	return program
+}
+
+func diffTab(cmd *cobra.Command, args []string) {
+	floorIndex, tab := floorTabArgs(args[:2])
+	otherFloorIndex, otherTab := floorIndex, tab
+	switch {
+	case diffTabAgainstBackup && len(args) > 2:
+		usageFatalf("OTHER_FLOOR and OTHER_TAB cannot be combined with --against-backup")
+	case !diffTabAgainstBackup && len(args) != 4:
+		usageFatalf("requires OTHER_FLOOR and OTHER_TAB or --against-backup")
+	case !diffTabAgainstBackup:
+		otherFloorIndex, otherTab = floorTabArgs(args[2:])
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+	newer := decodeTab(reader, slotNumber, floorIndex, tab)
+	olderName := fmt.Sprintf("floor %d tab %d", floorNumber(otherFloorIndex), otherTab+1)
+	newerName := fmt.Sprintf("floor %d tab %d", floorNumber(floorIndex), tab+1)
+
+	var older render.Program
+	if diffTabAgainstBackup {
+		current, err := profileFilePath()
+		if err != nil {
+			fatal(err)
+		}
+		backup, err := latestProfileBackup(current)
+		if err != nil {
+			fatal(err)
+		}
+		backupReader, err := openProfileAt(backup)
+		if err != nil {
+			fatal(err)
+		}
+		defer backupReader.Close()
+		older = decodeTab(backupReader, slotNumber, otherFloorIndex, otherTab)
+		olderName = backup + " " + olderName
+	} else {
+		older = decodeTab(reader, slotNumber, otherFloorIndex, otherTab)
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+
+	diff := instructions.DiffDisassembled(older.Disassembled, newer.Disassembled)
+	fmt.Fprintf(output, "--- %s\n+++ %s\n", olderName, newerName)
+	if diff.Equal() {
+		fmt.Fprintln(output, "no differences")
+		return
+	}
+	fmt.Fprint(output, diff.Text(textMnemonics()))
+}
+
+func diffTabCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff-tab FLOOR TAB [OTHER_FLOOR OTHER_TAB]",
+		Short: "Show the differences between the programs of two tabs",
+		Long: `Show the differences between the program of the tab OTHER_FLOOR OTHER_TAB
+(older) and the program of the tab FLOOR TAB (newer).
+
+The programs are compared instruction by instruction rather than line by
+line: comments and the names of labels are ignored, and jumps are equal when
+they jump to the same place in both programs, so a relabeled but otherwise
+identical program has no differences.
+
+With --against-backup the tab is compared with the same tab of the most
+recent backup of the profile.`,
+		Args: cobra.RangeArgs(2, 4),
+		Run:  diffTab,
+	}
+	addSlotFlag(cmd, "compare")
+	cmd.Flags().BoolVar(&diffTabAgainstBackup, "against-backup", false, "Compare against the same tab of the most recent backup of the profile")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the differences to")
+	return cmd
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/clj/hrm-profile-tool/journal"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+// The outcome of a doctor check
+type checkStatus string
+
+const (
+	checkOK   checkStatus = "ok"
+	checkWarn checkStatus = "warn"
+	checkFail checkStatus = "FAIL"
+	checkInfo checkStatus = "info"
+)
+
+// A report of doctor checks, written as it is produced
+type doctorReport struct {
+	w        io.Writer
+	failures int
+	warnings int
+}
+
+func (r *doctorReport) check(status checkStatus, format string, args ...interface{}) {
+	switch status {
+	case checkFail:
+		r.failures++
+	case checkWarn:
+		r.warnings++
+	}
+	fmt.Fprintf(r.w, "[%-4s] %s\n", status, fmt.Sprintf(format, args...))
+}
+
+// Check that a file can be written without modifying it, by opening it for
+// writing, and that its directory allows creating files (the game writes
+// the profile by replacing it)
+func checkWritable(r *doctorReport, path string) {
+	if file, err := os.OpenFile(path, os.O_WRONLY, 0); err != nil {
+		r.check(checkWarn, "profile is not writable, commands modifying it will fail: %v", err)
+	} else {
+		file.Close()
+		r.check(checkOK, "profile is writable")
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(path), ".hrm-doctor-")
+	if err != nil {
+		r.check(checkWarn, "cannot create files next to the profile (needed for backups): %v", err)
+		return
+	}
+	temp.Close()
+	os.Remove(temp.Name())
+	r.check(checkOK, "can create files next to the profile")
+}
+
+// Check the structure of the profile at path
+func checkProfile(r *doctorReport, path string) {
+	reader, err := openProfileAt(path)
+	if err != nil {
+		r.check(checkFail, "cannot open profile: %v", err)
+		return
+	}
+	defer reader.Close()
+	r.check(checkOK, "profile is readable")
+
+	size, err := profileSize(reader)
+	if err != nil {
+		r.check(checkFail, "cannot determine profile size: %v", err)
+		return
+	}
+	layout := profileLayout(reader)
+	slots := layout.SlotCount(size)
+	switch {
+	case slots == 0:
+		r.check(checkFail, "profile is %d bytes, smaller than a save slot (%d bytes in the %s layout)", size, layout.SlotSize(), layout.Name)
+		return
+	case size%layout.SlotSize() != 0:
+		r.check(checkWarn, "profile is %d bytes, %d save slot(s) and %d trailing bytes in the %s layout", size, slots, size%layout.SlotSize(), layout.Name)
+	default:
+		r.check(checkOK, "profile is %d bytes, %d save slot(s) in the %s layout", size, slots, layout.Name)
+	}
+
+	slotList, err := layout.ReadSlotsAt(reader, size)
+	if err != nil {
+		r.check(checkFail, "cannot read slot headers: %v", err)
+		return
+	}
+	for _, slot := range slotList {
+		zero := true
+		for _, b := range slot.Header {
+			zero = zero && b == 0
+		}
+		if zero {
+			r.check(checkOK, "slot %d header is as expected (all zeros)", slot.Number)
+		} else {
+			r.check(checkWarn, "slot %d header is not all zeros, this may be a newer or unknown profile format: % x", slot.Number, slot.Header)
+		}
+		// Quarantine the undecodable parts, so that all of them are reported
+		p, err := profile.DecodeAt(appContext, reader, profile.DecodeLayout(layout), profile.DecodeSlot(slot.Number), profile.Quarantine())
+		if err != nil {
+			r.check(checkFail, "slot %d does not decode: %v", slot.Number, err)
+			continue
+		}
+		for _, err := range p.Errors {
+			r.check(checkFail, "slot %d does not decode: %v", slot.Number, err)
+		}
+		s := profile.Summary(p)
+		if len(p.Errors) > 0 {
+			r.check(checkWarn, "slot %d partially decodes (see --keep-going): %d/%d floors solved", slot.Number, s.Solved, s.Floors)
+			continue
+		}
+		r.check(checkOK, "slot %d decodes: %d/%d floors solved", slot.Number, s.Solved, s.Floors)
+	}
+	r.check(checkInfo, "game version: not recorded in the profile (use --game-version when exporting)")
+
+	if !isProfileURL(path) {
+		checkWritable(r, path)
+	}
+}
+
+func doctor(cmd *cobra.Command, args []string) {
+	r := &doctorReport{w: os.Stdout}
+	fmt.Fprintln(r.w, "```")
+	fmt.Fprintf(r.w, "hrm-profile-tool %s (%s, %s/%s)\n", version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	candidates, err := profileCandidates()
+	if err != nil {
+		r.check(checkWarn, "default profile locations: %v", err)
+	}
+	for _, candidate := range candidates {
+		switch {
+		case candidate.exists():
+			r.check(checkInfo, "candidate %s exists (%d bytes, modified %s)", candidate.path, candidate.info.Size(), candidate.info.ModTime().Format("2006-01-02 15:04:05 MST"))
+		case os.IsNotExist(candidate.err):
+			r.check(checkInfo, "candidate %s does not exist", candidate.path)
+		default:
+			r.check(checkWarn, "candidate %s: %v", candidate.path, candidate.err)
+		}
+	}
+
+	path, err := profileFilePath()
+	if err != nil {
+		r.check(checkFail, "no profile selected: %v", err)
+	} else {
+		if profilePath != "" {
+			r.check(checkInfo, "selected profile (--profile): %s", path)
+		} else {
+			r.check(checkInfo, "selected profile: %s", path)
+		}
+		checkProfile(r, path)
+		if !isProfileURL(path) {
+			if backups, err := profileBackups(path); err == nil && len(backups) > 0 {
+				r.check(checkInfo, "most recent backup: %s", backups[0])
+			}
+		}
+	}
+
+	if dir, err := journal.DefaultDir(); err != nil {
+		r.check(checkWarn, "edit journal unavailable, modifications cannot be undone: %v", err)
+	} else if _, err := journal.Open(dir); err != nil {
+		r.check(checkWarn, "edit journal %s unavailable, modifications cannot be undone: %v", dir, err)
+	} else {
+		r.check(checkOK, "edit journal: %s", dir)
+	}
+
+	fmt.Fprintf(r.w, "%d failure(s), %d warning(s)\n", r.failures, r.warnings)
+	fmt.Fprintln(r.w, "```")
+	if r.failures > 0 {
+		os.Exit(1)
+	}
+}
+
+func doctorCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the environment and the profile",
+		Long: `Check the candidate profile locations, the selected profile (readability,
+size, slot headers, decoding) and write permissions, printing a report
+suitable for attaching to bug reports`,
+		Args: cobra.NoArgs,
+		Run:  doctor,
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/journal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dryRun        bool
+	dryRunHexdump bool
+)
+
+// Add the --dry-run flags, honoured by the commands modifying a profile
+func addDryRunFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVarP(&dryRun, "dry-run", "n", false, "Show what commands modifying a profile would change, without changing it")
+	cmd.PersistentFlags().BoolVar(&dryRunHexdump, "hexdump", false, "With --dry-run, show a hex dump of the changed bytes")
+}
+
+// Return the changes of the fields of two structs of the same type, e.g.
+// "SizeChallengeCommands: 0 -> 12". Fields which are not numbers or
+// strings are skipped
+func fieldChanges(before, after interface{}) []string {
+	b, a := reflect.ValueOf(before), reflect.ValueOf(after)
+	var changes []string
+	for i := 0; i < b.NumField(); i++ {
+		switch b.Field(i).Kind() {
+		case reflect.Slice, reflect.Map, reflect.Struct, reflect.Ptr, reflect.Interface:
+			continue
+		}
+		if oldValue, newValue := b.Field(i).Interface(), a.Field(i).Interface(); oldValue != newValue {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", b.Type().Field(i).Name, oldValue, newValue))
+		}
+	}
+	return changes
+}
+
+// Print the changes that would be made to the file at path. The original
+// bytes of the changes must be set (see journal.ReadOriginals). details
+// are the logical changes, e.g. from fieldChanges
+func printDryRun(verb, path, description string, changes []journal.Change, details ...string) {
+	fmt.Printf("Would %s %s: %s\n", verb, path, description)
+	for _, detail := range details {
+		fmt.Printf("    %s\n", detail)
+	}
+	for _, change := range changes {
+		changed := 0
+		for i := range change.Modified {
+			if change.Original[i] != change.Modified[i] {
+				changed++
+			}
+		}
+		fmt.Printf("    0x%08X-0x%08X: %d bytes, %d changed\n", change.Offset, change.Offset+int64(len(change.Modified)), len(change.Modified), changed)
+		if dryRunHexdump {
+			printHexdumpDiff(change)
+		}
+	}
+	fmt.Println("Dry run, nothing was changed")
+}
+
+// Print the rows of 16 bytes of a change which differ, as the original
+// (-) and modified (+) bytes
+func printHexdumpDiff(change journal.Change) {
+	row := func(sign string, offset int64, data []byte) string {
+		var hex, text strings.Builder
+		for i := 0; i < 16; i++ {
+			if i < len(data) {
+				fmt.Fprintf(&hex, "%02x ", data[i])
+				if data[i] >= 0x20 && data[i] < 0x7f {
+					text.WriteByte(data[i])
+				} else {
+					text.WriteByte('.')
+				}
+			} else {
+				hex.WriteString("   ")
+			}
+		}
+		return fmt.Sprintf("      %s 0x%08X  %s |%s|", sign, offset, hex.String(), text.String())
+	}
+	for start := 0; start < len(change.Modified); start += 16 {
+		end := start + 16
+		if end > len(change.Modified) {
+			end = len(change.Modified)
+		}
+		original, modified := change.Original[start:end], change.Modified[start:end]
+		if bytes.Equal(original, modified) {
+			continue
+		}
+		offset := change.Offset + int64(start)
+		fmt.Println(row("-", offset, original))
+		fmt.Println(row("+", offset, modified))
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/journal"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	editYes bool
+)
+
+// Add the flags shared by commands modifying a profile
+func addEditFlags(cmd *cobra.Command) {
+	addSlotFlag(cmd, "modify")
+	cmd.Flags().BoolVarP(&editYes, "yes", "y", false, "Do not ask for confirmation")
+}
+
+// Warn that the profile is about to be modified and ask for confirmation,
+// unless --yes was given. Exits if not confirmed
+func confirmEdit(description string) {
+	fmt.Fprintf(os.Stderr, tr("prompt.modify", "WARNING: about to modify the profile: %s")+"\n", description)
+	fmt.Fprintln(os.Stderr, tr("prompt.close-game", "WARNING: close Human Resource Machine first, it overwrites the profile when it exits"))
+	if editYes {
+		return
+	}
+	if !isTerminal(os.Stdin) {
+		usageFatalf("%s", tr("error.no-confirmation", "refusing to modify the profile without confirmation, use --yes"))
+	}
+	fmt.Fprint(os.Stderr, tr("prompt.continue", "Continue? [y/N]")+" ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if !confirmed(answer) {
+		fatalf("%s", tr("error.aborted", "aborted"))
+	}
+}
+
+// Report whether answer to a yes/no prompt is yes: y or yes, or one of the
+// comma separated answers of the "prompt.yes" message
+func confirmed(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	for _, yes := range strings.Split("y,yes,"+tr("prompt.yes", ""), ",") {
+		if yes = strings.ToLower(strings.TrimSpace(yes)); yes != "" && answer == yes {
+			return true
+		}
+	}
+	return false
+}
+
+// Copy the profile at path to a timestamped backup next to it, returning
+// the backup's path
+func backupProfile(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	stamp := time.Now().Format("20060102-150405")
+	backupPath := fmt.Sprintf("%s.%s.bak", path, stamp)
+	dst, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	for n := 2; os.IsExist(err); n++ {
+		backupPath = fmt.Sprintf("%s.%s-%d.bak", path, stamp, n)
+		dst, err = os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return "", err
+	}
+	return backupPath, dst.Close()
+}
+
+// Return the path of the selected profile, which is to be modified. Exits
+// if the profile cannot be modified
+func editProfilePath() string {
+	path, err := profileFilePath()
+	if err != nil {
+		fatal(err)
+	}
+	if isProfileURL(path) {
+		usageFatalf("cannot modify a downloaded profile")
+	}
+	return path
+}
+
+// Modify the header of the floor in the selected profile with edit, after
+// confirmation, backing up the profile and recording the change in the
+// edit journal (see: hrm undo)
+func editFloorHeader(command string, floor int, description string, edit func(*profile.FloorHeader)) {
+	path := editProfilePath()
+	floorIndex := floorIndexArg(floor)
+
+	reader, err := openProfileAt(path)
+	if err != nil {
+		fatal(err)
+	}
+	checkSlot(reader, slotNumber)
+	layout := profileLayout(reader)
+	header, err := layout.ReadFloorHeaderAt(reader, slotNumber, floorIndex)
+	reader.Close()
+	if err != nil {
+		fatal(&profile.DecodeError{Offset: layout.FloorStartAddr(slotNumber, floorIndex), Floor: floor, Err: err})
+	}
+	original := header
+	edit(&header)
+	data, err := header.MarshalBinary()
+	if err != nil {
+		fatal(err)
+	}
+	applyEdit(command, path, description, []journal.Change{{Offset: layout.FloorStartAddr(slotNumber, floorIndex), Modified: data}},
+		fieldChanges(original, header)...)
+}
+
+// Apply changes to the profile at path after confirmation, backing up the
+// profile and recording the changes in the edit journal (see: hrm undo).
+// With --dry-run the changes and the details (the logical changes) are
+// only shown
+func applyEdit(command, path, description string, changes []journal.Change, details ...string) {
+	if dryRun {
+		if err := journal.ReadOriginals(path, changes); err != nil {
+			fatal(err)
+		}
+		printDryRun("modify", path, description, changes, details...)
+		return
+	}
+	confirmEdit(description)
+	backupPath, err := backupProfile(path)
+	if err != nil {
+		fatal(fmt.Errorf("backing up the profile: %w", err))
+	}
+	fmt.Fprintf(os.Stderr, "Backed up the profile to %s\n", backupPath)
+
+	if _, err := openJournal().Apply(path, command, description, changes); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Modified %s: %s\n", path, description)
+}
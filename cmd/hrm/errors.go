@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/clj/hrm-profile-tool/profile"
+)
+
+// Emit errors as JSON objects on stderr (--json-errors)
+var jsonErrors bool
+
+// Error codes used in JSON error output
+const (
+	errorCodeGeneric    = "error"
+	errorCodeUsage      = "usage"
+	errorCodeNotFound   = "not_found"
+	errorCodePermission = "permission_denied"
+	errorCodeDecode     = "decode_error"
+)
+
+// An error with an explicit error code
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// Wrap err as a command line usage error
+func usageError(err error) error {
+	return &codedError{errorCodeUsage, err}
+}
+
+// The JSON representation of an error
+type jsonError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Offset  *int64 `json:"offset,omitempty"`
+	Floor   int    `json:"floor,omitempty"`
+	Tab     int    `json:"tab,omitempty"`
+}
+
+func newJSONError(err error) jsonError {
+	je := jsonError{Code: errorCodeGeneric, Message: err.Error()}
+	var coded *codedError
+	var decodeErr *profile.DecodeError
+	var statusErr *httpStatusError
+	switch {
+	case errors.As(err, &coded):
+		je.Code = coded.code
+	case errors.As(err, &statusErr) && statusErr.statusCode == http.StatusNotFound:
+		je.Code = errorCodeNotFound
+	case errors.As(err, &statusErr) && (statusErr.statusCode == http.StatusUnauthorized || statusErr.statusCode == http.StatusForbidden):
+		je.Code = errorCodePermission
+	case errors.As(err, &decodeErr):
+		je.Code = errorCodeDecode
+	case os.IsNotExist(errors.Unwrap(err)) || os.IsNotExist(err):
+		je.Code = errorCodeNotFound
+	case os.IsPermission(errors.Unwrap(err)) || os.IsPermission(err):
+		je.Code = errorCodePermission
+	}
+	if errors.As(err, &decodeErr) {
+		je.Offset = &decodeErr.Offset
+		je.Floor, je.Tab = decodeErr.Floor, decodeErr.Tab
+	}
+	return je
+}
+
+// Report err and exit
+func fatal(err error) {
+	if jsonErrors {
+		json.NewEncoder(os.Stderr).Encode(newJSONError(err))
+		os.Exit(1)
+	}
+	if useColor(os.Stderr) {
+		log.Fatal(paint(true, ansiRed, err.Error()))
+	}
+	log.Fatal(err)
+}
+
+// Report a formatted error and exit
+func fatalf(format string, args ...interface{}) {
+	fatal(fmt.Errorf(format, args...))
+}
+
+// Report a formatted usage error and exit
+func usageFatalf(format string, args ...interface{}) {
+	fatal(usageError(fmt.Errorf(format, args...)))
+}
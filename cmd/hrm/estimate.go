@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/analysis"
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/spf13/cobra"
+)
+
+var (
+	estimateInboxLength int
+)
+
+// Return "a·n + b" if the step counts for inbox lengths n-2, n-1 and n grow
+// linearly, otherwise ""
+func stepFormula(counts [3]int) string {
+	a := counts[2] - counts[1]
+	if counts[1]-counts[0] != a {
+		return ""
+	}
+	b := counts[2] - a*estimateInboxLength
+	switch {
+	case b == 0:
+		return fmt.Sprintf("%dn", a)
+	case b < 0:
+		return fmt.Sprintf("%dn - %d", a, -b)
+	}
+	return fmt.Sprintf("%dn + %d", a, b)
+}
+
+func estimate(cmd *cobra.Command, args []string) {
+	floor := parseInt(args[0])
+	tab := parseInt(args[1]) - 1
+	checkFloorArg(floor)
+	level := levels.Floor(floor)
+	if estimateInboxLength == 0 {
+		estimateInboxLength = level.Inbox().MaxLength
+	}
+	if estimateInboxLength == 0 {
+		usageFatalf("the inbox length of floor %d is not known, use --inbox-length", floor)
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+	program := decodeTab(reader, slotNumber, floorIndexArg(floor), tab)
+	if len(program.Disassembled) == 0 {
+		fmt.Printf("floor %d tab %d is empty\n", floor, tab+1)
+		return
+	}
+
+	bounds := analysis.EstimateSteps(program.Disassembled, estimateInboxLength)
+	fmt.Printf("Floor %d %s, tab %d, %d values in the inbox\n", floor, levelName(level), tab+1, estimateInboxLength)
+	if bounds.NeverEnds {
+		fmt.Println("the program never ends")
+		return
+	}
+
+	// The counts for shorter inboxes show how the bounds grow per value
+	var best, worst [3]int
+	formulas := estimateInboxLength >= 2
+	for i := 0; i < 3 && formulas; i++ {
+		b := analysis.EstimateSteps(program.Disassembled, estimateInboxLength-2+i)
+		best[i], worst[i] = b.Best, b.Worst
+		formulas = !b.NeverEnds
+	}
+	describe := func(steps int, formula string) string {
+		if formulas && formula != "" {
+			return fmt.Sprintf("%d steps (%s)", steps, formula)
+		}
+		return fmt.Sprintf("%d steps", steps)
+	}
+	fmt.Printf("best case:  %s\n", describe(bounds.Best, stepFormula(best)))
+	if bounds.Unbounded {
+		var lines []string
+		for _, index := range bounds.UnboundedLoops {
+			lines = append(lines, fmt.Sprint(program.Disassembled[index].(instructions.LineNumbered).Line()))
+		}
+		fmt.Printf("worst case: unbounded, the loops at line %s may repeat without taking from the inbox\n", strings.Join(lines, ", "))
+	} else {
+		fmt.Printf("worst case: %s\n", describe(bounds.Worst, stepFormula(worst)))
+	}
+	fmt.Printf("speed challenge: %d steps\n", level.SpeedChallenge)
+}
+
+func estimateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "estimate FLOOR TAB",
+		Short: "Estimate the number of steps a program takes without running it",
+		Long: `Estimate the best and worst case number of steps the program of a tab takes,
+as counted by the speed challenge, without running it.
+
+Every path through the program is assumed possible, with as many values in
+the inbox as the level gives (or --inbox-length), so the actual steps may lie
+well within the bounds. Loops which can repeat without taking from the inbox,
+e.g. counting down a value, make the worst case unbounded; use hrm run to
+measure such programs.`,
+		Args: cobra.ExactArgs(2),
+		Run:  estimate,
+	}
+	addSlotFlag(cmd, "read")
+	cmd.Flags().IntVar(&estimateInboxLength, "inbox-length", 0, "Estimate for `N` values in the inbox (default: the level's inbox length)")
+	return cmd
+}
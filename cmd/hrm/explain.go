@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	explainLine int
+)
+
+// Print an explanation, with the instruction as rendered in the header
+func printExplanation(header string, e instructions.Explanation) {
+	fmt.Println(header)
+	fmt.Printf("  opcode:  %d\n", e.Op)
+	if e.Operand != "" {
+		fmt.Printf("  operand: %s\n", e.Operand)
+	}
+	for i, effect := range e.Effects {
+		label := ""
+		if i == 0 {
+			label = "effect:"
+		}
+		fmt.Printf("  %-8s %s\n", label, effect)
+	}
+	if len(e.Needs) > 0 {
+		fmt.Printf("  needs:   %s\n", strings.Join(e.Needs, ", "))
+	}
+	if e.Values != "" {
+		fmt.Printf("  values:  %s\n", e.Values)
+	}
+}
+
+func explainOp(name string) {
+	mnemonics := textMnemonics()
+	if mnemonics == nil {
+		mnemonics = instructions.DefaultMnemonics()
+	}
+	op, found := mnemonics.Lookup(name)
+	if !found {
+		var names []string
+		for op := range instructions.OpSemantics {
+			names = append(names, mnemonics.Name(op))
+		}
+		sort.Strings(names)
+		usageFatalf("unknown instruction %q, the instructions are %s", name, strings.Join(names, ", "))
+	}
+	e, err := instructions.ExplainOp(op)
+	if err != nil {
+		fatal(err)
+	}
+	printExplanation(mnemonics.Name(op), e)
+}
+
+func explain(cmd *cobra.Command, args []string) {
+	if len(args) == 1 {
+		if explainLine != 0 {
+			usageFatalf("--line explains an instruction of a tab, give FLOOR and TAB")
+		}
+		explainOp(args[0])
+		return
+	}
+	if explainLine <= 0 {
+		usageFatalf("--line is required with FLOOR and TAB")
+	}
+	floorIndex, tab := floorTabArgs(args)
+	reader := openProfile()
+	defer reader.Close()
+	program := decodeTab(reader, slotNumber, floorIndex, tab)
+	start, end := program.Disassembled.LineRange(explainLine, explainLine)
+	if start == end {
+		usageFatalf("floor %d tab %d has no line %d", floorNumber(floorIndex), tab+1, explainLine)
+	}
+	// The range includes the comments and jump targets before the line
+	index := end - 1
+	e, err := program.Disassembled.Explain(index)
+	if err != nil {
+		fatal(err)
+	}
+	var opts []render.RenderInstructionsTextOption
+	if mnemonics := textMnemonics(); mnemonics != nil {
+		opts = append(opts, render.UseMnemonics(mnemonics))
+	}
+	text := render.RenderInstructionsText(program.Disassembled[index:end], opts...)
+	printExplanation(fmt.Sprintf("line %d: %s", explainLine, strings.TrimSpace(text)), e)
+}
+
+func explainCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain INSTRUCTION | FLOOR TAB --line LINE",
+		Short: "Explain what an instruction does",
+		Long: `Explain what an instruction does: its opcode, its operand, its effects on
+the worker's hand, the floor tiles and the outbox, and what it fails
+without. INSTRUCTION is a mnemonic, as rendered with --mnemonics or the
+language of the messages.
+
+Given FLOOR, TAB and --line, explain the instruction on that line of the
+tab's program in context: the tile it uses and the lines it continues
+with.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run:  explain,
+	}
+	addSlotFlag(cmd, "read")
+	cmd.Flags().IntVar(&explainLine, "line", 0, "Explain the instruction on `LINE` of the tab")
+	return cmd
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/clj/hrm-profile-tool/metadata"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportDir      string
+	exportJobs     int
+	exportAllTabs  bool
+	exportManifest bool
+)
+
+// A single tab to export
+type exportJob struct {
+	floorIndex int
+	tab        int
+}
+
+// The result of exporting a tab
+type exportResult struct {
+	job      exportJob
+	fileName string
+	err      error
+}
+
+// Return the file name used when exporting a tab
+func exportFileName(floorIndex, tab int, format render.Format) string {
+	ext := "." + format.Name
+	if len(format.Extensions) > 0 {
+		ext = format.Extensions[0]
+	}
+	if outputCompress {
+		ext = compressedExtension(ext)
+	}
+	return fmt.Sprintf("floor-%02d-tab-%d%s", floorNumber(floorIndex), tab+1, ext)
+}
+
+// Decode a tab and prepare it for rendering into dir. Reports false if
+// the tab is empty and is skipped
+func exportItem(reader profileReader, profileId int, format render.Format, job exportJob) (render.BatchItem, exportResult, bool) {
+	result := exportResult{job: job}
+	tabStart := profileLayout(reader).TabStartAddr(profileId, job.floorIndex, job.tab)
+	program, err := render.DecodeProgramAt(appContext, reader, tabStart, programDecodeOptions()...)
+	if err != nil {
+		result.err = &profile.DecodeError{Offset: tabStart, Floor: floorNumber(job.floorIndex), Tab: job.tab + 1, Err: err}
+		return render.BatchItem{}, result, true
+	}
+	if !exportAllTabs && len(program.Instructions) == 0 && len(program.RawComments) == 0 {
+		return render.BatchItem{}, result, false
+	}
+
+	result.fileName = filepath.Join(exportDir, exportFileName(job.floorIndex, job.tab, format))
+	options, err := renderOptions(result.fileName, program, reader, profileId, job.floorIndex, job.tab)
+	if err != nil {
+		result.err = err
+		return render.BatchItem{}, result, true
+	}
+	item := render.BatchItem{
+		Program: program,
+		Options: options,
+		Create: func() (io.WriteCloser, error) {
+			output, err := createOutputFile(result.fileName)
+			if err != nil {
+				return nil, err
+			}
+			if err := writeMetadata(output, result.fileName, format, program, reader, profileId, job.floorIndex); err != nil {
+				output.Close()
+				return nil, err
+			}
+			return output, nil
+		},
+	}
+	return item, result, true
+}
+
+func exportAll(cmd *cobra.Command, args []string) {
+	legacySlotArg(cmd, args)
+	profileId := slotNumber
+	format, err := selectFormat("text")
+	if err != nil {
+		fatal(usageError(err))
+	}
+	if exportJobs < 1 {
+		usageFatalf("--jobs must be at least 1")
+	}
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		fatal(err)
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+	checkSlot(reader, profileId)
+
+	var failures []exportResult
+	var items []render.BatchItem
+	var results []exportResult
+	for floorIndex := 0; floorIndex < len(profile.Profile{}.Floors); floorIndex++ {
+		for tab := 0; tab < 3; tab++ {
+			item, result, export := exportItem(reader, profileId, format, exportJob{floorIndex, tab})
+			switch {
+			case result.err != nil:
+				failures = append(failures, result)
+			case export:
+				items = append(items, item)
+				results = append(results, result)
+			}
+		}
+	}
+
+	bar := newProgress(len(items), "Exporting")
+	exported := 0
+	for batchResult := range render.Batch(appContext, format, items, render.BatchWorkers(exportJobs)) {
+		result := results[batchResult.Index]
+		if batchResult.Err != nil {
+			result.err = batchResult.Err
+			failures = append(failures, result)
+		} else {
+			exported++
+			logger.Debug("exported tab", "file", result.fileName)
+		}
+		bar.increment()
+	}
+	bar.finish()
+
+	if err := appContext.Err(); err != nil {
+		fatal(err)
+	}
+	if !logQuiet {
+		fmt.Fprintf(os.Stderr, "Exported %d tabs to %s\n", exported, exportDir)
+	}
+	if len(failures) > 0 {
+		fmt.Fprintf(os.Stderr, "%d tabs failed:\n", len(failures))
+		for _, failure := range failures {
+			fmt.Fprintf(os.Stderr, "    floor %d tab %d: %v\n", floorNumber(failure.job.floorIndex), failure.job.tab+1, failure.err)
+		}
+		fatalf("export incomplete")
+	}
+	if exportManifest {
+		var files []string
+		for _, result := range results {
+			files = append(files, result.fileName)
+			if withMetadata && !inlineMetadata(format) {
+				files = append(files, metadata.SidecarPath(result.fileName))
+			}
+		}
+		if err := writeExportManifest(reader, profileId, files); err != nil {
+			fatal(fmt.Errorf("writing the manifest: %w", err))
+		}
+	}
+}
+
+func exportAllCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-all",
+		Short: "Export all programs",
+		Long: `Render every non-empty tab of every floor into a directory, one file per tab.
+
+With --manifest the SHA-256 checksums of the files, the profile and the
+tool version are recorded in ` + metadata.ManifestName + `, so that
+hrm verify-export can detect stale or modified exports`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  exportAll,
+	}
+	addSlotFlag(cmd, "export")
+	cmd.Flags().StringVar(&exportDir, "dir", ".", "`DIR` to write the exported files to")
+	cmd.Flags().IntVarP(&exportJobs, "jobs", "j", runtime.NumCPU(), "Render `N` tabs concurrently")
+	cmd.Flags().BoolVar(&exportAllTabs, "all-tabs", false, "Also export empty tabs")
+	cmd.Flags().BoolVar(&exportManifest, "manifest", false, "Write a manifest of the exported files' checksums to DIR (see hrm verify-export)")
+	addFormatFlags(cmd)
+	addTextFlags(cmd)
+	addSVGFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var drawingsWidth int
+
+// Return the file name used when exporting the drawing of a comment
+func drawingFileName(floorIndex, tab int, comment int) string {
+	return fmt.Sprintf("floor-%02d-tab-%d-comment-%d.png", floorNumber(floorIndex), tab+1, comment)
+}
+
+// Write the drawing of a comment as a PNG file
+func writeDrawing(fileName string, comment instructions.Comment) error {
+	output, err := createOutputFile(fileName)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(output, render.RenderDrawing(comment, render.DrawingWidth(drawingsWidth))); err != nil {
+		output.Close()
+		return err
+	}
+	return output.Close()
+}
+
+func exportDrawings(cmd *cobra.Command, args []string) {
+	legacySlotArg(cmd, args)
+	profileId := slotNumber
+	if drawingsWidth < 3 {
+		usageFatalf("--width must be at least 3")
+	}
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		fatal(err)
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+	checkSlot(reader, profileId)
+
+	exported, failed := 0, 0
+	for floorIndex := 0; floorIndex < len(profile.Profile{}.Floors); floorIndex++ {
+		for tab := 0; tab < 3; tab++ {
+			if err := appContext.Err(); err != nil {
+				fatal(err)
+			}
+			tabStart := profileLayout(reader).TabStartAddr(profileId, floorIndex, tab)
+			program, err := render.DecodeProgramAt(appContext, reader, tabStart, programDecodeOptions()...)
+			if err != nil {
+				logger.Warn("skipping tab", "error", &profile.DecodeError{Offset: tabStart, Floor: floorNumber(floorIndex), Tab: tab + 1, Err: err})
+				failed++
+				continue
+			}
+			for i, comment := range program.Comments {
+				if len(comment) == 0 {
+					continue
+				}
+				fileName := filepath.Join(exportDir, drawingFileName(floorIndex, tab, i))
+				if err := writeDrawing(fileName, comment); err != nil {
+					fatal(err)
+				}
+				logger.Debug("exported drawing", "file", fileName)
+				exported++
+			}
+		}
+	}
+
+	if !logQuiet {
+		fmt.Fprintf(os.Stderr, "Exported %d drawings to %s\n", exported, exportDir)
+	}
+	if failed > 0 {
+		fatalf("export incomplete: %d tabs could not be decoded", failed)
+	}
+}
+
+func exportDrawingsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-drawings",
+		Short: "Export all comment drawings",
+		Long: `Write the drawing of every comment of every tab of every floor into a
+directory, one PNG image per drawing, named by floor, tab and comment index
+(e.g. floor-02-tab-1-comment-0.png). Empty drawings are skipped.
+
+The labels drawn on floor tiles are not exported, as where the game stores
+them in the profile is not known.`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  exportDrawings,
+	}
+	addSlotFlag(cmd, "export")
+	cmd.Flags().StringVar(&exportDir, "dir", ".", "`DIR` to write the images to")
+	cmd.Flags().IntVar(&drawingsWidth, "width", 384, "Width of the images in `PIXELS` (the height is a third of it)")
+	addCommentSpaceFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportSQLiteBackups bool
+)
+
+// The tables written by export-sqlite. Every export adds a snapshot, and
+// every other row belongs to a snapshot, so exports of the same profile
+// over time can be joined on floor and tab
+const sqliteTables = `
+CREATE TABLE IF NOT EXISTS snapshots (
+	id             INTEGER PRIMARY KEY,
+	path           TEXT NOT NULL,
+	modified       TEXT NOT NULL,
+	slot           INTEGER NOT NULL,
+	exported       TEXT NOT NULL,
+	generator      TEXT NOT NULL,
+	schema_version INTEGER NOT NULL,
+	UNIQUE (path, modified, slot)
+);
+CREATE TABLE IF NOT EXISTS stats (
+	snapshot             INTEGER PRIMARY KEY REFERENCES snapshots (id),
+	floors               INTEGER NOT NULL,
+	solved               INTEGER NOT NULL,
+	size_challenges_met  INTEGER NOT NULL,
+	speed_challenges_met INTEGER NOT NULL,
+	total_size           INTEGER NOT NULL,
+	total_steps          INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS floors (
+	snapshot     INTEGER NOT NULL REFERENCES snapshots (id),
+	floor        INTEGER NOT NULL,
+	name         TEXT NOT NULL,
+	completed    INTEGER NOT NULL,
+	size         INTEGER,
+	speed        INTEGER,
+	size_target  INTEGER NOT NULL,
+	speed_target INTEGER NOT NULL,
+	PRIMARY KEY (snapshot, floor)
+);
+CREATE TABLE IF NOT EXISTS tabs (
+	snapshot INTEGER NOT NULL REFERENCES snapshots (id),
+	floor    INTEGER NOT NULL,
+	tab      INTEGER NOT NULL,
+	size     INTEGER NOT NULL,
+	hash     TEXT NOT NULL,
+	PRIMARY KEY (snapshot, floor, tab)
+);
+CREATE TABLE IF NOT EXISTS instructions (
+	snapshot INTEGER NOT NULL REFERENCES snapshots (id),
+	floor    INTEGER NOT NULL,
+	tab      INTEGER NOT NULL,
+	position INTEGER NOT NULL,
+	line     INTEGER,
+	op       TEXT NOT NULL,
+	arg      INTEGER,
+	indirect INTEGER NOT NULL,
+	label    TEXT,
+	target   TEXT,
+	comment  INTEGER,
+	PRIMARY KEY (snapshot, floor, tab, position)
+);
+CREATE TABLE IF NOT EXISTS comment_points (
+	snapshot INTEGER NOT NULL REFERENCES snapshots (id),
+	floor    INTEGER NOT NULL,
+	tab      INTEGER NOT NULL,
+	comment  INTEGER NOT NULL,
+	stroke   INTEGER NOT NULL,
+	point    INTEGER NOT NULL,
+	x        INTEGER NOT NULL,
+	y        INTEGER NOT NULL,
+	PRIMARY KEY (snapshot, floor, tab, comment, stroke, point)
+);
+`
+
+// Return nil (NULL) for the zero value of a column, or the value
+func sqlNullable(v interface{}) interface{} {
+	switch v {
+	case 0, "":
+		return nil
+	}
+	return v
+}
+
+// Insert the profile decoded from a snapshot into the database, reporting
+// whether the snapshot was added (it was not if it was exported before)
+func insertSQLiteSnapshot(tx *sql.Tx, snapshot profileSnapshot, slot int, p profile.Profile) (bool, error) {
+	modified := snapshot.modTime.UTC().Format(time.RFC3339Nano)
+	var existing int64
+	err := tx.QueryRow(`SELECT id FROM snapshots WHERE path = ? AND modified = ? AND slot = ?`, snapshot.path, modified, slot).Scan(&existing)
+	if err == nil {
+		logger.Debug("snapshot already exported", "path", snapshot.path, "snapshot", existing)
+		return false, nil
+	} else if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	result, err := tx.Exec(`INSERT INTO snapshots (path, modified, slot, exported, generator, schema_version) VALUES (?, ?, ?, ?, ?, ?)`,
+		snapshot.path, modified, slot, time.Now().UTC().Format(time.RFC3339), "hrm-profile-tool "+version, schema.Version)
+	if err != nil {
+		return false, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+
+	s := profile.Summary(p)
+	if _, err := tx.Exec(`INSERT INTO stats VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, s.Floors, s.Solved, s.SizeChallengesMet, s.SpeedChallengesMet, s.TotalSize, s.TotalSteps); err != nil {
+		return false, err
+	}
+
+	for _, floor := range p.Schema(slot).Floors {
+		if _, err := tx.Exec(`INSERT INTO floors VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, floor.Number, floor.Name, floor.Completed, floor.Size, floor.Speed, floor.SizeTarget, floor.SpeedTarget); err != nil {
+			return false, err
+		}
+		for _, tab := range floor.Tabs {
+			if _, err := tx.Exec(`INSERT INTO tabs VALUES (?, ?, ?, ?, ?)`, id, floor.Number, tab.Tab, tab.Size, tab.Hash); err != nil {
+				return false, err
+			}
+			for position, inst := range tab.Instructions {
+				if _, err := tx.Exec(`INSERT INTO instructions VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+					id, floor.Number, tab.Tab, position, sqlNullable(inst.Line), inst.Op, inst.Arg, inst.Indirect,
+					sqlNullable(inst.Label), sqlNullable(inst.Target), inst.Comment); err != nil {
+					return false, err
+				}
+			}
+			for comment, strokes := range tab.Comments {
+				for stroke, points := range strokes {
+					for point, xy := range points {
+						if _, err := tx.Exec(`INSERT INTO comment_points VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+							id, floor.Number, tab.Tab, comment, stroke, point, xy.X, xy.Y); err != nil {
+							return false, err
+						}
+					}
+				}
+			}
+		}
+	}
+	return true, nil
+}
+
+// Export the profile in a snapshot to the database, reporting whether the
+// snapshot was added
+func exportSQLiteSnapshot(db *sql.DB, snapshot profileSnapshot) (bool, error) {
+	reader, err := openProfileAt(snapshot.path)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+	size, err := profileSize(reader)
+	if err != nil {
+		return false, err
+	}
+	layout := profileLayout(reader)
+	if layout.SlotCount(size) < slotNumber {
+		return false, fmt.Errorf("%s has no slot %d", snapshot.path, slotNumber)
+	}
+	p, err := decodeSlot(reader, slotNumber, profile.ProgramOptions(programDecodeOptions()...))
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := db.BeginTx(appContext, nil)
+	if err != nil {
+		return false, err
+	}
+	added, err := insertSQLiteSnapshot(tx, snapshot, slotNumber, p)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return added, tx.Commit()
+}
+
+func exportSQLite(cmd *cobra.Command, args []string) {
+	checkSlotNumber(slotNumber)
+	path, err := profileFilePath()
+	if err != nil {
+		fatal(err)
+	}
+	var snapshots []profileSnapshot
+	if exportSQLiteBackups {
+		if snapshots, err = profileSnapshots(path); err != nil {
+			fatal(err)
+		}
+	} else {
+		snapshot := profileSnapshot{path: path, modTime: time.Now()}
+		if info, err := os.Stat(path); err == nil {
+			snapshot.modTime = info.ModTime()
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	db, err := openSQLite(args[0])
+	if err != nil {
+		fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(appContext, sqliteTables); err != nil {
+		fatal(fmt.Errorf("%s: %w", args[0], err))
+	}
+
+	added := 0
+	for _, snapshot := range snapshots {
+		ok, err := exportSQLiteSnapshot(db, snapshot)
+		if err != nil && len(snapshots) == 1 {
+			fatal(err)
+		} else if err != nil {
+			logger.Warn("skipping snapshot", "path", snapshot.path, "error", err)
+		} else if ok {
+			added++
+		}
+	}
+	if !logQuiet {
+		fmt.Fprintf(os.Stderr, "Exported %d snapshot(s) to %s\n", added, args[0])
+	}
+}
+
+func exportSQLiteCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-sqlite DATABASE",
+		Short: "Export the profile to an SQLite database",
+		Long: `Export the floors, tabs, instructions, comment drawings and statistics of a
+save slot into relational tables of an SQLite database, for analysis with SQL.
+
+Every export adds a snapshot (the snapshots table) to the database, created if
+it does not exist, and every other table has a snapshot column. Exporting the
+same profile over time therefore allows comparing the snapshots, e.g. the
+progress of the size of floor 10:
+
+  SELECT snapshots.modified, floors.size FROM floors
+  JOIN snapshots ON snapshots.id = floors.snapshot
+  WHERE floors.floor = 10 ORDER BY snapshots.modified;
+
+A snapshot of an unchanged profile is only exported once. With --backups the
+backups of the profile (see hrm undo) are exported as well.`,
+		Args: cobra.ExactArgs(1),
+		Run:  exportSQLite,
+	}
+	addSlotFlag(cmd, "export")
+	cmd.Flags().BoolVar(&exportSQLiteBackups, "backups", false, "Also export the backups of the profile")
+	addCommentSpaceFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+// The recognizer run when --recognizer is not given
+const defaultRecognizer = "hrm-recognize"
+
+var (
+	findCommentRecognizer string
+	findCommentReindex    bool
+)
+
+// The recognized text of comment drawings, keyed by the recognizer and
+// the hash of the drawing, so that each drawing is only recognized once
+type commentIndex struct {
+	path    string
+	Entries map[string]string `json:"entries"`
+	changed bool
+}
+
+// Return the path of the comment index in the user's cache directory
+func commentIndexPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hrm-profile-tool", "comment-index.json"), nil
+}
+
+// Load the comment index, or start an empty index if it does not exist
+// or cannot be read
+func loadCommentIndex() *commentIndex {
+	index := &commentIndex{Entries: make(map[string]string)}
+	path, err := commentIndexPath()
+	if err != nil {
+		logger.Warn("not using the comment index", "error", err)
+		return index
+	}
+	index.path = path
+	if findCommentReindex {
+		return index
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("cannot read the comment index, recognizing all comments", "path", path, "error", err)
+		}
+		return index
+	}
+	if err := json.Unmarshal(data, index); err != nil || index.Entries == nil {
+		logger.Warn("invalid comment index, recognizing all comments", "path", path, "error", err)
+		index.Entries = make(map[string]string)
+	}
+	return index
+}
+
+// Write the comment index back to the cache directory, if it was changed
+func (index *commentIndex) save() error {
+	if index.path == "" || !index.changed {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(index.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(index.path, data, 0644)
+}
+
+// Return the recognized text of a drawing, running the recognizer if the
+// drawing is not in the index. The recognizer reads the drawing as a PNG
+// image on its standard input and writes the text to its standard output
+func (index *commentIndex) recognize(recognizer []string, comment instructions.Comment) (string, error) {
+	var image bytes.Buffer
+	if err := png.Encode(&image, render.RenderDrawing(comment)); err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(image.Bytes())
+	key := strings.Join(recognizer, " ") + "\x00" + hex.EncodeToString(hash[:])
+	if text, found := index.Entries[key]; found {
+		return text, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(appContext, recognizer[0], recognizer[1:]...)
+	cmd.Stdin = &image
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if appContext.Err() != nil {
+			return "", appContext.Err()
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("recognizer %s: %w: %s", recognizer[0], err, message)
+		}
+		return "", fmt.Errorf("recognizer %s: %w", recognizer[0], err)
+	}
+	text := strings.Join(strings.Fields(stdout.String()), " ")
+	index.Entries[key] = text
+	index.changed = true
+	return text, nil
+}
+
+// Return the number of the line preceding each entry of a program (0 for
+// entries before the first instruction), to locate comments
+func precedingLines(disassembled instructions.Disassembled) []int {
+	lines := make([]int, len(disassembled))
+	line := 0
+	for i, item := range disassembled {
+		lines[i] = line
+		if numbered, ok := item.(instructions.LineNumbered); ok {
+			line = numbered.Line()
+		}
+	}
+	return lines
+}
+
+func findComment(cmd *cobra.Command, args []string) {
+	recognizer := strings.Fields(findCommentRecognizer)
+	if len(recognizer) == 0 {
+		usageFatalf("--recognizer must not be empty")
+	}
+	if _, err := exec.LookPath(recognizer[0]); err != nil {
+		fatalf("%v (install a handwriting recognizer, see hrm find-comment --help)", err)
+	}
+	query := strings.ToLower(strings.Join(strings.Fields(args[0]), " "))
+
+	reader := openProfile()
+	defer reader.Close()
+	checkSlot(reader, slotNumber)
+
+	index := loadCommentIndex()
+	saveIndex := func() {
+		if err := index.save(); err != nil {
+			logger.Warn("cannot write the comment index", "path", index.path, "error", err)
+		}
+	}
+
+	matches := 0
+	for floorIndex := 0; floorIndex < len(profile.Profile{}.Floors); floorIndex++ {
+		for tab := 0; tab < 3; tab++ {
+			tabStart := profileLayout(reader).TabStartAddr(slotNumber, floorIndex, tab)
+			program, err := render.DecodeProgramAt(appContext, reader, tabStart, instructions.Logger(logger))
+			if err != nil {
+				logger.Warn("skipping tab", "error", &profile.DecodeError{Offset: tabStart, Floor: floorNumber(floorIndex), Tab: tab + 1, Err: err})
+				continue
+			}
+			lines := precedingLines(program.Disassembled)
+			for position, item := range program.Disassembled {
+				comment, ok := item.(instructions.DisassembleComment)
+				if !ok || int(comment.Index) >= len(program.Comments) || len(program.Comments[comment.Index]) == 0 {
+					continue
+				}
+				text, err := index.recognize(recognizer, program.Comments[comment.Index])
+				if err != nil {
+					saveIndex()
+					fatal(err)
+				}
+				if !strings.Contains(strings.ToLower(text), query) {
+					continue
+				}
+				matches++
+				fmt.Printf("floor %d tab %d position %d (after line %d) comment %d: %s\n",
+					floorNumber(floorIndex), tab+1, position, lines[position], comment.Index, text)
+			}
+		}
+	}
+	saveIndex()
+	if matches == 0 {
+		os.Exit(1)
+	}
+}
+
+func findCommentCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "find-comment TEXT",
+		Short: "Find comments by their handwritten text",
+		Long: `Recognize the handwriting of the comment drawings of every floor and report
+the comments whose text contains TEXT (ignoring case), with their floor, tab,
+position in the program and comment index. Exits with status 1 if no comment
+matches.
+
+Recognition is done by an external recognizer (--recognizer, by default
+` + defaultRecognizer + ` on PATH), which reads the drawing as a PNG image on its
+standard input and writes the recognized text to its standard output, e.g.
+--recognizer "tesseract stdin stdout --psm 7". The recognized text is kept in
+an index in the user's cache directory, so each drawing is only recognized
+once per recognizer.`,
+		Args: cobra.ExactArgs(1),
+		Run:  findComment,
+	}
+	addSlotFlag(cmd, "search")
+	cmd.Flags().StringVar(&findCommentRecognizer, "recognizer", defaultRecognizer, "Handwriting recognizer `COMMAND` and its arguments")
+	cmd.Flags().BoolVar(&findCommentReindex, "reindex", false, "Recognize all drawings again, replacing the index")
+	return cmd
+}
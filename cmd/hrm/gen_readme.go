@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	readmeDir   string
+	readmeTitle string
+)
+
+// The names of files written by export-all, see exportFileName
+var exportFileNameRegexp = regexp.MustCompile(`^floor-(\d+)-tab-(\d+)(\..+)$`)
+
+// An exported solution file found in the solutions directory
+type solutionFile struct {
+	tab  int
+	ext  string
+	path string
+}
+
+// Find the exported solution files in dir, by floor
+func findSolutionFiles(dir string) (map[int][]solutionFile, error) {
+	files := make(map[int][]solutionFile)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		match := exportFileNameRegexp.FindStringSubmatch(info.Name())
+		if match == nil || strings.HasSuffix(match[3], ".json") {
+			return nil
+		}
+		floor, _ := strconv.Atoi(match[1])
+		tab, _ := strconv.Atoi(match[2])
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[floor] = append(files[floor], solutionFile{tab, strings.TrimPrefix(match[3], "."), filepath.ToSlash(rel)})
+		return nil
+	})
+	for _, floorFiles := range files {
+		sort.Slice(floorFiles, func(i, j int) bool {
+			if floorFiles[i].tab != floorFiles[j].tab {
+				return floorFiles[i].tab < floorFiles[j].tab
+			}
+			return floorFiles[i].ext < floorFiles[j].ext
+		})
+	}
+	return files, err
+}
+
+// Return a challenge result and goal as a table cell
+func challengeCell(result, goal int, met bool) (string, string) {
+	if result < 0 {
+		return "", ""
+	}
+	value := strconv.Itoa(result)
+	if goal > 0 {
+		value += fmt.Sprintf(" / %d", goal)
+	}
+	if met {
+		return value, "✓"
+	}
+	return value, "✗"
+}
+
+// Write the README index of the solutions to w
+func writeReadme(w io.Writer, p profile.Profile, files map[int][]solutionFile) error {
+	floorIndexes := make(map[int]int)
+	var floors []int
+	for floorIndex := range p.Floors {
+		floor := floorNumber(floorIndex)
+		floorIndexes[floor] = floorIndex
+		floors = append(floors, floor)
+	}
+	sort.Ints(floors)
+
+	summary := profile.Summary(p)
+	fmt.Fprintf(w, "# %s\n\n", readmeTitle)
+	fmt.Fprintf(
+		w, "Solved %d/%d floors, %d size challenges and %d speed challenges met.\n\n",
+		summary.Solved, summary.Floors, summary.SizeChallengesMet, summary.SpeedChallengesMet)
+	fmt.Fprintf(w, "| Floor | Level | Solutions | Size | | Steps | |\n")
+	fmt.Fprintf(w, "|------:|-------|-----------|-----:|-|------:|-|\n")
+	for _, floorNumber := range floors {
+		floor := p.Floors[floorIndexes[floorNumber]]
+		level := levels.Floor(floorNumber)
+
+		var links []string
+		for _, file := range files[floorNumber] {
+			links = append(links, fmt.Sprintf("[tab %d (%s)](%s)", file.tab, file.ext, file.path))
+		}
+		size, sizeMet := challengeCell(floor.SizeChallenge, level.SizeChallenge, level.SizeChallengeMet(floor.SizeChallenge))
+		steps, stepsMet := challengeCell(floor.SpeedChallenge, level.SpeedChallenge, level.SpeedChallengeMet(floor.SpeedChallenge))
+		_, err := fmt.Fprintf(
+			w, "| %d | %s | %s | %s | %s | %s | %s |\n",
+			floorNumber, levelName(level), strings.Join(links, "<br>"), size, sizeMet, steps, stepsMet)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "\nGenerated by hrm-profile-tool %s\n", version)
+	return err
+}
+
+func genReadme(cmd *cobra.Command, args []string) {
+	files, err := findSolutionFiles(readmeDir)
+	if err != nil {
+		fatal(err)
+	}
+	p := decodeProfile()
+
+	if outputFileName == "" {
+		outputFileName = filepath.Join(readmeDir, "README.md")
+	}
+	if outputFileName == "-" {
+		outputFileName = ""
+	}
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+	if err := writeReadme(output, p, files); err != nil {
+		fatal(err)
+	}
+}
+
+func genReadmeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen-readme",
+		Short: "Generate a solutions README",
+		Long: `Scan a directory of exported solutions (see export-all) and generate a
+README.md with an index table linking the solutions of each floor, with
+the size and steps results and challenge checkmarks from the profile`,
+		Args: cobra.NoArgs,
+		Run:  genReadme,
+	}
+	addSlotFlag(cmd, "read")
+	cmd.Flags().StringVar(&readmeDir, "dir", ".", "`DIR` containing the exported solutions")
+	cmd.Flags().StringVar(&readmeTitle, "title", "Human Resource Machine solutions", "`TITLE` of the README")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write to (default DIR/README.md, - for stdout)")
+	return cmd
+}
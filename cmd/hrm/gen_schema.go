@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/clj/hrm-profile-tool/schema"
+	"github.com/spf13/cobra"
+)
+
+var schemaType string
+
+func genSchema(cmd *cobra.Command, args []string) {
+	var v interface{}
+	switch schemaType {
+	case "profile":
+		v = schema.Profile{}
+	case "program":
+		v = schema.Export{}
+	default:
+		usageFatalf("unknown type %q (available: profile, program)", schemaType)
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+	if err := schema.WriteJSONSchema(output, v, "hrm-profile-tool "+version); err != nil {
+		fatal(err)
+	}
+}
+
+func genSchemaCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen-schema",
+		Short: "Generate the JSON Schema of the JSON output",
+		Long: `Generate a JSON Schema of the JSON representation shared by all JSON
+features: profiles (hrm query, the playground) or programs (the json output
+format, external exporters). The schema is generated from the structures used
+to produce the JSON, and its schema_version field identifies the version`,
+		Args: cobra.NoArgs,
+		Run:  genSchema,
+	}
+	cmd.Flags().StringVar(&schemaType, "type", "profile", "`TYPE` to describe (profile, program)")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the schema to")
+	return cmd
+}
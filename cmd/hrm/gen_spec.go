@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+var specTarget string
+
+func genSpec(cmd *cobra.Command, args []string) {
+	layout := selectedLayout()
+	var write func(io.Writer, string) error
+	switch specTarget {
+	case "kaitai":
+		write = layout.WriteKaitaiSpec
+	case "010":
+		write = layout.Write010Template
+	default:
+		usageFatalf("unknown target %q (available: kaitai, 010)", specTarget)
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+	if err := write(output, "hrm-profile-tool "+version); err != nil {
+		fatal(err)
+	}
+}
+
+func genSpecCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen-spec",
+		Short: "Generate a description of the profile format",
+		Long: `Generate a description of the profile format from the constants and
+structures used by the decoder, as a Kaitai Struct (.ksy) or a 010 Editor
+binary template (.bt). The description is of the layout given with --layout,
+by default that of the PC versions`,
+		Args: cobra.NoArgs,
+		Run:  genSpec,
+	}
+	cmd.Flags().StringVar(&specTarget, "target", "kaitai", "`TARGET` format (kaitai, 010)")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the description to")
+	return cmd
+}
@@ -1,14 +1,25 @@
 module github.com/clj/hrm-profile-tool/cmd/hrm
 
 require (
+	github.com/clj/hrm-profile-tool/analysis v0.0.0
+	github.com/clj/hrm-profile-tool/instructions v0.0.0
+	github.com/clj/hrm-profile-tool/schema v0.0.0
+	github.com/clj/hrm-profile-tool/metadata v0.0.0
 	github.com/clj/hrm-profile-tool/profile v0.0.0
+	github.com/clj/hrm-profile-tool/render v0.0.0
 	github.com/clj/hrm-profile-tool/utils/text v0.0.0
-	github.com/clj/hrm-profile-tool/utils/seekbufio v0.0.0
+	github.com/clj/hrm-profile-tool/journal v0.0.0
+	github.com/clj/hrm-profile-tool/levels v0.0.0
+	github.com/clj/hrm-profile-tool/locale v0.0.0
+	github.com/clj/hrm-profile-tool/vm v0.0.0
 
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/jmespath/go-jmespath v0.4.0
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/mitchellh/go-homedir v1.0.0
 	github.com/spf13/cobra v0.0.3
-	github.com/spf13/pflag v1.0.2 // indirect
+	github.com/spf13/pflag v1.0.2
+	go.starlark.net v0.0.0-20200306205701-8dd3e2ee1dd5
 )
 
 replace github.com/clj/hrm-profile-tool/profile => ../../profile
@@ -18,3 +29,21 @@ replace github.com/clj/hrm-profile-tool/utils/text => ../../utils/text
 replace github.com/clj/hrm-profile-tool/utils/seekbufio => ../../utils/seekbufio
 
 replace github.com/clj/hrm-profile-tool/instructions => ../../instructions
+
+replace github.com/clj/hrm-profile-tool/render => ../../render
+
+replace github.com/clj/hrm-profile-tool/metadata => ../../metadata
+
+replace github.com/clj/hrm-profile-tool/journal => ../../journal
+
+replace github.com/clj/hrm-profile-tool/locale => ../../locale
+
+replace github.com/clj/hrm-profile-tool/utils/logging => ../../utils/logging
+
+replace github.com/clj/hrm-profile-tool/vm => ../../vm
+
+replace github.com/clj/hrm-profile-tool/analysis => ../../analysis
+
+replace github.com/clj/hrm-profile-tool/schema => ../../schema
+
+replace github.com/clj/hrm-profile-tool/levels => ../../levels
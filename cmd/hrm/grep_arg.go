@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	grepArgIndirectOnly bool
+	grepArgDirectOnly   bool
+	grepArgContext      int
+)
+
+// Report whether item is an instruction referencing tile
+func referencesTile(item instructions.DisassembleInterface, tile uint32) bool {
+	instruction, ok := item.(instructions.DisassembleArgInstruction)
+	switch {
+	case !ok || instruction.Arg != tile:
+		return false
+	case grepArgIndirectOnly:
+		return instruction.Indirect
+	case grepArgDirectOnly:
+		return !instruction.Indirect
+	}
+	return true
+}
+
+func grepArg(cmd *cobra.Command, args []string) {
+	tile := parseInt(args[0])
+	switch {
+	case tile < 0:
+		usageFatalf("invalid tile %d, tiles are numbered from 0", tile)
+	case grepArgIndirectOnly && grepArgDirectOnly:
+		usageFatalf("--indirect-only and --direct-only are mutually exclusive")
+	case grepArgContext < 0:
+		usageFatalf("--context must not be negative")
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+	checkSlot(reader, slotNumber)
+	p, err := decodeSlot(reader, slotNumber)
+	if err != nil {
+		fatal(err)
+	}
+
+	var opts []render.RenderInstructionsTextOption
+	if mnemonics := textMnemonics(); mnemonics != nil {
+		opts = append(opts, render.UseMnemonics(mnemonics))
+	}
+	matches := 0
+	shown := false // Whether any entries were shown, to separate the context groups
+	for _, floor := range profile.AllFloors() {
+		floorIndex, _ := profile.FloorToIndex(floor)
+		for tabIndex, tab := range p.Floors[floorIndex].Tabs {
+			// The rendered text has a line for each entry of the program
+			// (followed by the comment definitions)
+			var lines []string
+			lastShown := -1
+			for i, item := range tab.Code {
+				if !referencesTile(item, uint32(tile)) {
+					continue
+				}
+				if lines == nil {
+					lines = strings.Split(render.RenderInstructionsText(tab.Code, opts...), "\n")
+				}
+				if grepArgContext > 0 && shown && (lastShown < 0 || lastShown < i-grepArgContext-1) {
+					fmt.Println("--")
+				}
+				matches++
+				first, last := i-grepArgContext, i+grepArgContext
+				if first <= lastShown {
+					first = lastShown + 1
+				}
+				if first < 0 {
+					first = 0
+				}
+				if last >= len(tab.Code) {
+					last = len(tab.Code) - 1
+				}
+				for j := first; j <= last; j++ {
+					if j > i && referencesTile(tab.Code[j], uint32(tile)) {
+						// Shown as a match in its own right
+						break
+					}
+					separator := "-"
+					if j == i {
+						separator = ":"
+					}
+					line := ""
+					if numbered, ok := tab.Code[j].(instructions.LineNumbered); ok {
+						line = fmt.Sprint(numbered.Line())
+					}
+					fmt.Printf("floor %d tab %d line %3s%s %s\n", floor, tabIndex+1, line, separator, strings.TrimSpace(lines[j]))
+					lastShown, shown = j, true
+				}
+			}
+		}
+	}
+	if matches == 0 {
+		os.Exit(1)
+	}
+}
+
+func grepArgCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grep-arg TILE",
+		Short: "Find the instructions referencing a tile",
+		Long: `Report every instruction of every floor and tab that references the floor
+tile TILE (e.g. COPYFROM 24 or ADD [24]), with its floor, tab and line. Exits
+with status 1 if no instruction references the tile.`,
+		Args: cobra.ExactArgs(1),
+		Run:  grepArg,
+	}
+	addSlotFlag(cmd, "search")
+	cmd.Flags().BoolVar(&grepArgIndirectOnly, "indirect-only", false, "Only report indirect references (e.g. COPYFROM [24])")
+	cmd.Flags().BoolVar(&grepArgDirectOnly, "direct-only", false, "Only report direct references (e.g. COPYFROM 24)")
+	cmd.Flags().IntVarP(&grepArgContext, "context", "C", 0, "Show `N` entries of context around each reference")
+	return cmd
+}
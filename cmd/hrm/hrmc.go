@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var hrmcFloor int
+
+func hrmc(cmd *cobra.Command, args []string) {
+	if hrmcFloor != 0 {
+		checkFloorArg(hrmcFloor)
+	}
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		fatal(err)
+	}
+	program, err := instructions.Compile(string(data))
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", args[0], err))
+	}
+	disassembled, err := instructions.Disassemble(program)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", args[0], err))
+	}
+
+	var opts []render.RenderInstructionsTextOption
+	if mnemonics := textMnemonics(); mnemonics != nil {
+		opts = append(opts, render.UseMnemonics(mnemonics))
+	}
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	if _, err := output.Write([]byte(render.RenderInstructionsText(disassembled, opts...))); err != nil {
+		fatal(err)
+	}
+	if err := output.Close(); err != nil {
+		fatal(err)
+	}
+
+	if logQuiet {
+		return
+	}
+	size := disassembled.Size()
+	if hrmcFloor == 0 {
+		fmt.Fprintf(os.Stderr, "size: %d instructions\n", size)
+		return
+	}
+	level := levels.Floor(hrmcFloor)
+	verdict := "met"
+	if size > level.SizeChallenge {
+		verdict = fmt.Sprintf("missed by %d", size-level.SizeChallenge)
+	}
+	fmt.Fprintf(os.Stderr, "size: %d instructions, floor %d %s size challenge: %d (%s)\n", size, hrmcFloor, levelName(level), level.SizeChallenge, verdict)
+}
+
+func hrmcCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hrmc FILE",
+		Short: "Compile a program written in a small structured language",
+		Long: `Compile FILE (or - for stdin), written in hrmc, a small structured
+language, into a program, and write it in the text format of the game,
+ready to paste into a tab. The size of the program is reported on stderr,
+and with --floor compared against the size challenge of the floor.
+
+Variables live on the floor tiles, which are given when declared:
+
+  var a @ 0
+  var b @ 1
+  var zero @ 5 = 0     # the level starts with 0 on tile 5
+  loop {
+      a = inbox
+      b = inbox
+      if a < b { outbox a } else { outbox b }
+  }
+
+The statements are NAME = EXPRESSION, outbox EXPRESSION, ++NAME, --NAME,
+if/else if/else, while CONDITION, loop and break. Expressions start with
+inbox, ++NAME, --NAME or an operand, followed by any number of + OPERAND or
+- OPERAND, and conditions compare an expression with ==, !=, <, <=, > or
+>= to 0 or an operand. Operands are variables, *NAME for the tile NAME
+points to, @TILE for a tile by number, and literals (e.g. 0, -3 or 'A'),
+which must be declared as the initial value of a tile. Comments run from #
+to the end of the line.`,
+		Args: cobra.ExactArgs(1),
+		Run:  hrmc,
+	}
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the program to")
+	cmd.Flags().IntVar(&hrmcFloor, "floor", 0, "Compare the size against the size challenge of `FLOOR`")
+	return cmd
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/subtle"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// The cookie remembering the auth token given in a URL, so that the links
+// of the served pages work without it
+const authCookie = "hrm_token"
+
+var (
+	httpReadOnly  bool
+	httpAuthToken string
+	httpRateLimit int
+	httpBurst     int
+)
+
+// A token bucket per client address
+type rateLimiter struct {
+	mutex   sync.Mutex
+	rate    float64
+	burst   float64
+	clients map[string]*bucket
+	pruned  time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Return a limiter allowing rate requests per second, in bursts of up to
+// burst requests
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: float64(burst), clients: make(map[string]*bucket)}
+}
+
+// Report whether client may make a request now, and otherwise how long
+// until it may
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	// Forget clients whose buckets have filled up again
+	if full := time.Duration(l.burst / l.rate * float64(time.Second)); now.Sub(l.pruned) > full {
+		for address, b := range l.clients {
+			if now.Sub(b.updated) > full {
+				delete(l.clients, address)
+			}
+		}
+		l.pruned = now
+	}
+	b, found := l.clients[client]
+	if !found {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.clients[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// Report whether r carries token, as a bearer token, a token query
+// parameter or the cookie set for it
+func hasAuthToken(r *http.Request, token string) bool {
+	matches := func(s string) bool {
+		return subtle.ConstantTimeCompare([]byte(s), []byte(token)) == 1
+	}
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") && matches(header[len("Bearer "):]) {
+		return true
+	}
+	if cookie, err := r.Cookie(authCookie); err == nil && matches(cookie.Value) {
+		return true
+	}
+	return matches(r.URL.Query().Get("token"))
+}
+
+// Wrap handler with the checks of --read-only, --auth-token and
+// --rate-limit
+func guardHandler(handler http.Handler) http.Handler {
+	var limiter *rateLimiter
+	if httpRateLimit > 0 {
+		limiter = newRateLimiter(float64(httpRateLimit)/60, httpBurst)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limiter != nil {
+			client, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				client = r.RemoteAddr
+			}
+			if allowed, wait := limiter.allow(client, time.Now()); !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "too many requests", http.StatusTooManyRequests)
+				logger.Debug("rate limited", "client", client, "path", r.URL.Path)
+				return
+			}
+		}
+		if httpAuthToken != "" {
+			if !hasAuthToken(r, httpAuthToken) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="hrm"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("token") != "" {
+				http.SetCookie(w, &http.Cookie{Name: authCookie, Value: httpAuthToken, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+			}
+		}
+		// No endpoint modifies the profile or the server yet, so this only
+		// refuses the other methods before they reach a handler
+		if httpReadOnly && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "the server is read-only", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Check the HTTP flags, reading the auth token from HRM_AUTH_TOKEN unless
+// given, and warn about serving beyond localhost without a token
+func checkHTTPFlags(addr string) {
+	if httpRateLimit < 0 {
+		usageFatalf("--rate-limit must not be negative")
+	}
+	if httpRateLimit > 0 && httpBurst < 1 {
+		usageFatalf("--burst must be at least 1")
+	}
+	if httpAuthToken == "" {
+		httpAuthToken = os.Getenv("HRM_AUTH_TOKEN")
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		usageFatalf("invalid address %q: %v", addr, err)
+	}
+	if ip := net.ParseIP(host); httpAuthToken == "" && host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		logger.Warn("serving beyond localhost without --auth-token, anyone who can reach the server can use it", "addr", addr)
+	}
+}
+
+// Add the flags protecting the HTTP servers
+func addHTTPFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&httpReadOnly, "read-only", false, "Refuse requests other than GET and HEAD (no endpoint modifies anything yet)")
+	cmd.Flags().StringVar(&httpAuthToken, "auth-token", "", "Require `TOKEN` as a bearer token or ?token= parameter (default $HRM_AUTH_TOKEN)")
+	cmd.Flags().IntVar(&httpRateLimit, "rate-limit", 0, "Limit each client to `N` requests per minute (0 for no limit)")
+	cmd.Flags().IntVar(&httpBurst, "burst", 20, "Allow bursts of up to `N` requests with --rate-limit")
+}
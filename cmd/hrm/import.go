@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/analysis"
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/journal"
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/metadata"
+	"github.com/spf13/cobra"
+)
+
+var importForce bool
+
+// Describe the metadata of an imported program, e.g. "by Alice,
+// 2024-03-01, 12 instruction(s), 87 step(s), generated by hrm-profile-tool"
+func describeMetadata(m metadata.Metadata) string {
+	var parts []string
+	if m.Author != "" {
+		parts = append(parts, "by "+m.Author)
+	}
+	if !m.Date.IsZero() {
+		parts = append(parts, m.Date.Format("2006-01-02"))
+	}
+	if m.Size > 0 {
+		parts = append(parts, fmt.Sprintf("%d instruction(s)", m.Size))
+	}
+	if m.Steps > 0 {
+		parts = append(parts, fmt.Sprintf("%d step(s)", m.Steps))
+	}
+	if m.GameVersion != "" {
+		parts = append(parts, "game version "+m.GameVersion)
+	}
+	if m.Generator != "" {
+		parts = append(parts, "generated by "+m.Generator)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Assemble the program text read from source and write it into a tab of
+// the profile at path, after checking it against the floor. A metadata
+// header at the start of the text (see hrm text --metadata) takes the
+// place of the metadata of a sidecar file, it is reported and recorded in
+// the journal
+func importProgram(command, path, source string, text []byte, sidecar metadata.Metadata, floorIndex, tab int) {
+	floor := floorNumber(floorIndex)
+	meta, body, err := metadata.ParseText(string(text))
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", source, err))
+	}
+	if meta == (metadata.Metadata{}) {
+		meta = sidecar
+	}
+	var opts []instructions.AssembleOption
+	if mnemonics := textMnemonics(); mnemonics != nil {
+		opts = append(opts, instructions.AssembleMnemonics(mnemonics))
+	}
+	program, comments, err := instructions.Assemble(body, opts...)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", source, err))
+	}
+	disassembled, err := instructions.Disassemble(program)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", source, err))
+	}
+	size := disassembled.Size()
+	if size == 0 {
+		fatalf("%s: no instructions found", source)
+	}
+	var details []string
+	if meta != (metadata.Metadata{}) {
+		description := describeMetadata(meta)
+		details = append(details, "metadata: "+description)
+		if !logQuiet {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", source, description)
+		}
+		if meta.Size > 0 && meta.Size != size {
+			logger.Warn("the program has another size than recorded in its metadata", "size", size, "metadata_size", meta.Size)
+		}
+	}
+
+	// Validate the program against the floor, e.g. that its tiles exist
+	failed := 0
+	if level, found := levels.Lookup(floor); found {
+		for _, finding := range analysis.Analyze(disassembled, analysis.ForLevel(level), analysis.Enable("value-ranges")) {
+			if finding.Severity == analysis.SEVERITY_ERROR {
+				failed++
+				fmt.Fprintln(os.Stderr, finding)
+			} else {
+				logger.Warn(finding.Message, "line", finding.Line, "check", finding.Check)
+			}
+		}
+		if size > level.SizeChallenge {
+			logger.Info("the program misses the size challenge", "size", size, "challenge", level.SizeChallenge)
+		}
+	}
+	if failed > 0 && !importForce {
+		fatalf("%d error(s) in the program for floor %d, use --force to import it anyway", failed, floor)
+	}
+
+	data, err := instructions.EncodeTab(program, comments)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", source, err))
+	}
+	reader, err := openProfileAt(path)
+	if err != nil {
+		fatal(err)
+	}
+	checkSlot(reader, slotNumber)
+	layout := profileLayout(reader)
+	reader.Close()
+	if len(data) > layout.FloorTabSize {
+		fatalf("the program takes %d bytes, a tab is %d bytes", len(data), layout.FloorTabSize)
+	}
+
+	description := fmt.Sprintf("floor %d tab %d: import %d instruction(s) from %s", floor, tab+1, size, source)
+	if meta.Author != "" {
+		description += " by " + meta.Author
+	}
+	applyEdit(command, path, description, []journal.Change{{Offset: layout.TabStartAddr(slotNumber, floorIndex, tab), Modified: data}}, details...)
+}
+
+func importFile(cmd *cobra.Command, args []string) {
+	floorIndex, tab := floorTabArgs(args[1:])
+	path := editProfilePath()
+
+	var text []byte
+	var sidecar metadata.Metadata
+	var err error
+	if args[0] == "-" {
+		text, err = ioutil.ReadAll(os.Stdin)
+	} else if text, err = ioutil.ReadFile(args[0]); err == nil {
+		if sidecar, err = metadata.ReadSidecar(args[0]); os.IsNotExist(err) {
+			err = nil
+		} else if err != nil {
+			err = fmt.Errorf("%s: %w", metadata.SidecarPath(args[0]), err)
+		}
+	}
+	if err != nil {
+		fatal(err)
+	}
+	importProgram("import", path, args[0], text, sidecar, floorIndex, tab)
+}
+
+func importCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import FILE FLOOR TAB",
+		Short: "Import a program from a text file into a tab",
+		Long: `Write the program in FILE (- for stdin), as copied from the game or
+written by hrm text, into a tab of the profile, replacing its program.
+The metadata of the program, from a header written by hrm text --metadata
+or from the FILE.json sidecar file, is reported and recorded in the edit
+journal; a malformed header is an error.
+
+The text is assembled as when pasted into the game (with the mnemonics of
+--mnemonics or --lang) and checked against the floor: programs using tiles
+the floor does not have, or with other errors found by hrm lint, are only
+imported with --force.
+
+The profile is backed up first and the change is recorded in the edit
+journal, revert it with hrm undo`,
+		Args: cobra.ExactArgs(3),
+		Run:  importFile,
+	}
+	cmd.Flags().BoolVar(&importForce, "force", false, "Import the program even if it has errors")
+	addEditFlags(cmd)
+	return cmd
+}
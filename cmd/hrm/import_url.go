@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/metadata"
+	"github.com/spf13/cobra"
+)
+
+// The largest program text downloaded. Programs with many comments are
+// tens of kB
+const maxPasteSize = 1 << 20
+
+// Return the URL of the raw text of a paste or a file on GitHub, given
+// the URL of the page showing it. Other URLs are returned unchanged
+func rawPasteURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch host := strings.TrimPrefix(strings.ToLower(u.Host), "www."); {
+	case host == "pastebin.com" && len(parts) == 1:
+		u.Path = "/raw/" + parts[0]
+	case host == "hastebin.com" && len(parts) == 1:
+		u.Path = "/raw/" + parts[0]
+	case host == "paste.ee" && len(parts) == 2 && parts[0] == "p":
+		u.Path = "/r/" + parts[1]
+	case host == "dpaste.com" && len(parts) == 1 && !strings.HasSuffix(parts[0], ".txt"):
+		u.Path = "/" + parts[0] + ".txt"
+	case host == "rentry.co" && len(parts) == 1:
+		u.Path = "/" + parts[0] + "/raw"
+	case host == "github.com" && len(parts) >= 5 && parts[2] == "blob":
+		u.Host = "raw.githubusercontent.com"
+		u.Path = "/" + strings.Join(append(parts[:2:2], parts[3:]...), "/")
+	case host == "gist.github.com" && (len(parts) == 1 || len(parts) == 2):
+		u.Host = "gist.githubusercontent.com"
+		u.Path = "/" + strings.Join(parts, "/") + "/raw"
+	default:
+		return rawURL
+	}
+	u.RawQuery, u.Fragment = "", ""
+	return u.String()
+}
+
+func importURL(cmd *cobra.Command, args []string) {
+	floorIndex, tab := floorTabArgs(args[1:])
+	path := editProfilePath()
+
+	source := rawPasteURL(args[0])
+	if source != args[0] {
+		logger.Debug("downloading the raw text", "url", source)
+	}
+	text, err := fetchURL(source, maxPasteSize)
+	if err != nil {
+		fatal(err)
+	}
+	if strings.HasPrefix(http.DetectContentType(text), "text/html") {
+		fatalf("%s is a web page, not program text, give the URL of the raw text", source)
+	}
+	importProgram("import-url", path, args[0], text, metadata.Metadata{}, floorIndex, tab)
+}
+
+func importURLCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import-url URL FLOOR TAB",
+		Short: "Import a program from a paste or GitHub link into a tab",
+		Long: `Download a program shared as text, as copied from the game, and write it
+into a tab of the profile in one step.
+
+Links to pages of pastebin.com, hastebin.com, paste.ee, dpaste.com,
+rentry.co, GitHub files and gists are turned into links to their raw text;
+other URLs are downloaded as they are. The text is assembled as when
+pasted into the game (with the mnemonics of --mnemonics or --lang) and
+checked against the floor: programs using tiles the floor does not have,
+or with other errors found by hrm lint, are only imported with --force.
+
+The profile is backed up first and the change is recorded in the edit
+journal, revert it with hrm undo`,
+		Args: cobra.ExactArgs(3),
+		Run:  importURL,
+	}
+	cmd.Flags().BoolVar(&importForce, "force", false, "Import the program even if it has errors")
+	addEditFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/locale"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var lang string
+
+// The locale of the command's help and messages, or nil for English. Set
+// up by setupMessages before the commands are created
+var messageLocale *locale.Locale
+
+var (
+	appLocaleOnce sync.Once
+	appLocaleData *locale.Locale
+)
+
+// Return the locale selected with --lang, or nil for the game's English
+// text
+func appLocale() *locale.Locale {
+	appLocaleOnce.Do(func() {
+		if lang == "" || strings.EqualFold(lang, locale.DefaultCode) {
+			return
+		}
+		var err error
+		appLocaleData, err = locale.Get(lang)
+		if err != nil {
+			usageFatalf("%v", err)
+		}
+	})
+	return appLocaleData
+}
+
+// Return the name of level in the language selected with --lang
+func levelName(level levels.Level) string {
+	if l := appLocale(); l != nil {
+		return l.LevelName(level.Floor, level.Name)
+	}
+	return level.Name
+}
+
+// Return the message with id in the language of the command's messages,
+// or english if it has not been translated
+func tr(id, english string) string {
+	return messageLocale.Message(id, english)
+}
+
+// Return the language of the command's messages: --lang, or else the
+// language of the environment's LC_ALL, LC_MESSAGES or LANG. The command
+// line is scanned directly as the help text is translated before the
+// flags are parsed
+func messageLanguage(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--lang" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(arg, "--lang=") {
+			return strings.TrimPrefix(arg, "--lang=")
+		}
+	}
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Load the locale of the command's messages for the command line args.
+// Unknown languages fall back to English, an unknown --lang is reported
+// once the flags are parsed
+func setupMessages(args []string) {
+	code := messageLanguage(args)
+	if code == "" || code == "C" || code == "POSIX" {
+		return
+	}
+	messageLocale, _ = locale.Get(code)
+}
+
+// Translate the help of cmd and its subcommands: the short descriptions
+// (message id "cmd.PATH", e.g. "cmd.export-all"), the flag usages
+// ("flag.PATH.NAME", falling back to "flag.NAME" for flags shared by
+// several commands) and the headings of the usage template
+func localizeCommands(cmd *cobra.Command) {
+	if messageLocale == nil {
+		return
+	}
+	path := "" // The command path without the root command, e.g. "export-all"
+	if fields := strings.Fields(cmd.CommandPath()); len(fields) > 1 {
+		path = strings.Join(fields[1:], ".")
+		cmd.Short = tr("cmd."+path, cmd.Short)
+	}
+	localizeFlag := func(flag *pflag.Flag) {
+		usage := tr("flag."+flag.Name, flag.Usage)
+		if path != "" {
+			usage = tr("flag."+path+"."+flag.Name, usage)
+		}
+		flag.Usage = usage
+	}
+	cmd.Flags().VisitAll(localizeFlag)
+	cmd.PersistentFlags().VisitAll(localizeFlag)
+	if !cmd.HasParent() {
+		cmd.SetUsageTemplate(strings.NewReplacer(
+			"Usage:", tr("help.usage", "Usage:"),
+			"Aliases:", tr("help.aliases", "Aliases:"),
+			"Examples:", tr("help.examples", "Examples:"),
+			"Available Commands:", tr("help.commands", "Available Commands:"),
+			"Global Flags:", tr("help.global-flags", "Global Flags:"),
+			"Flags:", tr("help.flags", "Flags:"),
+			"Additional help topics:", tr("help.topics", "Additional help topics:"),
+			"for more information about a command.", tr("help.more", "for more information about a command."),
+		).Replace(cmd.UsageTemplate()))
+	}
+	for _, child := range cmd.Commands() {
+		localizeCommands(child)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+// The layout of profiles (--layout), auto detects it from the profile size.
+// Only the layout of the PC versions is known, so this is a switch between
+// detecting it (and warning about profiles of other sizes) and assuming it
+var layoutName = "auto"
+
+// The layouts of the open profiles, so that each is only detected once
+var detectedLayouts = struct {
+	sync.Mutex
+	layouts map[profileReader]profile.Layout
+}{layouts: make(map[profileReader]profile.Layout)}
+
+// Return the names of the values of --layout
+func layoutNames() []string {
+	names := []string{"auto"}
+	for _, layout := range profile.Layouts() {
+		names = append(names, layout.Name)
+	}
+	return names
+}
+
+// Return the layout given with --layout, or the default layout if it is
+// to be detected
+func selectedLayout() profile.Layout {
+	if layoutName == "auto" {
+		return profile.DefaultLayout
+	}
+	layout, found := profile.LayoutByName(layoutName)
+	if !found {
+		usageFatalf("unknown layout %q (available: %s)", layoutName, strings.Join(layoutNames(), ", "))
+	}
+	return layout
+}
+
+// Return the layout of the open profile r: the layout given with --layout,
+// or the layout detected from the size of the profile. Profiles of unknown
+// size or layout are assumed to have the default layout
+func profileLayout(r profileReader) profile.Layout {
+	if layoutName != "auto" {
+		return selectedLayout()
+	}
+	detectedLayouts.Lock()
+	defer detectedLayouts.Unlock()
+	if layout, found := detectedLayouts.layouts[r]; found {
+		return layout
+	}
+	layout := profile.DefaultLayout
+	if size, err := profileSize(r); err != nil {
+		logger.Warn("cannot detect the profile layout, assuming the default", "layout", layout.Name, "error", err)
+	} else if detected, found := profile.DetectLayout(size); found {
+		layout = detected
+		logger.Debug("detected profile layout", "layout", layout.Name, "size", size)
+	} else {
+		logger.Warn("unknown profile layout (early builds and console ports are not supported), assuming the default (see --layout)", "layout", layout.Name, "size", size)
+	}
+	detectedLayouts.layouts[r] = layout
+	return layout
+}
+
+// Add the --layout flag to cmd
+func addLayoutFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&layoutName, "layout", layoutName, "Profile `LAYOUT` of the game version ("+strings.Join(layoutNames(), ", ")+"), auto detects it from the profile size. Only the layout of the PC versions is supported")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/clj/hrm-profile-tool/analysis"
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintListChecks bool
+	lintEnable     []string
+)
+
+func lint(cmd *cobra.Command, args []string) {
+	if lintListChecks {
+		for _, check := range analysis.Checks() {
+			optional := ""
+			if check.Optional {
+				optional = " (optional)"
+			}
+			fmt.Printf("%-16s %s%s\n", check.Name, check.Doc, optional)
+		}
+		return
+	}
+	for _, name := range lintEnable {
+		found := false
+		for _, check := range analysis.Checks() {
+			found = found || check.Name == name
+		}
+		if !found {
+			usageFatalf("unknown check %q, see --list-checks", name)
+		}
+	}
+	floor, tab := 0, 0
+	if len(args) > 0 {
+		floor = parseInt(args[0])
+		checkFloorArg(floor)
+	}
+	if len(args) > 1 {
+		tab = parseInt(args[1])
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+	checkSlot(reader, slotNumber)
+	p, err := decodeSlot(reader, slotNumber)
+	if err != nil {
+		fatal(err)
+	}
+
+	failures := 0
+	for floorIndex, f := range p.Floors {
+		if floor != 0 && floorNumber(floorIndex) != floor {
+			continue
+		}
+		opts := []analysis.Option{analysis.Enable(lintEnable...)}
+		if level, found := levels.Lookup(floorNumber(floorIndex)); found {
+			opts = append(opts, analysis.ForLevel(level))
+		}
+		for tabIndex, t := range f.Tabs {
+			if tab != 0 && tabIndex+1 != tab {
+				continue
+			}
+			for _, finding := range analysis.Analyze(t.Code, opts...) {
+				if finding.Severity == analysis.SEVERITY_ERROR {
+					failures++
+				}
+				fmt.Printf("floor %d tab %d %s\n", floorNumber(floorIndex), tabIndex+1, finding)
+			}
+		}
+	}
+	if failures > 0 {
+		os.Exit(1)
+	}
+}
+
+func lintCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint [FLOOR [TAB]]",
+		Short: "Check programs for mistakes without running them",
+		Long: `Statically check the programs of every tab (or of FLOOR, or of FLOOR TAB) for
+mistakes such as loops that can never end. The exit status is 1 if an error
+was found.
+
+Use --list-checks to list the checks, and --enable to run optional checks,
+e.g. --enable value-ranges to find jumps that are never taken given the
+values the level puts in the inbox.`,
+		Args: cobra.MaximumNArgs(2),
+		Run:  lint,
+	}
+	addSlotFlag(cmd, "check")
+	cmd.Flags().BoolVar(&lintListChecks, "list-checks", false, "List the checks and exit")
+	cmd.Flags().StringSliceVar(&lintEnable, "enable", nil, "Run the optional `CHECKS` (comma separated)")
+	return cmd
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	logQuiet bool
+	logDebug bool
+)
+
+// The logger used for diagnostics, configured by setupLogging
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// Configure the logger according to --quiet and --debug. Warnings are
+// shown by default, and the levels of warnings and errors are colored as
+// by --color
+func setupLogging(cmd *cobra.Command, args []string) {
+	level := slog.LevelWarn
+	switch {
+	case logDebug:
+		level = slog.LevelDebug
+	case logQuiet:
+		level = slog.LevelError
+	}
+	checkColorMode()
+	options := &slog.HandlerOptions{Level: level}
+	if useColor(os.Stderr) {
+		options.ReplaceAttr = colorLevel
+	}
+	logger = slog.New(slog.NewTextHandler(os.Stderr, options))
+}
+
+// Color the level of log records
+func colorLevel(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Key != slog.LevelKey || len(groups) > 0 {
+		return attr
+	}
+	level, ok := attr.Value.Any().(slog.Level)
+	if !ok {
+		return attr
+	}
+	switch {
+	case level >= slog.LevelError:
+		attr.Value = slog.StringValue(paint(true, ansiRed, level.String()))
+	case level >= slog.LevelWarn:
+		attr.Value = slog.StringValue(paint(true, ansiYellow, level.String()))
+	}
+	return attr
+}
+
+// Add the logging flags to cmd
+func addLoggingFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVarP(&logQuiet, "quiet", "q", false, "Only log errors")
+	cmd.PersistentFlags().BoolVarP(&logDebug, "debug", "d", false, "Log debugging information (seek offsets, decode progress)")
+}
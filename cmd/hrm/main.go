@@ -1,32 +1,63 @@
 package main
 
 import (
+	"compress/zlib"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
-	"runtime"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/locale"
+	"github.com/clj/hrm-profile-tool/metadata"
 	"github.com/clj/hrm-profile-tool/profile"
 	"github.com/clj/hrm-profile-tool/render"
-	"github.com/clj/hrm-profile-tool/utils/seekbufio"
-	"github.com/clj/hrm-profile-tool/utils/text"
-	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 )
 
-type renderFn func(r io.ReadSeeker) (string, error)
+// The version of the tool, set at build time
+var version = "dev"
+
+// The context for long running operations, cancelled on interrupt
+var appContext = context.Background()
 
 var (
-	textOutput     string
-	profilePath    string
-	svgOutput      string
-	textVerbose    bool
-	textLineNumber bool
-	textInstNumber bool
-	textRaw        bool
+	profilePath         string
+	outputFileName      string
+	outputFormat        string
+	outputCompress      bool
+	textVerbose         bool
+	textLineNumber      bool
+	textInstNumber      bool
+	textRaw             bool
+	textNoComments      bool
+	textCommentPreview  string
+	textCommentLevel    = 6
+	textGameComments    bool
+	textWrap            int
+	textNoWrap          bool
+	textCase            string
+	textBanner          bool
+	svgFont             string
+	svgEmbedFont        string
+	svgCropComments     bool
+	svgCommentPadding   int
+	svgRouteJumps       bool
+	svgJumpLabels       bool
+	svgQuality          string
+	renderFromLine      int
+	renderToLine        int
+	mnemonicsPath       string
+	withMetadata        bool
+	metadataAuthor      string
+	metadataGameVersion string
 )
 
 func parseInt(str string) int {
@@ -37,107 +68,152 @@ func parseInt(str string) int {
 	}
 	i, err := strconv.ParseInt(str, base, strconv.IntSize)
 	if err != nil {
-		log.Fatal(err)
+		fatal(usageError(err))
 	}
 	return int(i)
 }
 
-// Paths from: https://steamcommunity.com/app/375820/discussions/0/483368526585564846/
+// Return the profile path from --profile or else the single profile found
+// in the default locations
 func profileFilePath() (string, error) {
 	if profilePath != "" {
 		return profilePath, nil
 	}
 
-	var profilePaths []string
-
-	switch runtime.GOOS {
-	case "windows":
-		profilePaths = []string{`%APPDATA%\Human Resource Machine\profiles.bin`}
-	case "darwin":
-		profilePaths = []string{
-			`~/Library/Application Support/Human Resource Machine/profiles.bin`,
-			`~/Library/Containers/Tomorrow-Corporation.Human-Resource-Machine/Data/Library/Application Support/Human Resource Machine/profiles.bin`}
-	case "linux":
-		profilePaths = []string{`~/.local/share/Tomorrow\ Corporation/Human\ Resource\ Machine/profiles.bin`}
-	default:
-		return "", fmt.Errorf("unknown OS, cannot determine default profile path, please specify with --profile")
+	candidates, err := profileCandidates()
+	if err != nil {
+		return "", err
 	}
 
-	numExists := 0
-	existsMap := make([]bool, len(profilePaths))
-	var profilePath string
-	for i, path := range profilePaths {
-		var err error
-		if path, err = homedir.Expand(path); err != nil {
-			return "", err
-		}
-		if _, err := os.Stat(path); err == nil {
-			numExists++
-			existsMap[i] = true
-			profilePath = path
+	var existing []profileCandidate
+	for _, candidate := range candidates {
+		if candidate.exists() {
+			existing = append(existing, candidate)
 		}
 	}
 
-	if numExists == 0 {
-		return "", fmt.Errorf("no profiles found in default locations, use --profile to specify an alternative")
-
+	if len(existing) == 0 {
+		return "", errors.New(tr("error.no-profiles", "no profiles found in default locations, use --profile to specify an alternative (see: hrm paths)"))
 	}
 
-	if numExists > 1 {
+	if len(existing) > 1 {
 		availableProfiles := ""
-		for i, exists := range existsMap {
-			if exists {
-				availableProfiles += fmt.Sprintf("    %s\n", profilePaths[i])
-			}
+		for _, candidate := range existing {
+			availableProfiles += fmt.Sprintf("    %s\n", candidate.path)
 		}
-		return "", fmt.Errorf("multiple profiles exist, use --profile to specify one:\n" + availableProfiles)
+		return "", errors.New(tr("error.multiple-profiles", "multiple profiles exist, use --profile to specify one:") + "\n" + availableProfiles)
 	}
 
-	return profilePath, nil
+	return existing[0].path, nil
 }
 
-func openProfile() seekbufio.SeekableBufferedReader {
+// Open the selected profile. The profile is decoded at explicit offsets
+// (io.ReaderAt) so no buffering is required
+func openProfile() profileReader {
 	profileFilePath, err := profileFilePath()
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
-	reader, err := seekbufio.OpenSeekableBufferedReader(profileFilePath)
+	logger.Debug("opening profile", "path", profileFilePath)
+	file, err := openProfileAt(profileFilePath)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
-	return reader
+	return file
 }
 
-func renderTab(args []string, outputFileName string, fn renderFn) {
-	reader := openProfile()
-	defer reader.Close()
+// Gather the metadata envelope for a program
+func programMetadata(reader profileReader, profileId, floorIndex int, program render.Program) (metadata.Metadata, error) {
+	m := metadata.Metadata{
+		Author:      metadataAuthor,
+		Date:        time.Now().UTC().Truncate(time.Second),
+		Size:        program.Disassembled.Size(),
+		GameVersion: metadataGameVersion,
+		Generator:   "hrm-profile-tool " + version,
+	}
+	floorHeader, err := profileLayout(reader).ReadFloorHeaderAt(reader, profileId, floorIndex)
+	if err != nil {
+		return m, err
+	}
+	if floorHeader.SpeedChallengeCompleted > 0 {
+		m.Steps = int(floorHeader.SpeedChallengeSteps)
+	}
+	return m, nil
+}
 
-	profileId := parseInt(args[0])
-	if profileId != 1 {
-		log.Fatal("Only profile slot 1 is supported currently")
+// Gather the banner of a text export of a tab
+func programBanner(reader profileReader, profileId, floorIndex, tab int, program render.Program) (render.Banner, error) {
+	floor := floorNumber(floorIndex)
+	banner := render.Banner{
+		Floor:     floor,
+		Tab:       tab + 1,
+		Size:      program.Disassembled.Size(),
+		Date:      time.Now().UTC().Truncate(time.Second),
+		Generator: "hrm-profile-tool " + version,
 	}
-	floor := parseInt(args[1])
-	tab := parseInt(args[2]) - 1
-	floorIndex := profile.FloorToIndex(floor)
-	tabStart := profile.TabStartAddr(profileId, floorIndex, tab)
+	if level, found := levels.Lookup(floor); found {
+		banner.Level = levelName(level)
+	}
+	floorHeader, err := profileLayout(reader).ReadFloorHeaderAt(reader, profileId, floorIndex)
+	if err != nil {
+		return banner, err
+	}
+	if floorHeader.SpeedChallengeCompleted > 0 {
+		banner.Steps = int(floorHeader.SpeedChallengeSteps)
+	}
+	return banner, nil
+}
 
-	reader.Seek(tabStart, io.SeekStart)
-	outputFile := os.Stdout
-	if outputFileName != "" {
-		var err error
-		outputFile, err = os.Create(outputFileName)
-		if err != nil {
-			log.Fatal(err)
+// Parse the FLOOR TAB arguments of the render commands, returning the
+// floor index and the tab index. The deprecated PROFILE PROGRAM TAB form
+// sets the save slot from PROFILE instead of --slot
+func renderTabArgs(cmd *cobra.Command, args []string) (int, int) {
+	if len(args) == 3 {
+		if cmd.Flags().Changed("slot") {
+			usageFatalf("PROFILE cannot be combined with --slot, give FLOOR TAB only")
 		}
+		logger.Warn("PROFILE PROGRAM TAB is deprecated, give FLOOR TAB and the save slot with --slot")
+		slotNumber, args = parseSlot(args[0]), args[1:]
+	}
+	return floorTabArgs(args)
+}
+
+// Exit with a usage error unless the floor given on the command line is
+// stored in the profile, explaining that cut-scenes are not
+func checkFloorArg(floor int) {
+	info, found := profile.FloorInfoFor(floor)
+	switch {
+	case !found:
+		usageFatalf(tr("error.no-floor", "floor %d does not exist, the floors are numbered from 1 to %d"), floor, len(profile.FloorTable))
+	case info.Cutscene:
+		usageFatalf(tr("error.cutscene", "floor %d (%s) is a cut-scene, it has no programs or scores"), floor, info.Name)
 	}
-	str, err := fn(reader)
+}
+
+// Return the floor index of a floor given on the command line, exiting
+// with a usage error if the floor is not stored in the profile (see
+// checkFloorArg)
+func floorIndexArg(floor int) int {
+	checkFloorArg(floor)
+	floorIndex, err := profile.FloorToIndex(floor)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
-	fmt.Fprint(outputFile, str)
+	return floorIndex
 }
 
-func renderText(cmd *cobra.Command, args []string) {
+// Return the floor number of a floor index. The index is that of a floor
+// of the profile (e.g. from iterating over the floors), so it is valid
+func floorNumber(floorIndex int) int {
+	floor, err := profile.IndexToFloor(floorIndex)
+	if err != nil {
+		panic(err)
+	}
+	return floor
+}
+
+// Return the text render options selected on the command line
+func textOptions() []render.RenderInstructionsTextOption {
 	var options []render.RenderInstructionsTextOption
 	if textVerbose || textLineNumber {
 		options = append(options, render.ShowLineNumbers())
@@ -148,61 +224,409 @@ func renderText(cmd *cobra.Command, args []string) {
 	if textVerbose || textRaw {
 		options = append(options, render.ShowRawInstructions())
 	}
+	if textNoComments {
+		options = append(options, render.HideCommentDefinitions())
+	}
+	if textCommentPreview != "" {
+		if !render.CommentPreviewStyle(textCommentPreview).Valid() {
+			usageFatalf("unknown comment preview style %q (available: %s)", textCommentPreview, strings.Join(render.CommentPreviewStyles(), ", "))
+		}
+		options = append(options, render.CommentPreview(render.CommentPreviewStyle(textCommentPreview)))
+	}
+	if mnemonics := textMnemonics(); mnemonics != nil {
+		options = append(options, render.UseMnemonics(mnemonics))
+	}
+	if textCase != "" {
+		if !render.MnemonicCase(textCase).Valid() {
+			usageFatalf("unknown mnemonic case %q (available: %s)", textCase, strings.Join(render.MnemonicCases(), ", "))
+		}
+		options = append(options, render.RenderMnemonicsIn(render.MnemonicCase(textCase)))
+	}
+	if textCommentLevel < zlib.HuffmanOnly || textCommentLevel > zlib.BestCompression {
+		usageFatalf("invalid comment compression level %d (%d to %d)", textCommentLevel, zlib.HuffmanOnly, zlib.BestCompression)
+	}
+	options = append(options, render.CommentCompressionLevel(textCommentLevel))
+	if textGameComments {
+		options = append(options, render.GameIdenticalComments())
+	}
+	if textNoWrap {
+		options = append(options, render.CommentWrap(0))
+	} else if textWrap > 0 {
+		options = append(options, render.CommentWrap(textWrap))
+	}
+	return options
+}
 
-	renderTab(args, textOutput, func(r io.ReadSeeker) (string, error) {
-		tab_start, err := r.Seek(0, io.SeekCurrent)
-		if err != nil {
-			return "", err
+// Return the mnemonics used for textual output: the --mnemonics set, the
+// --lang locale's mnemonics, or nil for the game's mnemonics
+func textMnemonics() *instructions.Mnemonics {
+	if mnemonics := mnemonicSet(); mnemonics != nil {
+		return mnemonics
+	}
+	if l := appLocale(); l != nil {
+		return l.Mnemonics
+	}
+	return nil
+}
+
+var (
+	mnemonicsOnce sync.Once
+	mnemonics     *instructions.Mnemonics
+)
+
+// Return the mnemonic set selected with --mnemonics, or nil for the game's
+// mnemonics
+func mnemonicSet() *instructions.Mnemonics {
+	mnemonicsOnce.Do(func() {
+		if mnemonicsPath == "" {
+			return
 		}
-		assembly, err := render.RenderInstructionsTextFromReader(r, options...)
+		var err error
+		mnemonics, err = instructions.LoadMnemonics(mnemonicsPath)
 		if err != nil {
-			return "", err
+			fatal(fmt.Errorf("loading mnemonics %s: %w", mnemonicsPath, err))
 		}
-		comments_start := tab_start + profile.INSTRUCTIONS_SIZE
-		r.Seek(comments_start, io.SeekStart)
-		comments, err := render.RenderCommentsTextFromReader(r)
-		if err != nil {
-			return "", err
+	})
+	return mnemonics
+}
+
+var (
+	svgOptionsOnce   sync.Once
+	svgRenderOptions []render.RenderSVGOption
+)
+
+// Return the SVG render options selected on the command line. The font is
+// loaded once and shared by all renders
+func svgOptions() []render.RenderSVGOption {
+	svgOptionsOnce.Do(func() {
+		switch {
+		case svgEmbedFont != "":
+			font, err := render.LoadFont(svgEmbedFont, svgFont)
+			if err != nil {
+				fatal(fmt.Errorf("loading font %s: %w", svgEmbedFont, err))
+			}
+			svgRenderOptions = append(svgRenderOptions, render.SVGFont(font), render.SVGEmbedFont())
+		case svgFont != "":
+			svgRenderOptions = append(svgRenderOptions, render.SVGFont(render.SystemFont(svgFont)))
+		}
+		if svgCropComments {
+			svgRenderOptions = append(svgRenderOptions, render.SVGCropComments(svgCommentPadding))
+		}
+		if svgRouteJumps {
+			svgRenderOptions = append(svgRenderOptions, render.SVGRouteJumps())
+		}
+		if svgJumpLabels {
+			svgRenderOptions = append(svgRenderOptions, render.SVGLabelJumps())
+		}
+		if svgQuality != "" {
+			if !render.SVGQuality(svgQuality).Valid() {
+				usageFatalf("unknown SVG quality %q (available: %s)", svgQuality, strings.Join(render.SVGQualities(), ", "))
+			}
+			svgRenderOptions = append(svgRenderOptions, render.SVGRenderQuality(render.SVGQuality(svgQuality)))
 		}
-		if comments != "" {
-			assembly += "\n" + text.Wrap(comments, 80)
+		if mnemonics := mnemonicSet(); mnemonics != nil {
+			svgRenderOptions = append(svgRenderOptions, render.SVGMnemonicSet(mnemonics))
+		} else if l := appLocale(); l != nil {
+			svgRenderOptions = append(svgRenderOptions, render.SVGLabels(l.Labels, l.Conditions))
 		}
-		return assembly, nil
 	})
+	return svgRenderOptions
+}
+
+// Report whether text written to the output file outputName ("" for
+// stdout) is colored
+func colorText(outputName string) bool {
+	return outputName == "" && !outputCompress && useColor(os.Stdout)
+}
+
+// Report whether metadata is written inline (rather than as a sidecar
+// file) for the format
+func inlineMetadata(format render.Format) bool {
+	return format.Name == "text"
+}
+
+// Write the metadata of a program, if requested, either to w (text) or as
+// a sidecar file next to the output file outputName
+func writeMetadata(w io.Writer, outputName string, format render.Format, program render.Program, reader profileReader, profileId, floorIndex int) error {
+	if !withMetadata {
+		return nil
+	}
+	m, err := programMetadata(reader, profileId, floorIndex, program)
+	if err != nil {
+		return err
+	}
+	if inlineMetadata(format) {
+		return m.WriteText(w)
+	}
+	return m.WriteSidecar(outputName)
 }
 
-func renderSVG(cmd *cobra.Command, args []string) {
-	renderTab(args, svgOutput, render.RenderSVGFromReader)
+// Return the render options selected on the command line for a program
+// rendered to the output file outputName. The banner, if requested, and
+// colors are rendered by the text format only
+func renderOptions(outputName string, program render.Program, reader profileReader, profileId, floorIndex, tab int) (render.Options, error) {
+	text := textOptions()
+	if colorText(outputName) {
+		text = append(text, render.ColorText())
+	}
+	if textBanner {
+		banner, err := programBanner(reader, profileId, floorIndex, tab, program)
+		if err != nil {
+			return render.Options{}, err
+		}
+		text = append(text, render.TextBanner(banner))
+	}
+	svg := svgOptions()
+	if renderFromLine > 0 || renderToLine > 0 {
+		text = append(text, render.TextLines(renderFromLine, renderToLine))
+		svg = append(svg[:len(svg):len(svg)], render.SVGLines(renderFromLine, renderToLine))
+	}
+	return render.Options{Text: text, SVG: svg, Logger: logger}, nil
+}
+
+// Render a program in format to w, preceded by its metadata (see
+// writeMetadata)
+func renderProgram(w io.Writer, outputName string, format render.Format, program render.Program, reader profileReader, profileId, floorIndex, tab int) error {
+	if err := writeMetadata(w, outputName, format, program, reader, profileId, floorIndex); err != nil {
+		return err
+	}
+	options, err := renderOptions(outputName, program, reader, profileId, floorIndex, tab)
+	if err != nil {
+		return err
+	}
+	return format.Render(appContext, w, program, options)
+}
+
+// Render a tab in the negotiated format
+func renderTab(cmd *cobra.Command, args []string, defaultFormat string) {
+	format, err := selectOutputFormat(defaultFormat)
+	if err != nil {
+		fatal(usageError(err))
+	}
+	if withMetadata && !inlineMetadata(format) && outputFileName == "" {
+		usageFatalf("--metadata requires --output, the metadata is written to a sidecar file")
+	}
+	if renderFromLine < 0 || renderToLine < 0 || (renderToLine > 0 && renderToLine < renderFromLine) {
+		usageFatalf("invalid line range %d to %d", renderFromLine, renderToLine)
+	}
+	if (renderFromLine > 0 || renderToLine > 0) && format.Name != "text" && format.Name != "svg" {
+		logger.Warn("--from-line and --to-line apply to the text and svg formats only", "format", format.Name)
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+
+	var program render.Program
+	var floorIndex, tab int
+	if renderingByteRange() {
+		program = decodeByteRange(reader)
+	} else {
+		floorIndex, tab = renderTabArgs(cmd, args)
+		checkSlot(reader, slotNumber)
+		tabStart := profileLayout(reader).TabStartAddr(slotNumber, floorIndex, tab)
+		logger.Debug("decoding tab", "floor", floorNumber(floorIndex), "floor_index", floorIndex, "tab", tab+1, "offset", tabStart)
+		if program, err = render.DecodeProgramAt(appContext, reader, tabStart, programDecodeOptions()...); err != nil {
+			fatal(&profile.DecodeError{Offset: tabStart, Floor: floorNumber(floorIndex), Tab: tab + 1, Err: err})
+		}
+	}
+	logger.Debug("decoded program", "instructions", len(program.Instructions), "size", program.Disassembled.Size(), "comments", len(program.RawComments))
+	if renderFromLine > 0 || renderToLine > 0 {
+		if start, end := program.Disassembled.LineRange(renderFromLine, renderToLine); start == end {
+			size := program.Disassembled.Size()
+			to := renderToLine
+			if to == 0 {
+				to = size
+			}
+			usageFatalf("invalid line range %d to %d, the program has %d line(s)", renderFromLine, to, size)
+		}
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+
+	logger.Debug("rendering", "format", format.Name, "output", outputFileName)
+	if err := renderProgram(output, outputFileName, format, program, reader, slotNumber, floorIndex, tab); err != nil {
+		fatal(err)
+	}
+}
+
+// Return a command Run function rendering a tab, using defaultFormat
+// unless another format is requested
+func renderTabCommand(defaultFormat string) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		renderTab(cmd, args, defaultFormat)
+	}
+}
+
+// Add the flags shared by all commands producing rendered output to a
+// single file
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the output to (the format is implied by the extension)")
+	addFormatFlags(cmd)
+}
+
+// Add the flags shared by all commands producing rendered output
+func addFormatFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&outputCompress, "compress", "z", false, "Compress the output with gzip (implied by .svgz and .gz output file names)")
+	addCommentSpaceFlags(cmd)
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "", "Output `FORMAT` ("+strings.Join(render.FormatNames(), ", ")+", or NAME for an "+render.ExternalExporterPrefix+"NAME exporter on PATH)")
+	cmd.Flags().BoolVar(&withMetadata, "metadata", false, "Include a metadata header (text) or write a JSON sidecar file (other formats)")
+	cmd.Flags().StringVar(&metadataAuthor, "author", "", "`NAME` of the author recorded in the metadata")
+	cmd.Flags().StringVar(&metadataGameVersion, "game-version", "", "`VERSION` of the game recorded in the metadata")
+}
+
+// Add the flags selecting the lines of a program to render
+func addLineRangeFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&renderFromLine, "from-line", 0, "Render only the instructions from line `N` on (text and SVG)")
+	cmd.Flags().IntVar(&renderToLine, "to-line", 0, "Render only the instructions up to line `N` (text and SVG)")
+}
+
+// Add the flags controlling the SVG format
+func addSVGFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&svgFont, "font", "", "Font `FAMILY` used for text (default Arial Black)")
+	cmd.Flags().StringVar(&svgEmbedFont, "embed-font", "", "Embed the TrueType/OpenType/WOFF font at `PATH` in the SVG")
+	cmd.Flags().BoolVar(&svgCropComments, "crop-comments", false, "Fit the strokes of each comment into its box instead of the whole comment canvas")
+	cmd.Flags().IntVar(&svgCommentPadding, "comment-padding", 4, "Padding in `PIXELS` around cropped comments (see --crop-comments)")
+	cmd.Flags().BoolVar(&svgRouteJumps, "route-jumps", false, "Nest the jump arcs by the lines they span so that they do not overlap")
+	cmd.Flags().BoolVar(&svgJumpLabels, "jump-labels", false, "Label the jump arcs and targets with the letter of the target")
+	cmd.Flags().StringVar(&svgQuality, "quality", "", "Render with the filters of `QUALITY` ("+strings.Join(render.SVGQualities(), ", ")+"): flat has none for small, fast files, full adds a paper texture (default soft)")
+}
+
+// Add the flags controlling the text format
+func addTextFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&textVerbose, "verbose", "v", false, "Show as much info as possible (same as -lir)")
+	cmd.Flags().BoolVarP(&textLineNumber, "line-number", "l", false, "Show line numbers")
+	cmd.Flags().BoolVarP(&textInstNumber, "inst-number", "i", false, "Show instruction numbers")
+	cmd.Flags().BoolVarP(&textRaw, "raw", "r", false, "Show raw (hex) instructions")
+	cmd.Flags().StringVar(&textCommentPreview, "comment-preview", "", "Append a preview of the comment drawings in `STYLE` ("+strings.Join(render.CommentPreviewStyles(), ", ")+")")
+	cmd.Flags().BoolVar(&textNoComments, "no-comments", false, "Leave out the comment definitions (the COMMENT markers are kept)")
+	cmd.Flags().IntVar(&textCommentLevel, "comment-level", textCommentLevel, "zlib compression `LEVEL` of the comment definitions (-2 for Huffman only to 9)")
+	cmd.Flags().BoolVar(&textBanner, "banner", false, "Prefix the output with comment lines identifying the floor, level, tab, size and steps, and the export")
+	cmd.Flags().StringVar(&textCase, "case", "", "Render the mnemonics in `CASE` ("+strings.Join(render.MnemonicCases(), ", ")+")")
+	cmd.Flags().IntVar(&textWrap, "wrap", 80, "Wrap the comment definitions at `N` columns")
+	cmd.Flags().BoolVar(&textNoWrap, "no-wrap", false, "Do not wrap the comment definitions")
+	cmd.Flags().BoolVar(&textGameComments, "game-comments", false, "Compress the comment definitions exactly as the game does, so they diff cleanly against in-game copies")
 }
 
 func main() {
-	var rootCmd = &cobra.Command{Use: "hrm"}
+	var stop context.CancelFunc
+	appContext, stop = signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	setupMessages(os.Args[1:])
+
+	var rootCmd = &cobra.Command{Use: "hrm", SilenceErrors: true, PersistentPreRun: setupLogging}
+
+	var cmdRender = &cobra.Command{
+		Use:   "render FLOOR TAB",
+		Short: "Render a program",
+		Long: `Render a single program in the requested format to stdout (or optionally directly to a file).
+
+The program is that of tab TAB of floor FLOOR in the save slot given with
+--slot. The PROFILE PROGRAM TAB form of earlier versions, giving the save
+slot first, is still accepted but deprecated.
 
+With --offset the program is decoded from any byte offset of the --profile
+file (which need not be a profile) instead of from a tab, e.g. to read
+fragments of unknown profile variants.`,
+		Args: tabOrByteRangeArgs,
+		Run:  renderTabCommand(""),
+	}
 	var cmdRenderText = &cobra.Command{
-		Use:   "text PROFILE PROGRAM TAB",
+		Use:   "text FLOOR TAB",
 		Short: "Render Text",
-		Long:  `Render a profile's program as text`,
-		Args:  cobra.ExactArgs(3),
-		Run:   renderText,
+		Long:  `Render a profile's program as text (same as render --format text)`,
+		Args:  tabOrByteRangeArgs,
+		Run:   renderTabCommand("text"),
 	}
 	var cmdRenderSVG = &cobra.Command{
-		Use:   "svg PROFILE PROGRAM TAB",
+		Use:   "svg FLOOR TAB",
 		Short: "Render SVG",
-		Long:  `Render a single program as an SVG to stdout (or optionally directly to a file)`,
-		Args:  cobra.ExactArgs(3),
-		Run:   renderSVG,
+		Long:  `Render a single program as an SVG (same as render --format svg)`,
+		Args:  tabOrByteRangeArgs,
+		Run:   renderTabCommand("svg"),
 	}
 
-	rootCmd.Flags().StringVarP(&profilePath, "profile", "p", "", "`PATH` to a profiles.bin (otherwise search in default locations)")
-	rootCmd.AddCommand(cmdRenderText)
-	cmdRenderText.Flags().StringVarP(&textOutput, "output", "o", "", "`FILENAME` to write text assembly data to")
-	cmdRenderText.Flags().BoolVarP(&textVerbose, "verbose", "v", false, "Show as much info as possible (same as -lir)")
-	cmdRenderText.Flags().BoolVarP(&textLineNumber, "line-number", "l", false, "Show line numbers")
-	cmdRenderText.Flags().BoolVarP(&textInstNumber, "inst-number", "i", false, "Show instruction numbers")
-	cmdRenderText.Flags().BoolVarP(&textRaw, "raw", "r", false, "Show raw (hex) instructions")
+	rootCmd.PersistentFlags().StringVarP(&profilePath, "profile", "p", "", "`PATH` or HTTP(S) URL of a profiles.bin (otherwise search in default locations)")
+	rootCmd.PersistentFlags().StringVar(&mnemonicsPath, "mnemonics", "", "Load the instruction mnemonics used for text and SVG output from the file at `PATH`")
+	rootCmd.PersistentFlags().StringVar(&lang, "lang", "", "Translate instructions, level names and messages to the game's `LANGUAGE` ("+strings.Join(locale.Codes(), ", ")+")")
+	rootCmd.PersistentFlags().BoolVar(&jsonErrors, "json-errors", false, "Report errors as JSON objects on stderr")
+	addLoggingFlags(rootCmd)
+	addDryRunFlags(rootCmd)
+	addColorFlags(rootCmd)
+	addLayoutFlags(rootCmd)
+	addQuarantineFlags(rootCmd)
+	for _, cmd := range []*cobra.Command{cmdRender, cmdRenderText, cmdRenderSVG} {
+		rootCmd.AddCommand(cmd)
+		addOutputFlags(cmd)
+		addByteRangeFlags(cmd)
+		addLineRangeFlags(cmd)
+		addSlotFlag(cmd, "read")
+	}
+	addTextFlags(cmdRender)
+	addTextFlags(cmdRenderText)
+	addSVGFlags(cmdRender)
+	addSVGFlags(cmdRenderSVG)
 
-	rootCmd.AddCommand(cmdRenderSVG)
-	cmdRenderSVG.Flags().StringVarP(&svgOutput, "output", "o", "", "`FILENAME` to write SVG assembly data to")
+	rootCmd.AddCommand(textFloorCommand())
+	rootCmd.AddCommand(dedupCommand())
+	rootCmd.AddCommand(opstatsCommand())
+	rootCmd.AddCommand(undoCommand())
+	rootCmd.AddCommand(pathsCommand())
+	rootCmd.AddCommand(exportAllCommand())
+	rootCmd.AddCommand(exportDrawingsCommand())
+	rootCmd.AddCommand(verifyExportCommand())
+	rootCmd.AddCommand(pipelineCommand())
+	rootCmd.AddCommand(trackCommand())
+	rootCmd.AddCommand(sessionsCommand())
+	rootCmd.AddCommand(anonymizeCommand())
+	rootCmd.AddCommand(thumbCommand())
+	rootCmd.AddCommand(panelCommand())
+	rootCmd.AddCommand(cardCommand())
+	rootCmd.AddCommand(badgeCommand())
+	rootCmd.AddCommand(genReadmeCommand())
+	rootCmd.AddCommand(mapCommand())
+	rootCmd.AddCommand(researchCommand())
+	rootCmd.AddCommand(genSpecCommand())
+	rootCmd.AddCommand(genSchemaCommand())
+	rootCmd.AddCommand(diffCommand())
+	rootCmd.AddCommand(diffTabCommand())
+	rootCmd.AddCommand(mergeProgramCommand())
+	rootCmd.AddCommand(blameCommand())
+	rootCmd.AddCommand(lintCommand())
+	rootCmd.AddCommand(auditCommand())
+	rootCmd.AddCommand(estimateCommand())
+	rootCmd.AddCommand(hrmcCommand())
+	rootCmd.AddCommand(pseudoCommand())
+	rootCmd.AddCommand(explainCommand())
+	rootCmd.AddCommand(slotsCommand())
+	rootCmd.AddCommand(doctorCommand())
+	rootCmd.AddCommand(newCommand())
+	rootCmd.AddCommand(setScoreCommand())
+	rootCmd.AddCommand(setCompletedCommand())
+	rootCmd.AddCommand(tilesCommand())
+	rootCmd.AddCommand(runCommand())
+	rootCmd.AddCommand(serveCommand())
+	rootCmd.AddCommand(daemonCommand())
+	rootCmd.AddCommand(scriptCommand())
+	rootCmd.AddCommand(queryCommand())
+	rootCmd.AddCommand(exportSQLiteCommand())
+	rootCmd.AddCommand(findCommentCommand())
+	rootCmd.AddCommand(grepArgCommand())
+	rootCmd.AddCommand(dumpRawCommand())
+	rootCmd.AddCommand(loadRawCommand())
+	rootCmd.AddCommand(importURLCommand())
+	rootCmd.AddCommand(importCommand())
+	rootCmd.AddCommand(copyCommand())
+	rootCmd.AddCommand(clearCommand())
+	rootCmd.AddCommand(mergeCommand())
+	rootCmd.AddCommand(exportAnnotationsCommand())
+	rootCmd.AddCommand(importAnnotationsCommand())
+	localizeCommands(rootCmd)
 
-	rootCmd.Execute()
+	if err := rootCmd.Execute(); err != nil {
+		fatal(usageError(err))
+	}
 }
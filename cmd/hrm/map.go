@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var mapFormat string
+
+// Return the progression map nodes of the levels, marking the floors
+// completed in p as solved
+func progressionMap(p profile.Profile) []render.MapNode {
+	var nodes []render.MapNode
+	for _, level := range levels.Levels() {
+		floorIndex, err := profile.FloorToIndex(level.Floor)
+		nodes = append(nodes, render.MapNode{
+			ID:     level.Floor,
+			Label:  fmt.Sprintf("%d %s", level.Floor, levelName(level)),
+			Solved: err == nil && p.Floors[floorIndex].Completed,
+			Next:   level.Unlocks,
+		})
+	}
+	return nodes
+}
+
+func progressionMapCommand(cmd *cobra.Command, args []string) {
+	format := mapFormat
+	if format == "" {
+		format = "svg"
+		if strings.ToLower(filepath.Ext(outputFileName)) == ".dot" {
+			format = "dot"
+		}
+	}
+	var renderMap func([]render.MapNode) string
+	switch format {
+	case "dot":
+		renderMap = render.RenderMapDOT
+	case "svg":
+		renderMap = render.RenderMapSVG
+	default:
+		usageFatalf("unknown map format %q (available: dot, svg)", format)
+	}
+
+	nodes := progressionMap(decodeProfile())
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+	if _, err := io.WriteString(output, renderMap(nodes)); err != nil {
+		fatal(err)
+	}
+}
+
+func mapCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "map",
+		Short: "Render the floor progression map",
+		Long: `Render the progression graph of the floors (which floors unlock which)
+as DOT or SVG, with the solved floors coloured in`,
+		Args: cobra.NoArgs,
+		Run:  progressionMapCommand,
+	}
+	addSlotFlag(cmd, "read")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the map to (the format is implied by a .dot or .svg extension)")
+	cmd.Flags().StringVarP(&mapFormat, "format", "f", "", "Output `FORMAT` (dot, svg)")
+	return cmd
+}
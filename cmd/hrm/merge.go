@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/clj/hrm-profile-tool/journal"
+	"github.com/spf13/cobra"
+)
+
+var mergeFromSlot int
+
+func merge(cmd *cobra.Command, args []string) {
+	fromSlot := slotNumber
+	if cmd.Flags().Changed("from-slot") {
+		fromSlot = mergeFromSlot
+	}
+	path := editProfilePath()
+
+	other, err := openProfileAt(args[0])
+	if err != nil {
+		fatal(err)
+	}
+	defer other.Close()
+	checkSlot(other, fromSlot)
+	theirs, err := decodeSlot(other, fromSlot)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", args[0], err))
+	}
+	reader, err := openProfileAt(path)
+	if err != nil {
+		fatal(err)
+	}
+	checkSlot(reader, slotNumber)
+	ours, err := decodeSlot(reader, slotNumber)
+	if err != nil {
+		fatal(err)
+	}
+	layout := profileLayout(reader)
+	reader.Close()
+
+	// Quarantined tabs (with --keep-going) decode as empty, but must not be
+	// overwritten
+	quarantined := make(map[[2]int]bool)
+	for _, e := range ours.Errors {
+		quarantined[[2]int{e.Floor, e.Tab}] = true
+	}
+
+	var changes []journal.Change
+	var details []string
+	skipped := 0
+	for floorIndex := range theirs.Floors {
+		floor := floorNumber(floorIndex)
+		present := make(map[string]bool)
+		var free []int
+		for tab, t := range ours.Floors[floorIndex].Tabs {
+			if t.Code.Size() > 0 {
+				present[t.Hash()] = true
+			} else if len(t.RawComments) == 0 && !quarantined[[2]int{floor, tab + 1}] && !quarantined[[2]int{floor, 0}] {
+				free = append(free, tab)
+			}
+		}
+		for tab, t := range theirs.Floors[floorIndex].Tabs {
+			if t.Code.Size() == 0 || present[t.Hash()] {
+				continue
+			}
+			present[t.Hash()] = true
+			if len(free) == 0 {
+				skipped++
+				logger.Warn("no empty tab left for the program", "floor", floor, "tab", tab+1, "size", t.Code.Size())
+				continue
+			}
+			changes = append(changes, journal.Change{Offset: layout.TabStartAddr(slotNumber, floorIndex, free[0]), Modified: readTabBlocks(other, fromSlot, floorIndex, tab)})
+			details = append(details, fmt.Sprintf("floor %d tab %d: %d instruction(s) from tab %d", floor, free[0]+1, t.Code.Size(), tab+1))
+			free = free[1:]
+		}
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "%d program(s) not merged, their floors have no empty tab left\n", skipped)
+	}
+	if len(changes) == 0 {
+		fmt.Println("Nothing to merge, the profile has every program of " + args[0])
+		return
+	}
+
+	if !dryRun {
+		for _, detail := range details {
+			fmt.Fprintln(os.Stderr, "  "+detail)
+		}
+	}
+	description := fmt.Sprintf("merge %d program(s) from %s", len(changes), args[0])
+	applyEdit("merge", path, description, changes, details...)
+}
+
+func mergeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge OTHER",
+		Short: "Copy the programs of another profile into empty tabs",
+		Long: `Copy the programs of the profile OTHER (a path or an HTTP(S) URL) which the
+profile does not have into the empty tabs of their floors, e.g. to combine
+the solutions written on two computers. Programs are the same when they
+differ only in comments and the names of labels. Programs whose floor has
+no empty tab left are reported and not merged. The results recorded for
+the floors are left as they are.
+
+The programs are read from the save slot of --from-slot (default the slot
+of --slot) of OTHER. The profile is backed up first and the change is
+recorded in the edit journal, revert it with hrm undo`,
+		Args: cobra.ExactArgs(1),
+		Run:  merge,
+	}
+	cmd.Flags().IntVar(&mergeFromSlot, "from-slot", 1, "Merge from save `SLOT` of OTHER (default the slot of --slot)")
+	addEditFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+// Assemble the program text file at path for merging
+func loadMergeProgram(path string) instructions.MergeProgram {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		fatal(err)
+	}
+	var opts []instructions.AssembleOption
+	if mnemonics := textMnemonics(); mnemonics != nil {
+		opts = append(opts, instructions.AssembleMnemonics(mnemonics))
+	}
+	program, comments, err := instructions.Assemble(string(data), opts...)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", path, err))
+	}
+	disassembled, err := instructions.Disassemble(program)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", path, err))
+	}
+	return instructions.MergeProgram{Disassembled: disassembled, Comments: comments}
+}
+
+func mergeProgram(cmd *cobra.Command, args []string) {
+	base, ours, theirs := loadMergeProgram(args[0]), loadMergeProgram(args[1]), loadMergeProgram(args[2])
+	merged := instructions.MergePrograms(base, ours, theirs)
+
+	var opts []render.RenderInstructionsTextOption
+	if mnemonics := textMnemonics(); mnemonics != nil {
+		opts = append(opts, render.UseMnemonics(mnemonics))
+	}
+	text, unresolved := render.RenderMergeText(merged, args[1], args[2], opts...)
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	if _, err := output.Write([]byte(text)); err != nil {
+		fatal(err)
+	}
+	if err := output.Close(); err != nil {
+		fatal(err)
+	}
+	if unresolved > 0 {
+		fmt.Fprintf(os.Stderr, "%d conflict(s) or removed jump target(s) to resolve\n", unresolved)
+		os.Exit(1)
+	}
+}
+
+func mergeProgramCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge-program BASE OURS THEIRS",
+		Short: "Three-way merge of program text files",
+		Long: `Merge the changes made to the program BASE by OURS and THEIRS, all program
+text files as copied from the game or written by hrm render.
+
+The programs are merged instruction by instruction: comments are compared by
+their drawing and the names of labels are ignored, so relabeling does not
+cause conflicts. Labels and comments are renumbered in the output. Regions
+changed differently by both sides are written between conflict markers, and
+jumps to a label removed by the merge jump to "?". The exit status is 1 if
+anything is left to resolve.
+
+To use it as a git mergetool for a solution repository:
+
+  [mergetool "hrm"]
+      cmd = hrm merge-program "$BASE" "$LOCAL" "$REMOTE" -o "$MERGED"
+      trustExitCode = true`,
+		Args: cobra.ExactArgs(3),
+		Run:  mergeProgram,
+	}
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the merged program to")
+	return cmd
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/profile"
+)
+
+// Escape a Prometheus label value
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Writes metrics in the Prometheus text exposition format
+type metricsWriter struct {
+	w *bufio.Writer
+}
+
+// Write the HELP and TYPE lines of a gauge
+func (m metricsWriter) gauge(name, help string) {
+	fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+// Write a sample of a metric, labels are name, value pairs
+func (m metricsWriter) sample(name string, value float64, labels ...string) {
+	m.w.WriteString(name)
+	for i := 0; i+1 < len(labels); i += 2 {
+		separator := ","
+		if i == 0 {
+			separator = "{"
+		}
+		fmt.Fprintf(m.w, `%s%s="%s"`, separator, labels[i], metricLabelEscaper.Replace(labels[i+1]))
+	}
+	if len(labels) > 0 {
+		m.w.WriteString("}")
+	}
+	fmt.Fprintf(m.w, " %s\n", strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// Write the metrics of the profile at path: its modification time, and
+// the completion totals and the per floor results of every save slot
+func writeMetrics(w io.Writer, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	reader, err := openProfileAt(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	size, err := profileSize(reader)
+	if err != nil {
+		return err
+	}
+	layout := profileLayout(reader)
+	var profiles []profile.Profile
+	for slot := 1; slot <= layout.SlotCount(size); slot++ {
+		p, err := decodeSlot(reader, slot)
+		if err != nil {
+			return err
+		}
+		profiles = append(profiles, p)
+	}
+
+	m := metricsWriter{bufio.NewWriter(w)}
+	m.gauge("hrm_profile_modified_timestamp_seconds", "Modification time of the profile in seconds since the epoch.")
+	m.sample("hrm_profile_modified_timestamp_seconds", float64(info.ModTime().UnixNano())/1e9)
+	m.gauge("hrm_profile_size_bytes", "Size of the profile.")
+	m.sample("hrm_profile_size_bytes", float64(size))
+
+	summaries := make([]profile.ProfileSummary, len(profiles))
+	for i, p := range profiles {
+		summaries[i] = profile.Summary(p)
+	}
+	totals := []struct {
+		name, help string
+		value      func(profile.ProfileSummary) int
+	}{
+		{"hrm_floors", "Number of floors stored in the save slot.", func(s profile.ProfileSummary) int { return s.Floors }},
+		{"hrm_floors_solved", "Number of floors with a recorded solution.", func(s profile.ProfileSummary) int { return s.Solved }},
+		{"hrm_size_challenges_met", "Number of floors meeting their size challenge.", func(s profile.ProfileSummary) int { return s.SizeChallengesMet }},
+		{"hrm_speed_challenges_met", "Number of floors meeting their speed challenge.", func(s profile.ProfileSummary) int { return s.SpeedChallengesMet }},
+		{"hrm_size_total", "Sum of the recorded sizes.", func(s profile.ProfileSummary) int { return s.TotalSize }},
+		{"hrm_steps_total", "Sum of the recorded steps.", func(s profile.ProfileSummary) int { return s.TotalSteps }},
+	}
+	for _, total := range totals {
+		m.gauge(total.name, total.help)
+		for i, summary := range summaries {
+			m.sample(total.name, float64(total.value(summary)), "slot", fmt.Sprint(i+1))
+		}
+	}
+
+	floors := []struct {
+		name, help string
+		value      func(profile.Floor) (float64, bool)
+	}{
+		{"hrm_floor_completed", "Whether the floor has a recorded solution (1) or not (0).", func(f profile.Floor) (float64, bool) {
+			if f.Completed {
+				return 1, true
+			}
+			return 0, true
+		}},
+		{"hrm_floor_size", "Recorded size of the solution of the floor.", func(f profile.Floor) (float64, bool) {
+			return float64(f.SizeChallenge), f.SizeChallenge >= 0
+		}},
+		{"hrm_floor_steps", "Recorded steps of the solution of the floor.", func(f profile.Floor) (float64, bool) {
+			return float64(f.SpeedChallenge), f.SpeedChallenge >= 0
+		}},
+	}
+	for _, metric := range floors {
+		m.gauge(metric.name, metric.help)
+		for i, p := range profiles {
+			for floorIndex, floor := range p.Floors {
+				value, recorded := metric.value(floor)
+				if !recorded {
+					continue
+				}
+				number := floorNumber(floorIndex)
+				// The english level name, so that the series do not depend
+				// on --lang
+				level := levels.Floor(number)
+				m.sample(metric.name, value, "slot", fmt.Sprint(i+1), "floor", fmt.Sprint(number), "level", level.Name)
+			}
+		}
+	}
+	return m.w.Flush()
+}
+
+// Serve the metrics of the profile at path, read afresh on every request
+func metricsHandler(path string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buffer strings.Builder
+		if err := writeMetrics(&buffer, path); err != nil {
+			logger.Warn("cannot read the profile for /metrics", "path", path, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		io.WriteString(w, buffer.String())
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	newSlots int
+	newForce bool
+)
+
+func newProfile(cmd *cobra.Command, args []string) {
+	if newSlots < 1 {
+		usageFatalf("--slots must be at least 1")
+	}
+	path := args[0]
+	if dryRun {
+		if _, err := os.Stat(path); err == nil && !newForce {
+			usageFatalf("%s already exists, use --force to overwrite it", path)
+		}
+		fmt.Printf("Would create empty profile %s (%d slot(s), %d bytes)\n", path, newSlots, selectedLayout().SlotSize()*int64(newSlots))
+		fmt.Println("Dry run, nothing was changed")
+		return
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if newForce {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if os.IsExist(err) {
+		usageFatalf("%s already exists, use --force to overwrite it", path)
+	} else if err != nil {
+		fatal(err)
+	}
+	if err := selectedLayout().WriteEmpty(file, newSlots); err != nil {
+		file.Close()
+		fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Created empty profile %s (%d slot(s))\n", path, newSlots)
+}
+
+func newCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "new PATH",
+		Short: "Create an empty profile",
+		Long: `Create a profiles.bin at PATH with no floors completed and no programs,
+for experimenting without touching a real save`,
+		Args: cobra.ExactArgs(1),
+		Run:  newProfile,
+	}
+	cmd.Flags().IntVar(&newSlots, "slots", 1, "Number of save `SLOTS` in the profile")
+	cmd.Flags().BoolVarP(&newForce, "force", "f", false, "Overwrite PATH if it exists")
+	return cmd
+}
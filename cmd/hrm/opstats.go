@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+// The usage of an opcode, or of all opcodes for the totals
+type opUsage struct {
+	Op       string  `json:"op"`
+	Opcode   int     `json:"opcode"`
+	Count    int     `json:"count"`
+	Share    float64 `json:"share"`
+	Indirect int     `json:"indirect"`
+	// The share of the uses of the opcode taking an indirect [N] argument
+	IndirectShare float64 `json:"indirect_share"`
+	// The average number of uses per program
+	PerProgram float64 `json:"per_program"`
+}
+
+// The instruction statistics of a profile
+type opStats struct {
+	Floors   int       `json:"floors"`
+	Programs int       `json:"programs"`
+	Total    opUsage   `json:"total"`
+	Ops      []opUsage `json:"ops"`
+}
+
+// Count the instructions of the programs of the solved floors of p
+func countOps(p profile.Profile) opStats {
+	var stats opStats
+	counts := make(map[instructions.OpCode]*opUsage)
+	for op := range instructions.OpSemantics {
+		counts[op] = &opUsage{Op: op.String(), Opcode: int(op)}
+	}
+	count := func(op instructions.OpCode, indirect bool) {
+		usage, found := counts[op]
+		if !found {
+			return
+		}
+		usage.Count++
+		stats.Total.Count++
+		if indirect {
+			usage.Indirect++
+			stats.Total.Indirect++
+		}
+	}
+	for _, floor := range p.Floors {
+		if !floor.Completed {
+			continue
+		}
+		stats.Floors++
+		for _, tab := range floor.Tabs {
+			if tab.Code.Size() == 0 {
+				continue
+			}
+			stats.Programs++
+			for _, diss := range tab.Code {
+				switch diss := diss.(type) {
+				case instructions.DisassembleArgInstruction:
+					count(diss.Op, diss.Indirect)
+				case instructions.DisassembleJumpInstruction:
+					count(diss.Op, false)
+				case instructions.DisassembleInstruction:
+					count(diss.Op, false)
+				}
+			}
+		}
+	}
+
+	ratio := func(n, d int) float64 {
+		if d == 0 {
+			return 0
+		}
+		return float64(n) / float64(d)
+	}
+	finish := func(usage *opUsage) {
+		usage.Share = ratio(usage.Count, stats.Total.Count)
+		usage.IndirectShare = ratio(usage.Indirect, usage.Count)
+		usage.PerProgram = ratio(usage.Count, stats.Programs)
+	}
+	for _, usage := range counts {
+		finish(usage)
+		stats.Ops = append(stats.Ops, *usage)
+	}
+	sort.Slice(stats.Ops, func(i, j int) bool {
+		if stats.Ops[i].Count != stats.Ops[j].Count {
+			return stats.Ops[i].Count > stats.Ops[j].Count
+		}
+		return stats.Ops[i].Opcode < stats.Ops[j].Opcode
+	})
+	stats.Total.Op = "total"
+	stats.Total.Opcode = -1
+	finish(&stats.Total)
+	return stats
+}
+
+func writeOpStatsText(w io.Writer, stats opStats) error {
+	mnemonics := textMnemonics()
+	if mnemonics == nil {
+		mnemonics = instructions.DefaultMnemonics()
+	}
+	fmt.Fprintf(w, "%d programs on %d solved floors, %.1f instructions per program\n\n", stats.Programs, stats.Floors, stats.Total.PerProgram)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "INSTRUCTION\tCOUNT\tSHARE\tINDIRECT\tPER PROGRAM")
+	row := func(name string, usage opUsage) {
+		indirect := ""
+		if usage.Indirect > 0 {
+			indirect = fmt.Sprintf("%.1f%%", usage.IndirectShare*100)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\t%s\t%.2f\n", name, usage.Count, usage.Share*100, indirect, usage.PerProgram)
+	}
+	for _, usage := range stats.Ops {
+		row(mnemonics.Name(instructions.OpCode(usage.Opcode)), usage)
+	}
+	row("total", stats.Total)
+	return tw.Flush()
+}
+
+func writeOpStatsCSV(w io.Writer, stats opStats) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"op", "opcode", "count", "share", "indirect", "indirect_share", "per_program"})
+	float := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', 4, 64)
+	}
+	row := func(usage opUsage, opcode string) {
+		cw.Write([]string{usage.Op, opcode, strconv.Itoa(usage.Count), float(usage.Share), strconv.Itoa(usage.Indirect), float(usage.IndirectShare), float(usage.PerProgram)})
+	}
+	for _, usage := range stats.Ops {
+		row(usage, strconv.Itoa(usage.Opcode))
+	}
+	row(stats.Total, "")
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeOpStatsJSON(w io.Writer, stats opStats) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+func opstats(cmd *cobra.Command, args []string) {
+	var stats opStats
+	write := reportWriters{
+		"text": func(w io.Writer) error { return writeOpStatsText(w, stats) },
+		"csv":  func(w io.Writer) error { return writeOpStatsCSV(w, stats) },
+		"json": func(w io.Writer) error { return writeOpStatsJSON(w, stats) },
+	}.selected()
+
+	stats = countOps(decodeProfile())
+	if stats.Programs == 0 {
+		fatalf("no solved floor has a program")
+	}
+	writeReport(write)
+}
+
+func opstatsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "opstats",
+		Short: "Summarize the instructions used across the profile",
+		Long: `Count the instructions of the programs of every solved floor: how often each
+instruction is used and its share of all instructions, the share of its
+uses taking an indirect [N] argument, and its average number of uses per
+program. The total row gives the average program length.
+
+The statistics are written as a table, or as CSV or JSON with --format or
+an output file name ending in .csv or .json. Shares are percentages in the
+table and fractions (0-1) in CSV and JSON`,
+		Args: cobra.NoArgs,
+		Run:  opstats,
+	}
+	addSlotFlag(cmd, "read")
+	addReportFlags(cmd, "statistics", "text", "csv", "json")
+	return cmd
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/render"
+)
+
+// Report whether f is connected to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Return the registered format called name, or the format provided by an
+// external hrm-export-NAME exporter on PATH
+func lookupFormat(name string) (render.Format, bool) {
+	if format, found := render.Lookup(name); found {
+		return format, true
+	}
+	return render.LookupExternal(name)
+}
+
+// Select the output format. An explicit --format takes precedence,
+// followed by the format implied by the --output file name, followed by
+// defaultFormat and finally text
+func selectFormat(defaultFormat string) (render.Format, error) {
+	var format render.Format
+	var found bool
+	switch {
+	case outputFormat != "":
+		if format, found = lookupFormat(outputFormat); !found {
+			return format, render.UnknownFormatError(outputFormat)
+		}
+	case outputFileName != "":
+		if format, found = render.LookupFileName(strings.TrimSuffix(outputFileName, ".gz")); found {
+			break
+		}
+		fallthrough
+	default:
+		if defaultFormat == "" {
+			defaultFormat = "text"
+		}
+		if format, found = render.Lookup(defaultFormat); !found {
+			return format, render.UnknownFormatError(defaultFormat)
+		}
+	}
+	return format, nil
+}
+
+// Select the output format for output written to --output or stdout,
+// see selectFormat. Binary formats are refused when the output would be
+// written to a terminal
+func selectOutputFormat(defaultFormat string) (render.Format, error) {
+	format, err := selectFormat(defaultFormat)
+	if err != nil {
+		return format, err
+	}
+	if (format.Binary || outputCompress) && outputFileName == "" && isTerminal(os.Stdout) {
+		return format, fmt.Errorf("refusing to write %s output to a terminal, use --output or redirect stdout", format.Name)
+	}
+	return format, nil
+}
+
+// A writer that does not close the underlying writer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// A gzip writer which also closes the underlying writer
+type gzipWriteCloser struct {
+	*gzip.Writer
+	underlying io.Closer
+}
+
+func (w gzipWriteCloser) Close() error {
+	err := w.Writer.Close()
+	if closeErr := w.underlying.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Report whether output written to fileName should be compressed, either
+// because of --compress or because the file name implies it
+func compressOutput(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	return outputCompress || ext == ".svgz" || ext == ".gz"
+}
+
+// Return the file name extension of a compressed file with extension ext
+func compressedExtension(ext string) string {
+	if strings.ToLower(ext) == ".svg" {
+		return ext + "z"
+	}
+	return ext + ".gz"
+}
+
+// Compress w if required, see compressOutput
+func maybeCompress(w io.WriteCloser, fileName string) io.WriteCloser {
+	if !compressOutput(fileName) {
+		return w
+	}
+	return gzipWriteCloser{gzip.NewWriter(w), w}
+}
+
+// Create an output file, compressed if required
+func createOutputFile(fileName string) (io.WriteCloser, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return maybeCompress(file, fileName), nil
+}
+
+// Create the output selected by --output, or stdout
+func createOutput() (io.WriteCloser, error) {
+	if outputFileName == "" {
+		return maybeCompress(nopWriteCloser{os.Stdout}, ""), nil
+	}
+	return createOutputFile(outputFileName)
+}
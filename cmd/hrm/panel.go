@@ -0,0 +1,74 @@
+package main
+
+import (
+	"image/png"
+	"os"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	panelHeight int
+	panelScroll int
+	panelScale  int
+	panelHint   string
+)
+
+func panel(cmd *cobra.Command, args []string) {
+	if panelHeight <= 0 || panelScale <= 0 {
+		usageFatalf("--height and --scale must be positive")
+	}
+	if outputFileName == "" && isTerminal(os.Stdout) {
+		usageFatalf("refusing to write png output to a terminal, use --output or redirect stdout")
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+
+	floorIndex, tab := floorTabArgs(args)
+	checkSlot(reader, slotNumber)
+	tabStart := profileLayout(reader).TabStartAddr(slotNumber, floorIndex, tab)
+	program, err := render.DecodeProgramAt(appContext, reader, tabStart, programDecodeOptions()...)
+	if err != nil {
+		fatal(&profile.DecodeError{Offset: tabStart, Floor: floorNumber(floorIndex), Tab: tab + 1, Err: err})
+	}
+
+	img, err := render.RenderPanelContext(appContext, program.Disassembled, program.Comments,
+		render.PanelTab(tab+1), render.PanelHeight(panelHeight), render.PanelScroll(panelScroll),
+		render.PanelScale(panelScale), render.PanelHint(panelHint), render.PanelLogger(logger))
+	if err != nil {
+		fatal(err)
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+	if err := png.Encode(output, img); err != nil {
+		fatal(err)
+	}
+}
+
+func panelCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "panel FLOOR TAB",
+		Short: "Render a program as shown in the game",
+		Long: `Render a PNG of a program as it appears in the right hand panel of the
+game: on the wooden background, below the tab buttons and above the hint
+area with the worker's hand, with shadows and a scroll bar where the
+program continues out of view`,
+		Args: cobra.ExactArgs(2),
+		Run:  panel,
+	}
+	addSlotFlag(cmd, "read")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the PNG to")
+	cmd.Flags().IntVar(&panelHeight, "height", 600, "Height of the panel in `PIXELS`, before scaling")
+	cmd.Flags().IntVar(&panelScroll, "scroll", 0, "Scroll the program to show `LINE` at the top")
+	cmd.Flags().IntVar(&panelScale, "scale", 1, "Scale the panel up by `FACTOR`")
+	cmd.Flags().StringVar(&panelHint, "hint", "", "`TEXT` to show next to the hand below the program")
+	addCommentSpaceFlags(cmd)
+	return cmd
+}
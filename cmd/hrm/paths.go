@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+// A candidate profile location
+type profileCandidate struct {
+	pattern string      // the path as listed in defaultProfilePaths
+	path    string      // the expanded path
+	info    os.FileInfo // nil if the path could not be stat'ed
+	err     error       // the error from stat'ing the path
+}
+
+func (c profileCandidate) exists() bool {
+	return c.info != nil
+}
+
+// Return the default profile locations for the current OS
+//
+// Paths from: https://steamcommunity.com/app/375820/discussions/0/483368526585564846/
+func defaultProfilePaths() ([]string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		return []string{`%APPDATA%\Human Resource Machine\profiles.bin`}, nil
+	case "darwin":
+		return []string{
+			`~/Library/Application Support/Human Resource Machine/profiles.bin`,
+			`~/Library/Containers/Tomorrow-Corporation.Human-Resource-Machine/Data/Library/Application Support/Human Resource Machine/profiles.bin`}, nil
+	case "linux":
+		return []string{`~/.local/share/Tomorrow Corporation/Human Resource Machine/profiles.bin`}, nil
+	default:
+		return nil, fmt.Errorf("unknown OS, cannot determine default profile path, please specify with --profile")
+	}
+}
+
+// Expand a default profile path, i.e. the home directory and environment
+// variables
+func expandProfilePath(path string) (string, error) {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		// os.ExpandEnv does not understand %VAR%
+		path = os.Expand(windowsEnvToShell(path), os.Getenv)
+	}
+	return path, nil
+}
+
+// Rewrite %VAR% style environment variables as ${VAR}
+func windowsEnvToShell(path string) string {
+	var result []byte
+	for i := 0; i < len(path); i++ {
+		if path[i] == '%' {
+			for j := i + 1; j < len(path); j++ {
+				if path[j] == '%' {
+					result = append(result, "${"+path[i+1:j]+"}"...)
+					i = j
+					break
+				}
+			}
+			if path[i] == '%' {
+				result = append(result, '%')
+			}
+			continue
+		}
+		result = append(result, path[i])
+	}
+	return string(result)
+}
+
+// Return all default profile locations for the current OS, expanded and
+// stat'ed, followed by the profiles found in the Steam libraries (see
+// steamProfileCandidates)
+func profileCandidates() ([]profileCandidate, error) {
+	paths, err := defaultProfilePaths()
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]profileCandidate, len(paths))
+	for i, pattern := range paths {
+		candidates[i].pattern = pattern
+		if candidates[i].path, err = expandProfilePath(pattern); err != nil {
+			return nil, err
+		}
+		candidates[i].info, candidates[i].err = os.Stat(candidates[i].path)
+	}
+	for _, steamCandidate := range steamProfileCandidates() {
+		duplicate := false
+		for _, candidate := range candidates {
+			duplicate = duplicate || sameFile(candidate.info, steamCandidate.info)
+		}
+		if !duplicate {
+			candidates = append(candidates, steamCandidate)
+		}
+	}
+	return candidates, nil
+}
+
+// Report whether the stat'ed files are the same file
+func sameFile(a, b os.FileInfo) bool {
+	return a != nil && b != nil && os.SameFile(a, b)
+}
+
+func paths(cmd *cobra.Command, args []string) {
+	candidates, err := profileCandidates()
+	if err != nil {
+		fatal(err)
+	}
+	selected, selectErr := profileFilePath()
+
+	fmt.Printf("Default profile locations (%s):\n", runtime.GOOS)
+	for _, candidate := range candidates {
+		marker := " "
+		if selectErr == nil && candidate.path == selected {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, candidate.path)
+		if candidate.pattern != candidate.path {
+			fmt.Printf("      from: %s\n", candidate.pattern)
+		}
+		if candidate.exists() {
+			fmt.Printf("      size: %d bytes\n", candidate.info.Size())
+			fmt.Printf("  modified: %s\n", candidate.info.ModTime().Format("2006-01-02 15:04:05 MST"))
+		} else if os.IsNotExist(candidate.err) {
+			fmt.Printf("            does not exist\n")
+		} else {
+			fmt.Printf("     error: %v\n", candidate.err)
+		}
+	}
+	fmt.Println()
+	if profilePath != "" {
+		fmt.Printf("Selected (--profile): %s\n", profilePath)
+	} else if selectErr != nil {
+		fmt.Printf("No profile would be selected: %v\n", selectErr)
+	} else {
+		fmt.Printf("Selected: %s\n", selected)
+	}
+}
+
+func pathsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "paths",
+		Short: "List candidate profile locations",
+		Long: `List every default profile location for the current OS, whether it exists, and which one would be selected.
+
+The Steam libraries (found through the registry on Windows, and the
+libraryfolders.vdf of the Steam installation) are searched as well, and the
+profiles found in them are listed.`,
+		Args: cobra.NoArgs,
+		Run:  paths,
+	}
+}
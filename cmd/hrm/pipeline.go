@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pipelineWatch    bool
+	pipelineInterval time.Duration
+)
+
+// A pipeline spec: the outputs rendered from a profile by hrm pipeline run
+type pipelineSpec struct {
+	// The profile, unless given with --profile
+	Profile string
+	Slot    int
+	Outputs []pipelineOutput
+}
+
+// A tab rendered by a pipeline
+type pipelineOutput struct {
+	// The line of the spec the output is defined on
+	line   int
+	Floor  int
+	Tab    int
+	Format string
+	// The directory the output is written to, named as by export-all
+	// unless File is set
+	Dir  string
+	File string
+}
+
+// Return the value of a scalar of a pipeline spec, unquoting single or
+// double quoted scalars
+func pipelineScalar(value string) (string, error) {
+	switch {
+	case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+		return strconv.Unquote(value)
+	case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+		return strings.Replace(value[1:len(value)-1], "''", "'", -1), nil
+	}
+	return value, nil
+}
+
+// Remove a # comment from a line of a pipeline spec
+func stripPipelineComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// Set a field of the spec or of an output from a key: value line
+func (s *pipelineSpec) set(output *pipelineOutput, key, value string) error {
+	value, err := pipelineScalar(value)
+	if err != nil {
+		return fmt.Errorf("invalid quoted value %s", value)
+	}
+	number := func(field *int) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", key, value)
+		}
+		*field = n
+		return nil
+	}
+	if output == nil {
+		switch key {
+		case "profile":
+			s.Profile = value
+		case "slot":
+			return number(&s.Slot)
+		default:
+			return fmt.Errorf("unknown key %q (profile, slot or outputs)", key)
+		}
+		return nil
+	}
+	switch key {
+	case "floor":
+		return number(&output.Floor)
+	case "tab":
+		return number(&output.Tab)
+	case "format":
+		output.Format = value
+	case "dir":
+		output.Dir = value
+	case "file":
+		output.File = value
+	default:
+		return fmt.Errorf("unknown output key %q (floor, tab, format, dir or file)", key)
+	}
+	return nil
+}
+
+// Parse a spec written in a subset of YAML: a mapping of keys, the value
+// of listKey being a sequence of mappings. Values are plain or quoted
+// scalars, # starts a comment. newItem is called with the line of each
+// item of the sequence and set with each key and value, item reporting
+// whether the key belongs to the last item
+func parseSpec(data []byte, listKey string, newItem func(line int), set func(item bool, key, value string) error) error {
+	inList, inItem := false, false
+	itemIndent := 0
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNumber := i + 1
+		line = strings.TrimRight(stripPipelineComment(line), " \t\r")
+		if strings.TrimSpace(line) == "" || line == "---" {
+			continue
+		}
+		content := strings.TrimLeft(line, " ")
+		indent := len(line) - len(content)
+		if strings.HasPrefix(content, "\t") {
+			return fmt.Errorf("line %d: indent with spaces, not tabs", lineNumber)
+		}
+
+		item := content == "-" || strings.HasPrefix(content, "- ")
+		switch {
+		case inList && item:
+			newItem(lineNumber)
+			inItem = true
+			itemIndent = indent
+			content = strings.TrimSpace(content[1:])
+			if content == "" {
+				continue
+			}
+		case inItem && indent > itemIndent:
+		case indent == 0 && !item:
+			inList, inItem = false, false
+		default:
+			return fmt.Errorf("line %d: unexpected indentation", lineNumber)
+		}
+
+		colon := strings.Index(content, ":")
+		if colon < 0 {
+			return fmt.Errorf("line %d: expected key: value", lineNumber)
+		}
+		key, value := strings.TrimSpace(content[:colon]), strings.TrimSpace(content[colon+1:])
+		if !inItem && key == listKey {
+			if value != "" {
+				return fmt.Errorf("line %d: %s must be a list of - key: value entries", lineNumber, listKey)
+			}
+			inList = true
+			continue
+		}
+		if err := set(inItem, key, value); err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+	return nil
+}
+
+// Parse a pipeline spec, see parseSpec
+func parsePipelineSpec(data []byte) (pipelineSpec, error) {
+	spec := pipelineSpec{Slot: 1}
+	err := parseSpec(data, "outputs", func(line int) {
+		spec.Outputs = append(spec.Outputs, pipelineOutput{line: line, Tab: 1})
+	}, func(item bool, key, value string) error {
+		var output *pipelineOutput
+		if item {
+			output = &spec.Outputs[len(spec.Outputs)-1]
+		}
+		return spec.set(output, key, value)
+	})
+	if err != nil {
+		return spec, err
+	}
+
+	if spec.Slot < 1 {
+		return spec, fmt.Errorf("profile slots are numbered from 1, got %d", spec.Slot)
+	}
+	for _, output := range spec.Outputs {
+		if _, err := profile.FloorToIndex(output.Floor); err != nil {
+			return spec, fmt.Errorf("line %d: %w", output.line, err)
+		}
+		if output.Tab < 1 || output.Tab > 3 {
+			return spec, fmt.Errorf("line %d: tabs are numbered from 1 to 3, got %d", output.line, output.Tab)
+		}
+	}
+	return spec, nil
+}
+
+// Read the pipeline spec at path. Relative paths in the spec are relative
+// to the directory of the spec
+func readPipelineSpec(path string) (pipelineSpec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return pipelineSpec{}, err
+	}
+	spec, err := parsePipelineSpec(data)
+	if err != nil {
+		return spec, fmt.Errorf("%s: %w", path, err)
+	}
+	relative := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(filepath.Dir(path), p)
+	}
+	if spec.Profile != "" && !isProfileURL(spec.Profile) {
+		if spec.Profile, err = expandProfilePath(spec.Profile); err != nil {
+			return spec, err
+		}
+		spec.Profile = relative(spec.Profile)
+	}
+	for i := range spec.Outputs {
+		spec.Outputs[i].Dir = relative(spec.Outputs[i].Dir)
+		if spec.Outputs[i].Dir == "" {
+			spec.Outputs[i].Dir = filepath.Dir(path)
+		}
+	}
+	return spec, nil
+}
+
+// Return the path of the profile read by the pipeline: --profile, the
+// spec's profile or else the default profile
+func (s pipelineSpec) profilePath() (string, error) {
+	if profilePath == "" && s.Profile != "" {
+		return s.Profile, nil
+	}
+	return profileFilePath()
+}
+
+// Render an output of a pipeline from reader. The file is only written if
+// its contents change, reports whether it was
+func (s pipelineSpec) render(reader profileReader, output pipelineOutput) (string, bool, error) {
+	floorIndex, _ := profile.FloorToIndex(output.Floor)
+	tab := output.Tab - 1
+
+	format, found := render.LookupFileName(output.File)
+	if output.Format != "" {
+		if format, found = lookupFormat(output.Format); !found {
+			return "", false, render.UnknownFormatError(output.Format)
+		}
+	} else if !found {
+		format, _ = render.Lookup("text")
+	}
+	fileName := output.File
+	if fileName == "" {
+		fileName = exportFileName(floorIndex, tab, format)
+	}
+	if !filepath.IsAbs(fileName) {
+		fileName = filepath.Join(output.Dir, fileName)
+	}
+
+	tabStart := profileLayout(reader).TabStartAddr(s.Slot, floorIndex, tab)
+	program, err := render.DecodeProgramAt(appContext, reader, tabStart, programDecodeOptions()...)
+	if err != nil {
+		return fileName, false, &profile.DecodeError{Offset: tabStart, Floor: output.Floor, Tab: output.Tab, Err: err}
+	}
+	options, err := renderOptions(fileName, program, reader, s.Slot, floorIndex, tab)
+	if err != nil {
+		return fileName, false, err
+	}
+	var buffer bytes.Buffer
+	w := maybeCompress(nopWriteCloser{&buffer}, fileName)
+	if err := format.Render(appContext, w, program, options); err != nil {
+		return fileName, false, err
+	}
+	if err := w.Close(); err != nil {
+		return fileName, false, err
+	}
+
+	if existing, err := ioutil.ReadFile(fileName); err == nil && bytes.Equal(existing, buffer.Bytes()) {
+		return fileName, false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return fileName, false, err
+	}
+	return fileName, true, ioutil.WriteFile(fileName, buffer.Bytes(), 0644)
+}
+
+// Run the pipeline spec at specPath, rendering every output. Outputs which
+// fail are reported and the remaining outputs rendered
+func runPipeline(specPath string) error {
+	spec, err := readPipelineSpec(specPath)
+	if err != nil {
+		return err
+	}
+	path, err := spec.profilePath()
+	if err != nil {
+		return err
+	}
+	reader, err := openProfileAt(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	size, err := profileSize(reader)
+	if err != nil {
+		return err
+	}
+	if count := profileLayout(reader).SlotCount(size); spec.Slot > count {
+		return fmt.Errorf("profile slot %d does not exist, the profile has %d slot(s)", spec.Slot, count)
+	}
+
+	failed, written := 0, 0
+	for _, output := range spec.Outputs {
+		fileName, changed, err := spec.render(reader, output)
+		switch {
+		case err != nil:
+			failed++
+			logger.Error("output failed", "line", output.line, "floor", output.Floor, "tab", output.Tab, "file", fileName, "error", err)
+		case changed:
+			written++
+			if !logQuiet {
+				fmt.Fprintf(os.Stderr, "wrote %s\n", fileName)
+			}
+		default:
+			logger.Debug("output unchanged", "file", fileName)
+		}
+	}
+	if !logQuiet {
+		fmt.Fprintf(os.Stderr, "%s: %d outputs, %d written, %d unchanged\n", specPath, len(spec.Outputs), written, len(spec.Outputs)-written-failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d outputs failed", failed, len(spec.Outputs))
+	}
+	return nil
+}
+
+// The modification time and size of a watched file
+type watchedFile struct {
+	modified time.Time
+	size     int64
+}
+
+func statWatched(paths []string) []watchedFile {
+	files := make([]watchedFile, len(paths))
+	for i, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			files[i] = watchedFile{info.ModTime(), info.Size()}
+		}
+	}
+	return files
+}
+
+func sameWatched(a, b []watchedFile) bool {
+	for i := range a {
+		if !a[i].modified.Equal(b[i].modified) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+// Poll the files at paths every interval until the context is cancelled,
+// calling run once they have changed and then stayed unchanged for an
+// interval, as the game may still be writing the profile
+func watchFiles(paths []string, interval time.Duration, run func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := statWatched(paths)
+	pending := false
+	for {
+		select {
+		case <-appContext.Done():
+			return
+		case <-ticker.C:
+		}
+		current := statWatched(paths)
+		switch {
+		case !sameWatched(current, last):
+			pending = true
+		case pending:
+			pending = false
+			run()
+		}
+		last = current
+	}
+}
+
+func pipelineRun(cmd *cobra.Command, args []string) {
+	specPath := args[0]
+	if !pipelineWatch {
+		if err := runPipeline(specPath); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	if pipelineInterval <= 0 {
+		usageFatalf("--interval must be positive")
+	}
+	spec, err := readPipelineSpec(specPath)
+	if err != nil {
+		fatal(err)
+	}
+	path, err := spec.profilePath()
+	if err != nil {
+		fatal(err)
+	}
+	if isProfileURL(path) {
+		usageFatalf("--watch requires a local profile, not %s", path)
+	}
+	activity := newActivityTracker(path, spec.Slot)
+	run := func() {
+		if err := runPipeline(specPath); err != nil {
+			logger.Error("pipeline failed", "spec", specPath, "error", err)
+		}
+		activity.observeFile()
+	}
+	run()
+	if !logQuiet {
+		fmt.Fprintf(os.Stderr, "Watching %s and %s for changes\n", path, specPath)
+	}
+	// A change of the spec's profile is picked up on the next change of
+	// either file
+	watchFiles([]string{path, specPath}, pipelineInterval, run)
+}
+
+func pipelineCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pipeline",
+		Short: "Run export pipelines",
+		Long: `Render tabs of a profile into several files, as described by a pipeline
+spec, instead of running hrm from a shell script.`,
+	}
+	runCmd := &cobra.Command{
+		Use:   "run SPEC",
+		Short: "Render the outputs of a pipeline spec",
+		Long: `Render the outputs described by the pipeline spec SPEC, a YAML file such as:
+
+  # Relative paths are relative to the directory of the spec
+  profile: ~/hrm/profiles.bin   # optional, --profile takes precedence
+  slot: 1
+  outputs:
+    - floor: 20
+      tab: 1
+      format: svg
+      dir: docs
+    - floor: 20
+      tab: 1
+      file: solutions/floor-20.txt
+
+Outputs are named as by hrm export-all unless file is given, the format is
+implied by the file name unless given (text by default). Files are only
+written if their contents change. The text and SVG flags apply to every
+output.
+
+With --watch the pipeline is run again whenever the profile or the spec
+changes, until interrupted, and every save of the profile is recorded in
+the activity log listed by hrm sessions.`,
+		Args: cobra.ExactArgs(1),
+		Run:  pipelineRun,
+	}
+	runCmd.Flags().BoolVarP(&pipelineWatch, "watch", "w", false, "Run the pipeline again whenever the profile or SPEC changes")
+	runCmd.Flags().DurationVar(&pipelineInterval, "interval", time.Second, "How often to check for changes with --watch")
+	addActivityFlags(runCmd)
+	addTextFlags(runCmd)
+	addSVGFlags(runCmd)
+	cmd.AddCommand(runCmd)
+	return cmd
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The width of the progress bar in characters
+const progressBarWidth = 40
+
+// A progress indicator drawn on a terminal. Nothing is drawn unless the
+// writer is a terminal. Safe for concurrent use
+type progress struct {
+	mu      sync.Mutex
+	w       io.Writer
+	enabled bool
+	color   bool
+	total   int
+	done    int
+	label   string
+}
+
+// Return a progress indicator for total steps drawn on stderr
+func newProgress(total int, label string) *progress {
+	return &progress{
+		w:       os.Stderr,
+		enabled: isTerminal(os.Stderr) && !logQuiet,
+		color:   useColor(os.Stderr),
+		total:   total,
+		label:   label,
+	}
+}
+
+// Mark one step as done and redraw
+func (p *progress) increment() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.draw()
+}
+
+func (p *progress) draw() {
+	if !p.enabled || p.total == 0 {
+		return
+	}
+	filled := progressBarWidth * p.done / p.total
+	fmt.Fprintf(p.w, "\r%s [%s%s] %d/%d", p.label,
+		paint(p.color, ansiGreen, strings.Repeat("=", filled)), strings.Repeat(" ", progressBarWidth-filled), p.done, p.total)
+}
+
+// Finish drawing, moving to the next line
+func (p *progress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.enabled {
+		fmt.Fprintln(p.w)
+	}
+}
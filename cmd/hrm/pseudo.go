@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var pseudoEmitHRM bool
+
+func pseudo(cmd *cobra.Command, args []string) {
+	floorIndex, tab := floorTabArgs(args)
+	reader := openProfile()
+	defer reader.Close()
+	program := decodeTab(reader, slotNumber, floorIndex, tab)
+	source, err := instructions.Decompile(program.Disassembled)
+	if err != nil {
+		fatal(fmt.Errorf("floor %d tab %d: %w", floorNumber(floorIndex), tab+1, err))
+	}
+
+	text := source
+	if pseudoEmitHRM {
+		compiled, err := instructions.Compile(source)
+		if err != nil {
+			fatal(fmt.Errorf("floor %d tab %d: the decompiled program does not compile: %w", floorNumber(floorIndex), tab+1, err))
+		}
+		disassembled, err := instructions.Disassemble(compiled)
+		if err != nil {
+			fatal(err)
+		}
+		if !instructions.Equivalent(program.Disassembled, disassembled) {
+			fatalf("floor %d tab %d: the recompiled program is not equivalent to the original", floorNumber(floorIndex), tab+1)
+		}
+		var opts []render.RenderInstructionsTextOption
+		if mnemonics := textMnemonics(); mnemonics != nil {
+			opts = append(opts, render.UseMnemonics(mnemonics))
+		}
+		text = render.RenderInstructionsText(disassembled, opts...)
+		if saved := program.Disassembled.Size() - disassembled.Size(); saved > 0 && !logQuiet {
+			fmt.Fprintf(os.Stderr, "%d redundant instruction(s) left out\n", saved)
+		}
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	if _, err := output.Write([]byte(text)); err != nil {
+		fatal(err)
+	}
+	if err := output.Close(); err != nil {
+		fatal(err)
+	}
+}
+
+func pseudoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pseudo FLOOR TAB",
+		Short: "Decompile a program into hrmc source",
+		Long: `Decompile the program of a tab into the small structured language compiled
+by hrm hrmc, for editing at a higher level than the game's instructions.
+
+Each tile used becomes a variable named after its number (tile 3 is t3),
+the instructions computing a value and storing, outputting or testing it
+become one statement, and jumps become gotos. The drawings of comments are
+not kept.
+
+With --emit-hrm the decompiled source is compiled again and checked to be
+equivalent to the original program: identical, apart from comments, the
+names of labels and COPYFROMs of a tile the worker already holds, which
+are left out. The recompiled program is written instead of the source.
+Once --emit-hrm succeeds for a tab, its source can be edited and compiled
+with hrm hrmc without losing the parts left alone.`,
+		Args: cobra.ExactArgs(2),
+		Run:  pseudo,
+	}
+	addSlotFlag(cmd, "read")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the source to")
+	cmd.Flags().BoolVar(&pseudoEmitHRM, "emit-hrm", false, "Write the recompiled program, checked to be equivalent to the original")
+	return cmd
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+// Decode save slots past the floors and tabs which fail to decode
+// (--keep-going)
+var keepGoing bool
+
+// Add the --keep-going flag to cmd
+func addQuarantineFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVarP(&keepGoing, "keep-going", "k", false, "Skip the floors and tabs of a save slot which fail to decode, rather than stopping")
+}
+
+// Decode the save slot of the open profile with opts. With --keep-going
+// the floors and tabs which fail to decode are left empty and logged as
+// warnings, see profile.Quarantine
+func decodeSlot(reader profileReader, slot int, opts ...profile.DecodeOption) (profile.Profile, error) {
+	opts = append([]profile.DecodeOption{profile.DecodeLayout(profileLayout(reader)), profile.DecodeSlot(slot), profile.Logger(logger)}, opts...)
+	if keepGoing {
+		opts = append(opts, profile.Quarantine())
+	}
+	return profile.DecodeAt(appContext, reader, opts...)
+}
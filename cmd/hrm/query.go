@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/jmespath/go-jmespath"
+	"github.com/spf13/cobra"
+)
+
+var (
+	queryRaw bool
+)
+
+// Write a query result as JSON, or with --raw strings without quotes and
+// lists of strings and numbers one element per line
+func writeQueryResult(w io.Writer, result interface{}) error {
+	if queryRaw {
+		switch result := result.(type) {
+		case string:
+			_, err := fmt.Fprintln(w, result)
+			return err
+		case []interface{}:
+			scalars := true
+			for _, element := range result {
+				switch element.(type) {
+				case string, float64, bool:
+				default:
+					scalars = false
+				}
+			}
+			if scalars {
+				for _, element := range result {
+					if _, err := fmt.Fprintln(w, element); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func query(cmd *cobra.Command, args []string) {
+	expression, err := jmespath.Compile(args[0])
+	if err != nil {
+		usageFatalf("invalid query: %v", err)
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+	checkSlot(reader, slotNumber)
+	p, err := decodeSlot(reader, slotNumber, profile.ProgramOptions(programDecodeOptions()...))
+	if err != nil {
+		fatal(err)
+	}
+
+	// Query the representation as decoded JSON, so that the query sees
+	// exactly the field names and types of the JSON output
+	representation := p.Schema(slotNumber)
+	for i, floor := range representation.Floors {
+		if level, found := levels.Lookup(floor.Number); found {
+			representation.Floors[i].Name = levelName(level)
+		}
+	}
+	data, err := json.Marshal(representation)
+	if err != nil {
+		fatal(err)
+	}
+	var view interface{}
+	if err := json.Unmarshal(data, &view); err != nil {
+		fatal(err)
+	}
+	result, err := expression.Search(view)
+	if err != nil {
+		fatal(err)
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	if err := writeQueryResult(output, result); err != nil {
+		fatal(err)
+	}
+	if err := output.Close(); err != nil {
+		fatal(err)
+	}
+}
+
+func queryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "query EXPRESSION",
+		Short: "Select data from the profile with a JMESPath expression",
+		Long: `Select data from the JSON representation of the profile with a JMESPath
+expression (https://jmespath.org) and print the result as JSON.
+
+The representation is an object with the fields schema_version, slot and
+floors, a list of floors with the fields number, name, completed, size and
+speed (the best results, null until completed), size_target, speed_target and
+tabs, a list of tabs with the fields tab, size, hash, instructions and
+comments. See hrm gen-schema for its JSON Schema.
+
+For example, the floors completed without meeting the speed challenge:
+
+  hrm query -r 'floors[?completed && speed > speed_target].number'
+
+Use an expression of @ to print the whole representation. The result is
+always JSON (or plain text with --raw), so query has no --format flag.`,
+		Args: cobra.ExactArgs(1),
+		Run:  query,
+	}
+	addSlotFlag(cmd, "read")
+	cmd.Flags().BoolVarP(&queryRaw, "raw", "r", false, "Print strings without quotes, and lists of strings and numbers one element per line")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the result to")
+	addCommentSpaceFlags(cmd)
+	return cmd
+}
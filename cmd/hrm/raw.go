@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/journal"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	loadRawForce bool
+)
+
+// Return the floor index and tab index of FLOOR TAB arguments, exiting
+// with a usage error if there is no such tab
+func floorTabArgs(args []string) (int, int) {
+	floorIndex := floorIndexArg(parseInt(args[0]))
+	tab := parseInt(args[1])
+	if tab < 1 || tab > 3 {
+		usageFatalf("tabs are numbered from 1 to 3, got %d", tab)
+	}
+	return floorIndex, tab - 1
+}
+
+func dumpRaw(cmd *cobra.Command, args []string) {
+	floorIndex, tab := floorTabArgs(args)
+	if outputFileName == "" && isTerminal(os.Stdout) {
+		usageFatalf("refusing to write binary output to a terminal, use --output or redirect stdout")
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+	checkSlot(reader, slotNumber)
+	layout := profileLayout(reader)
+	data := make([]byte, layout.FloorTabSize)
+	if _, err := reader.ReadAt(data, layout.TabStartAddr(slotNumber, floorIndex, tab)); err != nil {
+		fatal(err)
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+	if _, err := output.Write(data); err != nil {
+		fatal(err)
+	}
+}
+
+func loadRaw(cmd *cobra.Command, args []string) {
+	floorIndex, tab := floorTabArgs(args)
+	var data []byte
+	var err error
+	if args[2] == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(args[2])
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	path := editProfilePath()
+	reader, err := openProfileAt(path)
+	if err != nil {
+		fatal(err)
+	}
+	checkSlot(reader, slotNumber)
+	layout := profileLayout(reader)
+	reader.Close()
+	if len(data) != layout.FloorTabSize {
+		fatalf("%s is %d bytes, a tab is %d bytes", args[2], len(data), layout.FloorTabSize)
+	}
+	if _, err := render.DecodeProgramContext(appContext, bytes.NewReader(data), instructions.Logger(logger)); err != nil {
+		if !loadRawForce {
+			fatalf("%s does not decode as a tab (use --force to load it anyway): %v", args[2], err)
+		}
+		logger.Warn("loading a tab which does not decode", "error", err)
+	}
+
+	floor := floorNumber(floorIndex)
+	description := fmt.Sprintf("floor %d tab %d: load %s", floor, tab+1, args[2])
+	applyEdit("load-raw", path, description, []journal.Change{{Offset: layout.TabStartAddr(slotNumber, floorIndex, tab), Modified: data}})
+}
+
+func dumpRawCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dump-raw FLOOR TAB",
+		Short: "Write the raw bytes of a tab",
+		Long: `Write the raw bytes of a tab of the profile: the instruction block, the
+comment block and the padding up to the next tab, exactly as stored. The
+file can be edited with a hex editor and loaded back with hrm load-raw`,
+		Args: cobra.ExactArgs(2),
+		Run:  dumpRaw,
+	}
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the tab to")
+	addSlotFlag(cmd, "read")
+	return cmd
+}
+
+func loadRawCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "load-raw FLOOR TAB FILE",
+		Short: "Replace a tab with raw bytes",
+		Long: `Replace a tab of the profile with the raw bytes in FILE (- for stdin), as
+written by hrm dump-raw. The file must be exactly the size of a tab and
+decode as a program, unless --force is given.
+
+The profile is backed up first and the change is recorded in the edit
+journal, revert it with hrm undo`,
+		Args: cobra.ExactArgs(3),
+		Run:  loadRaw,
+	}
+	cmd.Flags().BoolVar(&loadRawForce, "force", false, "Load the file even if it does not decode as a program")
+	addEditFlags(cmd)
+	return cmd
+}
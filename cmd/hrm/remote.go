@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// The largest profile downloaded from a URL. Profiles are a few MB at most
+const maxRemoteProfileSize = 64 << 20
+
+// The client of the HTTP requests made by the tool, downloading profiles
+// and programs and posting to webhooks. Unlike http.DefaultClient it gives
+// up on servers which stop responding
+var httpClient = &http.Client{Timeout: 2 * time.Minute}
+
+// An open profile, either a file or a profile downloaded into memory
+type profileReader interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// A profile downloaded into memory
+type memoryProfile struct {
+	*bytes.Reader
+}
+
+func (memoryProfile) Close() error { return nil }
+
+// An unsuccessful HTTP response when downloading a profile
+type httpStatusError struct {
+	url        string
+	status     string
+	statusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("downloading %s: %s", e.url, e.status)
+}
+
+// Report whether path is an HTTP(S) URL rather than a file path
+func isProfileURL(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// Download url, failing if the response is larger than limit bytes
+func fetchURL(url string, limit int64) ([]byte, error) {
+	request, err := http.NewRequestWithContext(appContext, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", "hrm-profile-tool/"+version)
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{url, response.Status, response.StatusCode}
+	}
+	data, err := io.ReadAll(io.LimitReader(response.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", url, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("downloading %s: larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// Open the profile at path, which is either a file path or an HTTP(S) URL
+// which is downloaded into memory
+func openProfileAt(path string) (profileReader, error) {
+	if !isProfileURL(path) {
+		return os.Open(path)
+	}
+	logger.Debug("downloading profile", "url", path)
+	data, err := fetchURL(path, maxRemoteProfileSize)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("downloaded profile", "url", path, "size", len(data))
+	return memoryProfile{bytes.NewReader(data)}, nil
+}
+
+// Return the size of an open profile
+func profileSize(r profileReader) (int64, error) {
+	switch r := r.(type) {
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return info.Size(), nil
+	case memoryProfile:
+		return r.Size(), nil
+	default:
+		return 0, fmt.Errorf("cannot determine the size of the profile")
+	}
+}
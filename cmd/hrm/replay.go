@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/vm"
+)
+
+// A run of a program as recorded with hrm run --record: the inbox and
+// what the program made of it. Values are written as in the game
+type replay struct {
+	Floor  int      `json:"floor"`
+	Inbox  []string `json:"inbox"`
+	Outbox []string `json:"outbox"`
+	Steps  int      `json:"steps"`
+	// The error the run ended with, e.g. a runtime error, if any
+	Error string `json:"error,omitempty"`
+}
+
+// Return the strings of values
+func valueStrings(values []levels.Value) []string {
+	strs := make([]string, len(values))
+	for i, value := range values {
+		strs[i] = value.String()
+	}
+	return strs
+}
+
+// Return the replay of a run of a program on floor, which ended with err
+func newReplay(floor int, inbox []levels.Value, m *vm.Machine, err error) replay {
+	r := replay{Floor: floor, Inbox: valueStrings(inbox), Outbox: valueStrings(m.Outbox), Steps: m.Steps}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// Report whether err is a deterministic outcome of a run, which is
+// recorded in and compared against replays
+func replayableError(err error) bool {
+	var runtimeErr *vm.RuntimeError
+	return err == nil || errors.As(err, &runtimeErr) || errors.Is(err, vm.ErrStepLimit)
+}
+
+// Read the replay at path
+func readReplay(path string) (replay, error) {
+	var r replay
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Return the inbox of the replay
+func (r replay) inbox() ([]levels.Value, error) {
+	values := make([]levels.Value, len(r.Inbox))
+	for i, s := range r.Inbox {
+		value, err := vm.ParseValue(s)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// Write the replay to path
+func (r replay) write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Return the differences of the outcome of run from the recorded one
+func (r replay) differences(run replay) []string {
+	var differences []string
+	if strings.Join(r.Outbox, " ") != strings.Join(run.Outbox, " ") {
+		differences = append(differences, fmt.Sprintf("outbox: recorded %s, got %s", strings.Join(r.Outbox, " "), strings.Join(run.Outbox, " ")))
+	}
+	if r.Steps != run.Steps {
+		differences = append(differences, fmt.Sprintf("steps: recorded %d, got %d", r.Steps, run.Steps))
+	}
+	if r.Error != run.Error {
+		differences = append(differences, fmt.Sprintf("error: recorded %q, got %q", r.Error, run.Error))
+	}
+	return differences
+}
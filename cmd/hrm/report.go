@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// The writers of a report (the output of commands such as opstats, slots
+// and audit), by the name of a registered format. Reports are not
+// programs, so the render functions of the formats do not apply to them:
+// the format is selected through the registry like the format of a
+// program (see selectOutputFormat), and the command writes the report in
+// it. query is the exception, its output is the JSON result of the query
+type reportWriters map[string]func(io.Writer) error
+
+// Return the names of the formats the report can be written in
+func (writers reportWriters) names() []string {
+	var names []string
+	for name := range writers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Return the writer of the format selected with --format or implied by
+// the --output file name (text by default), exiting with a usage error if
+// the report cannot be written in it
+func (writers reportWriters) selected() func(io.Writer) error {
+	if _, found := writers[outputFormat]; outputFormat != "" && !found {
+		usageFatalf("unknown format %q for this report (available: %s)", outputFormat, strings.Join(writers.names(), ", "))
+	}
+	format, err := selectOutputFormat("text")
+	if err != nil {
+		usageFatalf("%v", err)
+	}
+	write, found := writers[format.Name]
+	if !found {
+		usageFatalf("cannot write this report as %s (available: %s)", format.Name, strings.Join(writers.names(), ", "))
+	}
+	return write
+}
+
+// Write the report with write to the output selected by --output
+func writeReport(write func(io.Writer) error) {
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+	if err := write(output); err != nil {
+		fatal(err)
+	}
+}
+
+// Add the --output and --format flags of a report command, whose report
+// is written in formats
+func addReportFlags(cmd *cobra.Command, what string, formats ...string) {
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the "+what+" to (the format is implied by the extension)")
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "", "Output `FORMAT` ("+strings.Join(formats, ", ")+")")
+	cmd.Flags().BoolVarP(&outputCompress, "compress", "z", false, "Compress the output with gzip (implied by .gz output file names)")
+}
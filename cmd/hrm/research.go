@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+var researchCompare string
+
+// A region of a profile which is not (fully) understood
+type researchRegion struct {
+	name   string
+	offset int64
+	data   []byte
+	// The floor header, for floor header regions
+	floorHeader *profile.FloorHeader
+}
+
+// Read the regions of a profile file which are not decoded: the file
+// header, the floor headers and any trailing data after the last floor
+func readResearchRegions(file profileReader, profileId int) ([]researchRegion, error) {
+	fileSize, err := profileSize(file)
+	if err != nil {
+		return nil, err
+	}
+	layout := profileLayout(file)
+	readRegion := func(name string, offset, size int64) (researchRegion, error) {
+		region := researchRegion{name: name, offset: offset}
+		if offset+size > fileSize {
+			size = fileSize - offset
+		}
+		if size <= 0 {
+			return region, nil
+		}
+		region.data = make([]byte, size)
+		_, err := file.ReadAt(region.data, offset)
+		return region, err
+	}
+
+	var regions []researchRegion
+	region, err := readRegion("header", layout.SlotStartAddr(profileId), int64(layout.FileHeaderSize))
+	if err != nil {
+		return nil, err
+	}
+	regions = append(regions, region)
+	numFloors := len(profile.Profile{}.Floors)
+	for floorIndex := 0; floorIndex < numFloors; floorIndex++ {
+		name := fmt.Sprintf("floor %02d", floorNumber(floorIndex))
+		region, err := readRegion(name, layout.FloorStartAddr(profileId, floorIndex), int64(layout.FloorHeaderSize))
+		if err != nil {
+			return nil, err
+		}
+		var floorHeader profile.FloorHeader
+		if err := floorHeader.UnmarshalBinary(region.data); err == nil {
+			region.floorHeader = &floorHeader
+		}
+		regions = append(regions, region)
+	}
+	end := layout.FloorStartAddr(profileId, numFloors)
+	region, err = readRegion("trailer", end, fileSize-end)
+	if err != nil {
+		return nil, err
+	}
+	return append(regions, region), nil
+}
+
+// Return the names and values of the decoded fields of a floor header
+func floorHeaderFields(floorHeader profile.FloorHeader) ([]string, []uint32) {
+	v := reflect.ValueOf(floorHeader)
+	var names []string
+	var values []uint32
+	for i := 0; i < v.NumField(); i++ {
+		switch field := v.Field(i); field.Kind() {
+		case reflect.Int32:
+			values = append(values, uint32(field.Int()))
+		case reflect.Uint32:
+			values = append(values, uint32(field.Uint()))
+		default:
+			continue // The RawHeader
+		}
+		names = append(names, v.Type().Field(i).Name)
+	}
+	return names, values
+}
+
+// Write a region, one line per field (floor headers) or per 16 bytes
+func dumpResearchRegion(w io.Writer, region researchRegion) {
+	if region.floorHeader != nil {
+		names, values := floorHeaderFields(*region.floorHeader)
+		for i, name := range names {
+			fmt.Fprintf(w, "%s %-24s 0x%08x %d\n", region.name, name, values[i], int32(values[i]))
+		}
+		return
+	}
+	for i := 0; i < len(region.data); i += 16 {
+		end := i + 16
+		if end > len(region.data) {
+			end = len(region.data)
+		}
+		var hex []string
+		for _, b := range region.data[i:end] {
+			hex = append(hex, fmt.Sprintf("%02x", b))
+		}
+		fmt.Fprintf(w, "%s +0x%04x (0x%08x) %s\n", region.name, i, region.offset+int64(i), strings.Join(hex, " "))
+	}
+}
+
+// Write the differences between two regions, returning the number of
+// differing fields or bytes
+func compareResearchRegion(w io.Writer, a, b researchRegion) int {
+	changed := 0
+	if a.floorHeader != nil && b.floorHeader != nil {
+		names, aValues := floorHeaderFields(*a.floorHeader)
+		_, bValues := floorHeaderFields(*b.floorHeader)
+		for i, name := range names {
+			if aValues[i] != bValues[i] {
+				fmt.Fprintf(w, "%s %-24s 0x%08x -> 0x%08x (%d -> %d)\n",
+					a.name, name, aValues[i], bValues[i], int32(aValues[i]), int32(bValues[i]))
+				changed++
+			}
+		}
+		return changed
+	}
+	n := len(a.data)
+	if len(b.data) > n {
+		n = len(b.data)
+	}
+	for i := 0; i < n; i++ {
+		aByte, bByte := "--", "--"
+		if i < len(a.data) {
+			aByte = fmt.Sprintf("%02x", a.data[i])
+		}
+		if i < len(b.data) {
+			bByte = fmt.Sprintf("%02x", b.data[i])
+		}
+		if aByte != bByte {
+			fmt.Fprintf(w, "%s +0x%04x (0x%08x) %s -> %s\n", a.name, i, a.offset+int64(i), aByte, bByte)
+			changed++
+		}
+	}
+	return changed
+}
+
+func research(cmd *cobra.Command, args []string) {
+	legacySlotArg(cmd, args)
+	profileId := slotNumber
+	reader := openProfile()
+	defer reader.Close()
+	checkSlot(reader, profileId)
+	regions, err := readResearchRegions(reader, profileId)
+	if err != nil {
+		fatal(err)
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+
+	if researchCompare == "" {
+		for _, region := range regions {
+			dumpResearchRegion(output, region)
+		}
+		return
+	}
+
+	other, err := openProfileAt(researchCompare)
+	if err != nil {
+		fatal(err)
+	}
+	defer other.Close()
+	otherRegions, err := readResearchRegions(other, profileId)
+	if err != nil {
+		fatal(err)
+	}
+	changed := 0
+	for i := range regions {
+		changed += compareResearchRegion(output, regions[i], otherRegions[i])
+	}
+	if !logQuiet {
+		fmt.Fprintf(os.Stderr, "%d unknown fields or bytes changed\n", changed)
+	}
+}
+
+func researchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "research",
+		Short: "Dump the unknown profile fields",
+		Long: `Dump the fields of the floor headers (including the unknown fields) and
+the undecoded file header and trailer, one value per line so that dumps
+can be diffed. With --compare only the values which differ between the
+profile and another save are shown`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  research,
+	}
+	addSlotFlag(cmd, "dump")
+	cmd.Flags().StringVar(&researchCompare, "compare", "", "`PATH` of another profiles.bin to compare against")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the dump to")
+	return cmd
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/clj/hrm-profile-tool/vm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	runInbox    string
+	runVisual   bool
+	runSpeed    float64
+	runMaxSteps int
+	runRecord   string
+	runReplay   string
+)
+
+// Parse a comma or space separated list of inbox values
+func parseInbox(s string) ([]levels.Value, error) {
+	var values []levels.Value
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		value, err := vm.ParseValue(field)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// Join values for display
+func joinValues(values []levels.Value) string {
+	return strings.Join(valueStrings(values), " ")
+}
+
+// Draw a frame of the visualization: the inbox, the worker's hand and the
+// outbox side by side, the floor tiles, and the program with the next
+// instruction highlighted
+func drawMachine(w io.Writer, level levels.Level, m *vm.Machine, listing []string, status string) {
+	var b strings.Builder
+	b.WriteString(ansiClear)
+	fmt.Fprintf(&b, "Floor %d %s    steps %d\n\n", level.Floor, levelName(level), m.Steps)
+
+	hand := ""
+	if m.Holding {
+		hand = "[" + m.Hand.String() + "]"
+	}
+	fmt.Fprintf(&b, "  %-6s  %-6s  %-6s\n", "INBOX", "HAND", "OUTBOX")
+	rows := len(m.Inbox)
+	if len(m.Outbox) > rows {
+		rows = len(m.Outbox)
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	if rows > 8 {
+		rows = 8
+	}
+	for row := 0; row < rows; row++ {
+		in, out, held := "", "", ""
+		if row < len(m.Inbox) {
+			in = m.Inbox[row].String()
+		}
+		// the most recent output at the top, as in the game
+		if row < len(m.Outbox) {
+			out = m.Outbox[len(m.Outbox)-1-row].String()
+		}
+		if row == 0 {
+			held = hand
+		}
+		fmt.Fprintf(&b, "  %6s  %-6s  %6s\n", in, held, out)
+	}
+
+	if columns := level.Columns(); columns > 0 {
+		b.WriteString("\n")
+		for i, tile := range m.Floor {
+			content := "."
+			if tile.Filled {
+				content = tile.Value.String()
+			}
+			fmt.Fprintf(&b, "%3d:%-4s", i, content)
+			if (i+1)%columns == 0 {
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	b.WriteString("\n")
+	for i, line := range listing {
+		if i == m.PC && !m.Halted {
+			fmt.Fprintf(&b, "> %s\n", paint(useColor(os.Stdout), ansiReverse, line))
+		} else {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	if status != "" {
+		fmt.Fprintf(&b, "\n%s\n", status)
+	}
+	io.WriteString(w, b.String())
+}
+
+// Run the machine, drawing a frame after each step
+func animateMachine(level levels.Level, m *vm.Machine, program instructions.Disassembled) error {
+	listing := strings.Split(strings.TrimRight(render.RenderInstructionsText(program, render.ShowLineNumbers()), "\n"), "\n")
+	delay := time.Duration(float64(time.Second) / runSpeed)
+	for !m.Halted {
+		drawMachine(os.Stdout, level, m, listing, "")
+		select {
+		case <-appContext.Done():
+			return appContext.Err()
+		case <-time.After(delay):
+		}
+		if m.Steps >= runMaxSteps {
+			return vm.ErrStepLimit
+		}
+		if err := m.Step(); err != nil {
+			drawMachine(os.Stdout, level, m, listing, "Error: "+err.Error())
+			return err
+		}
+	}
+	drawMachine(os.Stdout, level, m, listing, "Done")
+	return nil
+}
+
+func run(cmd *cobra.Command, args []string) {
+	if runSpeed <= 0 {
+		usageFatalf("--speed must be greater than 0")
+	}
+	var recorded replay
+	var inbox []levels.Value
+	var err error
+	if runReplay != "" {
+		if runInbox != "" {
+			usageFatalf("--inbox and --replay are mutually exclusive")
+		}
+		if recorded, err = readReplay(runReplay); err != nil {
+			fatal(err)
+		}
+		inbox, err = recorded.inbox()
+	} else {
+		inbox, err = parseInbox(runInbox)
+	}
+	if err != nil {
+		fatal(usageError(err))
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+	floorIndex, tab := floorTabArgs(args)
+	checkSlot(reader, slotNumber)
+	floor := floorNumber(floorIndex)
+	level, found := levels.Lookup(floor)
+	if !found {
+		usageFatalf("floor %d does not exist", floor)
+	}
+	if runReplay != "" && recorded.Floor != floor {
+		logger.Warn("the replay was recorded on another floor", "replay", runReplay, "floor", recorded.Floor)
+	}
+	tabStart := profileLayout(reader).TabStartAddr(slotNumber, floorIndex, tab)
+	program, err := render.DecodeProgramAt(appContext, reader, tabStart, instructions.Logger(logger))
+	if err != nil {
+		fatal(&profile.DecodeError{Offset: tabStart, Floor: floor, Tab: tab + 1, Err: err})
+	}
+
+	m := vm.NewForLevel(program.Disassembled, inbox, level)
+	visual := runVisual && isTerminal(os.Stdout)
+	if visual {
+		err = animateMachine(level, m, program.Disassembled)
+	} else {
+		err = m.Run(appContext, runMaxSteps)
+	}
+	if (runRecord != "" || runReplay != "") && replayableError(err) {
+		result := newReplay(floor, inbox, m, err)
+		if runRecord != "" {
+			if err := result.write(runRecord); err != nil {
+				fatal(err)
+			}
+		}
+		if runReplay != "" {
+			if differences := recorded.differences(result); len(differences) > 0 {
+				fatalf("the run does not match the replay %s:\n  %s", runReplay, strings.Join(differences, "\n  "))
+			}
+			// A recorded failure which reproduces is a match
+			if !visual {
+				fmt.Printf("outbox: %s\n", joinValues(m.Outbox))
+				fmt.Printf("steps:  %d\n", m.Steps)
+			}
+			if err != nil {
+				fmt.Printf("error:  %v\n", err)
+			}
+			fmt.Printf("matches the replay %s\n", runReplay)
+			return
+		}
+	}
+	var runtimeErr *vm.RuntimeError
+	switch {
+	case errors.As(err, &runtimeErr):
+		fatalf("the program failed after %d steps: %v", m.Steps, err)
+	case err != nil:
+		fatal(err)
+	}
+	if !visual {
+		fmt.Printf("outbox: %s\n", joinValues(m.Outbox))
+		fmt.Printf("steps:  %d\n", m.Steps)
+	}
+}
+
+func runCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run FLOOR TAB",
+		Short: "Run a program",
+		Long: `Run a program with the values given by --inbox, on the floor tiles of its
+level, printing the outbox and the number of steps executed.
+
+With --visual the floor, the worker's hand, the inbox and the outbox are
+drawn in the terminal as the program executes (when writing to a terminal).
+
+With --record the inbox, the outbox, the steps and any error are written
+to a JSON replay file. With --replay the program is run on the inbox of a
+replay file and fails unless it produces the recorded outbox, steps and
+error, so that a run can be reproduced and shared exactly`,
+		Args: cobra.ExactArgs(2),
+		Run:  run,
+	}
+	addSlotFlag(cmd, "run")
+	cmd.Flags().StringVar(&runInbox, "inbox", "", "Comma separated `VALUES` in the inbox, e.g. 3,-2,A")
+	cmd.Flags().BoolVar(&runVisual, "visual", false, "Draw the execution in the terminal")
+	cmd.Flags().Float64VarP(&runSpeed, "speed", "s", 4, "Steps per second with --visual")
+	cmd.Flags().StringVar(&runRecord, "record", "", "Write the inbox and the outcome of the run to the replay `FILE`")
+	cmd.Flags().StringVar(&runReplay, "replay", "", "Run on the inbox of the replay `FILE` and check the outcome matches it")
+	cmd.Flags().IntVar(&runMaxSteps, "max-steps", vm.DEFAULT_MAX_STEPS, "Stop after `STEPS` steps (e.g. for programs which never end)")
+	return cmd
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// Return a disassembled instruction as a Starlark struct
+func scriptInstruction(inst instructions.DisassembleInterface) starlark.Value {
+	fields := starlark.StringDict{
+		"op":       starlark.None,
+		"line":     starlark.None,
+		"arg":      starlark.None,
+		"indirect": starlark.False,
+		"label":    starlark.None,
+		"target":   starlark.None,
+		"comment":  starlark.None,
+	}
+	switch inst := inst.(type) {
+	case instructions.DisassembleComment:
+		fields["kind"] = starlark.String("comment")
+		fields["comment"] = starlark.MakeInt(int(inst.Index))
+	case instructions.DisassembleJumpTarget:
+		fields["kind"] = starlark.String("label")
+		fields["label"] = starlark.String(inst.Label)
+	case instructions.DisassembleJumpInstruction:
+		fields["kind"] = starlark.String("jump")
+		fields["op"] = starlark.String(inst.Op.String())
+		fields["line"] = starlark.MakeInt(inst.LineNumber)
+		fields["label"] = starlark.String(inst.TargetLabel)
+		fields["target"] = starlark.MakeInt(inst.Target)
+	case instructions.DisassembleArgInstruction:
+		fields["kind"] = starlark.String("arg")
+		fields["op"] = starlark.String(inst.Op.String())
+		fields["line"] = starlark.MakeInt(inst.LineNumber)
+		fields["arg"] = starlark.MakeInt(int(inst.Arg))
+		fields["indirect"] = starlark.Bool(inst.Indirect)
+	case instructions.DisassembleInstruction:
+		fields["kind"] = starlark.String("instruction")
+		fields["op"] = starlark.String(inst.Op.String())
+		fields["line"] = starlark.MakeInt(inst.LineNumber)
+	default:
+		// Instructions which are not disassembled (e.g. unknown opcodes)
+		fields["kind"] = starlark.String("unknown")
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, fields)
+}
+
+// Return a tab as a Starlark struct
+func scriptTab(floor, tab int, t profile.Tab) starlark.Value {
+	code := make([]starlark.Value, len(t.Code))
+	for i, inst := range t.Code {
+		code[i] = scriptInstruction(inst)
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"floor":        starlark.MakeInt(floor),
+		"tab":          starlark.MakeInt(tab),
+		"offset":       starlark.MakeInt(t.Offset),
+		"size":         starlark.MakeInt(t.Code.Size()),
+		"hash":         starlark.String(t.Hash()),
+		"instructions": starlark.NewList(code),
+		"comments":     starlark.MakeInt(len(t.RawComments)),
+	})
+}
+
+// Return a floor as a Starlark struct
+func scriptFloor(floorIndex int, f profile.Floor) starlark.Value {
+	floor := floorNumber(floorIndex)
+	fields := starlark.StringDict{
+		"floor":           starlark.MakeInt(floor),
+		"name":            starlark.None,
+		"completed":       starlark.Bool(f.Completed),
+		"size_challenge":  starlark.MakeInt(f.SizeChallenge),
+		"speed_challenge": starlark.MakeInt(f.SpeedChallenge),
+		"size_goal":       starlark.None,
+		"speed_goal":      starlark.None,
+	}
+	if level, found := levels.Lookup(floor); found {
+		fields["name"] = starlark.String(levelName(level))
+		fields["size_goal"] = starlark.MakeInt(level.SizeChallenge)
+		fields["speed_goal"] = starlark.MakeInt(level.SpeedChallenge)
+	}
+	tabs := make([]starlark.Value, len(f.Tabs))
+	for i, tab := range f.Tabs {
+		tabs[i] = scriptTab(floor, i+1, tab)
+	}
+	fields["tabs"] = starlark.NewList(tabs)
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, fields)
+}
+
+// Return the render(floor, tab, format="text") builtin, rendering a tab of
+// the profile read from reader like the render command
+func scriptRender(reader profileReader) *starlark.Builtin {
+	return starlark.NewBuiltin("render", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var floor, tab int
+		formatName := "text"
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "floor", &floor, "tab", &tab, "format?", &formatName); err != nil {
+			return nil, err
+		}
+		floorIndex, err := profile.FloorToIndex(floor)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fn.Name(), err)
+		}
+		if tab < 1 || tab > 3 {
+			return nil, fmt.Errorf("%s: tabs are numbered from 1 to 3, got %d", fn.Name(), tab)
+		}
+		format, found := render.Lookup(formatName)
+		if !found {
+			return nil, fmt.Errorf("%s: %v", fn.Name(), render.UnknownFormatError(formatName))
+		}
+		program := decodeTab(reader, slotNumber, floorIndex, tab-1)
+		var buffer bytes.Buffer
+		options := render.Options{Text: textOptions(), SVG: svgOptions(), Logger: logger}
+		if err := format.Render(appContext, &buffer, program, options); err != nil {
+			return nil, err
+		}
+		return starlark.String(buffer.String()), nil
+	})
+}
+
+func script(cmd *cobra.Command, args []string) {
+	src, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		fatal(err)
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+	checkSlot(reader, slotNumber)
+	p, err := decodeSlot(reader, slotNumber)
+	if err != nil {
+		fatal(err)
+	}
+
+	floors := make([]starlark.Value, len(p.Floors))
+	for floorIndex, floor := range p.Floors {
+		floors[floorIndex] = scriptFloor(floorIndex, floor)
+	}
+	scriptArgs := make([]starlark.Value, len(args)-1)
+	for i, arg := range args[1:] {
+		scriptArgs[i] = starlark.String(arg)
+	}
+	predeclared := starlark.StringDict{
+		"profile": starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"slot":   starlark.MakeInt(slotNumber),
+			"floors": starlark.NewList(floors),
+		}),
+		"args":   starlark.NewList(scriptArgs),
+		"render": scriptRender(reader),
+	}
+	predeclared.Freeze()
+
+	thread := &starlark.Thread{
+		Name: args[0],
+		Print: func(thread *starlark.Thread, msg string) {
+			fmt.Fprintln(os.Stdout, msg)
+		},
+	}
+	// Scripts are one-off programs rather than configuration, so allow the
+	// optional language features (top-level loops, while, floats, ...)
+	resolve.AllowNestedDef = true
+	resolve.AllowLambda = true
+	resolve.AllowFloat = true
+	resolve.AllowSet = true
+	resolve.AllowGlobalReassign = true
+	resolve.AllowRecursion = true
+	logger.Debug("running script", "path", args[0])
+	if _, err := starlark.ExecFile(thread, args[0], src, predeclared); err != nil {
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			fatalf("%s", evalErr.Backtrace())
+		}
+		fatal(err)
+	}
+}
+
+func scriptCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "script FILE [ARGS...]",
+		Short: "Run a Starlark script against the profile",
+		Long: `Run a Starlark (https://github.com/bazelbuild/starlark) script for one-off
+analyses of the profile without writing Go. The script has access to:
+
+  profile   the decoded save slot: profile.slot and profile.floors, a list of
+            floors with the fields floor, name, completed, size_challenge,
+            speed_challenge (-1 if not met), size_goal, speed_goal and tabs
+  tab       (in floor.tabs) floor, tab, offset, size, hash, comments (the
+            number of comments) and instructions
+  instr     (in tab.instructions) kind (instruction, arg, jump, label,
+            comment or unknown), op, line, arg, indirect, label, target and
+            comment, None where not applicable
+  args      the ARGS given after FILE
+  render    render(floor, tab, format="text") returns a tab rendered as by
+            the render command, honoring the text and SVG flags
+
+print() writes to stdout. For example, the total size of the solved floors
+using indirect addressing:
+
+  total = 0
+  for floor in profile.floors:
+      for tab in floor.tabs:
+          if floor.completed and [i for i in tab.instructions if i.indirect]:
+              total += tab.size
+  print(total)`,
+		Args: cobra.MinimumNArgs(1),
+		Run:  script,
+	}
+	addSlotFlag(cmd, "read")
+	addTextFlags(cmd)
+	addSVGFlags(cmd)
+	return cmd
+}
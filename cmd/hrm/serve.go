@@ -0,0 +1,120 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed playground/index.html
+var playgroundFiles embed.FS
+
+var (
+	serveAddr    string
+	serveWasmDir string
+	serveMetrics bool
+)
+
+// Serve the playground page, and hrm.wasm and wasm_exec.js from dir
+func playgroundHandler(dir string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/playground/", func(w http.ResponseWriter, r *http.Request) {
+		switch name := r.URL.Path[len("/playground/"):]; name {
+		case "":
+			data, _ := playgroundFiles.ReadFile("playground/index.html")
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(data)
+		case "hrm.wasm", "wasm_exec.js":
+			if name == "hrm.wasm" {
+				w.Header().Set("Content-Type", "application/wasm")
+			}
+			http.ServeFile(w, r, filepath.Join(dir, name))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	mux.Handle("/playground", http.RedirectHandler("/playground/", http.StatusMovedPermanently))
+	mux.Handle("/", http.RedirectHandler("/playground/", http.StatusFound))
+	return mux
+}
+
+func serve(cmd *cobra.Command, args []string) {
+	checkHTTPFlags(serveAddr)
+	playground := true
+	for _, name := range []string{"hrm.wasm", "wasm_exec.js"} {
+		if _, err := os.Stat(filepath.Join(serveWasmDir, name)); err != nil {
+			if !serveMetrics {
+				fatalf("%v (build hrm.wasm from cmd/hrm-wasm and copy wasm_exec.js from $(go env GOROOT)/lib/wasm into --wasm-dir)", err)
+			}
+			logger.Warn("serving the metrics only, the playground files are missing", "error", err)
+			playground = false
+			break
+		}
+	}
+	mux := http.NewServeMux()
+	if serveMetrics {
+		path, err := profileFilePath()
+		if err != nil {
+			fatal(err)
+		}
+		if isProfileURL(path) {
+			usageFatalf("--metrics requires a local profile, not %s", path)
+		}
+		mux.Handle("/metrics", metricsHandler(path))
+	}
+	if playground {
+		mux.Handle("/", playgroundHandler(serveWasmDir))
+	}
+	server := &http.Server{Addr: serveAddr, Handler: guardHandler(mux), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-appContext.Done()
+		server.Close()
+	}()
+	if playground {
+		fmt.Printf("Serving the playground at http://%s/playground/\n", serveAddr)
+	}
+	if serveMetrics {
+		fmt.Printf("Serving metrics at http://%s/metrics\n", serveAddr)
+	}
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatal(err)
+	}
+}
+
+func serveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the web playground",
+		Long: `Serve a playground page at /playground where visitors load their own
+profiles.bin, browse the floors, render programs and run them. Everything
+runs in the browser using the WebAssembly build (cmd/hrm-wasm), nothing is
+uploaded and the server keeps no state.
+
+With --metrics the completion totals and the per floor sizes and steps of
+every save slot of the local profile are served at /metrics in the
+Prometheus text format, for charting progress with Prometheus and
+Grafana. The profile is read on every request. Without the playground
+files only the metrics are served.
+
+Before serving beyond localhost, require a token with --auth-token (or
+HRM_AUTH_TOKEN): clients send it as a bearer token, or once as ?token= in
+a URL opened in a browser, which remembers it in a cookie. --rate-limit
+limits the requests of each client address and --read-only refuses any
+request other than GET and HEAD. Currently no endpoint modifies the
+profile or the server, so --read-only only matters for endpoints added
+later`,
+		Args: cobra.NoArgs,
+		Run:  serve,
+	}
+	cmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "`ADDRESS` to listen on")
+	cmd.Flags().StringVar(&serveWasmDir, "wasm-dir", ".", "`DIR` containing hrm.wasm and wasm_exec.js")
+	cmd.Flags().BoolVar(&serveMetrics, "metrics", false, "Serve Prometheus metrics of the profile at /metrics")
+	addHTTPFlags(cmd)
+	return cmd
+}
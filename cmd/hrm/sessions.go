@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sessionsGap   time.Duration
+	sessionsSince string
+)
+
+// A play session: saves of a profile no more than --gap apart
+type session struct {
+	profile    string
+	slot       int
+	start, end time.Time
+	saves      int
+	// The changes of each floor over the session, by floor
+	floors map[int]*activityFloor
+}
+
+// Merge the changes of a save into the session
+func (s *session) add(record activityRecord) {
+	s.end = record.Time
+	s.saves++
+	for _, change := range record.Floors {
+		floor, found := s.floors[change.Floor]
+		if !found {
+			floor = &activityFloor{Floor: change.Floor}
+			s.floors[change.Floor] = floor
+		}
+		for _, tab := range change.Tabs {
+			seen := false
+			for _, t := range floor.Tabs {
+				seen = seen || t == tab
+			}
+			if !seen {
+				floor.Tabs = append(floor.Tabs, tab)
+			}
+		}
+		floor.Completed = floor.Completed || change.Completed
+		merge := func(total **activityDelta, delta *activityDelta) {
+			switch {
+			case delta == nil:
+			case *total == nil:
+				*total = &activityDelta{delta.From, delta.To}
+			default:
+				(*total).To = delta.To
+			}
+		}
+		merge(&floor.Size, change.Size)
+		merge(&floor.Steps, change.Steps)
+	}
+}
+
+// Group the records into sessions, per profile and save slot
+func groupSessions(records []activityRecord, gap time.Duration) []*session {
+	var sessions []*session
+	current := make(map[string]*session)
+	for _, record := range records {
+		key := fmt.Sprintf("%s\x00%d", record.Profile, record.Slot)
+		s := current[key]
+		if s == nil || record.Time.Sub(s.end) > gap {
+			s = &session{profile: record.Profile, slot: record.Slot, start: record.Time, floors: make(map[int]*activityFloor)}
+			current[key] = s
+			sessions = append(sessions, s)
+		}
+		s.add(record)
+	}
+	return sessions
+}
+
+// Describe the changes of a floor over a session
+func (f activityFloor) describe() string {
+	var changes []string
+	if len(f.Tabs) > 0 {
+		sort.Ints(f.Tabs)
+		tabs := make([]string, len(f.Tabs))
+		for i, tab := range f.Tabs {
+			tabs[i] = fmt.Sprint(tab)
+		}
+		changes = append(changes, "edited tab "+strings.Join(tabs, ", "))
+	}
+	if f.Completed {
+		changes = append(changes, "completed")
+	}
+	if f.Size != nil && f.Size.From != f.Size.To {
+		changes = append(changes, fmt.Sprintf("size %s -> %s", challengeResult(f.Size.From), challengeResult(f.Size.To)))
+	}
+	if f.Steps != nil && f.Steps.From != f.Steps.To {
+		changes = append(changes, fmt.Sprintf("steps %s -> %s", challengeResult(f.Steps.From), challengeResult(f.Steps.To)))
+	}
+	return strings.Join(changes, ", ")
+}
+
+func sessionsRun(cmd *cobra.Command, args []string) {
+	if sessionsGap <= 0 {
+		usageFatalf("--gap must be positive")
+	}
+	var since time.Time
+	if sessionsSince != "" {
+		var err error
+		if since, err = time.ParseInLocation("2006-01-02", sessionsSince, time.Local); err != nil {
+			usageFatalf("--since must be a date such as 2006-01-02, got %q", sessionsSince)
+		}
+	}
+	records, err := readActivity()
+	if os.IsNotExist(err) {
+		fatalf("no activity recorded yet, it is recorded by hrm track, hrm daemon and hrm pipeline run --watch")
+	} else if err != nil {
+		fatal(err)
+	}
+	// Only the sessions of the profile given with --profile
+	if profilePath != "" {
+		path, err := profileFilePath()
+		if err != nil {
+			fatal(err)
+		}
+		path = activityProfilePath(path)
+		var selected []activityRecord
+		for _, record := range records {
+			if record.Profile == path {
+				selected = append(selected, record)
+			}
+		}
+		records = selected
+	}
+
+	sessions := groupSessions(records, sessionsGap)
+	profiles := make(map[string]bool)
+	for _, s := range sessions {
+		profiles[s.profile] = true
+	}
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+	for _, s := range sessions {
+		if s.end.Before(since) {
+			continue
+		}
+		start, end := s.start.Local(), s.end.Local()
+		header := fmt.Sprintf("%s %s-%s (%s, %d save(s))", start.Format("2006-01-02"), start.Format("15:04"), end.Format("15:04"), end.Sub(start).Round(time.Minute), s.saves)
+		if len(profiles) > 1 {
+			header += " " + s.profile
+		}
+		if s.slot != 1 {
+			header += fmt.Sprintf(" slot %d", s.slot)
+		}
+		fmt.Fprintln(output, header)
+		var floors []int
+		for floor := range s.floors {
+			floors = append(floors, floor)
+		}
+		sort.Ints(floors)
+		for _, floor := range floors {
+			name := ""
+			if level, found := levels.Lookup(floor); found {
+				name = " " + levelName(level)
+			}
+			fmt.Fprintf(output, "  floor %d%s: %s\n", floor, name, s.floors[floor].describe())
+		}
+		if len(floors) == 0 {
+			fmt.Fprintln(output, "  no floor changed")
+		}
+	}
+}
+
+func sessionsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "List the play sessions recorded in the activity log",
+		Long: `List the play sessions recorded in the activity log: every save of the
+profile seen by hrm track, hrm daemon or hrm pipeline run --watch is
+recorded with the floors which changed. Saves no more than --gap apart
+form a session, listed with its date and time, the floors worked on and
+how their results changed. With --profile only the sessions of that
+profile are listed.`,
+		Args: cobra.NoArgs,
+		Run:  sessionsRun,
+	}
+	cmd.Flags().DurationVar(&sessionsGap, "gap", 30*time.Minute, "Start a new session after `DURATION` without saves")
+	cmd.Flags().StringVar(&sessionsSince, "since", "", "List the sessions since `DATE` (2006-01-02)")
+	cmd.Flags().StringVar(&activityLogPath, "activity-log", "", "Read the activity log `FILE` (default activity.jsonl in the configuration directory)")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the sessions to")
+	return cmd
+}
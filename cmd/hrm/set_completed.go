@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	setCompletedUndo  bool
+	setCompletedSize  int
+	setCompletedSpeed int
+)
+
+func setCompleted(cmd *cobra.Command, args []string) {
+	floor := parseInt(args[0])
+	setSize, setSpeed := cmd.Flags().Changed("size"), cmd.Flags().Changed("speed")
+	if setCompletedUndo && (setSize || setSpeed) {
+		usageFatalf("--undo cannot be combined with --size or --speed")
+	}
+	if setSize && setCompletedSize < 1 {
+		usageFatalf("--size must be at least 1, got %d", setCompletedSize)
+	}
+	if setSpeed && setCompletedSpeed < 1 {
+		usageFatalf("--speed must be at least 1, got %d", setCompletedSpeed)
+	}
+
+	changes := []string{"mark completed"}
+	if setCompletedUndo {
+		changes = []string{"mark not completed"}
+	}
+	if setSize {
+		changes = append(changes, fmt.Sprintf("size %d", setCompletedSize))
+	}
+	if setSpeed {
+		changes = append(changes, fmt.Sprintf("speed %d", setCompletedSpeed))
+	}
+	description := fmt.Sprintf("floor %d: %s", floor, strings.Join(changes, ", "))
+	editFloorHeader("set-completed", floor, description, func(header *profile.FloorHeader) {
+		// The game considers a floor completed once it has recorded a
+		// result for either challenge. The results are kept when clearing
+		// the flags, so they reappear if the floor is marked completed again
+		if setCompletedUndo {
+			header.SizeChallengeCompleted, header.SpeedChallengeCompleted = 0, 0
+			return
+		}
+		if setSize {
+			setChallengeResult(&header.SizeChallengeCompleted, &header.SizeChallengeCommands, setCompletedSize)
+		}
+		if setSpeed {
+			setChallengeResult(&header.SpeedChallengeCompleted, &header.SpeedChallengeSteps, setCompletedSpeed)
+		}
+		if header.SizeChallengeCommands == 0 && header.SpeedChallengeSteps == 0 {
+			usageFatalf("floor %d has no recorded results to restore, give them with --size and/or --speed", floor)
+		}
+		setChallengeResult(&header.SizeChallengeCompleted, &header.SizeChallengeCommands, int(header.SizeChallengeCommands))
+		setChallengeResult(&header.SpeedChallengeCompleted, &header.SpeedChallengeSteps, int(header.SpeedChallengeSteps))
+	})
+}
+
+func setCompletedCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-completed FLOOR",
+		Short: "Mark a floor completed (or not completed)",
+		Long: `Set the completion flags in the floor header, unlocking (or with --undo
+relocking) the floor, for recovering a damaged save or constructing test
+profiles.
+
+The game records a floor as completed through its challenge results, so
+a floor without results needs them with --size and/or --speed (see also
+set-score). The results are kept by --undo, and marking the floor
+completed again without --size and --speed restores them.
+
+The profile is backed up first and the change is recorded in the edit
+journal, revert it with hrm undo`,
+		Args: cobra.ExactArgs(1),
+		Run:  setCompleted,
+	}
+	cmd.Flags().BoolVar(&setCompletedUndo, "undo", false, "Mark the floor not completed")
+	cmd.Flags().IntVar(&setCompletedSize, "size", 0, "Size challenge result in `COMMANDS`")
+	cmd.Flags().IntVar(&setCompletedSpeed, "speed", 0, "Speed challenge result in `STEPS`")
+	addEditFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	setScoreSize  int
+	setScoreSpeed int
+)
+
+// Set a challenge result of a floor header, clearing it for a value of 0
+func setChallengeResult(completed *int32, count *uint32, value int) {
+	*completed, *count = 0, 0
+	if value > 0 {
+		*completed, *count = 1, uint32(value)
+	}
+}
+
+func setScore(cmd *cobra.Command, args []string) {
+	floor := parseInt(args[0])
+	setSize, setSpeed := cmd.Flags().Changed("size"), cmd.Flags().Changed("speed")
+	if !setSize && !setSpeed {
+		usageFatalf("requires --size and/or --speed")
+	}
+	if setScoreSize < 0 {
+		usageFatalf("--size must be 0 or more, got %d", setScoreSize)
+	}
+	if setScoreSpeed < 0 {
+		usageFatalf("--speed must be 0 or more, got %d", setScoreSpeed)
+	}
+	var changes []string
+	if setSize {
+		if setScoreSize > 0 {
+			changes = append(changes, fmt.Sprintf("size %d", setScoreSize))
+		} else {
+			changes = append(changes, "clear size")
+		}
+	}
+	if setSpeed {
+		if setScoreSpeed > 0 {
+			changes = append(changes, fmt.Sprintf("speed %d", setScoreSpeed))
+		} else {
+			changes = append(changes, "clear speed")
+		}
+	}
+	description := fmt.Sprintf("floor %d: %s", floor, strings.Join(changes, ", "))
+	editFloorHeader("set-score", floor, description, func(header *profile.FloorHeader) {
+		if setSize {
+			setChallengeResult(&header.SizeChallengeCompleted, &header.SizeChallengeCommands, setScoreSize)
+		}
+		if setSpeed {
+			setChallengeResult(&header.SpeedChallengeCompleted, &header.SpeedChallengeSteps, setScoreSpeed)
+		}
+	})
+}
+
+func setScoreCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-score FLOOR",
+		Short: "Set the challenge results of a floor",
+		Long: `Set the size (number of commands) and speed (number of steps) results
+recorded for a floor, for repairing scores lost to save corruption or
+cloud rollbacks. A value of 0 clears the result.
+
+The profile is backed up first and the change is recorded in the edit
+journal, revert it with hrm undo`,
+		Args: cobra.ExactArgs(1),
+		Run:  setScore,
+	}
+	cmd.Flags().IntVar(&setScoreSize, "size", 0, "Size challenge result in `COMMANDS` (0 to clear)")
+	cmd.Flags().IntVar(&setScoreSpeed, "speed", 0, "Speed challenge result in `STEPS` (0 to clear)")
+	addEditFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/spf13/cobra"
+)
+
+// The save slot selected with --slot, see addSlotFlag
+var slotNumber int
+
+// Add the --slot flag selecting the save slot the command works on, e.g.
+// "read", "check" or "modify"
+func addSlotFlag(cmd *cobra.Command, verb string) {
+	cmd.Flags().IntVar(&slotNumber, "slot", 1, "Save `SLOT` to "+verb)
+}
+
+// Parse a PROFILE argument, a save slot number starting at 1
+func parseSlot(arg string) int {
+	slot := parseInt(arg)
+	checkSlotNumber(slot)
+	return slot
+}
+
+// Set the save slot of a command which took it as a PROFILE argument from
+// the deprecated argument, if given, rather than from --slot
+func legacySlotArg(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		return
+	}
+	if cmd.Flags().Changed("slot") {
+		usageFatalf("PROFILE cannot be combined with --slot, give the save slot with --slot only")
+	}
+	logger.Warn("the PROFILE argument is deprecated, give the save slot with --slot")
+	slotNumber = parseSlot(args[0])
+}
+
+// Exit with a usage error unless slot is a valid save slot number
+func checkSlotNumber(slot int) {
+	if slot < 1 {
+		usageFatalf("profile slots are numbered from 1, got %d", slot)
+	}
+}
+
+// Exit with a usage error unless the profile has the save slot
+func checkSlot(reader profileReader, slot int) {
+	checkSlotNumber(slot)
+	size, err := profileSize(reader)
+	if err != nil {
+		fatal(err)
+	}
+	if count := profileLayout(reader).SlotCount(size); slot > count {
+		usageFatalf("profile slot %d does not exist, the profile has %d slot(s) (see: hrm slots)", slot, count)
+	}
+}
+
+// The summary of a save slot listed by hrm slots
+type slotSummary struct {
+	Slot               int     `json:"slot"`
+	Name               string  `json:"name"`
+	Offset             int64   `json:"offset"`
+	Solved             int     `json:"solved"`
+	Floors             int     `json:"floors"`
+	SizeChallengesMet  int     `json:"size_challenges_met"`
+	SpeedChallengesMet int     `json:"speed_challenges_met"`
+	Completion         float64 `json:"completion"`
+}
+
+func writeSlotsText(w io.Writer, summaries []slotSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SLOT\tNAME\tOFFSET\tSOLVED\tSIZE MET\tSPEED MET\tCOMPLETION")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%d\t%s\t0x%08x\t%d/%d\t%d\t%d\t%.0f%%\n",
+			s.Slot, s.Name, s.Offset, s.Solved, s.Floors, s.SizeChallengesMet, s.SpeedChallengesMet, s.Completion*100)
+	}
+	return tw.Flush()
+}
+
+func writeSlotsCSV(w io.Writer, summaries []slotSummary) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"slot", "name", "offset", "solved", "floors", "size_challenges_met", "speed_challenges_met", "completion"})
+	for _, s := range summaries {
+		cw.Write([]string{strconv.Itoa(s.Slot), s.Name, strconv.FormatInt(s.Offset, 10), strconv.Itoa(s.Solved), strconv.Itoa(s.Floors),
+			strconv.Itoa(s.SizeChallengesMet), strconv.Itoa(s.SpeedChallengesMet), strconv.FormatFloat(s.Completion, 'f', 4, 64)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeSlotsJSON(w io.Writer, summaries []slotSummary) error {
+	data, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+func slots(cmd *cobra.Command, args []string) {
+	summaries := []slotSummary{}
+	write := reportWriters{
+		"text": func(w io.Writer) error { return writeSlotsText(w, summaries) },
+		"csv":  func(w io.Writer) error { return writeSlotsCSV(w, summaries) },
+		"json": func(w io.Writer) error { return writeSlotsJSON(w, summaries) },
+	}.selected()
+
+	reader := openProfile()
+	defer reader.Close()
+
+	size, err := profileSize(reader)
+	if err != nil {
+		fatal(err)
+	}
+	slotList, err := profileLayout(reader).ReadSlotsAt(reader, size)
+	if err != nil {
+		fatal(err)
+	}
+	if len(slotList) == 0 {
+		fatalf("the profile is too small (%d bytes) to contain a save slot", size)
+	}
+
+	for _, slot := range slotList {
+		p, err := decodeSlot(reader, slot.Number)
+		if err != nil {
+			fatal(err)
+		}
+		s := profile.Summary(p)
+		summaries = append(summaries, slotSummary{slot.Number, slot.Name(), slot.Offset, s.Solved, s.Floors, s.SizeChallengesMet, s.SpeedChallengesMet, s.Completion()})
+	}
+	writeReport(write)
+}
+
+func slotsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "slots",
+		Short: "List the save slots of a profile",
+		Long: `List each save slot in the profile with a summary of its completion. The
+slot number is the --slot of the other commands.
+
+The list is written as a table, or as CSV or JSON with --format or an
+output file name ending in .csv or .json`,
+		Args: cobra.NoArgs,
+		Run:  slots,
+	}
+	addReportFlags(cmd, "list", "text", "csv", "json")
+	return cmd
+}
@@ -0,0 +1,18 @@
+//go:build cgo
+
+package main
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Open the SQLite database at path, creating it if it does not exist
+func openSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	return db, db.PingContext(appContext)
+}
@@ -0,0 +1,14 @@
+//go:build !cgo
+
+package main
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Open the SQLite database at path. The SQLite driver requires cgo, so
+// builds without cgo (e.g. cross compiled releases) cannot open databases
+func openSQLite(path string) (*sql.DB, error) {
+	return nil, errors.New("this build of hrm does not support SQLite databases (built without cgo)")
+}
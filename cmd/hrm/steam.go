@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// The Steam app id of Human Resource Machine
+const steamAppID = "375820"
+
+// The directory of the game in the steamapps/common directory of a library
+const steamInstallDir = "Human Resource Machine"
+
+// Return the default Steam installation directories for the current OS.
+// On Windows these come from the registry
+func steamRootCandidates() []string {
+	switch runtime.GOOS {
+	case "windows":
+		roots := steamRegistryRoots()
+		if programFiles := os.Getenv("ProgramFiles(x86)"); programFiles != "" {
+			roots = append(roots, filepath.Join(programFiles, "Steam"))
+		}
+		return roots
+	case "darwin":
+		return []string{"~/Library/Application Support/Steam"}
+	case "linux":
+		return []string{
+			"~/.steam/steam",
+			"~/.steam/root",
+			"~/.local/share/Steam",
+			"~/.var/app/com.valvesoftware.Steam/.local/share/Steam",
+		}
+	}
+	return nil
+}
+
+// Split a Valve KeyValues (.vdf) document into its tokens: quoted strings
+// (unquoted, with escapes resolved), unquoted words, and braces
+func vdfTokens(r io.Reader) ([]string, error) {
+	var tokens []string
+	reader := bufio.NewReader(r)
+	for {
+		ch, _, err := reader.ReadRune()
+		if err == io.EOF {
+			return tokens, nil
+		} else if err != nil {
+			return nil, err
+		}
+		switch {
+		case unicode.IsSpace(ch):
+		case ch == '{' || ch == '}':
+			tokens = append(tokens, string(ch))
+		case ch == '/':
+			if _, err := reader.ReadString('\n'); err != nil && err != io.EOF {
+				return nil, err
+			}
+		case ch == '"':
+			var token strings.Builder
+			for {
+				ch, _, err := reader.ReadRune()
+				if err != nil {
+					return nil, fmt.Errorf("unterminated string: %w", err)
+				}
+				if ch == '"' {
+					break
+				}
+				if ch == '\\' {
+					if ch, _, err = reader.ReadRune(); err != nil {
+						return nil, fmt.Errorf("unterminated string: %w", err)
+					}
+					switch ch {
+					case 'n':
+						ch = '\n'
+					case 't':
+						ch = '\t'
+					}
+				}
+				token.WriteRune(ch)
+			}
+			tokens = append(tokens, token.String())
+		default:
+			word := string(ch)
+			for {
+				ch, _, err := reader.ReadRune()
+				if err != nil || unicode.IsSpace(ch) || ch == '{' || ch == '}' || ch == '"' {
+					if err == nil {
+						reader.UnreadRune()
+					}
+					break
+				}
+				word += string(ch)
+			}
+			tokens = append(tokens, word)
+		}
+	}
+}
+
+// Return the library directories listed in a libraryfolders.vdf: the path
+// values of the numbered library entries, or the numbered values
+// themselves in the format of older Steam versions
+func parseLibraryFolders(r io.Reader) ([]string, error) {
+	tokens, err := vdfTokens(r)
+	if err != nil {
+		return nil, err
+	}
+	isNumber := func(s string) bool {
+		return s != "" && strings.IndexFunc(s, func(ch rune) bool { return ch < '0' || ch > '9' }) < 0
+	}
+	var libraries []string
+	var keys []string // The keys of the enclosing sections
+	for i := 0; i < len(tokens); i++ {
+		switch {
+		case tokens[i] == "}":
+			if len(keys) == 0 {
+				return nil, fmt.Errorf("unbalanced braces")
+			}
+			keys = keys[:len(keys)-1]
+		case tokens[i] == "{":
+			return nil, fmt.Errorf("section without a name")
+		case i+1 < len(tokens) && tokens[i+1] == "{":
+			keys = append(keys, tokens[i])
+			i++
+		case i+1 < len(tokens):
+			key, value := tokens[i], tokens[i+1]
+			switch {
+			case len(keys) == 2 && isNumber(keys[1]) && strings.EqualFold(key, "path"):
+				libraries = append(libraries, value)
+			case len(keys) == 1 && isNumber(key):
+				libraries = append(libraries, value)
+			}
+			i++
+		}
+	}
+	return libraries, nil
+}
+
+// Return the Steam library directories: the Steam installation
+// directories found, and the libraries listed in their libraryfolders.vdf
+func steamLibraries() []string {
+	var libraries []string
+	seen := make(map[string]bool)
+	add := func(dir string) {
+		key := filepath.Clean(dir)
+		if runtime.GOOS == "windows" {
+			key = strings.ToLower(key)
+		}
+		if !seen[key] {
+			seen[key] = true
+			libraries = append(libraries, filepath.Clean(dir))
+		}
+	}
+	for _, root := range steamRootCandidates() {
+		root, err := homedir.Expand(root)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		add(root)
+		for _, name := range []string{filepath.Join("steamapps", "libraryfolders.vdf"), filepath.Join("config", "libraryfolders.vdf")} {
+			file, err := os.Open(filepath.Join(root, name))
+			if err != nil {
+				continue
+			}
+			folders, err := parseLibraryFolders(file)
+			file.Close()
+			if err != nil {
+				logger.Warn("cannot parse the Steam library folders", "path", filepath.Join(root, name), "error", err)
+				continue
+			}
+			for _, folder := range folders {
+				add(folder)
+			}
+		}
+	}
+	logger.Debug("found Steam libraries", "libraries", libraries)
+	return libraries
+}
+
+// Return the profile candidates in the Steam libraries: the game's
+// installation directory, and on Linux the Windows user directory of the
+// game's Proton prefix. Only the profiles which exist are returned, as
+// most libraries do not hold the game
+func steamProfileCandidates() []profileCandidate {
+	var candidates []profileCandidate
+	for _, library := range steamLibraries() {
+		paths := []string{filepath.Join(library, "steamapps", "common", steamInstallDir, "profiles.bin")}
+		if runtime.GOOS == "linux" {
+			paths = append(paths, filepath.Join(library, "steamapps", "compatdata", steamAppID, "pfx", "drive_c", "users", "steamuser",
+				"AppData", "Roaming", "Human Resource Machine", "profiles.bin"))
+		}
+		for _, path := range paths {
+			candidate := profileCandidate{pattern: "Steam library " + library, path: path}
+			if candidate.info, candidate.err = os.Stat(path); candidate.exists() {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	return candidates
+}
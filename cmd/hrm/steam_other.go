@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+// Return the Steam installation directories recorded in the registry,
+// which only exists on Windows
+func steamRegistryRoots() []string {
+	return nil
+}
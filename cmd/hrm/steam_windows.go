@@ -0,0 +1,36 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// The registry keys and values holding the Steam installation directory
+var steamRegistryValues = [][2]string{
+	{`HKCU\Software\Valve\Steam`, "SteamPath"},
+	{`HKLM\SOFTWARE\WOW6432Node\Valve\Steam`, "InstallPath"},
+	{`HKLM\SOFTWARE\Valve\Steam`, "InstallPath"},
+}
+
+// Return the Steam installation directories recorded in the registry,
+// queried with reg.exe
+func steamRegistryRoots() []string {
+	var roots []string
+	for _, value := range steamRegistryValues {
+		output, err := exec.Command("reg", "query", value[0], "/v", value[1]).Output()
+		if err != nil {
+			logger.Debug("Steam registry value not found", "key", value[0], "value", value[1], "error", err)
+			continue
+		}
+		// The value is printed as: NAME    REG_SZ    DATA
+		for _, line := range strings.Split(string(output), "\n") {
+			fields := strings.SplitN(strings.TrimSpace(line), "REG_SZ", 2)
+			if len(fields) == 2 && strings.EqualFold(strings.TrimSpace(fields[0]), value[1]) {
+				roots = append(roots, strings.TrimSpace(fields[1]))
+			}
+		}
+	}
+	return roots
+}
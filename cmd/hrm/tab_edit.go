@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/journal"
+	"github.com/spf13/cobra"
+)
+
+// The size of the instruction and comment blocks of a tab, the same in
+// every layout
+const tabBlocksSize = instructions.INSTRUCTIONS_BLOCK_SIZE + instructions.COMMENTS_BLOCK_SIZE
+
+var copyFromSlot int
+
+// Read the instruction and comment blocks of a tab
+func readTabBlocks(reader profileReader, slot, floorIndex, tab int) []byte {
+	data := make([]byte, tabBlocksSize)
+	if _, err := reader.ReadAt(data, profileLayout(reader).TabStartAddr(slot, floorIndex, tab)); err != nil {
+		fatal(err)
+	}
+	return data
+}
+
+// Describe the program a change replaces, e.g. " (replacing 12
+// instruction(s))", or nothing for an empty tab
+func replacing(program instructions.Disassembled) string {
+	if size := program.Size(); size > 0 {
+		return fmt.Sprintf(" (replacing %d instruction(s))", size)
+	}
+	return ""
+}
+
+func copyTab(cmd *cobra.Command, args []string) {
+	floorIndex, tab := floorTabArgs(args[:2])
+	toFloorIndex, toTab := floorTabArgs(args[2:])
+	fromSlot := slotNumber
+	if cmd.Flags().Changed("from-slot") {
+		fromSlot = copyFromSlot
+	}
+	if fromSlot == slotNumber && floorIndex == toFloorIndex && tab == toTab {
+		usageFatalf("cannot copy a tab onto itself")
+	}
+	path := editProfilePath()
+
+	reader, err := openProfileAt(path)
+	if err != nil {
+		fatal(err)
+	}
+	checkSlot(reader, fromSlot)
+	checkSlot(reader, slotNumber)
+	program := decodeTab(reader, fromSlot, floorIndex, tab)
+	replaced := decodeTab(reader, slotNumber, toFloorIndex, toTab)
+	data := readTabBlocks(reader, fromSlot, floorIndex, tab)
+	offset := profileLayout(reader).TabStartAddr(slotNumber, toFloorIndex, toTab)
+	reader.Close()
+
+	from := fmt.Sprintf("floor %d tab %d", floorNumber(floorIndex), tab+1)
+	if fromSlot != slotNumber {
+		from = fmt.Sprintf("slot %d %s", fromSlot, from)
+	}
+	description := fmt.Sprintf("floor %d tab %d: copy %d instruction(s) from %s%s", floorNumber(toFloorIndex), toTab+1, program.Disassembled.Size(), from, replacing(replaced.Disassembled))
+	applyEdit("copy", path, description, []journal.Change{{Offset: offset, Modified: data}})
+}
+
+func clearTabs(cmd *cobra.Command, args []string) {
+	floorIndex := floorIndexArg(parseInt(args[0]))
+	tabs := []int{0, 1, 2}
+	if len(args) > 1 {
+		_, tab := floorTabArgs(args)
+		tabs = []int{tab}
+	}
+	path := editProfilePath()
+
+	reader, err := openProfileAt(path)
+	if err != nil {
+		fatal(err)
+	}
+	checkSlot(reader, slotNumber)
+	layout := profileLayout(reader)
+	var changes []journal.Change
+	var details []string
+	for _, tab := range tabs {
+		program := decodeTab(reader, slotNumber, floorIndex, tab)
+		if program.Disassembled.Size() == 0 && len(program.RawComments) == 0 {
+			continue
+		}
+		changes = append(changes, journal.Change{Offset: layout.TabStartAddr(slotNumber, floorIndex, tab), Modified: make([]byte, tabBlocksSize)})
+		details = append(details, fmt.Sprintf("tab %d: %d instruction(s), %d comment(s)", tab+1, program.Disassembled.Size(), len(program.RawComments)))
+	}
+	reader.Close()
+	if len(changes) == 0 {
+		fmt.Println("Nothing to clear, the tab(s) are empty")
+		return
+	}
+
+	description := fmt.Sprintf("floor %d: clear %d tab(s)", floorNumber(floorIndex), len(changes))
+	applyEdit("clear", path, description, changes, details...)
+}
+
+func copyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "copy FLOOR TAB TO_FLOOR TO_TAB",
+		Short: "Copy the program of a tab to another tab",
+		Long: `Copy the program and comments of the tab FLOOR TAB to the tab TO_FLOOR
+TO_TAB, replacing its program. With --from-slot the program is copied from
+another save slot of the profile into the slot of --slot.
+
+The profile is backed up first and the change is recorded in the edit
+journal, revert it with hrm undo`,
+		Args: cobra.ExactArgs(4),
+		Run:  copyTab,
+	}
+	cmd.Flags().IntVar(&copyFromSlot, "from-slot", 1, "Copy from save `SLOT` (default the slot of --slot)")
+	addEditFlags(cmd)
+	return cmd
+}
+
+func clearCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clear FLOOR [TAB]",
+		Short: "Clear the program of a tab or of every tab of a floor",
+		Long: `Remove the program and comments of a tab, or of every tab of the floor
+when TAB is not given. The results recorded for the floor are left as
+they are, see hrm set-score and hrm set-completed.
+
+The profile is backed up first and the change is recorded in the edit
+journal, revert it with hrm undo`,
+		Args: cobra.RangeArgs(1, 2),
+		Run:  clearTabs,
+	}
+	addEditFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+// The separator line between the tabs of a floor, a comment in the game
+const textFloorSeparator = "-- ======================================================================"
+
+func textFloor(cmd *cobra.Command, args []string) {
+	floor := parseInt(args[0])
+	floorIndex := floorIndexArg(floor)
+
+	reader := openProfile()
+	defer reader.Close()
+
+	var builder strings.Builder
+	for tab := 0; tab < 3; tab++ {
+		program := decodeTab(reader, slotNumber, floorIndex, tab)
+		banner, err := programBanner(reader, slotNumber, floorIndex, tab, program)
+		if err != nil {
+			fatal(err)
+		}
+		if tab > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(textFloorSeparator + "\n")
+		if len(program.Instructions) == 0 && len(program.RawComments) == 0 {
+			builder.WriteString(banner.Text())
+			builder.WriteString("-- (empty)\n")
+			continue
+		}
+		opts := append(textOptions(), render.TextBanner(banner))
+		if colorText(outputFileName) {
+			opts = append(opts, render.ColorText())
+		}
+		text, err := render.RenderTextContext(appContext, program, opts...)
+		if err != nil {
+			fatal(err)
+		}
+		builder.WriteString(text)
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	if _, err := output.Write([]byte(builder.String())); err != nil {
+		fatal(err)
+	}
+	if err := output.Close(); err != nil {
+		fatal(err)
+	}
+}
+
+func textFloorCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "text-floor FLOOR",
+		Short: "Render the programs of all tabs of a floor as one text document",
+		Long: `Render the programs of the three tabs of a floor as text, one after the other,
+each preceded by a separator and a banner identifying it (see --banner).
+
+The separators and banners are comments, so each tab's section can be pasted
+into the game on its own.`,
+		Args: cobra.ExactArgs(1),
+		Run:  textFloor,
+	}
+	addSlotFlag(cmd, "read")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the output to")
+	cmd.Flags().BoolVarP(&outputCompress, "compress", "z", false, "Compress the output with gzip (implied by .gz output file names)")
+	addTextFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	thumbSize    int
+	thumbNoBadge bool
+)
+
+func thumb(cmd *cobra.Command, args []string) {
+	if thumbSize <= 0 {
+		usageFatalf("--size must be positive")
+	}
+	if outputFileName == "" && isTerminal(os.Stdout) {
+		usageFatalf("refusing to write png output to a terminal, use --output or redirect stdout")
+	}
+
+	reader := openProfile()
+	defer reader.Close()
+
+	floorIndex, tab := floorTabArgs(args)
+	checkSlot(reader, slotNumber)
+	tabStart := profileLayout(reader).TabStartAddr(slotNumber, floorIndex, tab)
+	program, err := render.DecodeProgramAt(appContext, reader, tabStart, programDecodeOptions()...)
+	if err != nil {
+		fatal(&profile.DecodeError{Offset: tabStart, Floor: floorNumber(floorIndex), Tab: tab + 1, Err: err})
+	}
+
+	opts := []render.ThumbnailOption{render.ThumbnailSize(thumbSize), render.ThumbnailLogger(logger)}
+	if !thumbNoBadge {
+		m, err := programMetadata(reader, slotNumber, floorIndex, program)
+		if err != nil {
+			fatal(err)
+		}
+		badge := []string{fmt.Sprintf("size %d", m.Size)}
+		if m.Steps > 0 {
+			badge = append(badge, fmt.Sprintf("steps %d", m.Steps))
+		}
+		opts = append(opts, render.ThumbnailBadge(badge...))
+	}
+	img, err := render.RenderThumbnailContext(appContext, program.Disassembled, program.Comments, opts...)
+	if err != nil {
+		fatal(err)
+	}
+
+	output, err := createOutput()
+	if err != nil {
+		fatal(err)
+	}
+	defer output.Close()
+	if err := png.Encode(output, img); err != nil {
+		fatal(err)
+	}
+}
+
+func thumbCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "thumb FLOOR TAB",
+		Short: "Render a thumbnail",
+		Long: `Render a compact square PNG thumbnail of a program, showing the first
+instructions and a badge with the size and steps results`,
+		Args: cobra.ExactArgs(2),
+		Run:  thumb,
+	}
+	addSlotFlag(cmd, "read")
+	cmd.Flags().StringVarP(&outputFileName, "output", "o", "", "`FILENAME` to write the PNG to")
+	cmd.Flags().IntVar(&thumbSize, "size", 256, "Width and height of the thumbnail in `PIXELS`")
+	cmd.Flags().BoolVar(&thumbNoBadge, "no-badge", false, "Do not show the size and steps badge")
+	addCommentSpaceFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/spf13/cobra"
+)
+
+// Write the floor tiles of a level as a grid of tile numbers and contents
+// ("." for empty tiles)
+func writeTiles(w io.Writer, level levels.Level) {
+	fmt.Fprintf(w, "Floor %d %s: ", level.Floor, levelName(level))
+	if level.TileCount() == 0 {
+		fmt.Fprintln(w, "no tiles")
+		return
+	}
+	fmt.Fprintf(w, "%dx%d, %d tiles\n", level.Columns(), level.Rows(), level.TileCount())
+	tiles := level.Tiles()
+	for row := 0; row < level.Rows(); row++ {
+		var cells []string
+		for column := 0; column < level.Columns(); column++ {
+			i := row*level.Columns() + column
+			content := "."
+			if tiles[i].Filled {
+				content = tiles[i].Value.String()
+			}
+			cells = append(cells, fmt.Sprintf("%3d %-4s", i, content))
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, " "), " "))
+	}
+}
+
+func tiles(cmd *cobra.Command, args []string) {
+	shown := levels.Levels()
+	if len(args) == 1 {
+		floor := parseInt(args[0])
+		checkFloorArg(floor)
+		shown = []levels.Level{levels.Floor(floor)}
+	}
+	for i, level := range shown {
+		if i > 0 {
+			fmt.Println()
+		}
+		writeTiles(os.Stdout, level)
+	}
+}
+
+func tilesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tiles [FLOOR]",
+		Short: "Show the floor tiles of the levels",
+		Long:  `Show the floor tiles of every level (or of FLOOR) and the values they hold at the start of the level`,
+		Args:  cobra.MaximumNArgs(1),
+		Run:   tiles,
+	}
+}
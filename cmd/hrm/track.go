@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/profile"
+	"github.com/clj/hrm-profile-tool/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	trackInterval     time.Duration
+	trackWebhook      string
+	trackNoAttachment bool
+)
+
+// A challenge result of a floor which improved since the last snapshot of
+// the profile
+type personalBest struct {
+	floorIndex int
+	// "size" or "steps"
+	challenge string
+	// The previous result, -1 if the challenge was not met
+	previous int
+	result   int
+	// The tab most likely holding the program, -1 if none
+	tab int
+}
+
+// Return the tab of newer most likely holding the program of a personal
+// best: a tab which changed since older, a tab of the size of the result
+// or else the first tab with a program
+func bestTab(older, newer profile.Floor, challenge string, result int) int {
+	for tab := range newer.Tabs {
+		if len(newer.Tabs[tab].Code) > 0 && newer.Tabs[tab].Hash() != older.Tabs[tab].Hash() {
+			return tab
+		}
+	}
+	if challenge == "size" {
+		for tab := range newer.Tabs {
+			if len(newer.Tabs[tab].Code) > 0 && newer.Tabs[tab].Code.Size() == result {
+				return tab
+			}
+		}
+	}
+	for tab := range newer.Tabs {
+		if len(newer.Tabs[tab].Code) > 0 {
+			return tab
+		}
+	}
+	return -1
+}
+
+// Return the challenge results of newer which improved on those of older,
+// i.e. challenges which were met for the first time or with a smaller
+// size or fewer steps
+func personalBests(older, newer profile.Profile) []personalBest {
+	var bests []personalBest
+	for floorIndex := range newer.Floors {
+		o, n := older.Floors[floorIndex], newer.Floors[floorIndex]
+		for _, c := range []struct {
+			challenge        string
+			previous, result int
+		}{
+			{"size", o.SizeChallenge, n.SizeChallenge},
+			{"steps", o.SpeedChallenge, n.SpeedChallenge},
+		} {
+			if c.result >= 0 && (c.previous < 0 || c.result < c.previous) {
+				bests = append(bests, personalBest{
+					floorIndex, c.challenge, c.previous, c.result,
+					bestTab(o, n, c.challenge, c.result)})
+			}
+		}
+	}
+	return bests
+}
+
+// Return the announcement of a personal best
+func (b personalBest) message() string {
+	floor := floorNumber(b.floorIndex)
+	name := fmt.Sprintf("floor %d", floor)
+	goal := -1
+	if level, found := levels.Lookup(floor); found {
+		name = fmt.Sprintf("floor %d (%s)", floor, levelName(level))
+		goal = level.SizeChallenge
+		if b.challenge == "steps" {
+			goal = level.SpeedChallenge
+		}
+	}
+	message := fmt.Sprintf("New personal best on %s: %s %s -> %d", name, b.challenge, challengeResult(b.previous), b.result)
+	if goal > 0 {
+		message += fmt.Sprintf(" (goal %d)", goal)
+	}
+	return message
+}
+
+// Report whether url is a Slack incoming webhook, otherwise it is taken to
+// be a Discord webhook
+func isSlackWebhook(webhook string) bool {
+	u, err := url.Parse(webhook)
+	return err == nil && strings.HasSuffix(strings.ToLower(u.Hostname()), "slack.com")
+}
+
+// Post message to the Discord or Slack webhook, attaching the PNG image
+// (if any) as name. Slack incoming webhooks cannot upload files, so the
+// image is only attached on Discord
+func postWebhook(webhook, message, name string, image []byte) error {
+	var body bytes.Buffer
+	contentType := "application/json"
+	switch {
+	case isSlackWebhook(webhook):
+		if err := json.NewEncoder(&body).Encode(map[string]string{"text": message}); err != nil {
+			return err
+		}
+	case image == nil:
+		if err := json.NewEncoder(&body).Encode(map[string]string{"content": message}); err != nil {
+			return err
+		}
+	default:
+		w := multipart.NewWriter(&body)
+		payload, err := json.Marshal(map[string]string{"content": message})
+		if err != nil {
+			return err
+		}
+		if err := w.WriteField("payload_json", string(payload)); err != nil {
+			return err
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files[0]"; filename=%q`, name))
+		header.Set("Content-Type", "image/png")
+		part, err := w.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(image); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		contentType = w.FormDataContentType()
+	}
+
+	request, err := http.NewRequestWithContext(appContext, http.MethodPost, webhook, &body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	request.Header.Set("User-Agent", "hrm-profile-tool/"+version)
+	response, err := httpClient.Do(request)
+	if urlErr, ok := err.(*url.Error); ok {
+		// Leave out the webhook URL, which is a secret
+		err = urlErr.Err
+	}
+	if err != nil {
+		return fmt.Errorf("posting to the webhook: %w", err)
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("posting to the webhook: %s", response.Status)
+	}
+	return nil
+}
+
+// Render the social card (see hrm card) of the program of a personal best
+func personalBestCard(reader profileReader, p profile.Profile, best personalBest) ([]byte, error) {
+	tab := p.Floors[best.floorIndex].Tabs[best.tab]
+	program := render.Program{Disassembled: tab.Code, RawComments: tab.RawComments, Comments: tab.Comments}
+	c, err := programCard(reader, slotNumber, best.floorIndex, program)
+	if err != nil {
+		return nil, err
+	}
+	img, err := render.RenderCardContext(appContext, c, program.Disassembled, program.Comments, render.CardLogger(logger))
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Announce a personal best on stdout and, with --webhook, to the webhook
+func announce(reader profileReader, p profile.Profile, best personalBest) {
+	message := best.message()
+	fmt.Printf("%s %s\n", time.Now().Format("15:04:05"), message)
+	if trackWebhook == "" {
+		return
+	}
+	var image []byte
+	name := ""
+	if !trackNoAttachment && best.tab >= 0 {
+		var err error
+		if image, err = personalBestCard(reader, p, best); err != nil {
+			logger.Warn("cannot render the program of the personal best", "floor", floorNumber(best.floorIndex), "tab", best.tab+1, "error", err)
+		}
+		name = fmt.Sprintf("floor-%02d-tab-%d.png", floorNumber(best.floorIndex), best.tab+1)
+	}
+	if err := postWebhook(trackWebhook, message, name, image); err != nil {
+		logger.Error("cannot announce the personal best", "floor", floorNumber(best.floorIndex), "error", err)
+	}
+}
+
+// Decode the tracked save slot of the profile at path, returning the
+// reader for rendering the programs of personal bests
+func decodeTracked(path string) (profileReader, profile.Profile, error) {
+	reader, err := openProfileAt(path)
+	if err != nil {
+		return nil, profile.Profile{}, err
+	}
+	size, err := profileSize(reader)
+	if err == nil && slotNumber > profileLayout(reader).SlotCount(size) {
+		err = fmt.Errorf("profile slot %d does not exist", slotNumber)
+	}
+	var p profile.Profile
+	if err == nil {
+		p, err = decodeSlot(reader, slotNumber, profile.ProgramOptions(programDecodeOptions()...))
+	}
+	if err != nil {
+		reader.Close()
+		return nil, p, err
+	}
+	return reader, p, nil
+}
+
+func track(cmd *cobra.Command, args []string) {
+	checkSlotNumber(slotNumber)
+	if trackInterval <= 0 {
+		usageFatalf("--interval must be positive")
+	}
+	if trackWebhook != "" {
+		if u, err := url.Parse(trackWebhook); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			usageFatalf("--webhook must be an HTTP(S) URL")
+		}
+	}
+	path, err := profileFilePath()
+	if err != nil {
+		fatal(err)
+	}
+	if isProfileURL(path) {
+		usageFatalf("hrm track requires a local profile, not %s", path)
+	}
+	reader, last, err := decodeTracked(path)
+	if err != nil {
+		fatal(err)
+	}
+	reader.Close()
+	activity := newActivityTracker(path, slotNumber)
+	activity.observe(last)
+
+	if !logQuiet {
+		fmt.Fprintf(os.Stderr, "Tracking %s for new personal bests\n", path)
+	}
+	watchFiles([]string{path}, trackInterval, func() {
+		reader, current, err := decodeTracked(path)
+		if err != nil {
+			logger.Warn("cannot read the profile", "path", path, "error", err)
+			return
+		}
+		defer reader.Close()
+		for _, best := range personalBests(last, current) {
+			announce(reader, current, best)
+		}
+		activity.observe(current)
+		last = current
+	})
+}
+
+func trackCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "track",
+		Short: "Announce new personal bests",
+		Long: `Watch the profile while playing and announce every floor whose size or
+speed challenge result improves on the previous save of the profile.
+
+With --webhook the announcements are also posted to a Discord or Slack
+webhook (Slack if the URL is on slack.com). On Discord the social card of
+the program (see hrm card) is attached, the tab is the one which changed
+or else the one matching the size.
+
+Every save is recorded in the activity log, listed by hrm sessions.`,
+		Args: cobra.NoArgs,
+		Run:  track,
+	}
+	addSlotFlag(cmd, "track")
+	cmd.Flags().DurationVar(&trackInterval, "interval", time.Second, "How often to check the profile for changes")
+	cmd.Flags().StringVar(&trackWebhook, "webhook", "", "Post personal bests to the Discord or Slack webhook `URL`")
+	cmd.Flags().BoolVar(&trackNoAttachment, "no-attachment", false, "Do not attach the card of the program to webhook posts")
+	addActivityFlags(cmd)
+	addCommentSpaceFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/clj/hrm-profile-tool/journal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoForce bool
+	undoList  bool
+)
+
+func openJournal() *journal.Journal {
+	j, err := journal.OpenDefault()
+	if err != nil {
+		fatal(err)
+	}
+	return j
+}
+
+func undo(cmd *cobra.Command, args []string) {
+	j := openJournal()
+
+	if undoList {
+		entries, err := j.Entries()
+		if err != nil {
+			fatal(err)
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			entry := entries[i]
+			fmt.Printf("%s  %-10s %s (%s)\n", entry.Time.Format("2006-01-02 15:04:05"), entry.Command, entry.Description, entry.Path)
+		}
+		return
+	}
+
+	if dryRun {
+		entry, err := j.Last()
+		if err == journal.ErrEmpty {
+			fatalf("nothing to undo")
+		} else if err != nil {
+			fatal(err)
+		}
+		if err := entry.CheckUnchanged(); err != nil && !undoForce {
+			fatal(err)
+		}
+		reverted := make([]journal.Change, len(entry.Changes))
+		for i, change := range entry.Changes {
+			reverted[i] = journal.Change{Offset: change.Offset, Original: change.Modified, Modified: change.Original}
+		}
+		printDryRun("revert "+entry.Command+" of", entry.Path, entry.Description, reverted)
+		return
+	}
+
+	entry, err := j.Undo(undoForce)
+	if err == journal.ErrEmpty {
+		fatalf("nothing to undo")
+	} else if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Reverted %s: %s (%s)\n", entry.Command, entry.Description, entry.Path)
+}
+
+func undoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Revert the most recent modification",
+		Long:  `Revert the most recent modification made to a profile by this tool, using the edit journal`,
+		Args:  cobra.NoArgs,
+		Run:   undo,
+	}
+	cmd.Flags().BoolVarP(&undoForce, "force", "f", false, "Revert even if the profile has changed since the modification")
+	cmd.Flags().BoolVarP(&undoList, "list", "l", false, "List the journal entries (most recent first) instead of reverting")
+	return cmd
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/metadata"
+	"github.com/spf13/cobra"
+)
+
+// Return the modification time and the hex encoded SHA-256 checksum of
+// the profile at path (opened as reader). Downloaded profiles have no
+// modification time
+func profileChecksum(path string, reader profileReader) (time.Time, string, error) {
+	var modified time.Time
+	if !isProfileURL(path) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return modified, "", err
+		}
+		modified = info.ModTime().UTC()
+	}
+	size, err := profileSize(reader)
+	if err != nil {
+		return modified, "", err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(reader, 0, size)); err != nil {
+		return modified, "", err
+	}
+	return modified, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Write the manifest of the exported files to exportDir
+func writeExportManifest(reader profileReader, profileId int, files []string) error {
+	path, err := profileFilePath()
+	if err != nil {
+		return err
+	}
+	if !isProfileURL(path) {
+		// Recorded for verify-export, which may run in another directory
+		if path, err = filepath.Abs(path); err != nil {
+			return err
+		}
+	}
+	m := metadata.Manifest{
+		Generator: "hrm-profile-tool " + version,
+		Created:   time.Now().UTC(),
+		Profile:   metadata.ManifestProfile{Path: path},
+		Slot:      profileId,
+	}
+	if m.Profile.Modified, m.Profile.SHA256, err = profileChecksum(path, reader); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := m.AddFile(exportDir, file); err != nil {
+			return err
+		}
+	}
+	return m.Write(exportDir)
+}
+
+func verifyExport(cmd *cobra.Command, args []string) {
+	dir := args[0]
+	m, err := metadata.ReadManifest(dir)
+	if os.IsNotExist(err) {
+		fatalf("%s has no %s, export with hrm export-all --manifest", dir, metadata.ManifestName)
+	} else if err != nil {
+		fatal(err)
+	}
+
+	mismatches, err := m.Verify(dir)
+	if err != nil {
+		fatal(err)
+	}
+	for _, mismatch := range mismatches {
+		fmt.Printf("%-9s %s\n", mismatch.Problem, mismatch.File)
+	}
+	failed := len(mismatches) > 0
+
+	// The profile is the one given with --profile, or else the one the
+	// export was made from
+	path := profilePath
+	if path == "" {
+		path = m.Profile.Path
+	}
+	reader, err := openProfileAt(path)
+	if err != nil {
+		logger.Warn("cannot check whether the export is stale", "error", err)
+	} else {
+		_, sum, err := profileChecksum(path, reader)
+		reader.Close()
+		switch {
+		case err != nil:
+			logger.Warn("cannot check whether the export is stale", "error", err)
+		case sum != m.Profile.SHA256:
+			fmt.Printf("stale     %s has changed since the export (%s)\n", path, m.Created.Local().Format("2006-01-02 15:04"))
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+	if !logQuiet {
+		fmt.Fprintf(os.Stderr, "%s matches its manifest (%d files, exported by %s)\n", dir, len(m.Files), m.Generator)
+	}
+}
+
+func verifyExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-export DIR",
+		Short: "Check an export against its manifest",
+		Long: `Check the files of a directory exported with hrm export-all --manifest
+against the checksums of the manifest, reporting files which are missing,
+modified or untracked (not in the manifest). The export is stale if the
+profile (--profile, or else the profile the export was made from) has
+changed since. Exits with status 1 if any problem is found.`,
+		Args: cobra.ExactArgs(1),
+		Run:  verifyExport,
+	}
+	return cmd
+}
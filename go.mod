@@ -3,9 +3,16 @@ module github.com/clj/hrm-profile-tool
 require (
 	github.com/ajstarks/svgo v0.0.0-20180830174826-7338bd80e790
 	github.com/clj/hrm-profile-tool/cmd/hrm v0.0.0
+	github.com/clj/hrm-profile-tool/analysis v0.0.0
 	github.com/clj/hrm-profile-tool/instructions v0.0.0
+	github.com/clj/hrm-profile-tool/schema v0.0.0
+	github.com/clj/hrm-profile-tool/metadata v0.0.0
 	github.com/clj/hrm-profile-tool/profile v0.0.0
 	github.com/clj/hrm-profile-tool/render v0.0.0
+	github.com/clj/hrm-profile-tool/journal v0.0.0
+	github.com/clj/hrm-profile-tool/levels v0.0.0
+	github.com/clj/hrm-profile-tool/locale v0.0.0
+	github.com/clj/hrm-profile-tool/vm v0.0.0
 
 )
 
@@ -17,6 +24,22 @@ replace github.com/clj/hrm-profile-tool/instructions => ./instructions
 
 replace github.com/clj/hrm-profile-tool/render => ./render
 
+replace github.com/clj/hrm-profile-tool/metadata => ./metadata
+
 replace github.com/clj/hrm-profile-tool/utils/text => ./utils/text
 
 replace github.com/clj/hrm-profile-tool/utils/seekbufio => ./utils/seekbufio
+
+replace github.com/clj/hrm-profile-tool/journal => ./journal
+
+replace github.com/clj/hrm-profile-tool/locale => ./locale
+
+replace github.com/clj/hrm-profile-tool/utils/logging => ./utils/logging
+
+replace github.com/clj/hrm-profile-tool/vm => ./vm
+
+replace github.com/clj/hrm-profile-tool/analysis => ./analysis
+
+replace github.com/clj/hrm-profile-tool/schema => ./schema
+
+replace github.com/clj/hrm-profile-tool/levels => ./levels
@@ -0,0 +1,193 @@
+package instructions
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type assembleOptions struct {
+	mnemonics *Mnemonics
+}
+
+// An assembler option
+type AssembleOption func(*assembleOptions)
+
+// Accept the mnemonics (and aliases) of m instead of the game's
+func AssembleMnemonics(m *Mnemonics) AssembleOption {
+	return func(o *assembleOptions) {
+		o.mnemonics = m
+	}
+}
+
+// An error assembling a program
+type AssembleError struct {
+	Line int
+	Err  error
+}
+
+func (e *AssembleError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *AssembleError) Unwrap() error {
+	return e.Err
+}
+
+// Assemble a program in the text format used by the game (and rendered by
+// the text renderer) into instructions and raw comments. Lines starting
+// with "--" are ignored, as are label drawings (DEFINE LABEL). Programs
+// with more than MAX_INSTRUCTIONS instructions do not fit in a tab and
+// return an error wrapping ErrTooManyInstructions
+func Assemble(text string, opts ...AssembleOption) (Instructions, RawComments, error) {
+	options := assembleOptions{mnemonics: DefaultMnemonics()}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var program Instructions
+	var comments RawComments
+	labels := make(map[string]int)
+	type jump struct {
+		index, line int
+		label       string
+	}
+	var jumps []jump
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	lineNumber := 0
+	fail := func(format string, args ...interface{}) (Instructions, RawComments, error) {
+		return nil, nil, &AssembleError{lineNumber, fmt.Errorf(format, args...)}
+	}
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch {
+		case strings.EqualFold(fields[0], "DEFINE"):
+			if len(fields) != 3 {
+				return fail("expected DEFINE COMMENT|LABEL n")
+			}
+			index, err := strconv.Atoi(fields[2])
+			if err != nil || index < 0 {
+				return fail("invalid index %q", fields[2])
+			}
+			var data strings.Builder
+			for !strings.HasSuffix(data.String(), ";") && scanner.Scan() {
+				lineNumber++
+				data.WriteString(strings.TrimSpace(scanner.Text()))
+			}
+			if !strings.EqualFold(fields[1], "COMMENT") {
+				continue
+			}
+			comment, err := DecodeCommentText(strings.TrimSuffix(data.String(), ";"))
+			if err != nil {
+				return fail("comment %d: %v", index, err)
+			}
+			for len(comments) <= index {
+				comments = append(comments, RawComment{})
+			}
+			comments[index] = comment
+		case strings.HasSuffix(line, ":") && len(fields) == 1:
+			labels[strings.TrimSuffix(line, ":")] = len(program)
+			program = append(program, Instruction{Op: OP_JUMP_TGT})
+		case strings.EqualFold(fields[0], "COMMENT"):
+			if len(fields) != 2 {
+				return fail("expected COMMENT n")
+			}
+			index, err := strconv.ParseUint(fields[1], 10, 32)
+			if err != nil {
+				return fail("invalid comment index %q", fields[1])
+			}
+			program = append(program, Instruction{Comment: 1, Op: uint32(index)})
+		default:
+			op, found := options.mnemonics.Lookup(fields[0])
+			if !found {
+				return fail("unknown instruction %q", fields[0])
+			}
+			inst := Instruction{Op: uint32(op)}
+			switch {
+			case InstructionsWithLabel.Member(op):
+				if len(fields) != 2 {
+					return fail("%s requires a label", fields[0])
+				}
+				jumps = append(jumps, jump{len(program), lineNumber, fields[1]})
+			case InstructionsWithArg.Member(op):
+				if len(fields) != 2 {
+					return fail("%s requires an argument", fields[0])
+				}
+				arg := fields[1]
+				inst.Mode = MODE_DIRECT
+				if strings.HasPrefix(arg, "[") && strings.HasSuffix(arg, "]") {
+					arg = arg[1 : len(arg)-1]
+					inst.Mode = MODE_INDIRECT
+				}
+				value, err := strconv.ParseUint(arg, 10, 32)
+				if err != nil {
+					return fail("invalid argument %q", fields[1])
+				}
+				inst.Arg = uint32(value)
+			default:
+				if len(fields) != 1 {
+					return fail("%s takes no argument", fields[0])
+				}
+			}
+			program = append(program, inst)
+		}
+		if len(program) > maxBlockInstructions {
+			return fail("%w: a tab holds at most %d instructions, including labels and comments", ErrTooManyInstructions, maxBlockInstructions)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	for _, j := range jumps {
+		target, found := labels[j.label]
+		if !found {
+			lineNumber = j.line
+			return fail("undefined label %q", j.label)
+		}
+		program[j.index].Arg = uint32(target)
+	}
+	return program, comments, nil
+}
+
+// Decode a comment definition, as found after DEFINE COMMENT n in programs
+// copied from the game: a base64 encoded (without padding) zlib compressed
+// comment record
+func DecodeCommentText(text string) (RawComment, error) {
+	compressed, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(text, "="))
+	if err != nil {
+		return nil, err
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	record, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(record) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	count := binary.LittleEndian.Uint32(record)
+	if int64(4+uint64(count)*4) > int64(len(record)) {
+		return nil, fmt.Errorf("invalid comment point count %d", count)
+	}
+	comment := make(RawComment, count)
+	for i := range comment {
+		copy(comment[i][:], record[4+i*4:])
+	}
+	return comment, nil
+}
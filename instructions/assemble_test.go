@@ -0,0 +1,146 @@
+package instructions
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAssembleDisassemble(t *testing.T) {
+	program, comments, err := Assemble(`-- HUMAN RESOURCE MACHINE PROGRAM --
+
+a:
+    INBOX
+    COMMENT  0
+    COPYTO   [3]
+    add      4
+    JUMPN    a
+b:
+    OUTBOX
+    JUMP     b
+
+
+DEFINE LABEL 3
+eJxjYGBgYAAAAAUAAQ;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 0 {
+		t.Errorf("got comments %v from a label drawing", comments)
+	}
+	disassembled, err := Disassemble(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Disassembled{
+		DisassembleJumpTarget{"a", 5},
+		DisassembleInstruction{1, OP_INBOX},
+		DisassembleComment{0},
+		DisassembleArgInstruction{DisassembleInstruction{2, OP_COPY_TO}, 3, true},
+		DisassembleArgInstruction{DisassembleInstruction{3, OP_ADD}, 4, false},
+		DisassembleJumpInstruction{DisassembleInstruction{4, OP_JUMP_NEG}, "a", 0},
+		DisassembleJumpTarget{"b", 8},
+		DisassembleInstruction{5, OP_OUTBOX},
+		DisassembleJumpInstruction{DisassembleInstruction{6, OP_JUMP}, "b", 6},
+	}
+	if !reflect.DeepEqual(disassembled, expected) {
+		t.Errorf("got %#v, expected %#v", disassembled, expected)
+	}
+	if disassembled.Size() != 6 {
+		t.Errorf("got size %d, expected 6", disassembled.Size())
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		line int
+		err  string
+	}{
+		{"INBOX\nFETCH 1\n", 2, `unknown instruction "FETCH"`},
+		{"COPYFROM\n", 1, "COPYFROM requires an argument"},
+		{"ADD 1 2\n", 1, "ADD requires an argument"},
+		{"COPYTO x\n", 1, `invalid argument "x"`},
+		{"COPYTO -1\n", 1, `invalid argument "-1"`},
+		{"BUMPUP [1\n", 1, `invalid argument "[1"`},
+		{"BUMPDN []\n", 1, `invalid argument "[]"`},
+		{"OUTBOX 1\n", 1, "OUTBOX takes no argument"},
+		{"JUMP\n", 1, "JUMP requires a label"},
+		{"COMMENT\n", 1, "expected COMMENT n"},
+		{"COMMENT x\n", 1, `invalid comment index "x"`},
+		{"DEFINE COMMENT\n", 1, "expected DEFINE COMMENT|LABEL n"},
+		{"DEFINE COMMENT -1\n", 1, `invalid index "-1"`},
+		{"a:\n    INBOX\n    JUMPZ b\n    JUMP a\n", 3, `undefined label "b"`},
+	}
+	for _, test := range tests {
+		_, _, err := Assemble(test.text)
+		var assembleErr *AssembleError
+		if !errors.As(err, &assembleErr) {
+			t.Errorf("%q: got error %v, expected %s", test.text, err, test.err)
+			continue
+		}
+		if assembleErr.Line != test.line || assembleErr.Err.Error() != test.err {
+			t.Errorf("%q: got %q on line %d, expected %q on line %d", test.text, assembleErr.Err, assembleErr.Line, test.err, test.line)
+		}
+	}
+}
+
+func TestAssembleTooManyInstructions(t *testing.T) {
+	text := strings.Repeat("INBOX\n", maxBlockInstructions)
+	if _, _, err := Assemble(text); err != nil {
+		t.Fatalf("a full tab: %v", err)
+	}
+	_, _, err := Assemble(text + "OUTBOX\n")
+	if !errors.Is(err, ErrTooManyInstructions) {
+		t.Errorf("got error %v, expected ErrTooManyInstructions", err)
+	}
+}
+
+func TestAssembleMnemonics(t *testing.T) {
+	mnemonics, err := ParseMnemonics(strings.NewReader(`
+# community aliases
+JUMPN = JLZ JNEG
+BUMPUP = inc
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mnemonics.Name(OP_JUMP_NEG) != "JLZ" || mnemonics.Name(OP_BUMP_PLUS) != "inc" || mnemonics.Name(OP_INBOX) != "INBOX" {
+		t.Errorf("got names %s, %s and %s", mnemonics.Name(OP_JUMP_NEG), mnemonics.Name(OP_BUMP_PLUS), mnemonics.Name(OP_INBOX))
+	}
+
+	text := "a:\n    INBOX\n    jneg a\n    JLZ a\n    JUMPN a\n    INC 0\n    bumpup 0\n"
+	program, _, err := Assemble(text, AssembleMnemonics(mnemonics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := []OpCode{OP_JUMP_TGT, OP_INBOX, OP_JUMP_NEG, OP_JUMP_NEG, OP_JUMP_NEG, OP_BUMP_PLUS, OP_BUMP_PLUS}
+	for i, inst := range program {
+		if OpCode(inst.Op) != ops[i] {
+			t.Errorf("instruction %d is %s, expected %s", i, OpCode(inst.Op), ops[i])
+		}
+	}
+
+	if _, _, err := Assemble(text); err == nil {
+		t.Error("the game's mnemonics accepted JNEG")
+	}
+}
+
+func TestParseMnemonicsErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"JUMPN JLZ\n", "line 1: expected MNEMONIC = REPLACEMENT [ALIAS...]"},
+		{"\nJLZ = JUMPN\n", `line 2: unknown mnemonic "JLZ"`},
+		{"JUMPN = # nothing\n", "line 1: missing replacement for JUMPN"},
+	}
+	for _, test := range tests {
+		_, err := ParseMnemonics(strings.NewReader(test.text))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: got error %v, expected %s", test.text, err, test.err)
+		}
+	}
+}
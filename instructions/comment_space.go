@@ -0,0 +1,100 @@
+package instructions
+
+import "math"
+
+// The corner of the comment canvas at which comment points have the
+// coordinates (0, 0). The X axis points away from the left or right edge
+// and the Y axis away from the top or bottom edge
+type CommentOrigin int
+
+const (
+	commentOriginRight  CommentOrigin = 1 << 0
+	commentOriginBottom CommentOrigin = 1 << 1
+)
+
+const (
+	// The origin of the points as stored by the game, and as drawn by
+	// renderers with a top left origin (SVG, images)
+	COMMENT_ORIGIN_TOP_LEFT     CommentOrigin = 0
+	COMMENT_ORIGIN_TOP_RIGHT    CommentOrigin = commentOriginRight
+	COMMENT_ORIGIN_BOTTOM_LEFT  CommentOrigin = commentOriginBottom
+	COMMENT_ORIGIN_BOTTOM_RIGHT CommentOrigin = commentOriginRight | commentOriginBottom
+)
+
+var commentOriginNames = map[CommentOrigin]string{
+	COMMENT_ORIGIN_TOP_LEFT:     "top-left",
+	COMMENT_ORIGIN_TOP_RIGHT:    "top-right",
+	COMMENT_ORIGIN_BOTTOM_LEFT:  "bottom-left",
+	COMMENT_ORIGIN_BOTTOM_RIGHT: "bottom-right",
+}
+
+// Return the name of the origin, e.g. "top-left"
+func (o CommentOrigin) String() string {
+	return commentOriginNames[o]
+}
+
+// Return the origin called name (see CommentOrigin.String)
+func ParseCommentOrigin(name string) (CommentOrigin, bool) {
+	for origin, originName := range commentOriginNames {
+		if originName == name {
+			return origin, true
+		}
+	}
+	return 0, false
+}
+
+// Mirror the points of the comment from the top left origin to origin
+func (c Comment) moveOrigin(origin CommentOrigin) {
+	for _, line := range c {
+		for i := range line {
+			if origin&commentOriginRight != 0 {
+				line[i].X = math.MaxUint16 - line[i].X
+			}
+			if origin&commentOriginBottom != 0 {
+				line[i].Y = math.MaxUint16 - line[i].Y
+			}
+		}
+	}
+}
+
+// Return the bounding box of the points of the comment, reporting false if
+// it has no points
+func (c Comment) bounds() (min, max CommentPoint, found bool) {
+	min = CommentPoint{math.MaxUint16, math.MaxUint16}
+	for _, line := range c {
+		for _, point := range line {
+			found = true
+			if point.X < min.X {
+				min.X = point.X
+			}
+			if point.Y < min.Y {
+				min.Y = point.Y
+			}
+			if point.X > max.X {
+				max.X = point.X
+			}
+			if point.Y > max.Y {
+				max.Y = point.Y
+			}
+		}
+	}
+	return min, max, found
+}
+
+// Scale and move the points of the comment, see NormalizeComments
+func (c Comment) normalize() {
+	min, max, found := c.bounds()
+	if !found {
+		return
+	}
+	scale := 1.0
+	if width, height := float64(max.X-min.X), float64(max.Y-min.Y); width > 0 || height > 0 {
+		scale = math.MaxUint16 / math.Max(width, height)
+	}
+	for _, line := range c {
+		for i, point := range line {
+			line[i].X = uint16(math.Round(float64(point.X-min.X) * scale))
+			line[i].Y = uint16(math.Round(float64(point.Y-min.Y) * scale))
+		}
+	}
+}
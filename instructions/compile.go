@@ -0,0 +1,659 @@
+package instructions
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// An error compiling a program
+type CompileError struct {
+	Line int
+	Err  error
+}
+
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *CompileError) Unwrap() error {
+	return e.Err
+}
+
+// Compile a program written in hrmc, a minimal structured language, into
+// instructions (via Assemble). For example, to output the smaller of each
+// pair of inbox values:
+//
+//	var a @ 0
+//	var b @ 1
+//	loop {
+//	    a = inbox
+//	    b = inbox
+//	    if a < b { outbox a } else { outbox b }
+//	}
+//
+// The language has:
+//
+//   - var NAME @ TILE declares a variable held on floor tile TILE, with
+//     = VALUE if the level starts with VALUE on the tile, after which the
+//     literal VALUE (a number or a letter, e.g. 'A') may be used
+//   - TARGET = EXPRESSION, outbox EXPRESSION, ++TARGET and --TARGET
+//   - if CONDITION { ... } else if CONDITION { ... } else { ... }
+//   - while CONDITION { ... }, loop { ... } and break
+//   - expressions: inbox, ++TARGET, --TARGET or an operand, followed by
+//     any number of + OPERAND and - OPERAND
+//   - conditions: EXPRESSION followed by ==, !=, <, <=, > or >= and 0 or
+//     an operand
+//   - operands: a variable, *VARIABLE for the tile the variable points
+//     to, @TILE for a tile by number, or a literal; targets are operands
+//     other than literals
+//   - comments from # to the end of the line
+//
+// For programs which do not fit these statements, e.g. those decompiled by
+// Decompile, there are also NAME: labels, goto NAME and
+// if CONDITION goto NAME, and hand, the value in the worker's hand, which
+// may start an expression and be assigned to (hand = EXPRESSION leaves the
+// value in the hand without copying it to a tile).
+//
+// The program ends, as in the game, when inbox is used on an empty inbox
+func Compile(source string) (Instructions, error) {
+	assembly, err := CompileAssembly(source)
+	if err != nil {
+		return nil, err
+	}
+	program, _, err := Assemble(assembly)
+	return program, err
+}
+
+// Like Compile, but return the program in the text format used by the
+// game (see Assemble)
+func CompileAssembly(source string) (string, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return "", err
+	}
+	c := &compiler{tokens: tokens, vars: make(map[string]*variable), tiles: make(map[int]string), userLabels: make(map[string]bool)}
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].kind == tokenIdent && tokens[i+1].kind == tokenSymbol && tokens[i+1].text == ":" {
+			c.userLabels[tokens[i].text] = false
+		}
+	}
+	c.out.WriteString("-- HUMAN RESOURCE MACHINE PROGRAM --\n\n")
+	for c.peek().kind != tokenEOF {
+		if err := c.statement(); err != nil {
+			return "", err
+		}
+	}
+	for _, t := range c.gotos {
+		if _, found := c.userLabels[t.text]; !found {
+			return "", c.fail(t, "undefined label %q", t.text)
+		}
+	}
+	for _, name := range c.order {
+		if v := c.vars[name]; v.literalLine > 0 && v.assignedLine > 0 {
+			return "", &CompileError{v.assignedLine, fmt.Errorf("%s is assigned to, so it does not hold %s for the literal on line %d", name, v.initial, v.literalLine)}
+		}
+	}
+	return c.out.String(), nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenLetter
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// The symbols of the language, longest first
+var compileSymbols = []string{"==", "!=", "<=", ">=", "++", "--", "<", ">", "=", "+", "-", "*", "@", "{", "}", ":"}
+
+// Split source into tokens
+func lex(source string) ([]token, error) {
+	var tokens []token
+	line := 1
+	for i := 0; i < len(source); {
+		ch := rune(source[i])
+		switch {
+		case ch == '\n':
+			line++
+			i++
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '#':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case unicode.IsDigit(ch):
+			start := i
+			for i < len(source) && unicode.IsDigit(rune(source[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, source[start:i], line})
+		case unicode.IsLetter(ch) || ch == '_':
+			start := i
+			for i < len(source) && (unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i])) || source[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, source[start:i], line})
+		case ch == '\'':
+			if i+2 >= len(source) || source[i+2] != '\'' || source[i+1] < 'A' || source[i+1] > 'Z' {
+				return nil, &CompileError{line, errors.New("letters are written as 'A' to 'Z'")}
+			}
+			tokens = append(tokens, token{tokenLetter, source[i+1 : i+2], line})
+			i += 3
+		default:
+			symbol := ""
+			for _, s := range compileSymbols {
+				if strings.HasPrefix(source[i:], s) {
+					symbol = s
+					break
+				}
+			}
+			if symbol == "" {
+				return nil, &CompileError{line, fmt.Errorf("unexpected %q", ch)}
+			}
+			tokens = append(tokens, token{tokenSymbol, symbol, line})
+			i += len(symbol)
+		}
+	}
+	return append(tokens, token{tokenEOF, "end of program", line}), nil
+}
+
+// A variable declared with var
+type variable struct {
+	tile int
+	// The value on the tile at the start, if declared
+	initial string
+	// The lines on which the variable is first assigned to and first used
+	// for a literal, 0 if never
+	assignedLine, literalLine int
+}
+
+// An operand of an instruction
+type operand struct {
+	// The argument of the instruction, e.g. "3" or "[3]"
+	arg string
+	// The variable, if any
+	variable *variable
+}
+
+type compiler struct {
+	tokens []token
+	pos    int
+	vars   map[string]*variable
+	// The variables in order of declaration, and by tile
+	order []string
+	tiles map[int]string
+	out   strings.Builder
+	// The argument of the tile known to hold the value in the worker's
+	// hand, "" if not known
+	hand   string
+	labels int
+	// The end labels of the enclosing loops, for break, and whether they
+	// are used
+	loops []*loopEnd
+	// The labels in the source, and whether they have been defined yet,
+	// and the label of each goto
+	userLabels map[string]bool
+	gotos      []token
+}
+
+type loopEnd struct {
+	label string
+	used  bool
+}
+
+func (c *compiler) peek() token {
+	return c.tokens[c.pos]
+}
+
+func (c *compiler) next() token {
+	t := c.tokens[c.pos]
+	if t.kind != tokenEOF {
+		c.pos++
+	}
+	return t
+}
+
+func (c *compiler) fail(t token, format string, args ...interface{}) error {
+	return &CompileError{t.line, fmt.Errorf(format, args...)}
+}
+
+// Report whether the next token is the symbol or keyword text, consuming
+// it if so
+func (c *compiler) accept(text string) bool {
+	if t := c.peek(); (t.kind == tokenSymbol || t.kind == tokenIdent) && t.text == text {
+		c.pos++
+		return true
+	}
+	return false
+}
+
+func (c *compiler) expect(text string) error {
+	if !c.accept(text) {
+		return c.fail(c.peek(), "expected %q, found %q", text, c.peek().text)
+	}
+	return nil
+}
+
+// Emit an instruction, keeping track of the value in the worker's hand
+func (c *compiler) emit(mnemonic string, arg string) {
+	if arg == "" {
+		fmt.Fprintf(&c.out, "    %s\n", mnemonic)
+	} else {
+		fmt.Fprintf(&c.out, "    %s %s\n", mnemonic, arg)
+	}
+	switch mnemonic {
+	case "COPYFROM", "COPYTO", "BUMPUP", "BUMPDN":
+		c.hand = arg
+		// Writing to the tile a tile points to may change the pointer
+		// (when the tile points to itself), so the tile read by the next
+		// indirect COPYFROM is not known
+		if strings.HasPrefix(arg, "[") && mnemonic != "COPYFROM" {
+			c.hand = ""
+		}
+	case "INBOX", "OUTBOX", "ADD", "SUB":
+		c.hand = ""
+	}
+}
+
+// Return a new label, other than those in the source
+func (c *compiler) newLabel() string {
+	for {
+		n := c.labels
+		c.labels++
+		label := ""
+		for ; n >= 0; n = n/26 - 1 {
+			label = string(rune('a'+n%26)) + label
+		}
+		if _, found := c.userLabels[label]; !found {
+			return label
+		}
+	}
+}
+
+// Emit a jump target. The value in the hand is not known when jumped to
+func (c *compiler) label(label string) {
+	fmt.Fprintf(&c.out, "%s:\n", label)
+	c.hand = ""
+}
+
+var compileKeywords = map[string]bool{
+	"var": true, "if": true, "else": true, "while": true, "loop": true,
+	"break": true, "inbox": true, "outbox": true, "hand": true, "goto": true,
+}
+
+func (c *compiler) statement() error {
+	t := c.peek()
+	switch {
+	case c.accept("var"):
+		return c.declaration()
+	case c.accept("if"):
+		return c.ifStatement()
+	case c.accept("while"):
+		top, end := c.newLabel(), c.newLabel()
+		c.label(top)
+		relation, err := c.condition()
+		if err != nil {
+			return err
+		}
+		c.jumpUnless(relation, end)
+		c.loops = append(c.loops, &loopEnd{end, true})
+		if err := c.block(); err != nil {
+			return err
+		}
+		c.loops = c.loops[:len(c.loops)-1]
+		c.emit("JUMP", top)
+		c.label(end)
+	case c.accept("loop"):
+		top := c.newLabel()
+		end := &loopEnd{label: c.newLabel()}
+		c.label(top)
+		c.loops = append(c.loops, end)
+		if err := c.block(); err != nil {
+			return err
+		}
+		c.loops = c.loops[:len(c.loops)-1]
+		c.emit("JUMP", top)
+		if end.used {
+			c.label(end.label)
+		}
+	case c.accept("break"):
+		if len(c.loops) == 0 {
+			return c.fail(t, "break outside of a loop")
+		}
+		end := c.loops[len(c.loops)-1]
+		end.used = true
+		c.emit("JUMP", end.label)
+	case c.accept("goto"):
+		label, err := c.gotoLabel()
+		if err != nil {
+			return err
+		}
+		c.emit("JUMP", label)
+	case t.kind == tokenIdent && c.tokens[c.pos+1].kind == tokenSymbol && c.tokens[c.pos+1].text == ":":
+		c.pos += 2
+		if c.userLabels[t.text] {
+			return c.fail(t, "label %s is already defined", t.text)
+		}
+		c.userLabels[t.text] = true
+		c.label(t.text)
+	case c.accept("hand"):
+		if err := c.expect("="); err != nil {
+			return err
+		}
+		return c.expression()
+	case c.accept("outbox"):
+		if err := c.expression(); err != nil {
+			return err
+		}
+		c.emit("OUTBOX", "")
+	case c.accept("++"):
+		return c.bump("BUMPUP")
+	case c.accept("--"):
+		return c.bump("BUMPDN")
+	default:
+		target, err := c.target()
+		if err != nil {
+			return err
+		}
+		if err := c.expect("="); err != nil {
+			return err
+		}
+		if err := c.expression(); err != nil {
+			return err
+		}
+		if target.variable != nil && target.variable.assignedLine == 0 {
+			target.variable.assignedLine = t.line
+		}
+		c.emit("COPYTO", target.arg)
+	}
+	return nil
+}
+
+func (c *compiler) declaration() error {
+	t := c.next()
+	if t.kind != tokenIdent || compileKeywords[t.text] {
+		return c.fail(t, "expected a variable name, found %q", t.text)
+	}
+	if _, found := c.vars[t.text]; found {
+		return c.fail(t, "%s is already declared", t.text)
+	}
+	if err := c.expect("@"); err != nil {
+		return err
+	}
+	tile, err := c.tileNumber()
+	if err != nil {
+		return err
+	}
+	if name, found := c.tiles[tile]; found {
+		return c.fail(t, "tile %d already holds %s", tile, name)
+	}
+	v := &variable{tile: tile}
+	if c.accept("=") {
+		if v.initial, err = c.literal(); err != nil {
+			return err
+		}
+	}
+	c.vars[t.text] = v
+	c.tiles[tile] = t.text
+	c.order = append(c.order, t.text)
+	return nil
+}
+
+func (c *compiler) tileNumber() (int, error) {
+	t := c.next()
+	tile, err := strconv.Atoi(t.text)
+	if t.kind != tokenNumber || err != nil {
+		return 0, c.fail(t, "expected a tile number, found %q", t.text)
+	}
+	return tile, nil
+}
+
+// Parse a literal, returning it as shown in the game
+func (c *compiler) literal() (string, error) {
+	negative := c.accept("-")
+	t := c.next()
+	switch {
+	case t.kind == tokenLetter && !negative:
+		return t.text, nil
+	case t.kind == tokenNumber:
+		n, err := strconv.Atoi(t.text)
+		if err != nil || n > 999 {
+			return "", c.fail(t, "numbers range from -999 to 999")
+		}
+		if negative {
+			n = -n
+		}
+		return strconv.Itoa(n), nil
+	}
+	return "", c.fail(t, "expected a number or a letter, found %q", t.text)
+}
+
+// Parse a target: a variable, *VARIABLE or @TILE
+func (c *compiler) target() (operand, error) {
+	t := c.next()
+	switch {
+	case t.kind == tokenSymbol && t.text == "*":
+		name := c.next()
+		v, found := c.vars[name.text]
+		if !found {
+			return operand{}, c.fail(name, "undeclared variable %q", name.text)
+		}
+		return operand{fmt.Sprintf("[%d]", v.tile), nil}, nil
+	case t.kind == tokenSymbol && t.text == "@":
+		tile, err := c.tileNumber()
+		if err != nil {
+			return operand{}, err
+		}
+		return operand{strconv.Itoa(tile), c.vars[c.tiles[tile]]}, nil
+	case t.kind == tokenIdent && !compileKeywords[t.text]:
+		v, found := c.vars[t.text]
+		if !found {
+			return operand{}, c.fail(t, "undeclared variable %q", t.text)
+		}
+		return operand{strconv.Itoa(v.tile), v}, nil
+	}
+	return operand{}, c.fail(t, "expected a variable, found %q", t.text)
+}
+
+// Parse an operand: a target, or a literal held on a tile
+func (c *compiler) operand() (operand, error) {
+	t := c.peek()
+	if t.kind != tokenNumber && t.kind != tokenLetter && !(t.kind == tokenSymbol && t.text == "-") {
+		return c.target()
+	}
+	value, err := c.literal()
+	if err != nil {
+		return operand{}, err
+	}
+	for _, name := range c.order {
+		if v := c.vars[name]; v.initial == value {
+			if v.literalLine == 0 {
+				v.literalLine = t.line
+			}
+			return operand{strconv.Itoa(v.tile), v}, nil
+		}
+	}
+	return operand{}, c.fail(t, "no tile holds %s, declare one with var NAME @ TILE = %s", value, value)
+}
+
+func (c *compiler) bump(mnemonic string) error {
+	t := c.peek()
+	target, err := c.target()
+	if err != nil {
+		return err
+	}
+	if target.variable != nil && target.variable.assignedLine == 0 {
+		target.variable.assignedLine = t.line
+	}
+	c.emit(mnemonic, target.arg)
+	return nil
+}
+
+// Compile an expression, leaving its value in the worker's hand
+func (c *compiler) expression() error {
+	switch {
+	case c.accept("hand"):
+	case c.accept("inbox"):
+		c.emit("INBOX", "")
+	case c.accept("++"):
+		if err := c.bump("BUMPUP"); err != nil {
+			return err
+		}
+	case c.accept("--"):
+		if err := c.bump("BUMPDN"); err != nil {
+			return err
+		}
+	default:
+		first, err := c.operand()
+		if err != nil {
+			return err
+		}
+		if c.hand != first.arg {
+			c.emit("COPYFROM", first.arg)
+		}
+	}
+	for {
+		mnemonic := ""
+		switch {
+		case c.accept("+"):
+			mnemonic = "ADD"
+		case c.accept("-"):
+			mnemonic = "SUB"
+		default:
+			return nil
+		}
+		o, err := c.operand()
+		if err != nil {
+			return err
+		}
+		c.emit(mnemonic, o.arg)
+	}
+}
+
+// Parse the label of a goto
+func (c *compiler) gotoLabel() (string, error) {
+	t := c.next()
+	if t.kind != tokenIdent || compileKeywords[t.text] {
+		return "", c.fail(t, "expected a label, found %q", t.text)
+	}
+	c.gotos = append(c.gotos, t)
+	return t.text, nil
+}
+
+// Compile a condition, leaving the value to compare against 0 in the
+// worker's hand, and return the comparison
+func (c *compiler) condition() (string, error) {
+	if err := c.expression(); err != nil {
+		return "", err
+	}
+	t := c.next()
+	relation := t.text
+	switch relation {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return "", c.fail(t, "expected a comparison, found %q", t.text)
+	}
+	if n := c.peek(); n.kind != tokenNumber || n.text != "0" {
+		o, err := c.operand()
+		if err != nil {
+			return "", err
+		}
+		c.emit("SUB", o.arg)
+	} else {
+		c.next()
+	}
+	return relation, nil
+}
+
+// The relation which holds when relation does not
+var negatedRelations = map[string]string{
+	"==": "!=", "!=": "==", "<": ">=", ">=": "<", ">": "<=", "<=": ">",
+}
+
+// Jump to label if the value in the hand compares to 0 by relation
+func (c *compiler) jumpIf(relation string, label string) {
+	// The game only jumps if the hand is zero or negative
+	switch relation {
+	case "==":
+		c.emit("JUMPZ", label)
+	case "<":
+		c.emit("JUMPN", label)
+	case "<=":
+		c.emit("JUMPN", label)
+		c.emit("JUMPZ", label)
+	default:
+		skip := c.newLabel()
+		c.jumpIf(negatedRelations[relation], skip)
+		c.emit("JUMP", label)
+		c.label(skip)
+	}
+}
+
+// Jump to label unless the value in the hand compares to 0 by relation
+func (c *compiler) jumpUnless(relation string, label string) {
+	c.jumpIf(negatedRelations[relation], label)
+}
+
+func (c *compiler) ifStatement() error {
+	relation, err := c.condition()
+	if err != nil {
+		return err
+	}
+	if c.accept("goto") {
+		label, err := c.gotoLabel()
+		if err != nil {
+			return err
+		}
+		c.jumpIf(relation, label)
+		return nil
+	}
+	otherwise := c.newLabel()
+	c.jumpUnless(relation, otherwise)
+	if err := c.block(); err != nil {
+		return err
+	}
+	if !c.accept("else") {
+		c.label(otherwise)
+		return nil
+	}
+	end := c.newLabel()
+	c.emit("JUMP", end)
+	c.label(otherwise)
+	if c.accept("if") {
+		if err := c.ifStatement(); err != nil {
+			return err
+		}
+	} else if err := c.block(); err != nil {
+		return err
+	}
+	c.label(end)
+	return nil
+}
+
+func (c *compiler) block() error {
+	if err := c.expect("{"); err != nil {
+		return err
+	}
+	for !c.accept("}") {
+		if c.peek().kind == tokenEOF {
+			return c.fail(c.peek(), "expected \"}\", found the end of the program")
+		}
+		if err := c.statement(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,7 +4,10 @@ package instructions
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
 )
 
@@ -39,30 +42,132 @@ const (
 	MODE_INDIRECT = 0x2
 )
 
+const (
+	// The size of the instruction block of a tab: the instruction count
+	// followed by the instructions
+	INSTRUCTIONS_BLOCK_SIZE = 4100
+	// The size of the comment block of a tab, which immediately follows
+	// the instruction block: the comment count followed by fixed size
+	// comment records
+	COMMENTS_BLOCK_SIZE = 42152
+)
+
+// The size of a single binary instruction
+const instructionSize = 4 * 4
+
+const (
+	// The size of a single binary instruction
+	INSTRUCTION_SIZE = instructionSize
+	// The maximum number of instructions in the instruction block of a tab
+	MAX_INSTRUCTIONS = maxBlockInstructions
+	// The maximum number of points in a single comment record, see
+	// CommentRecords for longer comments
+	MAX_COMMENT_POINTS = maxCommentPoints
+	// The size of a comment record: the point count followed by the points
+	COMMENT_RECORD_SIZE = 4 + maxCommentPoints*4
+	// The number of comment records in the comment block of a tab, i.e.
+	// the maximum number of comments of at most MAX_COMMENT_POINTS points
+	MAX_COMMENTS = (COMMENTS_BLOCK_SIZE - 4) / COMMENT_RECORD_SIZE
+)
+
+// The maximum number of instructions that fit in the instruction block
+// of a tab
+const maxBlockInstructions = (INSTRUCTIONS_BLOCK_SIZE - 4) / instructionSize
+
+// The maximum number of points in a single comment record
+const maxCommentPoints = 256
+
+// A program or instruction block has more instructions than fit in the
+// instruction block of a tab
+var ErrTooManyInstructions = errors.New("too many instructions")
+
+// Return an error if count instructions do not fit in the instruction block
+func checkInstructionCount(count uint32) error {
+	if count > maxBlockInstructions {
+		return fmt.Errorf("%w: count %d exceeds the %d instructions that fit in the instruction block", ErrTooManyInstructions, count, maxBlockInstructions)
+	}
+	return nil
+}
+
+// Return an error if count comments do not fit in the comment block, each
+// taking at least one comment record
+func checkCommentCount(count uint32) error {
+	if count > MAX_COMMENTS {
+		return fmt.Errorf("%w: count %d exceeds the %d comments that fit in the comment block", ErrTooManyComments, count, MAX_COMMENTS)
+	}
+	return nil
+}
+
+// Return the number of comment records taken by a comment of points
+// points. Comments with more than MAX_COMMENT_POINTS points (very dense
+// drawings) continue into the following records: the point count and the
+// points are stored contiguously, padded to a whole number of records
+func CommentRecords(points int) int {
+	records := (4 + points*4 + COMMENT_RECORD_SIZE - 1) / COMMENT_RECORD_SIZE
+	if records < 1 {
+		return 1
+	}
+	return records
+}
+
 // Decode and return a sequence of instructions read from the
 // passed in reader. The reader must be correctly positioned
-// so that the first word read contains the instruction count
-func DecodeInstructions(reader io.Reader) (Instructions, error) {
-	var length uint32
+// so that the first word read contains the instruction count.
+// Counts larger than MAX_INSTRUCTIONS (e.g. in a corrupt profile)
+// return an error wrapping ErrTooManyInstructions
+func DecodeInstructions(reader io.Reader, opts ...DecodeOption) (Instructions, error) {
+	return DecodeInstructionsContext(context.Background(), reader, opts...)
+}
 
-	if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+// Like DecodeInstructions, but stops decoding and returns the context's
+// error if ctx is cancelled
+func DecodeInstructionsContext(ctx context.Context, reader io.Reader, opts ...DecodeOption) (Instructions, error) {
+	options := newDecodeOptions(opts)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var word [4]byte
+	if _, err := io.ReadFull(reader, word[:]); err != nil {
+		return nil, err
+	}
+	length := binary.LittleEndian.Uint32(word[:])
+	if err := checkInstructionCount(length); err != nil {
+		return nil, err
+	}
+
+	// Read all instructions in one go and decode them in place
+	buffer := make([]byte, length*instructionSize)
+	if _, err := io.ReadFull(reader, buffer); err != nil {
 		return nil, err
 	}
-	buffer := make([]byte, 4*4)
 	instructions := make(Instructions, length)
-	for i := uint32(0); i < length; i++ {
-		if _, err := reader.Read(buffer); err != nil {
-			return nil, err
-		}
-		b := bytes.NewBuffer(buffer)
-		if err := binary.Read(b, binary.LittleEndian, &instructions[i]); err != nil {
-			return nil, err
-		}
+	for i := range instructions {
+		instructions[i] = decodeInstruction(buffer[i*instructionSize : (i+1)*instructionSize])
 	}
+	options.logger.Debug("decoded instructions", "count", length)
 
 	return instructions, nil
 }
 
+// Decode a single instruction from its binary representation
+func decodeInstruction(b []byte) Instruction {
+	return Instruction{
+		Comment: binary.LittleEndian.Uint32(b[0:4]),
+		Op:      binary.LittleEndian.Uint32(b[4:8]),
+		Mode:    binary.LittleEndian.Uint32(b[8:12]),
+		Arg:     binary.LittleEndian.Uint32(b[12:16]),
+	}
+}
+
+// Decode and return a sequence of instructions read from r starting at
+// offset, which must be the offset of the instruction count. Decoding stops
+// and the context's error is returned if ctx is cancelled. Unlike
+// DecodeInstructions, r may be shared by concurrent callers
+func DecodeInstructionsAt(ctx context.Context, r io.ReaderAt, offset int64, opts ...DecodeOption) (Instructions, error) {
+	return DecodeInstructionsContext(ctx, io.NewSectionReader(r, offset, INSTRUCTIONS_BLOCK_SIZE), opts...)
+}
+
 // Given a label, return the next label. The starting label for Human
 // Resource Machine programs should be "a"
 func NextLabel(label string) string {
@@ -76,7 +181,7 @@ func NextLabel(label string) string {
 		} else {
 			carry = 0
 		}
-		new_label = string(digit) + new_label
+		new_label = string(rune(digit)) + new_label
 	}
 	if carry == 1 {
 		new_label = "a" + new_label
@@ -110,20 +215,49 @@ type RawComments []RawComment
 // Decode binary comments found in reader into "raw" comments. RawComments
 // are useful when rendering the comments back to a textual Human Resource
 // Machine program representation. The reader must be correctly positioned
-// so that the first word read contains the comment count
-func DecodeRawComments(reader io.ReadSeeker) (RawComments, error) {
+// so that the first word read contains the comment count. Counts and
+// lengths which overflow the comment block (e.g. in a corrupt profile)
+// return an error wrapping ErrTooManyComments
+func DecodeRawComments(reader io.ReadSeeker, opts ...DecodeOption) (RawComments, error) {
+	return DecodeRawCommentsContext(context.Background(), reader, opts...)
+}
+
+// Like DecodeRawComments, but stops decoding and returns the context's
+// error if ctx is cancelled
+func DecodeRawCommentsContext(ctx context.Context, reader io.ReadSeeker, opts ...DecodeOption) (RawComments, error) {
+	options := newDecodeOptions(opts)
 	var commentsLength uint32
 
 	if err := binary.Read(reader, binary.LittleEndian, &commentsLength); err != nil {
 		return nil, err
 	}
+	if err := checkCommentCount(commentsLength); err != nil {
+		return nil, err
+	}
+	// The bytes of the comment block taken so far, checked before each
+	// comment is allocated
+	used := 4
 	comments := make(RawComments, commentsLength)
 	for commentIdx := uint32(0); commentIdx < commentsLength; commentIdx++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if used >= COMMENTS_BLOCK_SIZE {
+			return nil, fmt.Errorf("%w: comment %d of %d starts after the end of the comment block", ErrTooManyComments, commentIdx, commentsLength)
+		}
 		var commentLength uint32
 
 		if err := binary.Read(reader, binary.LittleEndian, &commentLength); err != nil {
 			return nil, err
 		}
+		if int(commentLength) > (COMMENTS_BLOCK_SIZE-used-4)/4 {
+			return nil, fmt.Errorf("%w: comment %d of %d points overflows the comment block", ErrTooManyComments, commentIdx, commentLength)
+		}
+		records := CommentRecords(int(commentLength))
+		used += records * COMMENT_RECORD_SIZE
+		if records > 1 {
+			options.logger.Debug("comment spans multiple records", "comment", commentIdx, "length", commentLength, "records", records)
+		}
 		comments[commentIdx] = make(RawComment, commentLength)
 		var i uint32
 		for i = 0; i < commentLength; i++ {
@@ -131,12 +265,23 @@ func DecodeRawComments(reader io.ReadSeeker) (RawComments, error) {
 				return nil, err
 			}
 		}
-		skip := int64(1024 - commentLength*4)
-		reader.Seek(skip, io.SeekCurrent)
+		skip := int64(records*COMMENT_RECORD_SIZE) - 4 - int64(commentLength)*4
+		if _, err := reader.Seek(skip, io.SeekCurrent); err != nil {
+			return nil, err
+		}
 	}
+	options.logger.Debug("decoded comments", "count", commentsLength)
 	return comments, nil
 }
 
+// Decode binary comments read from r starting at offset, which must be
+// the offset of the comment count. Decoding stops and the context's error
+// is returned if ctx is cancelled. Unlike DecodeRawComments, r may be
+// shared by concurrent callers
+func DecodeRawCommentsAt(ctx context.Context, r io.ReaderAt, offset int64, opts ...DecodeOption) (RawComments, error) {
+	return DecodeRawCommentsContext(ctx, io.NewSectionReader(r, offset, COMMENTS_BLOCK_SIZE), opts...)
+}
+
 // A point in a comment, represented by an X and Y coordinate
 // decoded from the RawComment
 type CommentPoint struct {
@@ -155,8 +300,11 @@ type Comment []CommentLine
 type Comments []Comment
 
 // Decode a sequence of RawComments into Comments. Comments
-// are useful when, for example, rendering the comments
-func DecodeComments(rawComments RawComments) (Comments, error) {
+// are useful when, for example, rendering the comments. The coordinate
+// space of the points is set with the CommentCoordinates, FlipCommentY
+// and NormalizeComments options
+func DecodeComments(rawComments RawComments, opts ...DecodeOption) (Comments, error) {
+	options := newDecodeOptions(opts)
 	comments := make(Comments, len(rawComments))
 	for i, rawComment := range rawComments {
 		comment := make(Comment, 0, len(rawComment)/2)
@@ -174,6 +322,10 @@ func DecodeComments(rawComments RawComments) (Comments, error) {
 			}
 			line = append(line, point)
 		}
+		if options.normalizeComments {
+			comment.normalize()
+		}
+		comment.moveOrigin(options.commentOrigin)
 		comments[i] = comment
 	}
 
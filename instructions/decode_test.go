@@ -0,0 +1,106 @@
+package instructions
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// Return a comment block holding the little endian words, padded to
+// COMMENTS_BLOCK_SIZE
+func commentBlock(words ...uint32) []byte {
+	block := make([]byte, COMMENTS_BLOCK_SIZE)
+	for i, word := range words {
+		binary.LittleEndian.PutUint32(block[i*4:], word)
+	}
+	return block
+}
+
+func TestDecodeRawCommentsCorruptCount(t *testing.T) {
+	for _, count := range []uint32{MAX_COMMENTS + 1, 0x7fffffff, 0xffffffff} {
+		_, err := DecodeRawComments(bytes.NewReader(commentBlock(count)))
+		if !errors.Is(err, ErrTooManyComments) {
+			t.Errorf("count 0x%x: got error %v, want ErrTooManyComments", count, err)
+		}
+	}
+}
+
+func TestDecodeRawCommentsCorruptLength(t *testing.T) {
+	// One comment filling the whole block, then one which cannot fit
+	full := uint32((COMMENTS_BLOCK_SIZE - 4 - 4) / 4)
+	tests := []struct {
+		name  string
+		words []uint32
+	}{
+		{"huge length", []uint32{1, 0x7fffffff}},
+		{"one point too many", []uint32{1, full + 1}},
+		{"second comment overflows", []uint32{2, full}},
+	}
+	for _, test := range tests {
+		_, err := DecodeRawCommentsAt(context.Background(), bytes.NewReader(commentBlock(test.words...)), 0)
+		if !errors.Is(err, ErrTooManyComments) {
+			t.Errorf("%s: got error %v, want ErrTooManyComments", test.name, err)
+		}
+	}
+}
+
+func TestDecodeRawCommentsFull(t *testing.T) {
+	full := uint32((COMMENTS_BLOCK_SIZE - 4 - 4) / 4)
+	comments, err := DecodeRawComments(bytes.NewReader(commentBlock(1, full)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 || len(comments[0]) != int(full) {
+		t.Errorf("got %d comment(s), want 1 of %d points", len(comments), full)
+	}
+
+	comments, err = DecodeRawComments(bytes.NewReader(commentBlock(MAX_COMMENTS)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != MAX_COMMENTS {
+		t.Errorf("got %d comment(s), want %d", len(comments), MAX_COMMENTS)
+	}
+}
+
+// Return an instruction block of count instructions
+func benchmarkInstructionBlock(b *testing.B, count int) []byte {
+	program := make(Instructions, count)
+	for i := range program {
+		program[i] = Instruction{Op: OP_COPY_TO, Arg: uint32(i % 25)}
+	}
+	tab, err := EncodeTab(program, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return tab[:INSTRUCTIONS_BLOCK_SIZE]
+}
+
+func BenchmarkDecodeInstructions(b *testing.B) {
+	block := benchmarkInstructionBlock(b, maxBlockInstructions)
+	reader := bytes.NewReader(block)
+	b.SetBytes(int64(len(block)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader.Reset(block)
+		if _, err := DecodeInstructions(reader); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeInstructionsAt(b *testing.B) {
+	block := benchmarkInstructionBlock(b, maxBlockInstructions)
+	reader := bytes.NewReader(block)
+	b.SetBytes(int64(len(block)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeInstructionsAt(context.Background(), reader, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package instructions
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// A Decoder reads the instructions of an instruction block one at a time,
+// without materializing the whole block
+type Decoder struct {
+	reader  io.Reader
+	options decodeOptions
+	started bool
+	err     error
+	count   uint32
+	read    uint32
+	buffer  [instructionSize]byte
+}
+
+// Return a decoder reading an instruction block from r. The reader must be
+// correctly positioned so that the first word read contains the instruction
+// count. Once all instructions have been read r is positioned directly after
+// the last instruction, so a new Decoder can be created for the next block
+// of concatenated (unpadded) blocks
+func NewDecoder(r io.Reader, opts ...DecodeOption) *Decoder {
+	return &Decoder{reader: r, options: newDecodeOptions(opts)}
+}
+
+func (d *Decoder) start() error {
+	if d.started {
+		return d.err
+	}
+	var word [4]byte
+	if _, err := io.ReadFull(d.reader, word[:]); err != nil {
+		return err
+	}
+	d.started = true
+	d.count = binary.LittleEndian.Uint32(word[:])
+	d.err = checkInstructionCount(d.count)
+	return d.err
+}
+
+// Return the number of instructions in the block, reading the instruction
+// count if required
+func (d *Decoder) Count() (int, error) {
+	if err := d.start(); err != nil {
+		return 0, err
+	}
+	return int(d.count), nil
+}
+
+// Return the next instruction, or io.EOF once all instructions of the
+// block have been read. A block truncated before the instruction count
+// or within an instruction returns io.ErrUnexpectedEOF
+func (d *Decoder) Next() (Instruction, error) {
+	if err := d.start(); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Instruction{}, err
+	}
+	if d.read >= d.count {
+		return Instruction{}, io.EOF
+	}
+	if _, err := io.ReadFull(d.reader, d.buffer[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Instruction{}, err
+	}
+	d.read++
+	return decodeInstruction(d.buffer[:]), nil
+}
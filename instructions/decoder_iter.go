@@ -0,0 +1,24 @@
+//go:build go1.23
+
+package instructions
+
+import (
+	"io"
+	"iter"
+)
+
+// Return an iterator over the remaining instructions of the block. A
+// decoding error is yielded once, ending the iteration
+func (d *Decoder) All() iter.Seq2[Instruction, error] {
+	return func(yield func(Instruction, error) bool) {
+		for {
+			instruction, err := d.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(instruction, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package instructions
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A program can not be decompiled because an instruction was not
+// disassembled or a jump has no target
+var ErrNotDecompilable = errors.New("program has undisassembled instructions or jumps without a target")
+
+// Decompile a program into hrmc source (see Compile), which compiles back
+// to an equivalent program (see Equivalent). Each tile used is declared as
+// a variable named after its number (tile 3 is t3), and the instructions
+// which compute a value and then store, output or test it are combined
+// into one statement, e.g. t3 = inbox + t1. Jumps become goto statements
+// and comments become # comment N lines, without their drawings
+func Decompile(d Disassembled) (string, error) {
+	tiles := make(map[uint32]bool)
+	labels := make(map[string]string)
+	for _, diss := range d {
+		switch diss := diss.(type) {
+		case nil:
+			return "", ErrNotDecompilable
+		case DisassembleArgInstruction:
+			tiles[diss.Arg] = true
+		case DisassembleJumpInstruction:
+			if diss.TargetLabel == "" {
+				return "", ErrNotDecompilable
+			}
+		case DisassembleJumpTarget:
+			labels[diss.Label] = diss.Label
+			if compileKeywords[diss.Label] {
+				labels[diss.Label] = diss.Label + "_"
+			}
+		}
+	}
+
+	var builder strings.Builder
+	var declared []int
+	for tile := range tiles {
+		declared = append(declared, int(tile))
+	}
+	sort.Ints(declared)
+	for _, tile := range declared {
+		fmt.Fprintf(&builder, "var t%d @ %d\n", tile, tile)
+	}
+	if len(declared) > 0 {
+		builder.WriteString("\n")
+	}
+
+	// The expression computed into the hand but not yet used
+	expression := ""
+	statement := func(format string, args ...interface{}) {
+		fmt.Fprintf(&builder, format+"\n", args...)
+	}
+	flush := func() {
+		switch {
+		case expression == "":
+		case strings.HasPrefix(expression, "++") || strings.HasPrefix(expression, "--"):
+			if !strings.Contains(expression, " ") {
+				// A bump on its own
+				statement("    %s", expression)
+				break
+			}
+			fallthrough
+		default:
+			statement("    hand = %s", expression)
+		}
+		expression = ""
+	}
+	use := func() string {
+		e := expression
+		if e == "" {
+			e = "hand"
+		}
+		expression = ""
+		return e
+	}
+	operand := func(inst DisassembleArgInstruction) string {
+		if inst.Indirect {
+			return fmt.Sprintf("*t%d", inst.Arg)
+		}
+		return fmt.Sprintf("t%d", inst.Arg)
+	}
+
+	for _, diss := range d {
+		switch diss := diss.(type) {
+		case DisassembleComment:
+			flush()
+			statement("    # comment %d", diss.Index)
+		case DisassembleJumpTarget:
+			flush()
+			statement("%s:", labels[diss.Label])
+		case DisassembleJumpInstruction:
+			label := labels[diss.TargetLabel]
+			switch diss.Op {
+			case OP_JUMP:
+				flush()
+				statement("    goto %s", label)
+			case OP_JUMP_ZERO:
+				statement("    if %s == 0 goto %s", use(), label)
+			case OP_JUMP_NEG:
+				statement("    if %s < 0 goto %s", use(), label)
+			}
+		case DisassembleArgInstruction:
+			switch diss.Op {
+			case OP_COPY_FROM:
+				flush()
+				expression = operand(diss)
+			case OP_BUMP_PLUS:
+				flush()
+				expression = "++" + operand(diss)
+			case OP_BUMP_MINUS:
+				flush()
+				expression = "--" + operand(diss)
+			case OP_ADD:
+				expression = use() + " + " + operand(diss)
+			case OP_SUB:
+				expression = use() + " - " + operand(diss)
+			case OP_COPY_TO:
+				statement("    %s = %s", operand(diss), use())
+			}
+		case DisassembleInstruction:
+			switch diss.Op {
+			case OP_INBOX:
+				flush()
+				expression = "inbox"
+			case OP_OUTBOX:
+				statement("    outbox %s", use())
+			}
+		}
+	}
+	flush()
+	return builder.String(), nil
+}
+
+// Report whether two programs are equivalent, i.e. behave identically
+// for every inbox and floor. The programs are compared instruction by
+// instruction, ignoring comments, the names of labels and COPYFROMs of
+// the tile which the hand is known to hold (e.g. straight after a COPYTO
+// to it), as left out by Compile. Programs which are not reported as
+// equivalent may still behave identically
+func Equivalent(a, b Disassembled) bool {
+	return withoutRedundantCopies(a).Hash() == withoutRedundantCopies(b).Hash()
+}
+
+// Return the program without comments and without the COPYFROMs of the
+// tile the hand is known to hold
+func withoutRedundantCopies(d Disassembled) Disassembled {
+	var program Disassembled
+	// The tile known to hold the value in the hand, as in the compiler
+	hand := ""
+	for _, diss := range d {
+		switch diss := diss.(type) {
+		case DisassembleComment:
+			continue
+		case DisassembleJumpTarget:
+			hand = ""
+		case DisassembleArgInstruction:
+			tile := fmt.Sprintf("%d %t", diss.Arg, diss.Indirect)
+			if diss.Op == OP_COPY_FROM && tile == hand {
+				continue
+			}
+			switch {
+			case diss.Op == OP_COPY_FROM:
+				hand = tile
+			case diss.Indirect:
+				// The write may have changed the pointer, see the compiler
+				hand = ""
+			case diss.Op == OP_COPY_TO, diss.Op == OP_BUMP_PLUS, diss.Op == OP_BUMP_MINUS:
+				hand = tile
+			default:
+				hand = ""
+			}
+		case DisassembleInstruction:
+			hand = ""
+		}
+		program = append(program, diss)
+	}
+	return program
+}
@@ -0,0 +1,188 @@
+package instructions
+
+import (
+	"testing"
+)
+
+// Assemble and disassemble a program
+func disassembled(t *testing.T, text string) Disassembled {
+	t.Helper()
+	program, _, err := Assemble(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Disassemble(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+var decompileTests = []struct {
+	name    string
+	program string
+}{
+	{"mail room", `
+a:
+    INBOX
+    OUTBOX
+    JUMP     a
+`},
+	{"zero exterminator", `
+a:
+b:
+    INBOX
+    JUMPZ    a
+    OUTBOX
+    JUMP     b
+`},
+	{"countdown", `
+a:
+    INBOX
+b:
+    COPYTO   0
+    OUTBOX
+    COPYFROM 0
+    JUMPZ    a
+    JUMPN    c
+    BUMPDN   0
+    JUMP     b
+c:
+    BUMPUP   0
+    JUMP     b
+`},
+	{"multiplication", `
+a:
+    INBOX
+    COPYTO   0
+    INBOX
+    COPYTO   1
+    COPYFROM 9
+    COPYTO   2
+b:
+    COPYFROM 1
+    JUMPZ    c
+    BUMPDN   1
+    COPYFROM 2
+    ADD      0
+    COPYTO   2
+    JUMP     b
+c:
+    COPYFROM 2
+    OUTBOX
+    JUMP     a
+`},
+	{"comments", `
+    COMMENT  0
+a:
+    INBOX
+    COMMENT  1
+    SUB      3
+    OUTBOX
+    JUMP     a
+`},
+	{"indirect", `
+a:
+    INBOX
+    COPYTO   24
+    COPYFROM [24]
+    OUTBOX
+    BUMPUP   [24]
+    COPYTO   [24]
+    COPYFROM [24]
+    ADD      [24]
+    OUTBOX
+    JUMP     a
+`},
+	{"string reverse", `
+a:
+    COPYFROM 14
+    COPYTO   13
+b:
+    INBOX
+    JUMPZ    c
+    COPYTO   [13]
+    BUMPUP   13
+    JUMP     b
+c:
+    BUMPDN   13
+    JUMPN    a
+    COPYFROM [13]
+    OUTBOX
+    JUMP     c
+`},
+}
+
+// Decompiling a program and compiling the result gives an equivalent
+// program
+func TestDecompileRoundTrip(t *testing.T) {
+	for _, test := range decompileTests {
+		t.Run(test.name, func(t *testing.T) {
+			original := disassembled(t, test.program)
+			source, err := Decompile(original)
+			if err != nil {
+				t.Fatal(err)
+			}
+			program, err := Compile(source)
+			if err != nil {
+				t.Fatalf("%v, compiling:\n%s", err, source)
+			}
+			compiled, err := Disassemble(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !Equivalent(original, compiled) {
+				assembly, _ := CompileAssembly(source)
+				t.Errorf("not equivalent, decompiled:\n%s\ncompiled:\n%s", source, assembly)
+			}
+		})
+	}
+}
+
+func TestDecompileNotDecompilable(t *testing.T) {
+	if _, err := Decompile(Disassembled{nil}); err != ErrNotDecompilable {
+		t.Errorf("got %v, expected ErrNotDecompilable", err)
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b       string
+		equivalent bool
+	}{
+		{"labels and comments", "a:\nINBOX\nCOMMENT 0\nOUTBOX\nJUMP a\n", "start:\nINBOX\nOUTBOX\nJUMP start\n", true},
+		{"redundant copy", "INBOX\nCOPYTO 5\nCOPYFROM 5\nOUTBOX\n", "INBOX\nCOPYTO 5\nOUTBOX\n", true},
+		{"redundant indirect read", "COPYFROM [5]\nCOPYFROM [5]\nOUTBOX\n", "COPYFROM [5]\nOUTBOX\n", true},
+		{"copy after a jump target", "INBOX\nCOPYTO 5\na:\nCOPYFROM 5\nOUTBOX\nJUMP a\n", "INBOX\nCOPYTO 5\na:\nOUTBOX\nJUMP a\n", false},
+		// With tile 5 holding 5 the COPYTO changes the pointer, so the
+		// COPYFROM reads another tile
+		{"indirect write", "INBOX\nCOPYTO [5]\nCOPYFROM [5]\nOUTBOX\n", "INBOX\nCOPYTO [5]\nOUTBOX\n", false},
+		{"indirect bump", "BUMPUP [5]\nCOPYFROM [5]\nOUTBOX\n", "BUMPUP [5]\nOUTBOX\n", false},
+		{"other tile", "INBOX\nCOPYTO 5\nOUTBOX\n", "INBOX\nCOPYTO 6\nOUTBOX\n", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if equivalent := Equivalent(disassembled(t, test.a), disassembled(t, test.b)); equivalent != test.equivalent {
+				t.Errorf("Equivalent = %v, expected %v", equivalent, test.equivalent)
+			}
+		})
+	}
+}
+
+// The compiler must not leave out reading a tile through a pointer after
+// writing through it, the write may have changed the pointer
+func TestCompileIndirectAlias(t *testing.T) {
+	program, err := Compile("var t5 @ 5\n*t5 = inbox\noutbox *t5\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := disassembled(t, "INBOX\nCOPYTO [5]\nCOPYFROM [5]\nOUTBOX\n")
+	compiled, err := Disassemble(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compiled.Hash() != expected.Hash() {
+		t.Errorf("compiled %v, expected %v", compiled, expected)
+	}
+}
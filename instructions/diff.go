@@ -0,0 +1,220 @@
+package instructions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The kind of a line of a Diff
+type DiffOp int
+
+const (
+	// The line is present in both programs
+	DIFF_EQUAL DiffOp = iota
+	// The line is only present in the older program
+	DIFF_DELETE
+	// The line is only present in the newer program
+	DIFF_INSERT
+)
+
+// A line of a Diff. Older and Newer are the indexes of the line in the
+// older and newer disassembly, -1 for inserted and deleted lines
+// respectively
+type DiffLine struct {
+	Op    DiffOp
+	Older int
+	Newer int
+}
+
+// The semantic difference between two disassembled programs
+type Diff struct {
+	Older Disassembled
+	Newer Disassembled
+	Lines []DiffLine
+}
+
+// The part of a disassembled instruction compared when aligning programs.
+// Jumps are compared by opcode only, their targets are checked once the
+// programs are aligned
+func diffKey(diss DisassembleInterface) (string, bool) {
+	switch diss := diss.(type) {
+	case DisassembleJumpTarget:
+		return ":", true
+	case DisassembleJumpInstruction:
+		return fmt.Sprintf("%d", diss.Op), true
+	case DisassembleArgInstruction:
+		return fmt.Sprintf("%d %d %t", diss.Op, diss.Arg, diss.Indirect), true
+	case DisassembleInstruction:
+		return fmt.Sprintf("%d", diss.Op), true
+	}
+	return "", false
+}
+
+// Compare two disassembled programs instruction by instruction, ignoring
+// comments and the names of labels. Instructions are aligned by opcode and
+// argument, and aligned jumps are equal when they jump to aligned
+// targets, so a relabeled but otherwise identical program has no
+// differences
+func DiffDisassembled(older, newer Disassembled) Diff {
+	return diffDisassembled(older, newer, diffKey, diffKey)
+}
+
+// Compare two disassembled programs, aligning the instructions with equal
+// keys. Instructions without a key are ignored
+func diffDisassembled(older, newer Disassembled, olderKey, newerKey func(DisassembleInterface) (string, bool)) Diff {
+	var olderIndex, newerIndex []int
+	var olderKeys, newerKeys []string
+	for i, diss := range older {
+		if key, ok := olderKey(diss); ok {
+			olderIndex = append(olderIndex, i)
+			olderKeys = append(olderKeys, key)
+		}
+	}
+	for i, diss := range newer {
+		if key, ok := newerKey(diss); ok {
+			newerIndex = append(newerIndex, i)
+			newerKeys = append(newerKeys, key)
+		}
+	}
+
+	// Longest common subsequence of the keys
+	n, m := len(olderKeys), len(newerKeys)
+	lengths := make([][]int, n+1)
+	for i := range lengths {
+		lengths[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case olderKeys[i] == newerKeys[j]:
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	var lines []DiffLine
+	aligned := make(map[int]int)
+	for i, j := 0, 0; i < n || j < m; {
+		switch {
+		case i < n && j < m && olderKeys[i] == newerKeys[j]:
+			lines = append(lines, DiffLine{DIFF_EQUAL, olderIndex[i], newerIndex[j]})
+			aligned[olderIndex[i]] = newerIndex[j]
+			i++
+			j++
+		case j == m || (i < n && lengths[i+1][j] >= lengths[i][j+1]):
+			lines = append(lines, DiffLine{DIFF_DELETE, olderIndex[i], -1})
+			i++
+		default:
+			lines = append(lines, DiffLine{DIFF_INSERT, -1, newerIndex[j]})
+			j++
+		}
+	}
+
+	// Aligned jumps to targets which are not aligned with each other
+	// differ
+	diff := Diff{Older: older, Newer: newer}
+	for _, line := range lines {
+		if line.Op == DIFF_EQUAL {
+			olderJump, olderIsJump := older[line.Older].(DisassembleJumpInstruction)
+			newerJump, newerIsJump := newer[line.Newer].(DisassembleJumpInstruction)
+			if olderIsJump && newerIsJump {
+				if target, found := aligned[olderJump.Target]; !found || target != newerJump.Target {
+					diff.Lines = append(diff.Lines,
+						DiffLine{DIFF_DELETE, line.Older, -1},
+						DiffLine{DIFF_INSERT, -1, line.Newer})
+					continue
+				}
+			}
+		}
+		diff.Lines = append(diff.Lines, line)
+	}
+	return diff
+}
+
+// Report whether the programs compared have no differences
+func (d Diff) Equal() bool {
+	for _, line := range d.Lines {
+		if line.Op != DIFF_EQUAL {
+			return false
+		}
+	}
+	return true
+}
+
+// Format a disassembled instruction as a line of assembly
+func formatDiffLine(diss DisassembleInterface, mnemonics *Mnemonics) string {
+	switch diss := diss.(type) {
+	case DisassembleJumpTarget:
+		return diss.Label + ":"
+	case DisassembleJumpInstruction:
+		return mnemonics.Name(diss.Op) + " " + diss.TargetLabel
+	case DisassembleArgInstruction:
+		if diss.Indirect {
+			return fmt.Sprintf("%s [%d]", mnemonics.Name(diss.Op), diss.Arg)
+		}
+		return fmt.Sprintf("%s %d", mnemonics.Name(diss.Op), diss.Arg)
+	case DisassembleInstruction:
+		return mnemonics.Name(diss.Op)
+	}
+	return ""
+}
+
+// Return the difference in the style of a unified diff, with the labels
+// of the older program on deleted lines and of the newer program
+// elsewhere. A nil mnemonics uses the default mnemonics
+func (d Diff) Text(mnemonics *Mnemonics) string {
+	if mnemonics == nil {
+		mnemonics = DefaultMnemonics()
+	}
+	var builder strings.Builder
+	for _, line := range d.Lines {
+		switch line.Op {
+		case DIFF_EQUAL:
+			fmt.Fprintf(&builder, "  %s\n", formatDiffLine(d.Newer[line.Newer], mnemonics))
+		case DIFF_DELETE:
+			fmt.Fprintf(&builder, "- %s\n", formatDiffLine(d.Older[line.Older], mnemonics))
+		case DIFF_INSERT:
+			fmt.Fprintf(&builder, "+ %s\n", formatDiffLine(d.Newer[line.Newer], mnemonics))
+		}
+	}
+	return builder.String()
+}
+
+// Return, for each disassembled instruction of the last of versions (the
+// versions of a program from oldest to newest), the index of the version
+// in which the instruction last changed as by DiffDisassembled.
+// Instructions unchanged since the first version are attributed to it.
+// Comments and instructions which were not disassembled are -1
+func Blame(versions []Disassembled) []int {
+	if len(versions) == 0 {
+		return nil
+	}
+	blame := make([]int, len(versions[0]))
+	for i, diss := range versions[0] {
+		blame[i] = -1
+		if _, ok := diffKey(diss); ok {
+			blame[i] = 0
+		}
+	}
+	for version := 1; version < len(versions); version++ {
+		next := make([]int, len(versions[version]))
+		for i := range next {
+			next[i] = -1
+		}
+		diff := DiffDisassembled(versions[version-1], versions[version])
+		for _, line := range diff.Lines {
+			switch line.Op {
+			case DIFF_EQUAL:
+				next[line.Newer] = blame[line.Older]
+			case DIFF_INSERT:
+				next[line.Newer] = version
+			}
+		}
+		blame = next
+	}
+	return blame
+}
@@ -0,0 +1,187 @@
+package instructions
+
+import (
+	"reflect"
+	"testing"
+)
+
+var diffTests = []struct {
+	name  string
+	older string
+	newer string
+	diff  string // Empty when the programs have no differences
+}{
+	{"relabeled", `
+start:
+    INBOX
+loop:
+    JUMPZ    start
+    OUTBOX
+    JUMP     loop
+`, `
+x:
+    INBOX
+y:
+    JUMPZ    x
+    OUTBOX
+    JUMP     y
+`, ""},
+	{"comments ignored", `
+a:
+    INBOX
+    OUTBOX
+    JUMP     a
+`, `
+    COMMENT  0
+a:
+    INBOX
+    COMMENT  1
+    OUTBOX
+    JUMP     a
+`, ""},
+	{"insert", `
+a:
+    INBOX
+    OUTBOX
+    JUMP     a
+`, `
+a:
+    INBOX
+    COPYTO   0
+    OUTBOX
+    JUMP     a
+`, `  a:
+  INBOX
++ COPYTO 0
+  OUTBOX
+  JUMP a
+`},
+	{"delete", `
+a:
+    INBOX
+    COPYTO   0
+    OUTBOX
+    JUMP     a
+`, `
+a:
+    INBOX
+    OUTBOX
+    JUMP     a
+`, `  a:
+  INBOX
+- COPYTO 0
+  OUTBOX
+  JUMP a
+`},
+	{"replace", `
+a:
+    INBOX
+    COPYTO   0
+    OUTBOX
+    JUMP     a
+`, `
+a:
+    INBOX
+    COPYTO   [0]
+    OUTBOX
+    JUMP     a
+`, `  a:
+  INBOX
+- COPYTO 0
++ COPYTO [0]
+  OUTBOX
+  JUMP a
+`},
+	{"jump targets renumbered", `
+a:
+    INBOX
+b:
+    JUMPZ    a
+    OUTBOX
+    JUMP     b
+`, `
+    INBOX
+    COPYTO   0
+c:
+    INBOX
+d:
+    JUMPZ    c
+    OUTBOX
+    JUMP     d
+`, `+ INBOX
++ COPYTO 0
+  a:
+  INBOX
+  b:
+  JUMPZ a
+  OUTBOX
+  JUMP b
+`},
+	{"jump retargeted", `
+a:
+    INBOX
+b:
+    OUTBOX
+    JUMP     a
+`, `
+a:
+    INBOX
+b:
+    OUTBOX
+    JUMP     b
+`, `- a:
+  INBOX
++ b:
+  OUTBOX
+- JUMP a
++ JUMP b
+`},
+}
+
+func TestDiffDisassembled(t *testing.T) {
+	for _, test := range diffTests {
+		t.Run(test.name, func(t *testing.T) {
+			diff := DiffDisassembled(disassembled(t, test.older), disassembled(t, test.newer))
+			if diff.Equal() != (test.diff == "") {
+				t.Errorf("Equal() is %v", diff.Equal())
+			}
+			if test.diff == "" {
+				return
+			}
+			if text := diff.Text(nil); text != test.diff {
+				t.Errorf("got\n%s\nexpected\n%s", text, test.diff)
+			}
+		})
+	}
+}
+
+func TestDiffDisassembledLines(t *testing.T) {
+	older := disassembled(t, "a:\nINBOX\nCOPYTO 0\nOUTBOX\nJUMP a\n")
+	newer := disassembled(t, "COMMENT 0\na:\nINBOX\nOUTBOX\nBUMPUP 0\nJUMP a\n")
+	expected := []DiffLine{
+		{DIFF_EQUAL, 0, 1},
+		{DIFF_EQUAL, 1, 2},
+		{DIFF_DELETE, 2, -1},
+		{DIFF_EQUAL, 3, 3},
+		{DIFF_INSERT, -1, 4},
+		{DIFF_EQUAL, 4, 5},
+	}
+	if diff := DiffDisassembled(older, newer); !reflect.DeepEqual(diff.Lines, expected) {
+		t.Errorf("got %v, expected %v", diff.Lines, expected)
+	}
+}
+
+func TestBlame(t *testing.T) {
+	versions := []Disassembled{
+		disassembled(t, "a:\nINBOX\nOUTBOX\nJUMP a\n"),
+		disassembled(t, "a:\nINBOX\nCOPYTO 0\nOUTBOX\nJUMP a\n"),
+		disassembled(t, "COMMENT 0\na:\nINBOX\nCOPYTO 0\nBUMPUP 0\nOUTBOX\nJUMP a\n"),
+	}
+	expected := []int{-1, 0, 0, 1, 2, 0, 0}
+	if blame := Blame(versions); !reflect.DeepEqual(blame, expected) {
+		t.Errorf("got %v, expected %v", blame, expected)
+	}
+	if blame := Blame(nil); blame != nil {
+		t.Errorf("got %v for no versions", blame)
+	}
+}
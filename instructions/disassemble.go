@@ -1,5 +1,12 @@
 package instructions
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
 // A type representing an opcode to instruction mnemonic map
 type instrunctionMnemonics map[OpCode]string
 
@@ -90,12 +97,24 @@ func (o OpCode) String() string {
 
 // An instruction taking no arguments (implements DisassembleInterface)
 type DisassembleInstruction struct {
-	Line int
-	Op   OpCode
+	LineNumber int
+	Op         OpCode
 }
 
 func (d DisassembleInstruction) isDissasemble() {}
 
+// Return the line number of the instruction, as shown in the game
+func (d DisassembleInstruction) Line() int {
+	return d.LineNumber
+}
+
+// Implemented by the disassembled instructions which have a line number
+// (i.e. not comments and jump targets)
+type LineNumbered interface {
+	DisassembleInterface
+	Line() int
+}
+
 // An jump instruction (implements DisassembleInterface)
 type DisassembleJumpInstruction struct {
 	DisassembleInstruction
@@ -117,17 +136,93 @@ func (d DisassembleArgInstruction) isDissasemble() {}
 // A list of disassembled instructions
 type Disassembled []DisassembleInterface
 
-// Given a sequence of instructions, return the disassembled
-// instructions
-func Disassemble(instructions Instructions) Disassembled {
+// A Visitor is called for each kind of disassembled instruction by
+// Disassembled.Walk, with the index of the instruction
+type Visitor interface {
+	VisitComment(index int, comment DisassembleComment)
+	VisitJumpTarget(index int, target DisassembleJumpTarget)
+	VisitInstruction(index int, instruction DisassembleInstruction)
+	VisitArgInstruction(index int, instruction DisassembleArgInstruction)
+	VisitJumpInstruction(index int, instruction DisassembleJumpInstruction)
+	// Called for instructions that were not disassembled (nil)
+	VisitMissing(index int)
+}
+
+// Call the method of v matching each disassembled instruction in order
+func (d Disassembled) Walk(v Visitor) {
+	for i, diss := range d {
+		switch diss := diss.(type) {
+		case DisassembleComment:
+			v.VisitComment(i, diss)
+		case DisassembleJumpTarget:
+			v.VisitJumpTarget(i, diss)
+		case DisassembleJumpInstruction:
+			v.VisitJumpInstruction(i, diss)
+		case DisassembleArgInstruction:
+			v.VisitArgInstruction(i, diss)
+		case DisassembleInstruction:
+			v.VisitInstruction(i, diss)
+		default:
+			v.VisitMissing(i)
+		}
+	}
+}
+
+// An invalid jump found while disassembling
+type DisassembleError struct {
+	// The index of the jump instruction
+	Index int
+	// The index the jump instruction jumps to
+	Target uint32
+	Err    error
+}
+
+func (e *DisassembleError) Error() string {
+	return fmt.Sprintf("instruction %d: %v (target %d)", e.Index, e.Err, e.Target)
+}
+
+func (e *DisassembleError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// A jump targets an index outside of the program
+	ErrJumpOutOfRange = errors.New("jump target outside of the program")
+	// A jump targets an instruction which is not a jump target
+	ErrJumpNotTarget = errors.New("jump to an instruction which is not a jump target")
+)
+
+// Given a sequence of instructions, return the disassembled instructions.
+// The disassembly is index aligned with instructions; instructions which
+// are not disassembled (e.g. unknown opcodes) are nil.
+//
+// Jumps which do not target a jump target are reported as a
+// *DisassembleError. The first error is returned together with a best
+// effort disassembly, in which the invalid jump has no target
+func Disassemble(instructions Instructions, opts ...DisassembleOption) (Disassembled, error) {
+	var options disassembleOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	var firstErr error
 	labels := MakeLabels(instructions)
 	disassembled := make(Disassembled, len(instructions))
+	if options.keepUnreachableTargets {
+		for i, inst := range instructions {
+			if inst.Comment == 0 && OpCode(inst.Op) == OP_JUMP_TGT {
+				disassembled[i] = DisassembleJumpTarget{labels[uint32(i)], -1}
+			}
+		}
+	}
 	instNum := 1
 	for i, inst := range instructions {
 		opCode := OpCode(inst.Op)
 		switch {
 		case inst.Comment > 0:
 			disassembled[i] = DisassembleComment{inst.Op}
+			if options.lineNumberComments {
+				instNum++
+			}
 			continue // Does not increment instNum
 		case opCode == OP_JUMP_TGT:
 			continue // Set by JUMP instruction; Does not increment instNum
@@ -135,7 +230,22 @@ func Disassemble(instructions Instructions) Disassembled {
 			label := labels[inst.Arg]
 			disassembled[i] = DisassembleJumpInstruction{
 				DisassembleInstruction{instNum, opCode}, label, int(inst.Arg)}
-			disassembled[inst.Arg] = DisassembleJumpTarget{label, i}
+			var err error
+			switch {
+			case inst.Arg >= uint32(len(instructions)):
+				err = ErrJumpOutOfRange
+			case instructions[inst.Arg].Comment > 0 || OpCode(instructions[inst.Arg].Op) != OP_JUMP_TGT:
+				err = ErrJumpNotTarget
+			default:
+				disassembled[inst.Arg] = DisassembleJumpTarget{label, i}
+			}
+			if err != nil {
+				disassembled[i] = DisassembleJumpInstruction{
+					DisassembleInstruction{instNum, opCode}, "", -1}
+				if firstErr == nil {
+					firstErr = &DisassembleError{i, inst.Arg, err}
+				}
+			}
 		case InstructionsWithArg.Member(opCode):
 			disassembled[i] = DisassembleArgInstruction{
 				DisassembleInstruction{instNum, opCode}, inst.Arg, inst.Mode == MODE_INDIRECT}
@@ -146,5 +256,76 @@ func Disassemble(instructions Instructions) Disassembled {
 		instNum++
 	}
 
-	return disassembled
+	return disassembled, firstErr
+}
+
+// Return the size of the program as counted by Human Resource Machine,
+// i.e. the number of instructions excluding comments and jump targets
+func (d Disassembled) Size() int {
+	size := 0
+	for _, diss := range d {
+		switch diss.(type) {
+		case DisassembleComment, DisassembleJumpTarget, nil:
+		default:
+			size++
+		}
+	}
+	return size
+}
+
+// Return the index range [start, end) of the instructions with the line
+// numbers from to to (inclusive), including the comments and jump targets
+// preceding them. A to of 0 (or less) is the last line. The range is
+// empty if no instruction is in range
+func (d Disassembled) LineRange(from, to int) (start, end int) {
+	last := -1
+	for i, diss := range d {
+		numbered, ok := diss.(LineNumbered)
+		if !ok {
+			continue
+		}
+		switch line := numbered.Line(); {
+		case line < from:
+			start = i + 1
+		case to > 0 && line > to:
+			if last < 0 {
+				return start, start
+			}
+			return start, last + 1
+		default:
+			last = i
+		}
+	}
+	if last < 0 {
+		return start, start
+	}
+	return start, len(d)
+}
+
+// Return a canonical hash of the program. The hash is computed over a
+// normalized form of the instructions which ignores comments and assigns
+// labels in order of appearance, so two tabs containing the same program
+// have the same hash regardless of their comments
+func (d Disassembled) Hash() string {
+	hash := sha256.New()
+	labels := make(map[string]int)
+	label := func(name string) int {
+		if _, found := labels[name]; !found {
+			labels[name] = len(labels)
+		}
+		return labels[name]
+	}
+	for _, diss := range d {
+		switch diss := diss.(type) {
+		case DisassembleJumpTarget:
+			fmt.Fprintf(hash, "%d:\n", label(diss.Label))
+		case DisassembleJumpInstruction:
+			fmt.Fprintf(hash, "%d %d\n", diss.Op, label(diss.TargetLabel))
+		case DisassembleArgInstruction:
+			fmt.Fprintf(hash, "%d %d %t\n", diss.Op, diss.Arg, diss.Indirect)
+		case DisassembleInstruction:
+			fmt.Fprintf(hash, "%d\n", diss.Op)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
 }
@@ -0,0 +1,98 @@
+package instructions
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// The comments of a program or comment block do not fit in the comment
+// block of a tab
+var ErrTooManyComments = errors.New("comments do not fit in the comment block")
+
+// Encode a program into the instruction and comment blocks of a tab, i.e.
+// INSTRUCTIONS_BLOCK_SIZE + COMMENTS_BLOCK_SIZE bytes, as stored in the
+// profile. In the game every jump has a jump target of its own, so targets
+// shared by several jumps (e.g. as assembled from a label jumped to more
+// than once) are repeated for each jump, and targets no jump jumps to are
+// left out. Programs which do not fit return an error wrapping
+// ErrTooManyInstructions or ErrTooManyComments
+func EncodeTab(program Instructions, comments RawComments) ([]byte, error) {
+	// The jumps to each jump target, in order
+	jumps := make(map[uint32][]int)
+	for i, inst := range program {
+		if inst.Comment == 0 && InstructionsWithLabel.Member(OpCode(inst.Op)) {
+			jumps[inst.Arg] = append(jumps[inst.Arg], i)
+		}
+	}
+	var encoded Instructions
+	// The index of the jump target of each jump in encoded
+	targets := make(map[int]uint32)
+	for i, inst := range program {
+		if inst.Comment == 0 && OpCode(inst.Op) == OP_JUMP_TGT {
+			for _, jump := range jumps[uint32(i)] {
+				targets[jump] = uint32(len(encoded))
+				encoded = append(encoded, inst)
+			}
+			continue
+		}
+		encoded = append(encoded, inst)
+	}
+	if err := checkInstructionCount(uint32(len(encoded))); err != nil {
+		return nil, err
+	}
+	// Renumber the jumps, which moved as targets were repeated or left out
+	jump := 0
+	for i := range encoded {
+		inst := &encoded[i]
+		if inst.Comment != 0 || !InstructionsWithLabel.Member(OpCode(inst.Op)) {
+			continue
+		}
+		for program[jump].Comment != 0 || !InstructionsWithLabel.Member(OpCode(program[jump].Op)) {
+			jump++
+		}
+		target, found := targets[jump]
+		if !found {
+			return nil, &DisassembleError{jump, program[jump].Arg, ErrJumpNotTarget}
+		}
+		inst.Arg = target
+		jump++
+	}
+
+	tab := make([]byte, INSTRUCTIONS_BLOCK_SIZE+COMMENTS_BLOCK_SIZE)
+	binary.LittleEndian.PutUint32(tab, uint32(len(encoded)))
+	for i, inst := range encoded {
+		word := tab[4+i*instructionSize:]
+		binary.LittleEndian.PutUint32(word[0:], inst.Comment)
+		binary.LittleEndian.PutUint32(word[4:], inst.Op)
+		binary.LittleEndian.PutUint32(word[8:], inst.Mode)
+		binary.LittleEndian.PutUint32(word[12:], inst.Arg)
+	}
+
+	block, err := EncodeComments(comments)
+	if err != nil {
+		return nil, err
+	}
+	copy(tab[INSTRUCTIONS_BLOCK_SIZE:], block)
+	return tab, nil
+}
+
+// Encode comments into the comment block of a tab, i.e.
+// COMMENTS_BLOCK_SIZE bytes, as stored in the profile. Comments which do
+// not fit return ErrTooManyComments
+func EncodeComments(comments RawComments) ([]byte, error) {
+	block := make([]byte, COMMENTS_BLOCK_SIZE)
+	binary.LittleEndian.PutUint32(block, uint32(len(comments)))
+	offset := 4
+	for _, comment := range comments {
+		length := CommentRecords(len(comment)) * COMMENT_RECORD_SIZE
+		if offset+length > len(block) {
+			return nil, ErrTooManyComments
+		}
+		binary.LittleEndian.PutUint32(block[offset:], uint32(len(comment)))
+		for i, point := range comment {
+			copy(block[offset+4+i*4:], point[:])
+		}
+		offset += length
+	}
+	return block, nil
+}
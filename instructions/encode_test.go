@@ -0,0 +1,78 @@
+package instructions
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+const roundTripProgram = `-- HUMAN RESOURCE MACHINE PROGRAM --
+
+    COMMENT  0
+a:
+b:
+    INBOX
+    JUMPZ    c
+    COPYTO   0
+    BUMPUP   [0]
+    OUTBOX
+    JUMP     a
+c:
+    COMMENT  1
+    JUMPN    b
+    JUMP     a
+`
+
+func TestEncodeTabRoundTrip(t *testing.T) {
+	program, _, err := Assemble(roundTripProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A comment of a single record and one continuing into a second
+	long := make(RawComment, maxCommentPoints+10)
+	for i := range long {
+		long[i] = [4]byte{byte(i), byte(i >> 8), 0x40, 0x01}
+	}
+	comments := RawComments{{{0x10, 0, 0x20, 0}, {0, 0, 0, 0}, {0xff, 0x03, 0x7f, 0x02}}, long}
+
+	tab, err := EncodeTab(program, comments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tab) != 46252 {
+		t.Fatalf("encoded tab is %d bytes, want 46252", len(tab))
+	}
+
+	decoded, err := DecodeInstructions(bytes.NewReader(tab))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedComments, err := DecodeRawComments(bytes.NewReader(tab[INSTRUCTIONS_BLOCK_SIZE:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decodedComments, comments) {
+		t.Errorf("decoded comments differ from the encoded ones")
+	}
+	reencoded, err := EncodeTab(decoded, decodedComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reencoded, tab) {
+		for i := range tab {
+			if reencoded[i] != tab[i] {
+				t.Fatalf("re-encoded tab differs at offset 0x%X: got 0x%02X, want 0x%02X", i, reencoded[i], tab[i])
+			}
+		}
+	}
+}
+
+func TestEncodeTabEmpty(t *testing.T) {
+	tab, err := EncodeTab(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tab, make([]byte, INSTRUCTIONS_BLOCK_SIZE+COMMENTS_BLOCK_SIZE)) {
+		t.Errorf("empty tab is not all zeros")
+	}
+}
@@ -0,0 +1,150 @@
+package instructions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// What an instruction does, as effects on the worker's hand, the tile of
+// its argument (tile), the inbox and the outbox
+type Semantics struct {
+	// What the hand and the tile hold afterwards, in terms of what they
+	// held before, "" if unchanged
+	Hand, Tile string
+	// Whether the instruction puts the value in the hand in the outbox
+	Outbox bool
+	// When the instruction jumps, "always" for an unconditional jump and
+	// "" for other instructions
+	Jump string
+	// When the instruction ends the program instead, if ever
+	Ends string
+	// Whether the hand and the tile must hold a value
+	NeedsHand, NeedsTile bool
+	// The values the instruction computes with, "" for any value
+	Values string
+}
+
+// The semantics of the instructions, by opcode (as implemented by the vm
+// package)
+var OpSemantics = map[OpCode]Semantics{
+	OP_INBOX:      {Hand: "the next value in the inbox", Ends: "the inbox is empty"},
+	OP_OUTBOX:     {Hand: "nothing", Outbox: true, NeedsHand: true},
+	OP_COPY_FROM:  {Hand: "tile", NeedsTile: true},
+	OP_COPY_TO:    {Tile: "hand", NeedsHand: true},
+	OP_ADD:        {Hand: "hand + tile", NeedsHand: true, NeedsTile: true, Values: "numbers"},
+	OP_SUB:        {Hand: "hand - tile", NeedsHand: true, NeedsTile: true, Values: "two numbers, or two letters (their distance in the alphabet)"},
+	OP_BUMP_PLUS:  {Tile: "tile + 1", Hand: "tile + 1", NeedsTile: true, Values: "numbers"},
+	OP_BUMP_MINUS: {Tile: "tile - 1", Hand: "tile - 1", NeedsTile: true, Values: "numbers"},
+	OP_JUMP:       {Jump: "always"},
+	OP_JUMP_ZERO:  {Jump: "hand == 0", NeedsHand: true, Values: "any value, a letter is never 0"},
+	OP_JUMP_NEG:   {Jump: "hand < 0", NeedsHand: true, Values: "any value, a letter is never negative"},
+}
+
+// An explanation of an instruction, generated from OpSemantics
+type Explanation struct {
+	Op OpCode
+	// The argument of the instruction, "" if it takes none
+	Operand string
+	// What the instruction does, in order
+	Effects []string
+	// What the instruction fails without
+	Needs []string
+	// The values the instruction computes with, "" for any value
+	Values string
+}
+
+// Explain what instructions with opcode op do
+func ExplainOp(op OpCode) (Explanation, error) {
+	operand := ""
+	switch {
+	case InstructionsWithArg.Member(op):
+		operand = "a tile: N for tile N, or [N] for the tile whose number is on tile N"
+	case InstructionsWithLabel.Member(op):
+		operand = "a label"
+	}
+	return explain(op, operand, "tile", "the label", "")
+}
+
+// Explain what the instruction at index does in the program: which tile it
+// uses, or which line it jumps to
+func (d Disassembled) Explain(index int) (Explanation, error) {
+	if index < 0 || index >= len(d) {
+		return Explanation{}, fmt.Errorf("no instruction at index %d", index)
+	}
+	switch diss := d[index].(type) {
+	case DisassembleArgInstruction:
+		tile := fmt.Sprintf("tile %d", diss.Arg)
+		operand := tile
+		if diss.Indirect {
+			tile = fmt.Sprintf("tile [%d]", diss.Arg)
+			operand = fmt.Sprintf("the tile whose number is on tile %d", diss.Arg)
+		}
+		return explain(diss.Op, operand, tile, "", d.lineAt(index+1))
+	case DisassembleJumpInstruction:
+		target := "an invalid target"
+		if diss.Target >= 0 {
+			target = d.lineAt(diss.Target)
+		}
+		return explain(diss.Op, fmt.Sprintf("label %s, at %s", diss.TargetLabel, target), "", target, d.lineAt(index+1))
+	case DisassembleInstruction:
+		return explain(diss.Op, "", "", "", d.lineAt(index+1))
+	}
+	return Explanation{}, fmt.Errorf("instruction %d is not an instruction executed by the worker", index)
+}
+
+// Return the line of the first instruction at or after index, as
+// continued with by jumps to index
+func (d Disassembled) lineAt(index int) string {
+	for ; index < len(d); index++ {
+		if numbered, ok := d[index].(LineNumbered); ok {
+			return fmt.Sprintf("line %d", numbered.Line())
+		}
+	}
+	return "the end of the program"
+}
+
+// Explain op, naming its tile, jump target and next instruction ("" if
+// not known)
+func explain(op OpCode, operand, tile, target, next string) (Explanation, error) {
+	semantics, found := OpSemantics[op]
+	if !found {
+		return Explanation{}, fmt.Errorf("unknown opcode %d", op)
+	}
+	e := Explanation{Op: op, Operand: operand, Values: semantics.Values}
+	formula := func(s string) string {
+		return strings.ReplaceAll(s, "tile", tile)
+	}
+	if semantics.Ends != "" {
+		e.Effects = append(e.Effects, fmt.Sprintf("the program ends if %s", semantics.Ends))
+	}
+	if semantics.Outbox {
+		e.Effects = append(e.Effects, "outbox ← hand")
+	}
+	if semantics.Tile != "" {
+		e.Effects = append(e.Effects, fmt.Sprintf("%s ← %s", tile, formula(semantics.Tile)))
+	}
+	if semantics.Hand != "" {
+		e.Effects = append(e.Effects, fmt.Sprintf("hand ← %s", formula(semantics.Hand)))
+	}
+	switch semantics.Jump {
+	case "":
+	case "always":
+		e.Effects = append(e.Effects, fmt.Sprintf("continue with %s", target))
+	default:
+		otherwise := next
+		if otherwise == "" {
+			otherwise = "the next instruction"
+		}
+		e.Effects = append(e.Effects, fmt.Sprintf("if %s continue with %s, otherwise with %s", semantics.Jump, target, otherwise))
+	}
+	if semantics.Jump == "" && next != "" {
+		e.Effects = append(e.Effects, fmt.Sprintf("continue with %s", next))
+	}
+	if semantics.NeedsHand {
+		e.Needs = append(e.Needs, "a value in the hand")
+	}
+	if semantics.NeedsTile {
+		e.Needs = append(e.Needs, fmt.Sprintf("a value on %s", tile))
+	}
+	return e, nil
+}
@@ -1,3 +1,8 @@
 module github.com/clj/hrm-profile-tool/instructions
 
-require github.com/ajstarks/svgo v0.0.0-20180830174826-7338bd80e790 // indirect
+require (
+	github.com/ajstarks/svgo v0.0.0-20180830174826-7338bd80e790 // indirect
+	github.com/clj/hrm-profile-tool/utils/logging v0.0.0
+)
+
+replace github.com/clj/hrm-profile-tool/utils/logging => ../utils/logging
@@ -0,0 +1,245 @@
+package instructions
+
+import "fmt"
+
+// One of the programs taking part in a three-way merge
+type MergeSource int
+
+const (
+	MERGE_BASE MergeSource = iota
+	MERGE_OURS
+	MERGE_THEIRS
+)
+
+// A program taking part in a three-way merge
+type MergeProgram struct {
+	Disassembled Disassembled
+	Comments     RawComments
+}
+
+// A disassembled instruction of one of the merged programs
+type MergeRef struct {
+	Source MergeSource
+	Index  int
+}
+
+// A chunk of a merged program. The lines of a chunk which is not a
+// conflict are in Ours
+type MergeChunk struct {
+	Conflict bool
+	Ours     []MergeRef
+	Theirs   []MergeRef
+}
+
+// The result of a three-way merge
+type Merged struct {
+	Programs [3]MergeProgram
+	Chunks   []MergeChunk
+	// The line of the merged program (counting the lines of both sides of
+	// conflicts) of each instruction, including the instructions of the
+	// other programs that were merged into it
+	lines map[MergeRef]int
+}
+
+// Return the number of conflicts
+func (m Merged) Conflicts() int {
+	conflicts := 0
+	for _, chunk := range m.Chunks {
+		if chunk.Conflict {
+			conflicts++
+		}
+	}
+	return conflicts
+}
+
+// Return the disassembled instruction ref refers to
+func (m Merged) Instruction(ref MergeRef) DisassembleInterface {
+	return m.Programs[ref.Source].Disassembled[ref.Index]
+}
+
+// Return the line of the merged program (counting the lines of both sides
+// of conflicts) which is the target of the jump instruction ref. The
+// target is not found if the merge removed it
+func (m Merged) Target(ref MergeRef) (int, bool) {
+	jump, ok := m.Instruction(ref).(DisassembleJumpInstruction)
+	if !ok || jump.Target < 0 {
+		return 0, false
+	}
+	line, found := m.lines[MergeRef{ref.Source, jump.Target}]
+	return line, found
+}
+
+// Return the key of the instructions of program used to align it with
+// another program. Comments are compared by their drawing
+func mergeKey(program MergeProgram) func(DisassembleInterface) (string, bool) {
+	return func(diss DisassembleInterface) (string, bool) {
+		if comment, ok := diss.(DisassembleComment); ok {
+			if int(comment.Index) < len(program.Comments) {
+				return fmt.Sprintf("comment %x", program.Comments[comment.Index]), true
+			}
+			return "comment", true
+		}
+		return diffKey(diss)
+	}
+}
+
+// The alignment of a program with the base program
+type mergeAlignment struct {
+	// The indexes of the aligned instructions, in order
+	sequence []int
+	// The position in sequence of each index
+	position map[int]int
+	// The index aligned with each index of the base program, and the
+	// reverse
+	fromBase map[int]int
+	toBase   map[int]int
+}
+
+// Align a program with the base program
+func alignMerge(base, program MergeProgram) (mergeAlignment, []int) {
+	alignment := mergeAlignment{
+		position: make(map[int]int),
+		fromBase: make(map[int]int),
+		toBase:   make(map[int]int),
+	}
+	var baseSequence []int
+	diff := diffDisassembled(base.Disassembled, program.Disassembled, mergeKey(base), mergeKey(program))
+	for _, line := range diff.Lines {
+		if line.Older >= 0 {
+			baseSequence = append(baseSequence, line.Older)
+		}
+		if line.Newer >= 0 {
+			alignment.position[line.Newer] = len(alignment.sequence)
+			alignment.sequence = append(alignment.sequence, line.Newer)
+		}
+		if line.Op == DIFF_EQUAL {
+			alignment.fromBase[line.Older] = line.Newer
+			alignment.toBase[line.Newer] = line.Older
+		}
+	}
+	return alignment, baseSequence
+}
+
+// Merge the changes made to base by ours and theirs at the instruction
+// level. Instructions, including comments compared by their drawing, are
+// aligned as by DiffDisassembled. A region changed on only one side, or
+// changed identically on both sides, takes the change; a region changed
+// differently on both sides is a conflict
+func MergePrograms(base, ours, theirs MergeProgram) Merged {
+	merged := Merged{Programs: [3]MergeProgram{base, ours, theirs}, lines: make(map[MergeRef]int)}
+	oursAlignment, baseSequence := alignMerge(base, ours)
+	theirsAlignment, _ := alignMerge(base, theirs)
+	alignments := [3]*mergeAlignment{nil, &oursAlignment, &theirsAlignment}
+
+	// Identify the target of a jump across the programs: targets aligned
+	// with the base program are identified by their base index
+	target := func(source MergeSource, index int) MergeRef {
+		jump, ok := merged.Programs[source].Disassembled[index].(DisassembleJumpInstruction)
+		if !ok {
+			return MergeRef{}
+		}
+		if source == MERGE_BASE {
+			return MergeRef{MERGE_BASE, jump.Target}
+		}
+		if baseIndex, found := alignments[source].toBase[jump.Target]; found {
+			return MergeRef{MERGE_BASE, baseIndex}
+		}
+		return MergeRef{source, jump.Target}
+	}
+	equal := func(a MergeSource, aIndexes []int, b MergeSource, bIndexes []int) bool {
+		if len(aIndexes) != len(bIndexes) {
+			return false
+		}
+		// Targets not aligned with the base program are the same when
+		// they are at the same position of the compared instructions,
+		// e.g. a label added by both sides
+		sameTarget := func(aTarget, bTarget MergeRef) bool {
+			if aTarget == bTarget {
+				return true
+			}
+			if aTarget.Source != a || bTarget.Source != b {
+				return false
+			}
+			for i := range aIndexes {
+				if aIndexes[i] == aTarget.Index {
+					return bIndexes[i] == bTarget.Index
+				}
+			}
+			return false
+		}
+		aKey, bKey := mergeKey(merged.Programs[a]), mergeKey(merged.Programs[b])
+		for i := range aIndexes {
+			aValue, _ := aKey(merged.Programs[a].Disassembled[aIndexes[i]])
+			bValue, _ := bKey(merged.Programs[b].Disassembled[bIndexes[i]])
+			if aValue != bValue || !sameTarget(target(a, aIndexes[i]), target(b, bIndexes[i])) {
+				return false
+			}
+		}
+		return true
+	}
+
+	line := 0
+	add := func(lines *[]MergeRef, source MergeSource, indexes []int) {
+		for _, index := range indexes {
+			ref := MergeRef{source, index}
+			*lines = append(*lines, ref)
+			merged.lines[ref] = line
+			line++
+		}
+	}
+	emit := func(source MergeSource, indexes []int) {
+		if len(indexes) == 0 {
+			return
+		}
+		if len(merged.Chunks) == 0 || merged.Chunks[len(merged.Chunks)-1].Conflict {
+			merged.Chunks = append(merged.Chunks, MergeChunk{})
+		}
+		add(&merged.Chunks[len(merged.Chunks)-1].Ours, source, indexes)
+	}
+	conflict := func(oursIndexes, theirsIndexes []int) {
+		merged.Chunks = append(merged.Chunks, MergeChunk{Conflict: true})
+		chunk := &merged.Chunks[len(merged.Chunks)-1]
+		add(&chunk.Ours, MERGE_OURS, oursIndexes)
+		add(&chunk.Theirs, MERGE_THEIRS, theirsIndexes)
+	}
+	// Register the instructions of another program as merged into the
+	// already emitted instructions
+	alias := func(source MergeSource, indexes []int, into MergeSource, intoIndexes []int) {
+		for i, index := range indexes {
+			merged.lines[MergeRef{source, index}] = merged.lines[MergeRef{into, intoIndexes[i]}]
+		}
+	}
+	region := func(baseIndexes, oursIndexes, theirsIndexes []int) {
+		switch {
+		case len(baseIndexes) == 0 && len(oursIndexes) == 0 && len(theirsIndexes) == 0:
+		case equal(MERGE_BASE, baseIndexes, MERGE_OURS, oursIndexes):
+			emit(MERGE_THEIRS, theirsIndexes)
+		case equal(MERGE_BASE, baseIndexes, MERGE_THEIRS, theirsIndexes):
+			emit(MERGE_OURS, oursIndexes)
+		case equal(MERGE_OURS, oursIndexes, MERGE_THEIRS, theirsIndexes):
+			emit(MERGE_OURS, oursIndexes)
+			alias(MERGE_THEIRS, theirsIndexes, MERGE_OURS, oursIndexes)
+		default:
+			conflict(oursIndexes, theirsIndexes)
+		}
+	}
+
+	// Walk the instructions of the base program aligned with both sides,
+	// merging the regions between them
+	lastBase, lastOurs, lastTheirs := 0, 0, 0
+	for position, baseIndex := range baseSequence {
+		oursIndex, inOurs := oursAlignment.fromBase[baseIndex]
+		theirsIndex, inTheirs := theirsAlignment.fromBase[baseIndex]
+		if !inOurs || !inTheirs {
+			continue
+		}
+		oursPosition, theirsPosition := oursAlignment.position[oursIndex], theirsAlignment.position[theirsIndex]
+		region(baseSequence[lastBase:position], oursAlignment.sequence[lastOurs:oursPosition], theirsAlignment.sequence[lastTheirs:theirsPosition])
+		emit(MERGE_OURS, []int{oursIndex})
+		alias(MERGE_BASE, []int{baseIndex}, MERGE_OURS, []int{oursIndex})
+		alias(MERGE_THEIRS, []int{theirsIndex}, MERGE_OURS, []int{oursIndex})
+		lastBase, lastOurs, lastTheirs = position+1, oursPosition+1, theirsPosition+1
+	}
+	region(baseSequence[lastBase:], oursAlignment.sequence[lastOurs:], theirsAlignment.sequence[lastTheirs:])
+	return merged
+}
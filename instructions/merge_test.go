@@ -0,0 +1,63 @@
+package instructions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func mergeProgram(t *testing.T, text string) MergeProgram {
+	t.Helper()
+	return MergeProgram{Disassembled: disassembled(t, text)}
+}
+
+func TestMergeProgramsConflict(t *testing.T) {
+	base := mergeProgram(t, "a:\nINBOX\nCOPYTO 0\nOUTBOX\nJUMP a\n")
+	ours := mergeProgram(t, "a:\nINBOX\nCOPYTO 1\nOUTBOX\nJUMP a\n")
+	theirs := mergeProgram(t, "a:\nINBOX\nCOPYTO 2\nBUMPUP 2\nOUTBOX\nJUMP a\n")
+
+	merged := MergePrograms(base, ours, theirs)
+	if merged.Conflicts() != 1 {
+		t.Fatalf("got %d conflicts, expected 1", merged.Conflicts())
+	}
+	expected := []MergeChunk{
+		{Ours: []MergeRef{{MERGE_OURS, 0}, {MERGE_OURS, 1}}},
+		{Conflict: true, Ours: []MergeRef{{MERGE_OURS, 2}}, Theirs: []MergeRef{{MERGE_THEIRS, 2}, {MERGE_THEIRS, 3}}},
+		{Ours: []MergeRef{{MERGE_OURS, 3}, {MERGE_OURS, 4}}},
+	}
+	if !reflect.DeepEqual(merged.Chunks, expected) {
+		t.Errorf("got chunks %v, expected %v", merged.Chunks, expected)
+	}
+	if line, found := merged.Target(MergeRef{MERGE_OURS, 4}); !found || line != 0 {
+		t.Errorf("the jump targets line %d (found %v), expected 0", line, found)
+	}
+}
+
+func TestMergeProgramsIdenticalChange(t *testing.T) {
+	base := mergeProgram(t, "INBOX\nOUTBOX\n")
+	changed := "INBOX\na:\nJUMPZ a\nOUTBOX\n"
+
+	merged := MergePrograms(base, mergeProgram(t, changed), mergeProgram(t, changed))
+	if merged.Conflicts() != 0 || len(merged.Chunks) != 1 || len(merged.Chunks[0].Ours) != 4 {
+		t.Fatalf("got chunks %v, expected the change once", merged.Chunks)
+	}
+	// The jump of theirs is merged into that of ours
+	for _, source := range []MergeSource{MERGE_OURS, MERGE_THEIRS} {
+		if line, found := merged.Target(MergeRef{source, 2}); !found || line != 1 {
+			t.Errorf("the jump of %d targets line %d (found %v), expected 1", source, line, found)
+		}
+	}
+}
+
+func TestMergeProgramsDeletedTarget(t *testing.T) {
+	base := mergeProgram(t, "a:\nINBOX\nOUTBOX\nJUMP a\n")
+	ours := mergeProgram(t, "INBOX\nOUTBOX\n")
+	theirs := mergeProgram(t, "a:\nINBOX\nJUMPZ a\nOUTBOX\nJUMP a\n")
+
+	merged := MergePrograms(base, ours, theirs)
+	if merged.Conflicts() != 0 {
+		t.Errorf("got %d conflicts, expected none", merged.Conflicts())
+	}
+	if line, found := merged.Target(MergeRef{MERGE_THEIRS, 2}); found {
+		t.Errorf("the jump to the deleted label targets line %d", line)
+	}
+}
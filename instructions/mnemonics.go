@@ -0,0 +1,93 @@
+package instructions
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A set of mnemonics used to render and assemble instructions, e.g.
+// lowercase or localized mnemonics, or community aliases such as JLZ
+type Mnemonics struct {
+	names   map[OpCode]string
+	aliases map[string]OpCode
+}
+
+// Return the mnemonics used by the game (see InstrunctionMnemonics)
+func DefaultMnemonics() *Mnemonics {
+	m := &Mnemonics{make(map[OpCode]string), make(map[string]OpCode)}
+	for op, name := range InstrunctionMnemonics {
+		m.Set(op, name)
+	}
+	return m
+}
+
+// Set the mnemonic rendered for op. The mnemonic and any aliases are
+// accepted by the assembler, in addition to the previous mnemonics
+func (m *Mnemonics) Set(op OpCode, name string, aliases ...string) {
+	m.names[op] = name
+	for _, alias := range append([]string{name}, aliases...) {
+		m.aliases[strings.ToUpper(alias)] = op
+	}
+}
+
+// Return the mnemonic of op
+func (m *Mnemonics) Name(op OpCode) string {
+	return m.names[op]
+}
+
+// Return the opcode of a mnemonic or alias (case insensitive)
+func (m *Mnemonics) Lookup(name string) (OpCode, bool) {
+	op, found := m.aliases[strings.ToUpper(name)]
+	return op, found
+}
+
+// Parse a mnemonics file. Each line maps a mnemonic used by the game to a
+// replacement, optionally followed by aliases accepted by the assembler:
+//
+//	# comments and blank lines are ignored
+//	JUMPN = JLZ JNEG
+//	BUMPUP = INC
+//
+// Opcodes not mentioned keep the game's mnemonic
+func ParseMnemonics(r io.Reader) (*Mnemonics, error) {
+	m := DefaultMnemonics()
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: expected MNEMONIC = REPLACEMENT [ALIAS...]", lineNumber)
+		}
+		op, found := DefaultMnemonics().Lookup(strings.TrimSpace(parts[0]))
+		if !found {
+			return nil, fmt.Errorf("line %d: unknown mnemonic %q", lineNumber, strings.TrimSpace(parts[0]))
+		}
+		names := strings.Fields(parts[1])
+		if len(names) == 0 {
+			return nil, fmt.Errorf("line %d: missing replacement for %s", lineNumber, op)
+		}
+		m.Set(op, names[0], names[1:]...)
+	}
+	return m, scanner.Err()
+}
+
+// Load a mnemonics file, see ParseMnemonics
+func LoadMnemonics(path string) (*Mnemonics, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseMnemonics(file)
+}
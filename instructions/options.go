@@ -0,0 +1,89 @@
+package instructions
+
+import (
+	"log/slog"
+
+	"github.com/clj/hrm-profile-tool/utils/logging"
+)
+
+type decodeOptions struct {
+	logger            *slog.Logger
+	commentOrigin     CommentOrigin
+	flipCommentY      bool
+	normalizeComments bool
+}
+
+// A decoding option
+type DecodeOption func(*decodeOptions)
+
+// Log decode warnings and progress to logger. By default nothing is logged
+func Logger(logger *slog.Logger) DecodeOption {
+	return func(o *decodeOptions) {
+		o.logger = logger
+	}
+}
+
+// Decode comment points with the origin (0, 0) at the corner origin of the
+// comment canvas. By default the points are decoded as stored by the game,
+// with the origin at the top left (COMMENT_ORIGIN_TOP_LEFT)
+func CommentCoordinates(origin CommentOrigin) DecodeOption {
+	return func(o *decodeOptions) {
+		o.commentOrigin = origin
+	}
+}
+
+// Flip the Y axis of the decoded comment points, moving the origin from
+// the top to the bottom of the comment canvas. Combined with
+// CommentCoordinates the axis is flipped relative to the given origin
+func FlipCommentY() DecodeOption {
+	return func(o *decodeOptions) {
+		o.flipCommentY = true
+	}
+}
+
+// Scale and move each decoded comment so that its bounding box starts at
+// the origin and fills the comment canvas in at least one direction. Both
+// axes are scaled equally, so the drawing keeps its proportions when
+// rendered
+func NormalizeComments() DecodeOption {
+	return func(o *decodeOptions) {
+		o.normalizeComments = true
+	}
+}
+
+func newDecodeOptions(opts []DecodeOption) decodeOptions {
+	var options decodeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.logger = logging.OrDiscard(options.logger)
+	if options.flipCommentY {
+		options.commentOrigin ^= commentOriginBottom
+	}
+	return options
+}
+
+type disassembleOptions struct {
+	keepUnreachableTargets bool
+	lineNumberComments     bool
+}
+
+// A disassembly option
+type DisassembleOption func(*disassembleOptions)
+
+// Keep jump targets which no jump instruction jumps to (as a
+// DisassembleJumpTarget with a Jumpee of -1). By default they are left
+// out of the disassembly (nil)
+func KeepUnreachableTargets() DisassembleOption {
+	return func(o *disassembleOptions) {
+		o.keepUnreachableTargets = true
+	}
+}
+
+// Count comments when numbering lines. By default only instructions are
+// numbered, as in the game
+func LineNumberComments() DisassembleOption {
+	return func(o *disassembleOptions) {
+		o.lineNumberComments = true
+	}
+}
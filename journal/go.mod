@@ -0,0 +1 @@
+module github.com/clj/hrm-profile-tool/journal
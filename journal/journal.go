@@ -0,0 +1,263 @@
+// Package journal records modifications made to profile files so that
+// they can be reverted. Every modification is stored as an entry holding
+// the original bytes of all changed ranges
+package journal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The extension used for journal entry files
+const entryExtension = ".json"
+
+// ErrEmpty is returned when the journal contains no entries
+var ErrEmpty = errors.New("journal is empty")
+
+// A single changed byte range in a file
+type Change struct {
+	Offset   int64  `json:"offset"`
+	Original []byte `json:"original"`
+	Modified []byte `json:"modified"`
+}
+
+// A journal entry describing a single modification of a file
+type Entry struct {
+	ID          string    `json:"-"`
+	Time        time.Time `json:"time"`
+	Command     string    `json:"command"`
+	Description string    `json:"description"`
+	Path        string    `json:"path"`
+	Changes     []Change  `json:"changes"`
+}
+
+// A journal stored as one file per entry in a directory
+type Journal struct {
+	Dir string
+}
+
+// Return the default journal directory inside the user's configuration
+// directory
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hrm-profile-tool", "journal"), nil
+}
+
+// Open the journal in dir, creating the directory if required
+func Open(dir string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Journal{dir}, nil
+}
+
+// Open the journal in the default directory
+//
+// See: DefaultDir
+func OpenDefault() (*Journal, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return Open(dir)
+}
+
+// Record an entry in the journal. The entry's ID and Time are set if
+// not already present
+func (j *Journal) Record(entry *Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.ID == "" {
+		entry.ID = fmt.Sprintf("%020d", entry.Time.UnixNano())
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(j.Dir, entry.ID+entryExtension), data, 0644)
+}
+
+// Return all entries in the journal, oldest first
+func (j *Journal) Entries() ([]Entry, error) {
+	files, err := ioutil.ReadDir(j.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), entryExtension) {
+			ids = append(ids, strings.TrimSuffix(file.Name(), entryExtension))
+		}
+	}
+	sort.Strings(ids)
+	entries := make([]Entry, 0, len(ids))
+	for _, id := range ids {
+		entry, err := j.read(id)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Return the most recent entry in the journal
+func (j *Journal) Last() (Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(entries) == 0 {
+		return Entry{}, ErrEmpty
+	}
+	return entries[len(entries)-1], nil
+}
+
+func (j *Journal) read(id string) (Entry, error) {
+	var entry Entry
+	data, err := ioutil.ReadFile(filepath.Join(j.Dir, id+entryExtension))
+	if err != nil {
+		return entry, err
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, fmt.Errorf("journal entry %s: %v", id, err)
+	}
+	entry.ID = id
+	return entry, nil
+}
+
+// Apply a set of changes (Offset and Modified must be set) to the file at
+// path, recording the original bytes in the journal before the file is
+// modified
+func (j *Journal) Apply(path, command, description string, changes []Change) (Entry, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	file, err := os.OpenFile(absPath, os.O_RDWR, 0)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer file.Close()
+
+	if err := readOriginals(file, changes); err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{
+		Command:     command,
+		Description: description,
+		Path:        absPath,
+		Changes:     changes,
+	}
+	if err := j.Record(&entry); err != nil {
+		return entry, err
+	}
+	for _, change := range changes {
+		if _, err := file.WriteAt(change.Modified, change.Offset); err != nil {
+			return entry, err
+		}
+	}
+	return entry, file.Sync()
+}
+
+// Set the original bytes of the changes to the bytes currently in the file
+// at path, without modifying it. Apply does the same before modifying the
+// file, so this shows what Apply would change
+func ReadOriginals(path string, changes []Change) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return readOriginals(file, changes)
+}
+
+func readOriginals(file io.ReaderAt, changes []Change) error {
+	for i := range changes {
+		changes[i].Original = make([]byte, len(changes[i].Modified))
+		if _, err := file.ReadAt(changes[i].Original, changes[i].Offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return an error if the file of the entry no longer contains the modified
+// bytes, i.e. it has been changed since the modification (for example by
+// the game)
+func (e Entry) CheckUnchanged() error {
+	file, err := os.Open(e.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return e.checkUnchanged(file)
+}
+
+func (e Entry) checkUnchanged(file io.ReaderAt) error {
+	for i, change := range e.Changes {
+		current := make([]byte, len(change.Modified))
+		if _, err := file.ReadAt(current, change.Offset); err != nil {
+			return err
+		}
+		// Later changes overwrite earlier overlapping ones
+		expected := append([]byte(nil), change.Modified...)
+		for _, later := range e.Changes[i+1:] {
+			for k, b := range later.Modified {
+				if at := later.Offset + int64(k) - change.Offset; at >= 0 && at < int64(len(expected)) {
+					expected[at] = b
+				}
+			}
+		}
+		if !bytes.Equal(current, expected) {
+			return fmt.Errorf("%s has changed since the modification at offset 0x%X, refusing to undo", e.Path, change.Offset)
+		}
+	}
+	return nil
+}
+
+// Revert the most recent modification and remove it from the journal.
+// Unless force is set, the modification is only reverted if the file
+// still contains the modified bytes, i.e. it has not been changed since
+// (for example by the game)
+func (j *Journal) Undo(force bool) (Entry, error) {
+	entry, err := j.Last()
+	if err != nil {
+		return entry, err
+	}
+	file, err := os.OpenFile(entry.Path, os.O_RDWR, 0)
+	if err != nil {
+		return entry, err
+	}
+	defer file.Close()
+
+	if !force {
+		if err := entry.checkUnchanged(file); err != nil {
+			return entry, err
+		}
+	}
+	// Revert in reverse order in case of overlapping changes
+	for i := len(entry.Changes) - 1; i >= 0; i-- {
+		change := entry.Changes[i]
+		if _, err := file.WriteAt(change.Original, change.Offset); err != nil {
+			return entry, err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return entry, err
+	}
+	return entry, os.Remove(filepath.Join(j.Dir, entry.ID+entryExtension))
+}
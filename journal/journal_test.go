@@ -0,0 +1,206 @@
+package journal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testFileData = []byte("0123456789abcdef")
+
+// Return a journal in a temporary directory and a file holding
+// testFileData next to it. Remove the directory with the returned function
+func testJournal(t *testing.T) (*Journal, string, func()) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := Open(filepath.Join(dir, "journal"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "profiles.bin")
+	if err := ioutil.WriteFile(path, testFileData, 0644); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return j, path, func() { os.RemoveAll(dir) }
+}
+
+func readFile(t *testing.T, path string) []byte {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func testChanges() []Change {
+	return []Change{
+		{Offset: 2, Modified: []byte("XY")},
+		{Offset: 10, Modified: []byte("Z")},
+	}
+}
+
+func TestApplyUndo(t *testing.T) {
+	j, path, cleanup := testJournal(t)
+	defer cleanup()
+
+	entry, err := j.Apply(path, "set-score", "floor 1: size 5", testChanges())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); string(got) != "01XY456789Zbcdef" {
+		t.Errorf("the file after Apply is %q", got)
+	}
+	if string(entry.Changes[0].Original) != "23" || string(entry.Changes[1].Original) != "a" {
+		t.Errorf("recorded the originals %q and %q, expected \"23\" and \"a\"", entry.Changes[0].Original, entry.Changes[1].Original)
+	}
+	if err := entry.CheckUnchanged(); err != nil {
+		t.Errorf("CheckUnchanged after Apply: %v", err)
+	}
+
+	undone, err := j.Undo(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if undone.ID != entry.ID {
+		t.Errorf("undid entry %s, expected %s", undone.ID, entry.ID)
+	}
+	if got := readFile(t, path); !bytes.Equal(got, testFileData) {
+		t.Errorf("the file after Undo is %q, expected %q", got, testFileData)
+	}
+	if _, err := j.Undo(false); err != ErrEmpty {
+		t.Errorf("a second Undo returned %v, expected ErrEmpty", err)
+	}
+}
+
+func TestUndoOverlappingChanges(t *testing.T) {
+	j, path, cleanup := testJournal(t)
+	defer cleanup()
+
+	changes := []Change{
+		{Offset: 4, Modified: []byte("AAAA")},
+		{Offset: 6, Modified: []byte("BB")},
+	}
+	if _, err := j.Apply(path, "import", "overlapping", changes); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); string(got) != "0123AABB89abcdef" {
+		t.Errorf("the file after Apply is %q", got)
+	}
+	if _, err := j.Undo(false); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); !bytes.Equal(got, testFileData) {
+		t.Errorf("the file after Undo is %q, expected %q", got, testFileData)
+	}
+}
+
+func TestUndoRefusesChangedFile(t *testing.T) {
+	j, path, cleanup := testJournal(t)
+	defer cleanup()
+
+	if _, err := j.Apply(path, "set-score", "floor 1: size 5", testChanges()); err != nil {
+		t.Fatal(err)
+	}
+	// The game saves over one of the modified bytes
+	changed := []byte("01XW456789Zbcdef")
+	if err := ioutil.WriteFile(path, changed, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := j.Undo(false); err == nil {
+		t.Fatal("Undo succeeded on a changed file")
+	}
+	if got := readFile(t, path); !bytes.Equal(got, changed) {
+		t.Errorf("the refused Undo modified the file to %q", got)
+	}
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("the refused Undo left %d entries, expected 1", len(entries))
+	}
+}
+
+func TestUndoForce(t *testing.T) {
+	j, path, cleanup := testJournal(t)
+	defer cleanup()
+
+	if _, err := j.Apply(path, "set-score", "floor 1: size 5", testChanges()); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte("01XW456789Zbcdef"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := j.Undo(true); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); !bytes.Equal(got, testFileData) {
+		t.Errorf("the file after a forced Undo is %q, expected %q", got, testFileData)
+	}
+	if _, err := j.Last(); err != ErrEmpty {
+		t.Errorf("Last after a forced Undo returned %v, expected ErrEmpty", err)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	j, path, cleanup := testJournal(t)
+	defer cleanup()
+
+	if _, err := j.Last(); err != ErrEmpty {
+		t.Errorf("Last of an empty journal returned %v, expected ErrEmpty", err)
+	}
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	recorded := []Entry{
+		{Time: start.Add(2 * time.Minute), Command: "clear", Description: "floor 2 tab 1"},
+		{Time: start, Command: "set-score", Description: "floor 1: size 5"},
+		{Time: start.Add(time.Minute), Command: "import", Description: "floor 1 tab 2"},
+	}
+	for i := range recorded {
+		recorded[i].Path = path
+		recorded[i].Changes = []Change{{Offset: int64(i), Original: []byte{'0'}, Modified: []byte{'X'}}}
+		if err := j.Record(&recorded[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A file that is not a journal entry
+	if err := ioutil.WriteFile(filepath.Join(j.Dir, "notes.txt"), []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"set-score", "import", "clear"}
+	if len(entries) != len(expected) {
+		t.Fatalf("got %d entries, expected %d", len(entries), len(expected))
+	}
+	for i, entry := range entries {
+		if entry.Command != expected[i] {
+			t.Errorf("entry %d is %s, expected %s", i, entry.Command, expected[i])
+		}
+		if entry.ID == "" || entry.Path != path || len(entry.Changes) != 1 {
+			t.Errorf("entry %d was not read back: %+v", i, entry)
+		}
+	}
+	if !entries[0].Time.Equal(start) {
+		t.Errorf("the first entry is from %v, expected %v", entries[0].Time, start)
+	}
+
+	last, err := j.Last()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Command != "clear" {
+		t.Errorf("the last entry is %s, expected clear", last.Command)
+	}
+}
@@ -0,0 +1,62 @@
+package levels
+
+// The values a level may put in the inbox
+type InboxConstraints struct {
+	// Whether the inbox may hold numbers, and their range
+	Numbers  bool
+	Min, Max int
+	// Whether the inbox may hold letters
+	Letters bool
+	// The largest number of values in the inbox, 0 if unknown
+	MaxLength int
+}
+
+// The inbox constraints, by floor (as shown in the game). The inboxes are
+// generated by the game, the constraints are what has been observed
+var inboxConstraints = map[int]InboxConstraints{
+	1:  {true, -9, 9, true, 3},
+	2:  {true, -9, 9, true, 12},
+	3:  {true, -9, 9, true, 6},
+	4:  {true, -9, 9, true, 8},
+	6:  {true, -9, 9, false, 8},
+	7:  {true, -9, 9, true, 8},
+	8:  {true, -9, 9, false, 4},
+	9:  {true, -9, 9, true, 8},
+	10: {true, -9, 9, false, 4},
+	11: {true, -9, 9, false, 8},
+	12: {true, -9, 9, false, 4},
+	13: {true, -9, 9, false, 8},
+	14: {true, -9, 9, false, 8},
+	16: {true, -99, 99, false, 5},
+	17: {true, -9, 9, false, 8},
+	19: {true, -9, 9, false, 4},
+	20: {true, 0, 9, false, 8},
+	21: {true, -9, 9, false, 12},
+	22: {true, 1, 30, false, 4},
+	23: {true, -99, 99, false, 16},
+	24: {true, 0, 9, false, 8},
+	25: {true, 0, 9, false, 4},
+	26: {true, 0, 9, false, 8},
+	28: {true, -9, 9, true, 12},
+	29: {true, 0, 9, false, 5},
+	30: {true, 0, 23, false, 12},
+	31: {true, 0, 0, true, 15},
+	32: {true, 0, 0, true, 8},
+	34: {true, 0, 0, true, 12},
+	35: {false, 0, 0, true, 12},
+	36: {true, 0, 0, true, 16},
+	37: {true, 0, 24, false, 2},
+	38: {true, 0, 999, false, 4},
+	39: {true, 0, 15, false, 4},
+	40: {true, 2, 50, false, 4},
+	41: {true, -99, 99, true, 20},
+}
+
+// Return the values the level may put in the inbox. Any value is allowed
+// for floors without known constraints
+func (l Level) Inbox() InboxConstraints {
+	if constraints, found := inboxConstraints[l.Floor]; found {
+		return constraints
+	}
+	return InboxConstraints{true, -999, 999, true, 0}
+}
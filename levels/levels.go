@@ -0,0 +1,89 @@
+// Package levels provides the levels of Human Resource Machine: their
+// names, challenge goals, floor tiles and inboxes, by floor as shown in the
+// game
+package levels
+
+// A level of the game, with the size and speed challenge goals shown in
+// the game
+type Level struct {
+	Floor          int
+	Name           string
+	SizeChallenge  int
+	SpeedChallenge int
+	// The floors unlocked by completing this floor
+	Unlocks []int
+}
+
+// Report whether size meets the level's size challenge
+func (l Level) SizeChallengeMet(size int) bool {
+	return size > 0 && size <= l.SizeChallenge
+}
+
+// Report whether steps meets the level's speed challenge
+func (l Level) SpeedChallengeMet(steps int) bool {
+	return steps > 0 && steps <= l.SpeedChallenge
+}
+
+// The levels present in the profile, by floor (as shown in the game)
+var levelsByFloor = map[int]Level{
+	1:  {1, "Mail Room", 6, 6, []int{2}},
+	2:  {2, "Busy Mail Room", 6, 25, []int{3}},
+	3:  {3, "Copy Floor", 6, 6, []int{4}},
+	4:  {4, "Scrambler Handler", 7, 21, []int{6}},
+	6:  {6, "Rainy Summer", 6, 24, []int{7}},
+	7:  {7, "Zero Exterminator", 4, 23, []int{8}},
+	8:  {8, "Tripler Room", 6, 24, []int{9}},
+	9:  {9, "Zero Preservation Initiative", 5, 25, []int{10}},
+	10: {10, "Octoplier Suite", 9, 36, []int{11}},
+	11: {11, "Sub Hallway", 10, 40, []int{12, 13}},
+	12: {12, "Tetracontiplier", 14, 56, nil},
+	13: {13, "Equalization Room", 9, 27, []int{14}},
+	14: {14, "Maximization Room", 10, 34, []int{16}},
+	16: {16, "Absolute Positivity", 8, 36, []int{17, 19}},
+	17: {17, "Exclusive Lounge", 12, 28, nil},
+	19: {19, "Countdown", 10, 82, []int{20}},
+	20: {20, "Multiplication Workshop", 15, 109, []int{21}},
+	21: {21, "Zero Terminated Sum", 10, 72, []int{22, 23, 24}},
+	22: {22, "Fibonacci Visitor", 19, 156, nil},
+	23: {23, "The Littlest Number", 13, 75, nil},
+	24: {24, "Mod Module", 7, 57, []int{25, 26}},
+	25: {25, "Cumulative Countdown", 12, 82, nil},
+	26: {26, "Small Divide", 15, 76, []int{28}},
+	28: {28, "Three Sort", 34, 78, []int{29}},
+	29: {29, "Storage Floor", 5, 25, []int{30}},
+	30: {30, "String Storage Floor", 7, 203, []int{31}},
+	31: {31, "String Reverse", 11, 121, []int{32}},
+	32: {32, "Inventory Report", 16, 393, []int{34}},
+	34: {34, "Vowel Incinerator", 13, 113, []int{35}},
+	35: {35, "Duplicate Removal", 17, 167, []int{36}},
+	36: {36, "Alphabetizer", 39, 109, []int{37}},
+	37: {37, "Scavenger Chain", 8, 63, []int{38}},
+	38: {38, "Digit Exploder", 30, 165, []int{39}},
+	39: {39, "Re-Coordinator", 14, 76, []int{40}},
+	40: {40, "Prime Factory", 28, 399, []int{41}},
+	41: {41, "Sorting Floor", 34, 714, nil},
+}
+
+// Return the levels, ordered by floor
+func Levels() []Level {
+	var ordered []Level
+	for floor := 1; len(ordered) < len(levelsByFloor); floor++ {
+		if level, found := levelsByFloor[floor]; found {
+			ordered = append(ordered, level)
+		}
+	}
+	return ordered
+}
+
+// Return the level on floor (as shown in the game), reporting whether the
+// floor has a level
+func Lookup(floor int) (Level, bool) {
+	level, found := levelsByFloor[floor]
+	return level, found
+}
+
+// Return the level on floor (as shown in the game), or the zero Level
+// (without floor tiles) if the floor has no level, see Lookup
+func Floor(floor int) Level {
+	return levelsByFloor[floor]
+}
@@ -0,0 +1,111 @@
+package levels
+
+import "fmt"
+
+// A value held by a worker or a floor tile: a number or a letter
+type Value struct {
+	Number int
+	Letter byte // 'A' to 'Z', 0 for a number
+}
+
+// Return a number value
+func NumberValue(n int) Value {
+	return Value{Number: n}
+}
+
+// Return a letter value
+func LetterValue(letter byte) Value {
+	return Value{Letter: letter}
+}
+
+// Report whether the value is a letter
+func (v Value) IsLetter() bool {
+	return v.Letter != 0
+}
+
+func (v Value) String() string {
+	if v.IsLetter() {
+		return string(v.Letter)
+	}
+	return fmt.Sprint(v.Number)
+}
+
+// A floor tile, which is either empty or holds a value
+type Tile struct {
+	Filled bool
+	Value  Value
+}
+
+// The dimensions of the floor of a level and the tiles filled at the start
+// of the level
+type floorLayout struct {
+	columns, rows int
+	tiles         map[int]Value
+}
+
+// The floor layouts, by floor (as shown in the game). Levels without
+// floor tiles are not present
+var floorLayouts = map[int]floorLayout{
+	3:  {3, 2, map[int]Value{0: LetterValue('U'), 1: LetterValue('J'), 2: LetterValue('X'), 3: LetterValue('G'), 4: LetterValue('B'), 5: LetterValue('E')}},
+	4:  {3, 1, nil},
+	6:  {3, 1, nil},
+	7:  {3, 3, nil},
+	8:  {3, 1, nil},
+	9:  {3, 3, nil},
+	10: {5, 1, nil},
+	11: {3, 1, nil},
+	12: {5, 1, nil},
+	13: {3, 1, nil},
+	14: {3, 1, nil},
+	16: {3, 1, nil},
+	17: {3, 2, map[int]Value{4: NumberValue(0), 5: NumberValue(1)}},
+	19: {5, 2, nil},
+	20: {5, 2, map[int]Value{9: NumberValue(0)}},
+	21: {3, 2, map[int]Value{5: NumberValue(0)}},
+	22: {5, 2, map[int]Value{9: NumberValue(0)}},
+	23: {5, 2, nil},
+	24: {5, 2, nil},
+	25: {3, 2, map[int]Value{5: NumberValue(0)}},
+	26: {5, 2, map[int]Value{9: NumberValue(0)}},
+	28: {5, 2, nil},
+	29: {5, 2, map[int]Value{0: LetterValue('N'), 1: LetterValue('K'), 2: LetterValue('A'), 3: LetterValue('E'), 4: LetterValue('R'), 5: LetterValue('D'), 6: LetterValue('O'), 7: LetterValue('L'), 8: LetterValue('Y'), 9: LetterValue('J')}},
+	30: {5, 5, map[int]Value{24: NumberValue(0)}},
+	31: {5, 3, map[int]Value{14: NumberValue(0)}},
+	32: {4, 4, map[int]Value{14: NumberValue(0)}},
+	34: {5, 2, map[int]Value{0: LetterValue('A'), 1: LetterValue('E'), 2: LetterValue('I'), 3: LetterValue('O'), 4: LetterValue('U'), 5: NumberValue(0)}},
+	35: {5, 3, map[int]Value{14: NumberValue(0)}},
+	36: {5, 5, map[int]Value{23: NumberValue(0), 24: NumberValue(10)}},
+	37: {5, 5, nil},
+	38: {4, 3, map[int]Value{9: NumberValue(0), 10: NumberValue(10), 11: NumberValue(100)}},
+	39: {4, 4, map[int]Value{14: NumberValue(0), 15: NumberValue(4)}},
+	40: {5, 5, map[int]Value{24: NumberValue(0)}},
+	41: {5, 5, map[int]Value{24: NumberValue(0)}},
+}
+
+// Return the number of columns of floor tiles
+func (l Level) Columns() int {
+	return floorLayouts[l.Floor].columns
+}
+
+// Return the number of rows of floor tiles
+func (l Level) Rows() int {
+	return floorLayouts[l.Floor].rows
+}
+
+// Return the number of floor tiles, i.e. the valid COPYTO/COPYFROM
+// addresses are 0 to TileCount()-1
+func (l Level) TileCount() int {
+	layout := floorLayouts[l.Floor]
+	return layout.columns * layout.rows
+}
+
+// Return the floor tiles at the start of the level, numbered as in the
+// game (row by row)
+func (l Level) Tiles() []Tile {
+	layout := floorLayouts[l.Floor]
+	tiles := make([]Tile, layout.columns*layout.rows)
+	for i, value := range layout.tiles {
+		tiles[i] = Tile{true, value}
+	}
+	return tiles
+}
@@ -0,0 +1,18 @@
+package levels
+
+import "testing"
+
+func TestFloorTiles(t *testing.T) {
+	tiles := Floor(20).Tiles()
+	if len(tiles) != 10 {
+		t.Fatalf("floor 20 has %d tiles, expected 10", len(tiles))
+	}
+	for i, tile := range tiles {
+		if expected := (Tile{i == 9, NumberValue(0)}); tile != expected {
+			t.Errorf("floor 20 tile %d is %+v, expected %+v", i, tile, expected)
+		}
+	}
+	if tiles := Floor(5).Tiles(); len(tiles) != 0 {
+		t.Errorf("floor 5 (a cut-scene) has %d tiles, expected none", len(tiles))
+	}
+}
@@ -0,0 +1,172 @@
+// Package locale provides translations of the instruction mnemonics and
+// level names matching the languages Human Resource Machine is localized
+// into, so rendered programs look like they do to non-English players. It
+// also holds the translations of the hrm command's messages
+package locale
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+//go:embed locales/*.locale
+var localeFiles embed.FS
+
+// The code of the locale used when no other locale is requested
+const DefaultCode = "en"
+
+// A translation of the text shown by the game
+type Locale struct {
+	// Language code, e.g. "es"
+	Code string
+	// Name of the language in the language itself, e.g. "Español"
+	Name string
+	// Mnemonics used for text output and accepted by the assembler (in
+	// addition to the English mnemonics)
+	Mnemonics *instructions.Mnemonics
+	// Instruction labels as shown on the game's instruction boxes, e.g.
+	// "entrada". Conditional jumps additionally have a condition
+	Labels     map[instructions.OpCode]string
+	Conditions map[instructions.OpCode]string
+	// Level names by floor. Levels without a translation are not present
+	LevelNames map[int]string
+	// Translations of the hrm command's help and messages by message id.
+	// Messages without a translation are not present
+	Messages map[string]string
+}
+
+// Return the name of the level on floor, or fallback (the English name)
+// if it has not been translated
+func (l *Locale) LevelName(floor int, fallback string) string {
+	if name, found := l.LevelNames[floor]; found {
+		return name
+	}
+	return fallback
+}
+
+// Return the message with id, or fallback (the English message) if it has
+// not been translated. A nil locale returns fallback
+func (l *Locale) Message(id, fallback string) string {
+	if l == nil {
+		return fallback
+	}
+	if message, found := l.Messages[id]; found {
+		return message
+	}
+	return fallback
+}
+
+// Return the codes of the available locales, sorted
+func Codes() []string {
+	entries, _ := localeFiles.ReadDir("locales")
+	codes := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		codes = append(codes, strings.TrimSuffix(entry.Name(), ".locale"))
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Return the locale with code (e.g. "es" or "es_ES.UTF-8")
+func Get(code string) (*Locale, error) {
+	code = strings.ToLower(code)
+	if i := strings.IndexAny(code, "_-."); i >= 0 {
+		code = code[:i]
+	}
+	file, err := localeFiles.Open(path.Join("locales", code+".locale"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown language %q (available: %s)", code, strings.Join(Codes(), ", "))
+	}
+	defer file.Close()
+	locale, err := Parse(code, file)
+	if err != nil {
+		return nil, fmt.Errorf("locale %s: %w", code, err)
+	}
+	return locale, nil
+}
+
+// Parse a locale file. The file consists of a name and sections of
+// translations keyed by the game's (English) mnemonics or floor numbers:
+//
+//	name = Español
+//	[mnemonics]
+//	INBOX = ENTRADA
+//	[labels]
+//	JUMPZ = saltar | si cero
+//	[levels]
+//	1 = Sala de correo
+//	[messages]
+//	prompt.continue = ¿Continuar? [s/N]
+//
+// Messages are keyed by message id, and \n in a message is a line break.
+// Lines starting with # are ignored
+func Parse(code string, r io.Reader) (*Locale, error) {
+	locale := &Locale{
+		Code:       code,
+		Name:       code,
+		Mnemonics:  instructions.DefaultMnemonics(),
+		Labels:     make(map[instructions.OpCode]string),
+		Conditions: make(map[instructions.OpCode]string),
+		LevelNames: make(map[int]string),
+		Messages:   make(map[string]string),
+	}
+	section := ""
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: expected KEY = VALUE", lineNumber)
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		switch section {
+		case "":
+			if key != "name" {
+				return nil, fmt.Errorf("line %d: unknown key %q", lineNumber, key)
+			}
+			locale.Name = value
+		case "mnemonics", "labels":
+			op, found := instructions.DefaultMnemonics().Lookup(key)
+			if !found {
+				return nil, fmt.Errorf("line %d: unknown mnemonic %q", lineNumber, key)
+			}
+			if section == "mnemonics" {
+				locale.Mnemonics.Set(op, value)
+				continue
+			}
+			label := strings.SplitN(value, "|", 2)
+			locale.Labels[op] = strings.TrimSpace(label[0])
+			if len(label) == 2 {
+				locale.Conditions[op] = strings.TrimSpace(label[1])
+			}
+		case "levels":
+			floor, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid floor %q", lineNumber, key)
+			}
+			locale.LevelNames[floor] = value
+		case "messages":
+			locale.Messages[key] = strings.ReplaceAll(value, `\n`, "\n")
+		default:
+			return nil, fmt.Errorf("line %d: unknown section %q", lineNumber, section)
+		}
+	}
+	return locale, scanner.Err()
+}
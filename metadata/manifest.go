@@ -0,0 +1,125 @@
+package metadata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// The name of the manifest file in an export directory
+const ManifestName = "hrm-manifest.json"
+
+// The profile an export was made from
+type ManifestProfile struct {
+	Path     string    `json:"path"`
+	Modified time.Time `json:"modified"`
+	SHA256   string    `json:"sha256"`
+}
+
+// A list of the files of an export with their SHA-256 checksums, for
+// detecting exports which are stale (the profile has changed since) or
+// which have been modified
+type Manifest struct {
+	Generator string          `json:"generator,omitempty"`
+	Created   time.Time       `json:"created"`
+	Profile   ManifestProfile `json:"profile"`
+	Slot      int             `json:"slot,omitempty"`
+	// Hex encoded SHA-256 checksums keyed by the path of the file relative
+	// to the export directory, with / separators
+	Files map[string]string `json:"files"`
+}
+
+// A file of an export directory which does not match its manifest
+type ManifestMismatch struct {
+	// The path relative to the export directory, with / separators
+	File string
+	// One of "missing", "modified" or "untracked" (not in the manifest)
+	Problem string
+}
+
+// Return the hex encoded SHA-256 checksum of the file at path
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Add the file at path, which is in the export directory dir, to the
+// manifest
+func (m *Manifest) AddFile(dir, path string) error {
+	name, err := filepath.Rel(dir, path)
+	if err != nil {
+		return err
+	}
+	sum, err := HashFile(path)
+	if err != nil {
+		return err
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]string)
+	}
+	m.Files[filepath.ToSlash(name)] = sum
+	return nil
+}
+
+// Write the manifest to the export directory dir
+func (m Manifest) Write(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, ManifestName), append(data, '\n'), 0644)
+}
+
+// Read the manifest of the export directory dir
+func ReadManifest(dir string) (Manifest, error) {
+	var m Manifest
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+// Compare the files of the export directory dir with the manifest,
+// returning the files which do not match sorted by path. Files in
+// subdirectories are not checked unless they are in the manifest
+func (m Manifest) Verify(dir string) ([]ManifestMismatch, error) {
+	var mismatches []ManifestMismatch
+	for name, sum := range m.Files {
+		actual, err := HashFile(filepath.Join(dir, filepath.FromSlash(name)))
+		switch {
+		case os.IsNotExist(err):
+			mismatches = append(mismatches, ManifestMismatch{name, "missing"})
+		case err != nil:
+			return nil, err
+		case actual != sum:
+			mismatches = append(mismatches, ManifestMismatch{name, "modified"})
+		}
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if _, found := m.Files[entry.Name()]; !found && !entry.IsDir() && entry.Name() != ManifestName {
+			mismatches = append(mismatches, ManifestMismatch{entry.Name(), "untracked"})
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].File < mismatches[j].File })
+	return mismatches, nil
+}
@@ -0,0 +1,190 @@
+// Package metadata provides an optional provenance envelope for exported
+// Human Resource Machine solutions. The envelope is emitted as comment
+// lines at the top of text exports and as a JSON sidecar file next to
+// image exports (SVG, PNG, ...). A manifest of checksums records the files
+// of a whole export directory
+package metadata
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The first line of a metadata header in a text export
+const TextHeader = "-- hrm-profile-tool metadata"
+
+// The prefix of every metadata line in a text export. Lines starting with
+// "--" are treated as comments by Human Resource Machine
+const textLinePrefix = "-- "
+
+// The extension appended to a file name to form its sidecar file name
+const SidecarExtension = ".json"
+
+// Provenance information for an exported solution. Zero valued fields are
+// considered unknown and are not emitted
+type Metadata struct {
+	Author      string    `json:"author,omitempty"`
+	Date        time.Time `json:"date,omitempty"`
+	Size        int       `json:"size,omitempty"`
+	Steps       int       `json:"steps,omitempty"`
+	GameVersion string    `json:"game_version,omitempty"`
+	Generator   string    `json:"generator,omitempty"`
+}
+
+// Marshal the metadata as JSON. encoding/json does not omit a zero
+// time.Time, so an unknown Date is left out here
+func (m Metadata) MarshalJSON() ([]byte, error) {
+	type plain Metadata
+	view := struct {
+		plain
+		Date *time.Time `json:"date,omitempty"`
+	}{plain: plain(m)}
+	if !m.Date.IsZero() {
+		view.Date = &m.Date
+	}
+	return json.Marshal(view)
+}
+
+// Write the metadata as a block of comment lines suitable for prefixing
+// a text export
+func (m Metadata) WriteText(w io.Writer) error {
+	var builder strings.Builder
+	builder.WriteString(TextHeader + "\n")
+	line := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&builder, "%s%s: %s\n", textLinePrefix, key, value)
+		}
+	}
+	line("author", m.Author)
+	if !m.Date.IsZero() {
+		line("date", m.Date.Format(time.RFC3339))
+	}
+	if m.Size > 0 {
+		line("size", strconv.Itoa(m.Size))
+	}
+	if m.Steps > 0 {
+		line("steps", strconv.Itoa(m.Steps))
+	}
+	line("game-version", m.GameVersion)
+	line("generator", m.Generator)
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// Return the metadata as a block of comment lines
+//
+// See: WriteText
+func (m Metadata) Text() string {
+	var builder strings.Builder
+	m.WriteText(&builder)
+	return builder.String()
+}
+
+// Parse a metadata header from the start of a text export. The returned
+// string is the remainder of the text following the header. If the text
+// has no metadata header, then the zero Metadata and the unmodified text
+// are returned
+func ParseText(text string) (Metadata, string, error) {
+	var m Metadata
+	if !strings.HasPrefix(text, TextHeader+"\n") {
+		return m, text, nil
+	}
+	rest := text[len(TextHeader)+1:]
+	for strings.HasPrefix(rest, textLinePrefix) {
+		end := strings.IndexByte(rest, '\n')
+		if end < 0 {
+			end = len(rest)
+		}
+		line := rest[len(textLinePrefix):end]
+		rest = rest[end:]
+		if len(rest) > 0 {
+			rest = rest[1:]
+		}
+		sep := strings.Index(line, ": ")
+		if sep < 0 {
+			return m, text, fmt.Errorf("malformed metadata line: %q", line)
+		}
+		key, value := line[:sep], line[sep+2:]
+		var err error
+		switch key {
+		case "author":
+			m.Author = value
+		case "date":
+			m.Date, err = time.Parse(time.RFC3339, value)
+		case "size":
+			m.Size, err = strconv.Atoi(value)
+		case "steps":
+			m.Steps, err = strconv.Atoi(value)
+		case "game-version":
+			m.GameVersion = value
+		case "generator":
+			m.Generator = value
+		}
+		if err != nil {
+			return m, text, fmt.Errorf("malformed metadata %s: %v", key, err)
+		}
+	}
+	return m, rest, nil
+}
+
+// Return the name of the sidecar file for the given export file name
+func SidecarPath(path string) string {
+	return path + SidecarExtension
+}
+
+// Write the metadata as a JSON sidecar file for the export file at path
+func (m Metadata) WriteSidecar(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(SidecarPath(path), append(data, '\n'), 0644)
+}
+
+// Read the JSON sidecar file for the export file at path
+func ReadSidecar(path string) (Metadata, error) {
+	var m Metadata
+	data, err := ioutil.ReadFile(SidecarPath(path))
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+// Load the metadata for the export file at path. A sidecar file takes
+// precedence, otherwise the file itself is checked for a text header.
+// The zero Metadata is returned if the export carries no metadata
+func Load(path string) (Metadata, error) {
+	if m, err := ReadSidecar(path); err == nil {
+		return m, nil
+	} else if !os.IsNotExist(err) {
+		return m, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer file.Close()
+	var builder strings.Builder
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		builder.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return Metadata{}, err
+	}
+	m, _, err := ParseText(builder.String())
+	return m, err
+}
@@ -0,0 +1,119 @@
+package metadata
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testMetadata = Metadata{
+	Author:      "Alice",
+	Date:        time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+	Size:        12,
+	Steps:       87,
+	GameVersion: "1.0.31459",
+	Generator:   "hrm-profile-tool test",
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	for _, m := range []Metadata{testMetadata, {Author: "Bob"}, {}} {
+		text := m.Text() + "INBOX\nOUTBOX\n"
+		parsed, rest, err := ParseText(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != m {
+			t.Errorf("parsed %+v, expected %+v", parsed, m)
+		}
+		if rest != "INBOX\nOUTBOX\n" {
+			t.Errorf("the remainder is %q", rest)
+		}
+	}
+}
+
+func TestParseTextWithoutHeader(t *testing.T) {
+	text := "-- a comment\nINBOX\n"
+	m, rest, err := ParseText(text)
+	if err != nil || m != (Metadata{}) || rest != text {
+		t.Errorf("ParseText(%q) = %+v, %q, %v", text, m, rest, err)
+	}
+}
+
+func TestParseTextMalformed(t *testing.T) {
+	for _, text := range []string{
+		TextHeader + "\n-- author Alice\n",
+		TextHeader + "\n-- size: twelve\n",
+		TextHeader + "\n-- date: yesterday\n",
+	} {
+		if _, _, err := ParseText(text); err == nil {
+			t.Errorf("ParseText(%q) succeeded", text)
+		}
+	}
+}
+
+func TestSidecarRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i, m := range []Metadata{testMetadata, {Author: "Bob"}} {
+		path := filepath.Join(dir, "export"+string(rune('0'+i))+".svg")
+		if err := m.WriteSidecar(path); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadSidecar(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read != m {
+			t.Errorf("read %+v, expected %+v", read, m)
+		}
+		loaded, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded != m {
+			t.Errorf("loaded %+v, expected %+v", loaded, m)
+		}
+	}
+}
+
+func TestLoadTextHeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "export.txt")
+	if err := ioutil.WriteFile(path, []byte(testMetadata.Text()+"INBOX\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != testMetadata {
+		t.Errorf("loaded %+v, expected %+v", m, testMetadata)
+	}
+}
+
+func TestMarshalOmitsZeroDate(t *testing.T) {
+	data, err := Metadata{Author: "Alice"}.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "date") {
+		t.Errorf("the JSON of metadata without a date is %s", data)
+	}
+	data, err = testMetadata.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"date":"2024-03-01T12:30:00Z"`) {
+		t.Errorf("the JSON of metadata with a date is %s", data)
+	}
+}
@@ -0,0 +1,121 @@
+package profile
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+type anonymizeOptions struct {
+	stripComments bool
+}
+
+// An Anonymize option
+type AnonymizeOption func(*anonymizeOptions)
+
+// Remove the drawings of the comments. The comments themselves are kept
+// (empty), so that the programs referring to them are unchanged
+func StripComments() AnonymizeOption {
+	return func(o *anonymizeOptions) {
+		o.stripComments = true
+	}
+}
+
+// What Anonymize changed
+type AnonymizeStats struct {
+	// The number of bytes which changed
+	ChangedBytes int
+	// The number of comments whose drawing was removed
+	StrippedComments int
+	// The number of bytes after the last whole slot which were dropped
+	TrailingBytes int
+}
+
+// A comment block of a tab does not hold the comments it claims
+var errCommentBlockOverflow = errors.New("comments overflow the comment block")
+
+// Anonymize the profiles.bin data in place and return it, truncated to
+// whole save slots. The game is not known to store anything identifying,
+// so this clears what could hold it unnoticed: the undecoded slot headers
+// and the stale data after the instructions and comments of every tab
+// (e.g. the drawings of deleted comments). Programs and scores are kept
+func (l Layout) Anonymize(data []byte, opts ...AnonymizeOption) ([]byte, AnonymizeStats, error) {
+	var options anonymizeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	var stats AnonymizeStats
+	original := append([]byte(nil), data...)
+
+	slots := l.SlotCount(int64(len(data)))
+	stats.TrailingBytes = len(data) - slots*int(l.SlotSize())
+	data = data[:slots*int(l.SlotSize())]
+	for slot := 1; slot <= slots; slot++ {
+		start := l.SlotStartAddr(slot)
+		zero(data[start : start+int64(l.FileHeaderSize)])
+		for floorIndex := 0; floorIndex < numFloors; floorIndex++ {
+			for tab := 0; tab < 3; tab++ {
+				offset := l.TabStartAddr(slot, floorIndex, tab)
+				if err := anonymizeTab(data[offset:offset+int64(l.FloorTabSize)], options, &stats); err != nil {
+					return nil, stats, &DecodeError{offset, indexToFloor(floorIndex), tab + 1, err}
+				}
+			}
+		}
+	}
+
+	for i := range data {
+		if data[i] != original[i] {
+			stats.ChangedBytes++
+		}
+	}
+	return data, stats, nil
+}
+
+// Clear the unused parts of the instruction and comment blocks of a tab,
+// and with StripComments the drawings of its comments
+func anonymizeTab(tab []byte, options anonymizeOptions, stats *AnonymizeStats) error {
+	count := binary.LittleEndian.Uint32(tab)
+	if count > instructions.MAX_INSTRUCTIONS {
+		return fmt.Errorf("%w: count %d", instructions.ErrTooManyInstructions, count)
+	}
+	zero(tab[4+count*instructions.INSTRUCTION_SIZE : instructions.INSTRUCTIONS_BLOCK_SIZE])
+
+	block := tab[instructions.INSTRUCTIONS_BLOCK_SIZE : instructions.INSTRUCTIONS_BLOCK_SIZE+instructions.COMMENTS_BLOCK_SIZE]
+	cleared := make([]byte, len(block))
+	comments := binary.LittleEndian.Uint32(block)
+	copy(cleared, block[:4])
+	from, to := 4, 4
+	for i := uint32(0); i < comments; i++ {
+		if from+4 > len(block) {
+			return errCommentBlockOverflow
+		}
+		points := int(binary.LittleEndian.Uint32(block[from:]))
+		length := instructions.CommentRecords(points) * instructions.COMMENT_RECORD_SIZE
+		if from+length > len(block) {
+			return errCommentBlockOverflow
+		}
+		if options.stripComments {
+			// An empty comment still takes a record
+			if points > 0 {
+				stats.StrippedComments++
+			}
+			to += instructions.COMMENT_RECORD_SIZE
+		} else {
+			copy(cleared[to:], block[from:from+4+points*4])
+			to += length
+		}
+		from += length
+	}
+	copy(block, cleared)
+	// Any padding after the comment block
+	zero(tab[instructions.INSTRUCTIONS_BLOCK_SIZE+instructions.COMMENTS_BLOCK_SIZE:])
+	return nil
+}
+
+func zero(data []byte) {
+	for i := range data {
+		data[i] = 0
+	}
+}
@@ -1,87 +1,29 @@
 package profile
 
 import (
+	"bytes"
+	"context"
 	"encoding/binary"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/clj/hrm-profile-tool/instructions"
 )
 
-const (
-	FILE_HEADER_OFFSET = 0
-	FILE_HEADER_SIZE   = 36
-	FLOOR_HEADER_SIZE  = 40
-	FLOOR_TAB_SIZE     = 46252
-	INSTRUCTIONS_SIZE  = 4100
-)
-
-// Number of floors present in the save file
-// Some floors are cut-scenes and are therefore not
-// present
-const numFloors = 36
-
-// The 'missing' floors (i.e. cut-scenes)
-var missingFloors = [...]int{5, 15, 18, 27, 33, -1}
-
-// The order of the data in the profile is not exactly
-// that of the order of floors in the game, this provides
-// a mapping from floors to indexes into the profile data
-// file
-var floorToIdx = map[int]int{
-	36: 39,
-	37: 36,
-	38: 40,
-	39: 37,
-	40: 41,
-	41: 38,
-}
-
-// Provides the above mapping, but in reverse
-var idxToFloor map[int]int // set up in init()
-
-// Given a floor (as shown in the game) return the index
-// in the profile data file for that floor
-func FloorToIndex(floor int) int {
-	if adjustedFloor, found := floorToIdx[floor]; found {
-		floor = adjustedFloor
-	}
-	var missingFloor int
-	i := 0
-	for i, missingFloor = range missingFloors {
-		if floor < missingFloor {
-			break
-		}
-	}
-	return floor - i - 1
-}
+// The size of the instruction block at the start of a tab, the comment
+// block follows it. The sizes of the other parts of a profile depend on the
+// game version, see Layout
+const INSTRUCTIONS_SIZE = instructions.INSTRUCTIONS_BLOCK_SIZE
 
-// Given an index into the profile data file return the
-// floor number (as shown in the game)
-func IndexToFloor(index int) int {
-	var missingFloor int
-	i := 0
-	for i, missingFloor = range missingFloors {
-		if index < missingFloor-1-i {
-			break
-		}
-	}
-	floor := index + i + 1
-	if adjustedFloor, found := idxToFloor[floor]; found {
-		floor = adjustedFloor
-	}
-	return floor
-}
-
-// Given a profile number and a floor index (e.g. from FloorToIndex) return the start address
-// in the profiles.bin file of the floor
+// Like Layout.FloorStartAddr, for the default layout
 func FloorStartAddr(profile, floorIndex int) int64 {
-	return int64(FILE_HEADER_OFFSET + FILE_HEADER_SIZE + floorIndex*(FLOOR_HEADER_SIZE+FLOOR_TAB_SIZE*3))
+	return DefaultLayout.FloorStartAddr(profile, floorIndex)
 }
 
-// Given a profile number and a floor index (e.g. from FloorToIndex), and a tab number return
-// the start address in the profiles.bin file of the tab from that floor
+// Like Layout.TabStartAddr, for the default layout
 func TabStartAddr(profile, floorIndex, tab int) int64 {
-	return FloorStartAddr(profile, floorIndex) + FLOOR_HEADER_SIZE + int64(FLOOR_TAB_SIZE*tab)
+	return DefaultLayout.TabStartAddr(profile, floorIndex, tab)
 }
 
 // A decoded code tab
@@ -92,6 +34,11 @@ type Tab struct {
 	Comments    instructions.Comments
 }
 
+// Return the canonical hash of the tab's program, see Disassembled.Hash
+func (t Tab) Hash() string {
+	return t.Code.Hash()
+}
+
 // A decoded floor
 type Floor struct {
 	Offset         int
@@ -104,6 +51,9 @@ type Floor struct {
 // A decoded profile
 type Profile struct {
 	Floors [numFloors]Floor
+	// The errors of the floor headers and blocks which failed to decode
+	// when decoding with Quarantine. The floors and tabs are left empty
+	Errors []*DecodeError
 }
 
 // The raw floor header
@@ -118,11 +68,67 @@ type FloorHeader struct {
 	SpeedChallengeSteps     uint32
 	Unknown8                uint32
 	Unknown9                uint32
+	// The header as read from the profile, including the parts of it not
+	// decoded into the fields above. MarshalBinary writes the fields over
+	// it, so that the rest of the header is preserved byte for byte
+	RawHeader []byte
+}
+
+// The size of the decoded fields at the start of a floor header
+const floorHeaderFieldsSize = 10 * 4
+
+// Return pointers to the decoded fields of the header, in file order
+func (h *FloorHeader) fields() []interface{} {
+	return []interface{}{
+		&h.Unknown0, &h.Unknown1, &h.Unknown2, &h.Unknown3,
+		&h.SizeChallengeCompleted, &h.SpeedChallengeCompleted,
+		&h.SizeChallengeCommands, &h.SpeedChallengeSteps,
+		&h.Unknown8, &h.Unknown9,
+	}
+}
+
+// Like Layout.ReadFloorHeader, for the default layout
+func ReadFloorHeader(reader io.ReadSeeker, profile, floorIndex int) (FloorHeader, error) {
+	return DefaultLayout.ReadFloorHeader(reader, profile, floorIndex)
 }
 
-// Given an in game floor number, return the floor data
-func (p Profile) GetFloor(number int) Floor {
-	return p.Floors[FloorToIndex(number)]
+// Like Layout.ReadFloorHeaderAt, for the default layout
+func ReadFloorHeaderAt(r io.ReaderAt, profile, floorIndex int) (FloorHeader, error) {
+	return DefaultLayout.ReadFloorHeaderAt(r, profile, floorIndex)
+}
+
+// An error decoding part of a profile
+type DecodeError struct {
+	Offset int64 // Start address of the data that failed to decode
+	Floor  int   // In game floor number, or 0 if not applicable
+	Tab    int   // Tab number (starting at 1), or 0 if not applicable
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	switch {
+	case e.Tab > 0:
+		return fmt.Sprintf("floor %d tab %d (offset 0x%X): %v", e.Floor, e.Tab, e.Offset, e.Err)
+	case e.Floor > 0:
+		return fmt.Sprintf("floor %d (offset 0x%X): %v", e.Floor, e.Offset, e.Err)
+	default:
+		return fmt.Sprintf("offset 0x%X: %v", e.Offset, e.Err)
+	}
+}
+
+// Return the underlying error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Given an in game floor number, return the floor data. Returns an error
+// wrapping ErrNoSuchFloor for cut-scenes and out of range numbers
+func (p Profile) GetFloor(number int) (Floor, error) {
+	index, err := FloorToIndex(number)
+	if err != nil {
+		return Floor{}, err
+	}
+	return p.Floors[index], nil
 }
 
 // func (t Tab) RenderSVG() string {
@@ -138,61 +144,141 @@ func (p Profile) GetFloor(number int) Floor {
 // }
 
 // Decode and return a profile from the given reader
-func Decode(reader io.ReadSeeker) (Profile, error) {
-	var profile Profile
+func Decode(reader io.ReadSeeker, opts ...DecodeOption) (Profile, error) {
+	return DecodeContext(context.Background(), reader, opts...)
+}
+
+// Like Decode, but stops decoding and returns the context's error if ctx
+// is cancelled
+func DecodeContext(ctx context.Context, reader io.ReadSeeker, opts ...DecodeOption) (Profile, error) {
+	if r, ok := reader.(io.ReaderAt); ok {
+		return DecodeAt(ctx, r, opts...)
+	}
+	return DecodeAt(ctx, readSeekerAt{reader}, opts...)
+}
 
-	missingIdx := 0
-	for floorNumber := 0; floorNumber < numFloors; floorNumber++ {
-		var floorHeader FloorHeader
-		var floor Floor
-		if missingIdx < len(missingFloors) && floorNumber+1+missingIdx == missingFloors[missingIdx] {
-			missingIdx++
+// Decode and return a profile from r. All data is read at explicit offsets,
+// so r may be shared by concurrent callers (e.g. an *os.File). Decoding
+// stops and the context's error is returned if ctx is cancelled.
+//
+// By default the first floor header or block that fails to decode is
+// returned as a *DecodeError, see Quarantine to decode the rest
+func DecodeAt(ctx context.Context, r io.ReaderAt, opts ...DecodeOption) (Profile, error) {
+	options := newDecodeOptions(opts)
+	start := time.Now()
+	var profile Profile
+	// Return err, or with Quarantine record it and return nil. Cancellation
+	// is never quarantined
+	quarantine := func(err *DecodeError) error {
+		if !options.quarantine || ctx.Err() != nil {
+			return err
 		}
-		floor_start := int64(FILE_HEADER_OFFSET + FILE_HEADER_SIZE + floorNumber*(FLOOR_HEADER_SIZE+FLOOR_TAB_SIZE*3))
-		reader.Seek(floor_start, io.SeekStart)
-		if err := binary.Read(reader, binary.LittleEndian, &floorHeader); err != nil {
+		options.logger.Warn("quarantined undecodable data", "error", err)
+		profile.Errors = append(profile.Errors, err)
+		return nil
+	}
+
+	for floorIndex := 0; floorIndex < numFloors; floorIndex++ {
+		if err := ctx.Err(); err != nil {
 			return Profile{}, err
 		}
+		floorStart := options.layout.FloorStartAddr(options.slot, floorIndex)
+		floorNumber := indexToFloor(floorIndex)
+		options.logger.Debug("decoding floor", "floor", floorNumber, "floor_index", floorIndex, "offset", floorStart)
+		floor := Floor{Offset: int(floorStart)}
 		floor.SizeChallenge, floor.SpeedChallenge = -1, -1
-		if floorHeader.SpeedChallengeCompleted > 0 {
-			floor.SpeedChallenge = int(floorHeader.SpeedChallengeSteps)
-		}
-		if floorHeader.SizeChallengeCompleted > 0 {
-			floor.SizeChallenge = int(floorHeader.SizeChallengeCommands)
+		floorHeader, err := options.layout.ReadFloorHeaderAt(r, options.slot, floorIndex)
+		if err != nil {
+			if err := quarantine(&DecodeError{floorStart, floorNumber, 0, err}); err != nil {
+				return Profile{}, err
+			}
+		} else {
+			floor.Completed = floorHeader.SizeChallengeCompleted > 0 || floorHeader.SpeedChallengeCompleted > 0
+			// No program takes 0 commands or steps, a flag set with a
+			// count of 0 records no result
+			if floorHeader.SpeedChallengeCompleted > 0 && floorHeader.SpeedChallengeSteps > 0 {
+				floor.SpeedChallenge = int(floorHeader.SpeedChallengeSteps)
+			}
+			if floorHeader.SizeChallengeCompleted > 0 && floorHeader.SizeChallengeCommands > 0 {
+				floor.SizeChallenge = int(floorHeader.SizeChallengeCommands)
+			}
 		}
 
 		for tab := 0; tab < 3; tab++ {
-			tab_start := floor_start + FLOOR_HEADER_SIZE + int64(FLOOR_TAB_SIZE*tab)
+			tabStart := options.layout.TabStartAddr(options.slot, floorIndex, tab)
+			tabLogger := options.logger.With("floor", floorNumber, "tab", tab+1)
+			decodeOpts := append([]instructions.DecodeOption{instructions.Logger(tabLogger)}, options.programOpts...)
+			floor.Tabs[tab].Offset = int(tabStart)
 
-			reader.Seek(tab_start, io.SeekStart)
-
-			instructionList, err := instructions.DecodeInstructions(reader)
-			if err != nil {
-				return Profile{}, err
+			instructionList, err := instructions.DecodeInstructionsAt(ctx, r, tabStart, decodeOpts...)
+			if err == nil {
+				floor.Tabs[tab].Code, err = instructions.Disassemble(instructionList)
 			}
-			floor.Tabs[tab].Code = instructions.Disassemble(instructionList)
 			if err != nil {
-				return Profile{}, err
+				floor.Tabs[tab].Code = nil
+				if err := quarantine(&DecodeError{tabStart, floorNumber, tab + 1, err}); err != nil {
+					return Profile{}, err
+				}
 			}
 
-			reader.Seek(tab_start+INSTRUCTIONS_SIZE, io.SeekStart)
-			floor.Tabs[tab].RawComments, err = instructions.DecodeRawComments(reader)
-			if err != nil {
-				return Profile{}, err
+			commentsStart := tabStart + INSTRUCTIONS_SIZE
+			floor.Tabs[tab].RawComments, err = instructions.DecodeRawCommentsAt(ctx, r, commentsStart, decodeOpts...)
+			if err == nil {
+				floor.Tabs[tab].Comments, err = instructions.DecodeComments(floor.Tabs[tab].RawComments, decodeOpts...)
 			}
-			floor.Tabs[tab].Comments, err = instructions.DecodeComments(floor.Tabs[tab].RawComments)
 			if err != nil {
-				return Profile{}, err
+				floor.Tabs[tab].RawComments, floor.Tabs[tab].Comments = nil, nil
+				if err := quarantine(&DecodeError{commentsStart, floorNumber, tab + 1, err}); err != nil {
+					return Profile{}, err
+				}
 			}
 		}
-		profile.Floors[floorNumber] = floor
+		profile.Floors[floorIndex] = floor
 	}
+	options.logger.Debug("decoded profile", "floors", numFloors, "quarantined", len(profile.Errors), "duration", time.Since(start))
 	return profile, nil
 }
 
-func init() {
-	idxToFloor = make(map[int]int)
-	for key := range floorToIdx {
-		idxToFloor[floorToIdx[key]] = key
+// Adapts an io.ReadSeeker to an io.ReaderAt. Unlike a real io.ReaderAt
+// it is not safe for concurrent use
+type readSeekerAt struct {
+	io.ReadSeeker
+}
+
+func (r readSeekerAt) ReadAt(p []byte, offset int64) (int, error) {
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r, p)
+}
+
+// Decode a floor header as stored in a profile, retaining all of data as
+// the RawHeader
+func (h *FloorHeader) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+	for _, field := range h.fields() {
+		if err := binary.Read(reader, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+	h.RawHeader = append([]byte(nil), data...)
+	return nil
+}
+
+// Return the floor header as stored in a profile: the RawHeader with the
+// decoded fields written over its start
+func (h FloorHeader) MarshalBinary() ([]byte, error) {
+	var fields bytes.Buffer
+	for _, field := range h.fields() {
+		if err := binary.Write(&fields, binary.LittleEndian, field); err != nil {
+			return nil, err
+		}
+	}
+	data := make([]byte, floorHeaderFieldsSize)
+	if len(h.RawHeader) > len(data) {
+		data = make([]byte, len(h.RawHeader))
 	}
+	copy(data, h.RawHeader)
+	copy(data, fields.Bytes())
+	return data, nil
 }
@@ -0,0 +1,166 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+func TestDecodeQuarantinesCorruptCommentCount(t *testing.T) {
+	data := make([]byte, LayoutPC.SlotSize())
+	commentsStart := LayoutPC.TabStartAddr(1, 0, 1) + INSTRUCTIONS_SIZE
+	binary.LittleEndian.PutUint32(data[commentsStart:], 0x7fffffff)
+
+	_, err := DecodeAt(context.Background(), bytes.NewReader(data))
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || !errors.Is(err, instructions.ErrTooManyComments) {
+		t.Fatalf("got error %v, want a *DecodeError wrapping ErrTooManyComments", err)
+	}
+
+	profile, err := DecodeAt(context.Background(), bytes.NewReader(data), Quarantine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profile.Errors) != 1 {
+		t.Fatalf("got %d quarantined error(s), want 1", len(profile.Errors))
+	}
+	if e := profile.Errors[0]; e.Offset != commentsStart || e.Tab != 2 || !errors.Is(e, instructions.ErrTooManyComments) {
+		t.Errorf("got quarantined error %v, want floor 1 tab 2 at 0x%X", e, commentsStart)
+	}
+}
+
+func TestFloorHeaderRoundTrip(t *testing.T) {
+	// A floor header with every byte set, including those after the
+	// decoded fields, as written by a layout with a longer header
+	for _, size := range []int{floorHeaderFieldsSize, floorHeaderFieldsSize + 12} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i*7 + 1)
+		}
+		var header FloorHeader
+		if err := header.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := header.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encoded, data) {
+			t.Errorf("%d byte header: got %x, want %x", size, encoded, data)
+		}
+
+		// Changing a field changes only its bytes
+		header.SpeedChallengeSteps = 0x11223344
+		encoded, err = header.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := append([]byte(nil), data...)
+		binary.LittleEndian.PutUint32(want[7*4:], 0x11223344)
+		if !bytes.Equal(encoded, want) {
+			t.Errorf("%d byte header with new steps: got %x, want %x", size, encoded, want)
+		}
+	}
+}
+
+func TestReadFloorHeaderRoundTrip(t *testing.T) {
+	data := make([]byte, LayoutPC.SlotSize())
+	for i := range data {
+		data[i] = byte(i)
+	}
+	start := LayoutPC.FloorStartAddr(1, 3)
+	header, err := LayoutPC.ReadFloorHeaderAt(bytes.NewReader(data), 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := header.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := data[start : start+int64(LayoutPC.FloorHeaderSize)]; !bytes.Equal(encoded, want) {
+		t.Errorf("got %x, want %x", encoded, want)
+	}
+}
+
+// Return a save slot with a program of instructions and a comment in every
+// tab
+func benchmarkSlot(b *testing.B) []byte {
+	text := "-- HUMAN RESOURCE MACHINE PROGRAM --\n\n    COMMENT 0\na:\n"
+	for i := 0; i < 40; i++ {
+		text += "    INBOX\n    COPYTO 0\n    ADD [0]\n    OUTBOX\n"
+	}
+	text += "    JUMP a\n"
+	program, _, err := instructions.Assemble(text)
+	if err != nil {
+		b.Fatal(err)
+	}
+	comment := make(instructions.RawComment, 100)
+	tab, err := instructions.EncodeTab(program, instructions.RawComments{comment})
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := make([]byte, LayoutPC.SlotSize())
+	for floorIndex := 0; floorIndex < numFloors; floorIndex++ {
+		for t := 0; t < 3; t++ {
+			copy(data[LayoutPC.TabStartAddr(1, floorIndex, t):], tab)
+		}
+	}
+	return data
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data := benchmarkSlot(b)
+	reader := bytes.NewReader(data)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeAt(context.Background(), reader); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// A challenge flag set with a count of 0 (as written by older versions of
+// set-completed) completes the floor but records no result
+func TestDecodeZeroResults(t *testing.T) {
+	data := make([]byte, LayoutPC.SlotSize())
+	write := func(floorIndex int, header FloorHeader) {
+		encoded, err := header.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		copy(data[LayoutPC.FloorStartAddr(1, floorIndex):], encoded)
+	}
+	write(0, FloorHeader{SizeChallengeCompleted: 1, SpeedChallengeCompleted: 1})
+	write(1, FloorHeader{SizeChallengeCompleted: 1, SpeedChallengeCompleted: 1, SizeChallengeCommands: 6, SpeedChallengeSteps: 25})
+
+	p, err := DecodeAt(context.Background(), bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if floor := p.Floors[0]; !floor.Completed || floor.SizeChallenge != -1 || floor.SpeedChallenge != -1 {
+		t.Errorf("floor 1: got completed %v, size %d, speed %d, want true, -1, -1", floor.Completed, floor.SizeChallenge, floor.SpeedChallenge)
+	}
+	summary := Summary(p)
+	if summary.Solved != 2 || summary.SizeResults != 1 || summary.TotalSize != 6 || summary.StepsResults != 1 || summary.TotalSteps != 25 {
+		t.Errorf("got summary %+v", summary)
+	}
+}
+
+func TestSummaryIgnoresZeroResults(t *testing.T) {
+	var p Profile
+	for i := range p.Floors {
+		p.Floors[i].SizeChallenge, p.Floors[i].SpeedChallenge = -1, -1
+	}
+	p.Floors[0] = Floor{Completed: true, SizeChallenge: 0, SpeedChallenge: 0}
+	p.Floors[1] = Floor{Completed: true, SizeChallenge: 6, SpeedChallenge: 6}
+	summary := Summary(p)
+	if summary.SizeResults != 1 || summary.StepsResults != 1 || summary.AverageSize() != 6 || summary.AverageSteps() != 6 {
+		t.Errorf("got summary %+v", summary)
+	}
+}
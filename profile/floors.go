@@ -0,0 +1,143 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A floor of the game, as shown in the elevator. The cut-scenes between
+// some floors count as floors, but have no data in the profile
+type FloorInfo struct {
+	// The floor number, as shown in the game
+	Number int
+	// The index of the floor's data in the profile, or -1 for cut-scenes
+	Index int
+	// The English name of the floor
+	Name string
+	// Whether the floor is a cut-scene
+	Cutscene bool
+}
+
+// Every floor of the game, in game order. The floors are not stored in game
+// order in the profile, the last floors are interleaved
+var FloorTable = []FloorInfo{
+	{1, 0, "Mail Room", false},
+	{2, 1, "Busy Mail Room", false},
+	{3, 2, "Copy Floor", false},
+	{4, 3, "Scrambler Handler", false},
+	{5, -1, "Coffee Time", true},
+	{6, 4, "Rainy Summer", false},
+	{7, 5, "Zero Exterminator", false},
+	{8, 6, "Tripler Room", false},
+	{9, 7, "Zero Preservation Initiative", false},
+	{10, 8, "Octoplier Suite", false},
+	{11, 9, "Sub Hallway", false},
+	{12, 10, "Tetracontiplier", false},
+	{13, 11, "Equalization Room", false},
+	{14, 12, "Maximization Room", false},
+	{15, -1, "Employee Morale Insertion", true},
+	{16, 13, "Absolute Positivity", false},
+	{17, 14, "Exclusive Lounge", false},
+	{18, -1, "Sabbatical Beach Paradise", true},
+	{19, 15, "Countdown", false},
+	{20, 16, "Multiplication Workshop", false},
+	{21, 17, "Zero Terminated Sum", false},
+	{22, 18, "Fibonacci Visitor", false},
+	{23, 19, "The Littlest Number", false},
+	{24, 20, "Mod Module", false},
+	{25, 21, "Cumulative Countdown", false},
+	{26, 22, "Small Divide", false},
+	{27, -1, "Midnight Petroleum", true},
+	{28, 23, "Three Sort", false},
+	{29, 24, "Storage Floor", false},
+	{30, 25, "String Storage Floor", false},
+	{31, 26, "String Reverse", false},
+	{32, 27, "Inventory Report", false},
+	{33, -1, "Where's Carol?", true},
+	{34, 28, "Vowel Incinerator", false},
+	{35, 29, "Duplicate Removal", false},
+	{36, 33, "Alphabetizer", false},
+	{37, 30, "Scavenger Chain", false},
+	{38, 34, "Digit Exploder", false},
+	{39, 31, "Re-Coordinator", false},
+	{40, 35, "Prime Factory", false},
+	{41, 32, "Sorting Floor", false},
+}
+
+// Number of floors present in the save file, i.e. the floors of
+// FloorTable which are not cut-scenes
+const numFloors = 36
+
+// The floor stored at each index of the profile
+var floorsByIndex = func() (floors [numFloors]FloorInfo) {
+	for _, info := range FloorTable {
+		// Invalid indexes are reported by TestFloorTable
+		if !info.Cutscene && info.Index < numFloors {
+			floors[info.Index] = info
+		}
+	}
+	return floors
+}()
+
+// An error for floor numbers which are not floors of the game and for
+// indexes beyond the floors of the profile
+var ErrNoSuchFloor = errors.New("no such floor")
+
+// An error for floor numbers of cut-scenes, which have no data in the
+// profile. It wraps ErrNoSuchFloor
+var ErrCutscene = fmt.Errorf("cut-scene: %w", ErrNoSuchFloor)
+
+// Return the floor of FloorTable with the number (as shown in the game)
+func FloorInfoFor(number int) (FloorInfo, bool) {
+	if number < 1 || number > len(FloorTable) {
+		return FloorInfo{}, false
+	}
+	return FloorTable[number-1], true
+}
+
+// Report whether the floor (as shown in the game) is stored in the
+// profile, i.e. it is a floor of the game and not a cut-scene
+func FloorExists(floor int) bool {
+	info, found := FloorInfoFor(floor)
+	return found && !info.Cutscene
+}
+
+// Return the numbers of the floors stored in the profile, in game order
+func AllFloors() []int {
+	floors := make([]int, 0, numFloors)
+	for _, info := range FloorTable {
+		if !info.Cutscene {
+			floors = append(floors, info.Number)
+		}
+	}
+	return floors
+}
+
+// Given a floor (as shown in the game) return the index in the profile
+// data file for that floor. Returns an error wrapping ErrCutscene for
+// cut-scenes, or ErrNoSuchFloor for numbers which are not floors
+func FloorToIndex(floor int) (int, error) {
+	info, found := FloorInfoFor(floor)
+	switch {
+	case !found:
+		return 0, fmt.Errorf("floor %d: %w", floor, ErrNoSuchFloor)
+	case info.Cutscene:
+		return 0, fmt.Errorf("floor %d (%s): %w", floor, info.Name, ErrCutscene)
+	}
+	return info.Index, nil
+}
+
+// Given an index into the profile data file return the floor number (as
+// shown in the game). Returns an error wrapping ErrNoSuchFloor if the
+// index is out of range
+func IndexToFloor(index int) (int, error) {
+	if index < 0 || index >= numFloors {
+		return 0, fmt.Errorf("floor index %d: %w", index, ErrNoSuchFloor)
+	}
+	return indexToFloor(index), nil
+}
+
+// Like IndexToFloor, for indexes known to be valid
+func indexToFloor(index int) int {
+	return floorsByIndex[index].Number
+}
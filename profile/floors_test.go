@@ -0,0 +1,49 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/clj/hrm-profile-tool/levels"
+)
+
+// FloorTable must map the floors to the indexes of the profile one to one,
+// and agree with the levels
+func TestFloorTable(t *testing.T) {
+	seen := make(map[int]int)
+	for i, info := range FloorTable {
+		if info.Number != i+1 {
+			t.Errorf("FloorTable entry %d has floor number %d", i, info.Number)
+		}
+		level, isLevel := levels.Lookup(info.Number)
+		switch {
+		case info.Cutscene != (info.Index < 0):
+			t.Errorf("floor %d has index %d, but cut-scene is %v", info.Number, info.Index, info.Cutscene)
+		case info.Cutscene == isLevel:
+			t.Errorf("floor %d is a level, but cut-scene is %v", info.Number, info.Cutscene)
+		case isLevel && level.Name != info.Name:
+			t.Errorf("floor %d is named %q, but the level is %q", info.Number, info.Name, level.Name)
+		case info.Cutscene:
+		case info.Index >= numFloors:
+			t.Errorf("floor %d has index %d, beyond the %d floors of the profile", info.Number, info.Index, numFloors)
+		case seen[info.Index] != 0:
+			t.Errorf("floors %d and %d have the same index %d", seen[info.Index], info.Number, info.Index)
+		default:
+			seen[info.Index] = info.Number
+		}
+	}
+	if len(seen) != numFloors {
+		t.Errorf("FloorTable has %d floors, expected %d", len(seen), numFloors)
+	}
+}
+
+func TestFloorIndexRoundTrip(t *testing.T) {
+	for _, floor := range AllFloors() {
+		index, err := FloorToIndex(floor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := IndexToFloor(index); err != nil || got != floor {
+			t.Errorf("floor %d has index %d, which maps back to floor %d (%v)", floor, index, got, err)
+		}
+	}
+}
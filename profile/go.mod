@@ -2,6 +2,15 @@ module github.com/clj/hrm-profile-tool/profile
 
 require (
 	github.com/clj/hrm-profile-tool/instructions v0.0.0
+	github.com/clj/hrm-profile-tool/levels v0.0.0
+	github.com/clj/hrm-profile-tool/schema v0.0.0
+	github.com/clj/hrm-profile-tool/utils/logging v0.0.0
 )
 
 replace github.com/clj/hrm-profile-tool/instructions => ../instructions
+
+replace github.com/clj/hrm-profile-tool/utils/logging => ../utils/logging
+
+replace github.com/clj/hrm-profile-tool/schema => ../schema
+
+replace github.com/clj/hrm-profile-tool/levels => ../levels
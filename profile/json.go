@@ -0,0 +1,39 @@
+package profile
+
+import (
+	"github.com/clj/hrm-profile-tool/levels"
+	"github.com/clj/hrm-profile-tool/schema"
+)
+
+// Return the JSON representation (see the schema package) of the profile
+// decoded from save slot
+func (p Profile) Schema(slot int) schema.Profile {
+	view := schema.Profile{SchemaVersion: schema.Version, Slot: slot, Floors: []schema.Floor{}}
+	for _, level := range levels.Levels() {
+		floor, err := p.GetFloor(level.Floor)
+		if err != nil {
+			continue
+		}
+		f := schema.Floor{
+			Number:      level.Floor,
+			Name:        level.Name,
+			Completed:   floor.Completed,
+			SizeTarget:  level.SizeChallenge,
+			SpeedTarget: level.SpeedChallenge,
+			Tabs:        []schema.Tab{},
+		}
+		if floor.SizeChallenge >= 0 {
+			size := floor.SizeChallenge
+			f.Size = &size
+		}
+		if floor.SpeedChallenge >= 0 {
+			speed := floor.SpeedChallenge
+			f.Speed = &speed
+		}
+		for i, tab := range floor.Tabs {
+			f.Tabs = append(f.Tabs, schema.Tab{Tab: i + 1, Program: schema.NewProgram(tab.Code, tab.Comments)})
+		}
+		view.Floors = append(view.Floors, f)
+	}
+	return view
+}
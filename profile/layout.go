@@ -0,0 +1,121 @@
+package profile
+
+import "io"
+
+// The layout of the data in a profiles.bin, which differs between game
+// versions. All sizes are in bytes. The layout of a tab (the instruction
+// and comment blocks, see the instructions package) is the same in every
+// version
+type Layout struct {
+	// The name of the layout, e.g. for a --layout flag
+	Name string
+	// The size of the header at the start of a save slot
+	FileHeaderSize int
+	// The size of the header of a floor, at least the size of the decoded
+	// fields of FloorHeader
+	FloorHeaderSize int
+	// The size of a tab: the instruction block, the comment block and
+	// any padding
+	FloorTabSize int
+}
+
+// The layout of the released PC versions (Steam, GOG, Humble)
+var LayoutPC = Layout{
+	Name:            "pc",
+	FileHeaderSize:  36,
+	FloorHeaderSize: 40,
+	FloorTabSize:    46252,
+}
+
+// The layout used when no layout is given or detected
+var DefaultLayout = LayoutPC
+
+// The known layouts, in order of preference when detecting the layout of
+// a profile. Layouts of other versions (early builds, console ports) are
+// added here as they are documented
+var layouts = []Layout{LayoutPC}
+
+// Return the known layouts
+func Layouts() []Layout {
+	return append([]Layout(nil), layouts...)
+}
+
+// Return the known layout called name
+func LayoutByName(name string) (Layout, bool) {
+	for _, layout := range layouts {
+		if layout.Name == name {
+			return layout, true
+		}
+	}
+	return Layout{}, false
+}
+
+// Return the layout of a profiles.bin of size bytes: the first known layout
+// for which the profile consists of whole save slots
+func DetectLayout(size int64) (Layout, bool) {
+	for _, layout := range layouts {
+		if size > 0 && size%layout.SlotSize() == 0 {
+			return layout, true
+		}
+	}
+	return Layout{}, false
+}
+
+// Return the size of a save slot: a file header followed by the floors
+func (l Layout) SlotSize() int64 {
+	return int64(l.FileHeaderSize + numFloors*l.floorSize())
+}
+
+// Return the size of a floor: the floor header followed by three tabs
+func (l Layout) floorSize() int {
+	return l.FloorHeaderSize + l.FloorTabSize*3
+}
+
+// Return the number of complete slots in a profiles.bin of size bytes
+func (l Layout) SlotCount(size int64) int {
+	return int(size / l.SlotSize())
+}
+
+// Return the start address in the profiles.bin file of the slot number
+// (starting at 1)
+func (l Layout) SlotStartAddr(number int) int64 {
+	return int64(number-1) * l.SlotSize()
+}
+
+// Given a profile number and a floor index (e.g. from FloorToIndex) return the start address
+// in the profiles.bin file of the floor
+func (l Layout) FloorStartAddr(profile, floorIndex int) int64 {
+	return l.SlotStartAddr(profile) + int64(l.FileHeaderSize+floorIndex*l.floorSize())
+}
+
+// Given a profile number and a floor index (e.g. from FloorToIndex), and a tab number return
+// the start address in the profiles.bin file of the tab from that floor
+func (l Layout) TabStartAddr(profile, floorIndex, tab int) int64 {
+	return l.FloorStartAddr(profile, floorIndex) + int64(l.FloorHeaderSize+l.FloorTabSize*tab)
+}
+
+// Read the raw floor header of the floor at the given floor index
+// (e.g. from FloorToIndex)
+func (l Layout) ReadFloorHeader(reader io.ReadSeeker, profile, floorIndex int) (FloorHeader, error) {
+	var floorHeader FloorHeader
+	if _, err := reader.Seek(l.FloorStartAddr(profile, floorIndex), io.SeekStart); err != nil {
+		return floorHeader, err
+	}
+	data := make([]byte, l.FloorHeaderSize)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return floorHeader, err
+	}
+	err := floorHeader.UnmarshalBinary(data)
+	return floorHeader, err
+}
+
+// Like ReadFloorHeader, but reads from r at the floor's offset
+func (l Layout) ReadFloorHeaderAt(r io.ReaderAt, profile, floorIndex int) (FloorHeader, error) {
+	var floorHeader FloorHeader
+	data := make([]byte, l.FloorHeaderSize)
+	if _, err := r.ReadAt(data, l.FloorStartAddr(profile, floorIndex)); err != nil {
+		return floorHeader, err
+	}
+	err := floorHeader.UnmarshalBinary(data)
+	return floorHeader, err
+}
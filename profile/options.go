@@ -0,0 +1,69 @@
+package profile
+
+import (
+	"log/slog"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/utils/logging"
+)
+
+type decodeOptions struct {
+	logger      *slog.Logger
+	slot        int
+	layout      Layout
+	programOpts []instructions.DecodeOption
+	quarantine  bool
+}
+
+// A Decode option
+type DecodeOption func(*decodeOptions)
+
+// Log decode warnings, progress, and timing to logger. By default nothing
+// is logged
+func Logger(logger *slog.Logger) DecodeOption {
+	return func(o *decodeOptions) {
+		o.logger = logger
+	}
+}
+
+// Decode the profile in slot number (starting at 1). By default slot 1 is
+// decoded
+func DecodeSlot(number int) DecodeOption {
+	return func(o *decodeOptions) {
+		o.slot = number
+	}
+}
+
+// Decode a profile with layout (see DetectLayout). By default
+// DefaultLayout is used
+func DecodeLayout(layout Layout) DecodeOption {
+	return func(o *decodeOptions) {
+		o.layout = layout
+	}
+}
+
+// Decode the programs and comments of the tabs with opts, e.g. the
+// coordinate space of the comments (see instructions.CommentCoordinates)
+func ProgramOptions(opts ...instructions.DecodeOption) DecodeOption {
+	return func(o *decodeOptions) {
+		o.programOpts = append(o.programOpts, opts...)
+	}
+}
+
+// Carry on decoding past floor headers and blocks which fail to decode,
+// leaving their floor or tab empty and recording the errors in
+// Profile.Errors, so that mostly intact profiles can still be read
+func Quarantine() DecodeOption {
+	return func(o *decodeOptions) {
+		o.quarantine = true
+	}
+}
+
+func newDecodeOptions(opts []DecodeOption) decodeOptions {
+	options := decodeOptions{slot: 1, layout: DefaultLayout}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.logger = logging.OrDiscard(options.logger)
+	return options
+}
@@ -0,0 +1,68 @@
+package profile
+
+import (
+	"fmt"
+	"io"
+)
+
+// A save slot in a profiles.bin
+type Slot struct {
+	Number int // Starting at 1
+	Offset int64
+	// The header of the slot (Layout.FileHeaderSize bytes). No field of the
+	// header has been decoded yet, it is all zeros in every profile seen so
+	// far
+	Header []byte
+}
+
+// Return the name of the slot. The game does not appear to store a name
+// in the header, so the name is derived from the slot number
+func (s Slot) Name() string {
+	return fmt.Sprintf("Slot %d", s.Number)
+}
+
+// Like Layout.SlotCount, for the default layout
+func SlotCount(size int64) int {
+	return DefaultLayout.SlotCount(size)
+}
+
+// Like Layout.SlotStartAddr, for the default layout
+func SlotStartAddr(number int) int64 {
+	return DefaultLayout.SlotStartAddr(number)
+}
+
+// Like Layout.ReadSlotsAt, for the default layout
+func ReadSlotsAt(r io.ReaderAt, size int64) ([]Slot, error) {
+	return DefaultLayout.ReadSlotsAt(r, size)
+}
+
+// Read the slots of a profiles.bin of size bytes
+func (l Layout) ReadSlotsAt(r io.ReaderAt, size int64) ([]Slot, error) {
+	slots := make([]Slot, l.SlotCount(size))
+	for i := range slots {
+		slots[i].Number = i + 1
+		slots[i].Offset = l.SlotStartAddr(i + 1)
+		slots[i].Header = make([]byte, l.FileHeaderSize)
+		if _, err := r.ReadAt(slots[i].Header, slots[i].Offset); err != nil {
+			return nil, &DecodeError{slots[i].Offset, 0, 0, err}
+		}
+	}
+	return slots, nil
+}
+
+// Like Layout.WriteEmpty, for the default layout
+func WriteEmpty(w io.Writer, slots int) error {
+	return DefaultLayout.WriteEmpty(w, slots)
+}
+
+// Write an empty profile with the number of save slots: every header is
+// zero and every tab holds no instructions and no comments
+func (l Layout) WriteEmpty(w io.Writer, slots int) error {
+	empty := make([]byte, l.SlotSize())
+	for i := 0; i < slots; i++ {
+		if _, err := w.Write(empty); err != nil {
+			return err
+		}
+	}
+	return nil
+}
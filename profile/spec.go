@@ -0,0 +1,163 @@
+package profile
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+// A field of the floor header, as described by a format specification
+type specField struct {
+	name   string
+	signed bool
+}
+
+// Return the decoded fields of the FloorHeader struct, which mirror the
+// layout in the file (the RawHeader is not a field of the file)
+func floorHeaderSpecFields() []specField {
+	t := reflect.TypeOf(FloorHeader{})
+	var fields []specField
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type.Kind() == reflect.Slice {
+			continue
+		}
+		fields = append(fields, specField{t.Field(i).Name, t.Field(i).Type.Kind() == reflect.Int32})
+	}
+	return fields
+}
+
+// Convert a Go identifier (e.g. SizeChallengeCompleted) to snake case
+func snakeCase(name string) string {
+	var builder strings.Builder
+	for i, ch := range name {
+		if ch >= 'A' && ch <= 'Z' {
+			if i > 0 {
+				builder.WriteByte('_')
+			}
+			ch += 'a' - 'A'
+		}
+		builder.WriteRune(ch)
+	}
+	return builder.String()
+}
+
+// Return the opcodes, ordered by value
+func specOpCodes() []instructions.OpCode {
+	var ops []instructions.OpCode
+	for op := range instructions.InstrunctionMnemonics {
+		ops = append(ops, op)
+	}
+	ops = append(ops, instructions.OP_JUMP_TGT)
+	sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+	return ops
+}
+
+func specOpCodeName(op instructions.OpCode) string {
+	if op == instructions.OP_JUMP_TGT {
+		return "jump_target"
+	}
+	return strings.ToLower(op.String())
+}
+
+// Like Layout.WriteKaitaiSpec, for the default layout
+func WriteKaitaiSpec(w io.Writer, generator string) error {
+	return DefaultLayout.WriteKaitaiSpec(w, generator)
+}
+
+// Write a Kaitai Struct (.ksy) description of the profile format
+func (l Layout) WriteKaitaiSpec(w io.Writer, generator string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by %s, do not edit\n", generator)
+	fmt.Fprintf(&b, "meta:\n  id: hrm_profile\n  title: Human Resource Machine profile\n  file-extension: bin\n  endian: le\n")
+	fmt.Fprintf(&b, "seq:\n")
+	fmt.Fprintf(&b, "  - id: header\n    size: %d\n", l.FileHeaderSize)
+	fmt.Fprintf(&b, "  - id: floors\n    type: floor\n    repeat: expr\n    repeat-expr: %d\n", numFloors)
+	fmt.Fprintf(&b, "  - id: trailer\n    size-eos: true\n")
+	fmt.Fprintf(&b, "types:\n")
+	fmt.Fprintf(&b, "  floor:\n    seq:\n")
+	fmt.Fprintf(&b, "      - id: header\n        type: floor_header\n        size: %d\n", l.FloorHeaderSize)
+	fmt.Fprintf(&b, "      - id: tabs\n        type: tab\n        size: %d\n        repeat: expr\n        repeat-expr: 3\n", l.FloorTabSize)
+	fmt.Fprintf(&b, "  floor_header:\n    seq:\n")
+	for _, field := range floorHeaderSpecFields() {
+		typ := "u4"
+		if field.signed {
+			typ = "s4"
+		}
+		fmt.Fprintf(&b, "      - id: %s\n        type: %s\n", snakeCase(field.name), typ)
+	}
+	fmt.Fprintf(&b, "  tab:\n    seq:\n")
+	fmt.Fprintf(&b, "      - id: instructions\n        type: instruction_block\n        size: %d\n", instructions.INSTRUCTIONS_BLOCK_SIZE)
+	fmt.Fprintf(&b, "      - id: comments\n        type: comment_block\n        size: %d\n", instructions.COMMENTS_BLOCK_SIZE)
+	fmt.Fprintf(&b, "  instruction_block:\n    seq:\n")
+	fmt.Fprintf(&b, "      - id: count\n        type: u4\n")
+	fmt.Fprintf(&b, "      - id: instructions\n        type: instruction\n        repeat: expr\n        repeat-expr: count\n")
+	fmt.Fprintf(&b, "  instruction:\n    seq:\n")
+	fmt.Fprintf(&b, "      - id: comment\n        type: u4\n")
+	fmt.Fprintf(&b, "      - id: op\n        type: u4\n        enum: opcode\n")
+	fmt.Fprintf(&b, "      - id: mode\n        type: u4\n        enum: mode\n")
+	fmt.Fprintf(&b, "      - id: arg\n        type: u4\n")
+	fmt.Fprintf(&b, "  comment_block:\n    seq:\n")
+	fmt.Fprintf(&b, "      - id: count\n        type: u4\n")
+	fmt.Fprintf(&b, "      - id: comments\n        type: comment\n        repeat: expr\n        repeat-expr: count\n")
+	fmt.Fprintf(&b, "  comment:\n    doc: Comments of more than %d points continue into the following records\n    seq:\n", instructions.MAX_COMMENT_POINTS)
+	fmt.Fprintf(&b, "      - id: count\n        type: u4\n")
+	fmt.Fprintf(&b, "      - id: points\n        type: comment_point\n        repeat: expr\n        repeat-expr: count\n")
+	fmt.Fprintf(&b, "      - id: padding\n        size: (4 + count * 4 + %[1]d) / %[2]d * %[2]d - 4 - count * 4\n", instructions.COMMENT_RECORD_SIZE-1, instructions.COMMENT_RECORD_SIZE)
+	fmt.Fprintf(&b, "  comment_point:\n    doc: A point with both coordinates 0 ends a line\n    seq:\n")
+	fmt.Fprintf(&b, "      - id: x\n        type: u2\n")
+	fmt.Fprintf(&b, "      - id: y\n        type: u2\n")
+	fmt.Fprintf(&b, "enums:\n  opcode:\n")
+	for _, op := range specOpCodes() {
+		fmt.Fprintf(&b, "    0x%x: %s\n", uint32(op), specOpCodeName(op))
+	}
+	fmt.Fprintf(&b, "  mode:\n    0x%x: direct\n    0x%x: indirect\n", instructions.MODE_DIRECT, instructions.MODE_INDIRECT)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Like Layout.Write010Template, for the default layout
+func Write010Template(w io.Writer, generator string) error {
+	return DefaultLayout.Write010Template(w, generator)
+}
+
+// Write a 010 Editor binary template (.bt) describing the profile format
+func (l Layout) Write010Template(w io.Writer, generator string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Human Resource Machine profile\n// Generated by %s, do not edit\n", generator)
+	fmt.Fprintf(&b, "LittleEndian();\n\n")
+	fmt.Fprintf(&b, "typedef enum <uint32> {\n")
+	for _, op := range specOpCodes() {
+		fmt.Fprintf(&b, "    OP_%s = 0x%x,\n", strings.ToUpper(specOpCodeName(op)), uint32(op))
+	}
+	fmt.Fprintf(&b, "} OPCODE;\n\n")
+	fmt.Fprintf(&b, "typedef enum <uint32> {\n    MODE_DIRECT = 0x%x,\n    MODE_INDIRECT = 0x%x\n} MODE;\n\n",
+		instructions.MODE_DIRECT, instructions.MODE_INDIRECT)
+	fmt.Fprintf(&b, "typedef struct {\n    uint32 comment;\n    OPCODE op;\n    MODE mode;\n    uint32 arg;\n} INSTRUCTION;\n\n")
+	fmt.Fprintf(&b, "typedef struct {\n    uint16 x;\n    uint16 y;\n} COMMENT_POINT;\n\n")
+	fmt.Fprintf(&b, "typedef struct {\n    uint32 count;\n    if (count > 0)\n        COMMENT_POINT points[count];\n    FSkip((4 + count * 4 + %[1]d) / %[2]d * %[2]d - 4 - count * 4);\n} COMMENT;\n\n",
+		instructions.COMMENT_RECORD_SIZE-1, instructions.COMMENT_RECORD_SIZE)
+	fmt.Fprintf(&b, "typedef struct {\n    local int64 start = FTell();\n")
+	fmt.Fprintf(&b, "    uint32 instructionCount;\n    if (instructionCount > 0)\n        INSTRUCTION instructions[instructionCount];\n")
+	fmt.Fprintf(&b, "    FSeek(start + %d);\n", instructions.INSTRUCTIONS_BLOCK_SIZE)
+	fmt.Fprintf(&b, "    uint32 commentCount;\n    if (commentCount > 0)\n        COMMENT comments[commentCount] <optimize=false>;\n")
+	fmt.Fprintf(&b, "    FSeek(start + %d);\n} TAB;\n\n", l.FloorTabSize)
+	fmt.Fprintf(&b, "typedef struct {\n")
+	for _, field := range floorHeaderSpecFields() {
+		typ := "uint32"
+		if field.signed {
+			typ = "int32"
+		}
+		fmt.Fprintf(&b, "    %s %s;\n", typ, field.name)
+	}
+	fmt.Fprintf(&b, "} FLOOR_HEADER;\n\n")
+	fmt.Fprintf(&b, "typedef struct {\n    local int64 start = FTell();\n    FLOOR_HEADER header;\n    FSeek(start + %d);\n    TAB tabs[3] <optimize=false>;\n} FLOOR;\n\n", l.FloorHeaderSize)
+	fmt.Fprintf(&b, "FSeek(0);\nuchar header[%d];\nFLOOR floors[%d] <optimize=false>;\n",
+		l.FileHeaderSize, numFloors)
+	fmt.Fprintf(&b, "if (!FEof())\n    uchar trailer[FileSize() - FTell()];\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
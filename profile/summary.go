@@ -0,0 +1,73 @@
+package profile
+
+import "github.com/clj/hrm-profile-tool/levels"
+
+// Completion and scoring totals of a profile
+type ProfileSummary struct {
+	// The number of floors in the profile
+	Floors int
+	// The number of floors with a recorded solution
+	Solved int
+	// The number of floors meeting their size and speed challenges
+	SizeChallengesMet  int
+	SpeedChallengesMet int
+	// The sum of the recorded sizes and steps, over the floors with a
+	// recorded size or steps result
+	TotalSize  int
+	TotalSteps int
+	// The number of floors with a recorded size or steps result
+	SizeResults  int
+	StepsResults int
+}
+
+// Return the average size of the recorded solutions, or 0 if none are
+// recorded
+func (s ProfileSummary) AverageSize() float64 {
+	if s.SizeResults == 0 {
+		return 0
+	}
+	return float64(s.TotalSize) / float64(s.SizeResults)
+}
+
+// Return the average steps of the recorded solutions, or 0 if none are
+// recorded
+func (s ProfileSummary) AverageSteps() float64 {
+	if s.StepsResults == 0 {
+		return 0
+	}
+	return float64(s.TotalSteps) / float64(s.StepsResults)
+}
+
+// Return the fraction (0-1) of floors solved
+func (s ProfileSummary) Completion() float64 {
+	if s.Floors == 0 {
+		return 0
+	}
+	return float64(s.Solved) / float64(s.Floors)
+}
+
+// Compute the completion and scoring totals of a profile
+func Summary(p Profile) ProfileSummary {
+	summary := ProfileSummary{Floors: len(p.Floors)}
+	for floorIndex, floor := range p.Floors {
+		level, found := levels.Lookup(indexToFloor(floorIndex))
+		if floor.Completed {
+			summary.Solved++
+		}
+		if floor.SizeChallenge > 0 {
+			summary.TotalSize += floor.SizeChallenge
+			summary.SizeResults++
+			if found && level.SizeChallengeMet(floor.SizeChallenge) {
+				summary.SizeChallengesMet++
+			}
+		}
+		if floor.SpeedChallenge > 0 {
+			summary.TotalSteps += floor.SpeedChallenge
+			summary.StepsResults++
+			if found && level.SpeedChallengeMet(floor.SpeedChallenge) {
+				summary.SpeedChallengesMet++
+			}
+		}
+	}
+	return summary
+}
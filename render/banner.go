@@ -0,0 +1,80 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Identifying information about an exported program, rendered by
+// RenderText as comment lines ("-- ...", ignored by the game) above the
+// program so that standalone exports remain identifiable. Zero valued
+// fields are considered unknown and are left out
+type Banner struct {
+	Floor     int
+	Level     string
+	Tab       int
+	Size      int
+	Steps     int
+	Date      time.Time
+	Generator string
+}
+
+// Prefix the output of RenderText with banner
+func TextBanner(banner Banner) RenderInstructionsTextOption {
+	return func(o *renderInstructionsTextOptions) {
+		o.banner = &banner
+	}
+}
+
+// Return the banner as comment lines
+func (b Banner) Text() string {
+	var lines []string
+	var where []string
+	if b.Floor > 0 {
+		floor := fmt.Sprintf("floor %d", b.Floor)
+		if b.Level != "" {
+			floor += ": " + b.Level
+		}
+		where = append(where, floor)
+	} else if b.Level != "" {
+		where = append(where, b.Level)
+	}
+	if b.Tab > 0 {
+		where = append(where, fmt.Sprintf("tab %d", b.Tab))
+	}
+	if len(where) > 0 {
+		lines = append(lines, strings.Join(where, ", "))
+	}
+
+	var stats []string
+	if b.Size > 0 {
+		stats = append(stats, fmt.Sprintf("size %d", b.Size))
+	}
+	if b.Steps > 0 {
+		stats = append(stats, fmt.Sprintf("steps %d", b.Steps))
+	}
+	if len(stats) > 0 {
+		lines = append(lines, strings.Join(stats, ", "))
+	}
+
+	var export string
+	if !b.Date.IsZero() {
+		export = "exported " + b.Date.Format(time.RFC3339)
+	}
+	if b.Generator != "" {
+		if export == "" {
+			export = "exported"
+		}
+		export += " by " + b.Generator
+	}
+	if export != "" {
+		lines = append(lines, export)
+	}
+
+	var builder strings.Builder
+	for _, line := range lines {
+		fmt.Fprintf(&builder, "-- %s\n", line)
+	}
+	return builder.String()
+}
@@ -0,0 +1,105 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// A program rendered by Batch
+type BatchItem struct {
+	Program Program
+	Options Options
+	// Create the writer the program is rendered to, closed once the
+	// program is rendered. If nil the output is returned in the
+	// BatchResult
+	Create func() (io.WriteCloser, error)
+}
+
+// The result of rendering an item of a batch
+type BatchResult struct {
+	// The index of the item in the batch
+	Index int
+	// The rendered output, unless the item has a Create function
+	Output []byte
+	Err    error
+}
+
+type batchOptions struct {
+	workers int
+}
+
+// A Batch option
+type BatchOption func(*batchOptions)
+
+// Render at most n items concurrently (default: the number of CPUs)
+func BatchWorkers(n int) BatchOption {
+	return func(o *batchOptions) {
+		o.workers = n
+	}
+}
+
+// Render the items in format concurrently. A result for every item is
+// sent on the returned channel as soon as the item is rendered, so results
+// arrive in any order; errors are reported per item and do not stop the
+// batch. The channel is closed once all items are rendered. If ctx is
+// cancelled, the items not yet started are not rendered and have no result
+func Batch(ctx context.Context, format Format, items []BatchItem, opts ...BatchOption) <-chan BatchResult {
+	options := batchOptions{workers: runtime.NumCPU()}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.workers < 1 {
+		options.workers = 1
+	}
+
+	indexes := make(chan int)
+	results := make(chan BatchResult)
+	var wg sync.WaitGroup
+	for i := 0; i < options.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results <- renderBatchItem(ctx, format, index, items[index])
+			}
+		}()
+	}
+	go func() {
+		defer close(indexes)
+		for index := range items {
+			select {
+			case indexes <- index:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+func renderBatchItem(ctx context.Context, format Format, index int, item BatchItem) BatchResult {
+	result := BatchResult{Index: index}
+	if item.Create == nil {
+		var buffer bytes.Buffer
+		result.Err = format.Render(ctx, &buffer, item.Program, item.Options)
+		result.Output = buffer.Bytes()
+		return result
+	}
+	w, err := item.Create()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Err = format.Render(ctx, w, item.Program, item.Options)
+	if err := w.Close(); result.Err == nil {
+		result.Err = err
+	}
+	return result
+}
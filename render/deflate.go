@@ -0,0 +1,762 @@
+package render
+
+import (
+	"encoding/binary"
+	"hash/adler32"
+)
+
+// A port of the reference zlib implementation's deflate (zlib 1.2.11,
+// deflate.c and trees.c) at the default compression level, as used by the
+// game when copying comments. Go's compress/flate chooses matches and
+// Huffman codes differently, so its output decompresses to the same data
+// but is not byte-equal to the game's. Only compressing a whole buffer at
+// once (as by zlib's compress()) is supported.
+
+const (
+	zMinMatch     = 3
+	zMaxMatch     = 258
+	zWindowBits   = 15
+	zWindowSize   = 1 << zWindowBits
+	zWindowMask   = zWindowSize - 1
+	zHashBits     = 15 // memLevel 8 + 7
+	zHashSize     = 1 << zHashBits
+	zHashMask     = zHashSize - 1
+	zHashShift    = (zHashBits + zMinMatch - 1) / zMinMatch
+	zMinLookahead = zMaxMatch + zMinMatch + 1
+	zMaxDist      = zWindowSize - zMinLookahead
+	zLitBufSize   = 1 << 14 // memLevel 8 + 6
+	zTooFar       = 4096
+
+	// The configuration of the default compression level (6)
+	zGoodLength = 8
+	zMaxLazy    = 16
+	zNiceLength = 128
+	zMaxChain   = 128
+
+	zMaxBits     = 15
+	zMaxBLBits   = 7
+	zLengthCodes = 29
+	zLiterals    = 256
+	zLCodes      = zLiterals + 1 + zLengthCodes
+	zDCodes      = 30
+	zBLCodes     = 19
+	zHeapSize    = 2*zLCodes + 1
+	zEndBlock    = 256
+	zRep3To6     = 16
+	zRepZ3To10   = 17
+	zRepZ11To138 = 18
+)
+
+var (
+	zExtraLBits  = [zLengthCodes]int{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5, 0}
+	zExtraDBits  = [zDCodes]int{0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11, 12, 12, 13, 13}
+	zExtraBLBits = [zBLCodes]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 3, 7}
+	zBLOrder     = [zBLCodes]int{16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15}
+
+	zLengthCode  [zMaxMatch - zMinMatch + 1]uint8
+	zBaseLength  [zLengthCodes]int
+	zDistCode    [512]uint8
+	zBaseDist    [zDCodes]int
+	zStaticLTree [zLCodes + 2]zCode
+	zStaticDTree [zDCodes]zCode
+)
+
+// An element of a Huffman tree. As in zlib, the frequency and the code
+// share a field, and so do the parent node and the code length
+type zCode struct {
+	freqOrCode uint16
+	dadOrLen   uint16
+}
+
+// A Huffman tree and its static description
+type zTreeDesc struct {
+	tree      []zCode
+	maxCode   int
+	static    []zCode
+	extra     []int
+	extraBase int
+	elems     int
+	maxLength int
+}
+
+func init() {
+	length := 0
+	code := 0
+	for code = 0; code < zLengthCodes-1; code++ {
+		zBaseLength[code] = length
+		for n := 0; n < 1<<zExtraLBits[code]; n++ {
+			zLengthCode[length] = uint8(code)
+			length++
+		}
+	}
+	zLengthCode[length-1] = uint8(code)
+
+	dist := 0
+	for code = 0; code < 16; code++ {
+		zBaseDist[code] = dist
+		for n := 0; n < 1<<zExtraDBits[code]; n++ {
+			zDistCode[dist] = uint8(code)
+			dist++
+		}
+	}
+	dist >>= 7
+	for ; code < zDCodes; code++ {
+		zBaseDist[code] = dist << 7
+		for n := 0; n < 1<<(zExtraDBits[code]-7); n++ {
+			zDistCode[256+dist] = uint8(code)
+			dist++
+		}
+	}
+
+	var blCount [zMaxBits + 1]int
+	n := 0
+	for ; n <= 143; n++ {
+		zStaticLTree[n].dadOrLen = 8
+		blCount[8]++
+	}
+	for ; n <= 255; n++ {
+		zStaticLTree[n].dadOrLen = 9
+		blCount[9]++
+	}
+	for ; n <= 279; n++ {
+		zStaticLTree[n].dadOrLen = 7
+		blCount[7]++
+	}
+	for ; n <= 287; n++ {
+		zStaticLTree[n].dadOrLen = 8
+		blCount[8]++
+	}
+	zGenCodes(zStaticLTree[:], zLCodes+1, blCount[:])
+	for n := 0; n < zDCodes; n++ {
+		zStaticDTree[n] = zCode{uint16(zReverse(n, 5)), 5}
+	}
+}
+
+func zReverse(code, length int) int {
+	res := 0
+	for ; length > 0; length-- {
+		res |= code & 1
+		code >>= 1
+		res <<= 1
+	}
+	return res >> 1
+}
+
+func zGenCodes(tree []zCode, maxCode int, blCount []int) {
+	var nextCode [zMaxBits + 1]int
+	code := 0
+	for bits := 1; bits <= zMaxBits; bits++ {
+		code = (code + blCount[bits-1]) << 1
+		nextCode[bits] = code
+	}
+	for n := 0; n <= maxCode; n++ {
+		length := int(tree[n].dadOrLen)
+		if length == 0 {
+			continue
+		}
+		tree[n].freqOrCode = uint16(zReverse(nextCode[length], length))
+		nextCode[length]++
+	}
+}
+
+func zDCode(dist int) int {
+	if dist < 256 {
+		return int(zDistCode[dist])
+	}
+	return int(zDistCode[256+(dist>>7)])
+}
+
+// The state of a deflate stream
+type zDeflater struct {
+	input []byte
+	out   []byte
+
+	bitBuf   uint32
+	bitCount uint
+
+	window     [2 * zWindowSize]byte
+	prev       [zWindowSize]uint16
+	head       [zHashSize]uint16
+	insH       int
+	insert     int
+	strStart   int
+	lookahead  int
+	blockStart int
+
+	matchLength    int
+	matchStart     int
+	matchAvailable bool
+	prevLength     int
+	prevMatch      int
+
+	dynLTree  [zHeapSize]zCode
+	dynDTree  [2*zDCodes + 1]zCode
+	blTree    [2*zBLCodes + 1]zCode
+	lDesc     zTreeDesc
+	dDesc     zTreeDesc
+	blDesc    zTreeDesc
+	blCount   [zMaxBits + 1]int
+	heap      [2*zLCodes + 1]int
+	heapLen   int
+	heapMax   int
+	depth     [2*zLCodes + 1]uint8
+	optLen    int
+	staticLen int
+
+	dists []uint16
+	lits  []uint8
+}
+
+// Compress data as zlib's compress() does at the default compression level
+func zlibCompress(data []byte) []byte {
+	s := &zDeflater{input: data}
+	s.lDesc = zTreeDesc{tree: s.dynLTree[:], static: zStaticLTree[:], extra: zExtraLBits[:], extraBase: zLiterals + 1, elems: zLCodes, maxLength: zMaxBits}
+	s.dDesc = zTreeDesc{tree: s.dynDTree[:], static: zStaticDTree[:], extra: zExtraDBits[:], elems: zDCodes, maxLength: zMaxBits}
+	s.blDesc = zTreeDesc{tree: s.blTree[:], extra: zExtraBLBits[:], elems: zBLCodes, maxLength: zMaxBLBits}
+	s.initBlock()
+	s.matchLength = zMinMatch - 1
+	s.prevLength = zMinMatch - 1
+
+	// The header of the default compression level with a 32K window
+	s.out = append(s.out, 0x78, 0x9c)
+	s.deflateSlow()
+	var trailer [4]byte
+	binary.BigEndian.PutUint32(trailer[:], adler32.Checksum(data))
+	return append(s.out, trailer[:]...)
+}
+
+func (s *zDeflater) updateHash(h int, c byte) int {
+	return ((h << zHashShift) ^ int(c)) & zHashMask
+}
+
+// Insert the string at str in the hash table, returning the previous head
+// of its hash chain
+func (s *zDeflater) insertString(str int) int {
+	s.insH = s.updateHash(s.insH, s.window[str+zMinMatch-1])
+	head := int(s.head[s.insH])
+	s.prev[str&zWindowMask] = uint16(head)
+	s.head[s.insH] = uint16(str)
+	return head
+}
+
+func (s *zDeflater) fillWindow() {
+	for {
+		more := len(s.window) - s.lookahead - s.strStart
+		if s.strStart >= zWindowSize+zMaxDist {
+			copy(s.window[:zWindowSize-more], s.window[zWindowSize:])
+			s.matchStart -= zWindowSize
+			s.strStart -= zWindowSize
+			s.blockStart -= zWindowSize
+			for n := range s.head {
+				if m := int(s.head[n]); m >= zWindowSize {
+					s.head[n] = uint16(m - zWindowSize)
+				} else {
+					s.head[n] = 0
+				}
+			}
+			for n := range s.prev {
+				if m := int(s.prev[n]); m >= zWindowSize {
+					s.prev[n] = uint16(m - zWindowSize)
+				} else {
+					s.prev[n] = 0
+				}
+			}
+			more += zWindowSize
+		}
+		if len(s.input) == 0 {
+			break
+		}
+		n := copy(s.window[s.strStart+s.lookahead:s.strStart+s.lookahead+more], s.input)
+		s.input = s.input[n:]
+		s.lookahead += n
+
+		if s.lookahead+s.insert >= zMinMatch {
+			str := s.strStart - s.insert
+			s.insH = int(s.window[str])
+			s.insH = s.updateHash(s.insH, s.window[str+1])
+			for s.insert > 0 {
+				s.insH = s.updateHash(s.insH, s.window[str+zMinMatch-1])
+				s.prev[str&zWindowMask] = s.head[s.insH]
+				s.head[s.insH] = uint16(str)
+				str++
+				s.insert--
+				if s.lookahead+s.insert < zMinMatch {
+					break
+				}
+			}
+		}
+		if s.lookahead >= zMinLookahead || len(s.input) == 0 {
+			break
+		}
+	}
+}
+
+func (s *zDeflater) longestMatch(curMatch int) int {
+	chainLength := zMaxChain
+	scan := s.strStart
+	bestLen := s.prevLength
+	niceMatch := zNiceLength
+	limit := 0
+	if s.strStart > zMaxDist {
+		limit = s.strStart - zMaxDist
+	}
+	scanEnd1 := s.window[scan+bestLen-1]
+	scanEnd := s.window[scan+bestLen]
+	if s.prevLength >= zGoodLength {
+		chainLength >>= 2
+	}
+	if niceMatch > s.lookahead {
+		niceMatch = s.lookahead
+	}
+	for {
+		match := curMatch
+		if s.window[match+bestLen] == scanEnd && s.window[match+bestLen-1] == scanEnd1 &&
+			s.window[match] == s.window[scan] && s.window[match+1] == s.window[scan+1] {
+			// The third bytes are equal since the hashes are
+			length := 3
+			for length < zMaxMatch && s.window[scan+length] == s.window[match+length] {
+				length++
+			}
+			if length > bestLen {
+				s.matchStart = curMatch
+				bestLen = length
+				if length >= niceMatch {
+					break
+				}
+				scanEnd1 = s.window[scan+bestLen-1]
+				scanEnd = s.window[scan+bestLen]
+			}
+		}
+		curMatch = int(s.prev[curMatch&zWindowMask])
+		if curMatch <= limit {
+			break
+		}
+		chainLength--
+		if chainLength == 0 {
+			break
+		}
+	}
+	if bestLen <= s.lookahead {
+		return bestLen
+	}
+	return s.lookahead
+}
+
+func (s *zDeflater) deflateSlow() {
+	for {
+		if s.lookahead < zMinLookahead {
+			s.fillWindow()
+			if s.lookahead == 0 {
+				break
+			}
+		}
+		hashHead := 0
+		if s.lookahead >= zMinMatch {
+			hashHead = s.insertString(s.strStart)
+		}
+		s.prevLength, s.prevMatch = s.matchLength, s.matchStart
+		s.matchLength = zMinMatch - 1
+
+		if hashHead != 0 && s.prevLength < zMaxLazy && s.strStart-hashHead <= zMaxDist {
+			s.matchLength = s.longestMatch(hashHead)
+			if s.matchLength <= 5 && s.matchLength == zMinMatch && s.strStart-s.matchStart > zTooFar {
+				s.matchLength = zMinMatch - 1
+			}
+		}
+		switch {
+		case s.prevLength >= zMinMatch && s.matchLength <= s.prevLength:
+			maxInsert := s.strStart + s.lookahead - zMinMatch
+			flush := s.tally(s.strStart-1-s.prevMatch, s.prevLength-zMinMatch)
+			s.lookahead -= s.prevLength - 1
+			for s.prevLength -= 2; s.prevLength != 0; s.prevLength-- {
+				s.strStart++
+				if s.strStart <= maxInsert {
+					s.insertString(s.strStart)
+				}
+			}
+			s.matchAvailable = false
+			s.matchLength = zMinMatch - 1
+			s.strStart++
+			if flush {
+				s.flushBlock(false)
+			}
+		case s.matchAvailable:
+			if s.tally(0, int(s.window[s.strStart-1])) {
+				s.flushBlock(false)
+			}
+			s.strStart++
+			s.lookahead--
+		default:
+			s.matchAvailable = true
+			s.strStart++
+			s.lookahead--
+		}
+	}
+	if s.matchAvailable {
+		s.tally(0, int(s.window[s.strStart-1]))
+		s.matchAvailable = false
+	}
+	s.flushBlock(true)
+}
+
+// Record a literal (dist 0) or a match, reporting whether the block must
+// be flushed
+func (s *zDeflater) tally(dist, lc int) bool {
+	s.dists = append(s.dists, uint16(dist))
+	s.lits = append(s.lits, uint8(lc))
+	if dist == 0 {
+		s.dynLTree[lc].freqOrCode++
+	} else {
+		dist--
+		s.dynLTree[int(zLengthCode[lc])+zLiterals+1].freqOrCode++
+		s.dynDTree[zDCode(dist)].freqOrCode++
+	}
+	return len(s.lits) == zLitBufSize-1
+}
+
+func (s *zDeflater) initBlock() {
+	for n := 0; n < zLCodes; n++ {
+		s.dynLTree[n].freqOrCode = 0
+	}
+	for n := 0; n < zDCodes; n++ {
+		s.dynDTree[n].freqOrCode = 0
+	}
+	for n := 0; n < zBLCodes; n++ {
+		s.blTree[n].freqOrCode = 0
+	}
+	s.dynLTree[zEndBlock].freqOrCode = 1
+	s.optLen, s.staticLen = 0, 0
+	s.dists, s.lits = s.dists[:0], s.lits[:0]
+}
+
+func (s *zDeflater) flushBlock(last bool) {
+	storedLen := s.strStart - s.blockStart
+	s.buildTree(&s.lDesc)
+	s.buildTree(&s.dDesc)
+	maxBLIndex := s.buildBLTree()
+	optLenB := (s.optLen + 3 + 7) >> 3
+	staticLenB := (s.staticLen + 3 + 7) >> 3
+	if staticLenB <= optLenB {
+		optLenB = staticLenB
+	}
+	lastBit := 0
+	if last {
+		lastBit = 1
+	}
+	switch {
+	case storedLen+4 <= optLenB && s.blockStart >= 0:
+		s.sendBits(lastBit, 3)
+		s.alignBits()
+		s.out = append(s.out, byte(storedLen), byte(storedLen>>8), ^byte(storedLen), ^byte(storedLen>>8))
+		s.out = append(s.out, s.window[s.blockStart:s.blockStart+storedLen]...)
+	case staticLenB == optLenB:
+		s.sendBits(2+lastBit, 3)
+		s.compressBlock(zStaticLTree[:], zStaticDTree[:])
+	default:
+		s.sendBits(4+lastBit, 3)
+		s.sendAllTrees(s.lDesc.maxCode+1, s.dDesc.maxCode+1, maxBLIndex+1)
+		s.compressBlock(s.dynLTree[:], s.dynDTree[:])
+	}
+	s.initBlock()
+	if last {
+		s.alignBits()
+	}
+	s.blockStart = s.strStart
+}
+
+func (s *zDeflater) sendBits(value int, length uint) {
+	s.bitBuf |= uint32(value) << s.bitCount
+	s.bitCount += length
+	for s.bitCount >= 8 {
+		s.out = append(s.out, byte(s.bitBuf))
+		s.bitBuf >>= 8
+		s.bitCount -= 8
+	}
+}
+
+func (s *zDeflater) alignBits() {
+	if s.bitCount > 0 {
+		s.out = append(s.out, byte(s.bitBuf))
+	}
+	s.bitBuf, s.bitCount = 0, 0
+}
+
+func (s *zDeflater) sendCode(c int, tree []zCode) {
+	s.sendBits(int(tree[c].freqOrCode), uint(tree[c].dadOrLen))
+}
+
+func (s *zDeflater) smaller(tree []zCode, n, m int) bool {
+	return tree[n].freqOrCode < tree[m].freqOrCode ||
+		(tree[n].freqOrCode == tree[m].freqOrCode && s.depth[n] <= s.depth[m])
+}
+
+func (s *zDeflater) downHeap(tree []zCode, k int) {
+	v := s.heap[k]
+	j := k << 1
+	for j <= s.heapLen {
+		if j < s.heapLen && s.smaller(tree, s.heap[j+1], s.heap[j]) {
+			j++
+		}
+		if s.smaller(tree, v, s.heap[j]) {
+			break
+		}
+		s.heap[k] = s.heap[j]
+		k = j
+		j <<= 1
+	}
+	s.heap[k] = v
+}
+
+func (s *zDeflater) buildTree(desc *zTreeDesc) {
+	tree := desc.tree
+	maxCode := -1
+	s.heapLen, s.heapMax = 0, zHeapSize
+	for n := 0; n < desc.elems; n++ {
+		if tree[n].freqOrCode != 0 {
+			s.heapLen++
+			s.heap[s.heapLen] = n
+			maxCode = n
+			s.depth[n] = 0
+		} else {
+			tree[n].dadOrLen = 0
+		}
+	}
+	for s.heapLen < 2 {
+		node := 0
+		if maxCode < 2 {
+			maxCode++
+			node = maxCode
+		}
+		s.heapLen++
+		s.heap[s.heapLen] = node
+		tree[node].freqOrCode = 1
+		s.depth[node] = 0
+		s.optLen--
+		if desc.static != nil {
+			s.staticLen -= int(desc.static[node].dadOrLen)
+		}
+	}
+	desc.maxCode = maxCode
+	for n := s.heapLen / 2; n >= 1; n-- {
+		s.downHeap(tree, n)
+	}
+	node := desc.elems
+	for {
+		n := s.heap[1]
+		s.heap[1] = s.heap[s.heapLen]
+		s.heapLen--
+		s.downHeap(tree, 1)
+		m := s.heap[1]
+		s.heapMax--
+		s.heap[s.heapMax] = n
+		s.heapMax--
+		s.heap[s.heapMax] = m
+		tree[node].freqOrCode = tree[n].freqOrCode + tree[m].freqOrCode
+		depth := s.depth[n]
+		if s.depth[m] > depth {
+			depth = s.depth[m]
+		}
+		s.depth[node] = depth + 1
+		tree[n].dadOrLen = uint16(node)
+		tree[m].dadOrLen = uint16(node)
+		s.heap[1] = node
+		node++
+		s.downHeap(tree, 1)
+		if s.heapLen < 2 {
+			break
+		}
+	}
+	s.heapMax--
+	s.heap[s.heapMax] = s.heap[1]
+	s.genBitLengths(desc)
+	zGenCodes(tree, maxCode, s.blCount[:])
+}
+
+func (s *zDeflater) genBitLengths(desc *zTreeDesc) {
+	tree := desc.tree
+	for bits := range s.blCount {
+		s.blCount[bits] = 0
+	}
+	tree[s.heap[s.heapMax]].dadOrLen = 0
+	overflow := 0
+	h := s.heapMax + 1
+	for ; h < zHeapSize; h++ {
+		n := s.heap[h]
+		bits := int(tree[tree[n].dadOrLen].dadOrLen) + 1
+		if bits > desc.maxLength {
+			bits = desc.maxLength
+			overflow++
+		}
+		tree[n].dadOrLen = uint16(bits)
+		if n > desc.maxCode {
+			continue
+		}
+		s.blCount[bits]++
+		xbits := 0
+		if n >= desc.extraBase {
+			xbits = desc.extra[n-desc.extraBase]
+		}
+		f := int(tree[n].freqOrCode)
+		s.optLen += f * (bits + xbits)
+		if desc.static != nil {
+			s.staticLen += f * (int(desc.static[n].dadOrLen) + xbits)
+		}
+	}
+	if overflow == 0 {
+		return
+	}
+	for overflow > 0 {
+		bits := desc.maxLength - 1
+		for s.blCount[bits] == 0 {
+			bits--
+		}
+		s.blCount[bits]--
+		s.blCount[bits+1] += 2
+		s.blCount[desc.maxLength]--
+		overflow -= 2
+	}
+	for bits := desc.maxLength; bits != 0; bits-- {
+		for n := s.blCount[bits]; n != 0; {
+			h--
+			m := s.heap[h]
+			if m > desc.maxCode {
+				continue
+			}
+			if int(tree[m].dadOrLen) != bits {
+				s.optLen += (bits - int(tree[m].dadOrLen)) * int(tree[m].freqOrCode)
+				tree[m].dadOrLen = uint16(bits)
+			}
+			n--
+		}
+	}
+}
+
+// Walk the code lengths of tree as run-length encoded by the bit length
+// tree, calling emit for each run
+func zScanTree(tree []zCode, maxCode int, emit func(curLen, prevLen, count int)) {
+	prevLen := -1
+	nextLen := int(tree[0].dadOrLen)
+	count := 0
+	maxCount := 7
+	if nextLen == 0 {
+		maxCount = 138
+	}
+	for n := 0; n <= maxCode; n++ {
+		curLen := nextLen
+		nextLen = int(tree[n+1].dadOrLen)
+		count++
+		if count < maxCount && curLen == nextLen {
+			continue
+		}
+		emit(curLen, prevLen, count)
+		count = 0
+		prevLen = curLen
+		switch {
+		case nextLen == 0:
+			maxCount = 138
+		case curLen == nextLen:
+			maxCount = 6
+		default:
+			maxCount = 7
+		}
+	}
+}
+
+// Return the minimum length of a run of curLen, following a run of
+// prevLen, encoded with a repeat code
+func zMinCount(curLen, prevLen int) int {
+	if curLen == 0 || curLen == prevLen {
+		return 3
+	}
+	return 4
+}
+
+func (s *zDeflater) scanTree(tree []zCode, maxCode int) {
+	tree[maxCode+1].dadOrLen = 0xffff
+	zScanTree(tree, maxCode, func(curLen, prevLen, count int) {
+		switch {
+		case count < zMinCount(curLen, prevLen):
+			s.blTree[curLen].freqOrCode += uint16(count)
+		case curLen != 0:
+			if curLen != prevLen {
+				s.blTree[curLen].freqOrCode++
+			}
+			s.blTree[zRep3To6].freqOrCode++
+		case count <= 10:
+			s.blTree[zRepZ3To10].freqOrCode++
+		default:
+			s.blTree[zRepZ11To138].freqOrCode++
+		}
+	})
+}
+
+func (s *zDeflater) sendTree(tree []zCode, maxCode int) {
+	zScanTree(tree, maxCode, func(curLen, prevLen, count int) {
+		switch {
+		case count < zMinCount(curLen, prevLen):
+			for ; count != 0; count-- {
+				s.sendCode(curLen, s.blTree[:])
+			}
+		case curLen != 0:
+			if curLen != prevLen {
+				s.sendCode(curLen, s.blTree[:])
+				count--
+			}
+			s.sendCode(zRep3To6, s.blTree[:])
+			s.sendBits(count-3, 2)
+		case count <= 10:
+			s.sendCode(zRepZ3To10, s.blTree[:])
+			s.sendBits(count-3, 3)
+		default:
+			s.sendCode(zRepZ11To138, s.blTree[:])
+			s.sendBits(count-11, 7)
+		}
+	})
+}
+
+func (s *zDeflater) buildBLTree() int {
+	s.scanTree(s.dynLTree[:], s.lDesc.maxCode)
+	s.scanTree(s.dynDTree[:], s.dDesc.maxCode)
+	s.buildTree(&s.blDesc)
+	maxBLIndex := zBLCodes - 1
+	for ; maxBLIndex >= 3; maxBLIndex-- {
+		if s.blTree[zBLOrder[maxBLIndex]].dadOrLen != 0 {
+			break
+		}
+	}
+	s.optLen += 3*(maxBLIndex+1) + 5 + 5 + 4
+	return maxBLIndex
+}
+
+func (s *zDeflater) sendAllTrees(lCodes, dCodes, blCodes int) {
+	s.sendBits(lCodes-257, 5)
+	s.sendBits(dCodes-1, 5)
+	s.sendBits(blCodes-4, 4)
+	for rank := 0; rank < blCodes; rank++ {
+		s.sendBits(int(s.blTree[zBLOrder[rank]].dadOrLen), 3)
+	}
+	s.sendTree(s.dynLTree[:], lCodes-1)
+	s.sendTree(s.dynDTree[:], dCodes-1)
+}
+
+func (s *zDeflater) compressBlock(lTree, dTree []zCode) {
+	for i, lc := range s.lits {
+		dist := int(s.dists[i])
+		if dist == 0 {
+			s.sendCode(int(lc), lTree)
+			continue
+		}
+		code := int(zLengthCode[lc])
+		s.sendCode(code+zLiterals+1, lTree)
+		if extra := zExtraLBits[code]; extra != 0 {
+			s.sendBits(int(lc)-zBaseLength[code], uint(extra))
+		}
+		dist--
+		code = zDCode(dist)
+		s.sendCode(code, dTree)
+		if extra := zExtraDBits[code]; extra != 0 {
+			s.sendBits(dist-zBaseDist[code], uint(extra))
+		}
+	}
+	s.sendCode(zEndBlock, lTree)
+}
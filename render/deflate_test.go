@@ -0,0 +1,169 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+// The expected outputs are those of the reference zlib's compress2() at
+// level 6
+
+// Return the comment record(s) of a comment as stored in a tab, as the game
+// compresses them
+func commentRecord(points [][2]uint16) []byte {
+	var record bytes.Buffer
+	binary.Write(&record, binary.LittleEndian, uint32(len(points)))
+	for _, point := range points {
+		binary.Write(&record, binary.LittleEndian, point)
+	}
+	size := instructions.CommentRecords(len(points)) * instructions.COMMENT_RECORD_SIZE
+	record.Write(make([]byte, size-record.Len()))
+	return record.Bytes()
+}
+
+// A drawing of two strokes, the second after a pen up (0, 0)
+func strokePoints() [][2]uint16 {
+	var points [][2]uint16
+	for i := 0; i < 12; i++ {
+		points = append(points, [2]uint16{uint16(1024 + i*256), uint16(2048 + i*128)})
+	}
+	points = append(points, [2]uint16{0, 0})
+	for i := 0; i < 6; i++ {
+		points = append(points, [2]uint16{8192, uint16(8192 - i*512)})
+	}
+	return points
+}
+
+// A drawing too dense for one comment record
+func densePoints() [][2]uint16 {
+	points := make([][2]uint16, 300)
+	for i := range points {
+		points[i] = [2]uint16{uint16(i * 97), uint16(i * i * 13)}
+	}
+	return points
+}
+
+func TestZlibCompress(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty input", nil, "789c030000000001"},
+		{"short text", []byte("Human Resource Machine"), "789cf328cd4dcc53084a2dce2f2d4a4e55f04d4ccec8cc4b05005da30837"},
+		{"empty comment", commentRecord(nil), "789c63601805a360148c64000004040001"},
+		{"two strokes", commentRecord(strokePoints()),
+			"789ced8bcb0a80301003e7e0a3ed76f5204811113f753fdde85f089d30811cb2210612632426327364ad428ea2362c8c" +
+				"aa7854169c35fcbd707fb9e4290fd9e44ea7d3f9150fd9c60566"},
+		{"dense comment", commentRecord(densePoints()),
+			"789cedd3f95f93750000e0ef18635c1bd73b8ec9e47cb9c600d93beec13b611cc2e005df8d7320c7360ec7519b643551" +
+				"7125269aba95a4d30ec8c4a6664334254d058f20df7458a9b312e7412191a32c23ead3dfd08f3effc3134d02ffa90534" +
+				"700e202084a4069b4804b09284a46c3b33e9809dc6ce918c929bc82cfb2b6488c2b6871db6da63d4197bbd6321c5e674" +
+				"84a274717700b40e0723fd9a83ca9d4b157beaa818344f95794b1c75bec38e16a6af13ca5aeb341270d3090f4e75a6c0" +
+				"7b9d89f005e7a1a86a1713e78ccb445ca02b4036b88a12efba9a523269bcf47e1a21a0d0b542391dcfbb48e78b22dcd0" +
+				"e21e37a978da4d579eef6e957eec8ed5d13c26e5ad1eaa96ab1e9cf6384fa0dee139b3f6574fdbba955e8c4d262fac87" +
+				"010d6c5b0341ba6f20435f1203dddfc758e8ff93611eacf41e3d7ada9b38cef2993fbdce0739ffbd4fef65d41710eff9" +
+				"f6deb0f3432cf57ef35317fc886998393af71ad3fcf40173613177094a3db8c44077f687bc5bfc0758e3fe18cc613138" +
+				"db583664963593862d05c24f9672449e012af18b019352730026e7055a5bdf0ad475fe1e285d5f1684f69c0ce2ef6406" +
+				"e37b5e09d6f6df0e268cfc10def0be10d3d9c510d19555a1c0fc45e8842518363de88687e6eec1c4336118c5e1c330dc" +
+				"9d1a3ec26c0c47e1cbe196d8a8085dca1b1132e1cf1118268a14571e8e54c9dda28c1ded5140f3759472733cdbb66b17" +
+				"5bbfdfc6c60e89a3e1e1e3d1d0791f0eebea4b1cf4d6771ccd839418f3933d3142f0570ce12a8d55fb7d1e8b8405c4d1" +
+				"b8ebe300fa631c4db47c1952f1c132b5c23e9e50c9e2851bc7e2cddbc3b91ac3662e7ae81197757205025d3c84c093ae" +
+				"3c6c4ac9d3cf7dc5b32dc62628e96f2600d65c82915d92a84af934519c07256165ea2499e246926e4d62b245bb3b19d5" +
+				"ff913cd25f91829b4ea5502ef8a712d735a9435377524d4f32d226c8efa60188c41785d6f14dc8793e4f189a4e88b5e9" +
+				"5af9fd74bc332783bff9a30cb4cf09950e36a3ba535fa2d6f1680176a7573039fb58a022152de7781d5d0e608fcc9984" +
+				"17326db9d73319954816a6d4670d74fd9605ed2c151afa4f08d161bfec85cb2f679b6fdfca1e9d4dcb21ec0c39f38cbf" +
+				"7390c89adcdeb4b3b9000bcaebaddf9887744ee5cd6fc95a41ec1b58317acc21df3ca6c85fb879291f9d8d2c3090b714" +
+				"40be3f150c441788308151c490d00b6dcd6d85335d4421d02f2be20cee2c529d79523469c6316c7a08b32e7a17eb189d" +
+				"c552f6b7c5a820b9845ffa4e09ae7c56a2edae5a49f48dace41d5d8a9bc6ba7091e5071cd804e209e7f7c5a620b26428" +
+				"a94142148d4a288ab0527cddeba523fa87a5a831afcc7261b04c77dba55c665b5d8eb94e948b43632a5469db2b8cf82f" +
+				"1540595ca9d41eabb419bcaaf4c75555d8d5c92af861821422bd2d65319f4a516e79b5a6e0b36ab36c498db0ebd51a62" +
+				"b7a5467d2c7d1532be7f15edfebff9416d2d8d79ae164142ead4859bea88466b9db03bbbde6c3850af39e1d8805e6f6a" +
+				"603dbed20039b16570e85619963123d35714ca6dea2372e50e77053076288c97ae2954f7b88d62a06bc4fce71b654992" +
+				"261d3edc6469f76d467bd7368f1cbcd98c8fa5b650a6f6b6108b0b2d43fed5ab4dc967564f48029540b54129da715769" +
+				"3a9cd9ca1bef6f251e51dab454791b0e5f6ce36746b4a3b53dedd2aee9769d21bfc37a0a3cf7dc73ffab7f008510e670"},
+	}
+	for _, test := range tests {
+		got := zlibCompress(test.data)
+		if hex.EncodeToString(got) != test.want {
+			t.Errorf("%s: got %x, want %s", test.name, got, test.want)
+		}
+		checkInflates(t, test.name, got, test.data)
+	}
+}
+
+// Inputs spanning several deflate blocks and more than the window
+func TestZlibCompressLarge(t *testing.T) {
+	words := []string{"INBOX", "OUTBOX", "COPYFROM", "COPYTO", "ADD", "SUB", "BUMPUP", "BUMPDN", "JUMP", "JUMPZ", "JUMPN", "the", "mail", "room", "floor"}
+	var text bytes.Buffer
+	for x := uint64(1); text.Len() < 200000; {
+		x = (x*1103515245 + 12345) % (1 << 31)
+		text.WriteString(words[(x>>16)%uint64(len(words))])
+		if (x>>8)%7 == 0 {
+			text.WriteByte('\n')
+		} else {
+			text.WriteByte(' ')
+		}
+	}
+	binary := make([]byte, 70000)
+	for i := range binary {
+		binary[i] = byte((i*i*7 + i/13) % 251)
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		size   int
+		sha256 string
+	}{
+		{"text", text.Bytes(), 34225, "6636ae2149dce639433e7110255caebdb1acc4ce54cb9821109e2c2aa568315c"},
+		{"binary", binary, 3833, "6c78e33394c5853437cf91122cd5c19bee8762959bc52804c9ee5458820e3473"},
+	}
+	for _, test := range tests {
+		got := zlibCompress(test.data)
+		sum := sha256.Sum256(got)
+		if len(got) != test.size || hex.EncodeToString(sum[:]) != test.sha256 {
+			t.Errorf("%s: got %d bytes with SHA-256 %x, want %d bytes with %s", test.name, len(got), sum, test.size, test.sha256)
+		}
+		checkInflates(t, test.name, got, test.data)
+	}
+}
+
+// Comments rendered with GameIdenticalComments are the text the game
+// copies to the clipboard
+func TestRenderCommentsGameIdentical(t *testing.T) {
+	comment := make(instructions.RawComment, 0)
+	for _, point := range strokePoints() {
+		var data [4]byte
+		binary.LittleEndian.PutUint16(data[0:], point[0])
+		binary.LittleEndian.PutUint16(data[2:], point[1])
+		comment = append(comment, data)
+	}
+	got := RenderCommentsText(instructions.RawComments{comment}, GameIdenticalComments())
+	want := "DEFINE COMMENT 0\neJzti8sKgDAQA+fgo+129SBIERE/dT/d6F8InTCBHLIhBhJjJCYyc2StQo6iNiyMqnhUFpw1/L1wf7nkKQ/Z5E6n0/kVD9nGBWY;\n\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func checkInflates(t *testing.T, name string, compressed, data []byte) {
+	t.Helper()
+	reader, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Errorf("%s: %v", name, err)
+		return
+	}
+	inflated, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Errorf("%s: %v", name, err)
+	} else if !bytes.Equal(inflated, data) {
+		t.Errorf("%s: does not inflate to the input", name)
+	}
+}
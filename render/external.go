@@ -0,0 +1,105 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/schema"
+)
+
+// The executable name prefix of external exporters. The format NAME is
+// provided by an executable named hrm-export-NAME found on PATH
+const ExternalExporterPrefix = "hrm-export-"
+
+// The version of the external exporter protocol
+const ExternalProtocolVersion = 1
+
+// The request written as a single JSON document to the standard input of
+// an external exporter. The exporter writes the rendered output to its
+// standard output and exits with a non-zero status on failure, with a
+// description of the failure on its standard error
+type ExternalRequest struct {
+	Version      int                   `json:"version"`
+	Format       string                `json:"format"`
+	Text         string                `json:"text"`
+	Instructions []ExternalInstruction `json:"instructions"`
+	Comments     [][][]ExternalPoint   `json:"comments"`
+}
+
+// An instruction of an ExternalRequest, in the shared JSON representation
+type ExternalInstruction = schema.Instruction
+
+// A point of a comment drawing of an ExternalRequest
+type ExternalPoint = schema.Point
+
+// Return the format provided by an external exporter for name, if an
+// executable for it is found on PATH
+func LookupExternal(name string) (Format, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return Format{}, false
+	}
+	path, err := exec.LookPath(ExternalExporterPrefix + name)
+	if err != nil {
+		return Format{}, false
+	}
+	return Format{
+		Name: name,
+		Render: func(ctx context.Context, w io.Writer, program Program, options Options) error {
+			return renderExternal(ctx, path, name, w, program, options)
+		},
+	}, true
+}
+
+// Build the request sent to an external exporter for program
+func NewExternalRequest(ctx context.Context, format string, program Program, options Options) (ExternalRequest, error) {
+	request := ExternalRequest{
+		Version: ExternalProtocolVersion,
+		Format:  format,
+	}
+	opts := append([]RenderInstructionsTextOption{TextLogger(options.Logger)}, options.Text...)
+	text, err := RenderTextContext(ctx, program, opts...)
+	if err != nil {
+		return request, err
+	}
+	request.Text = text
+
+	representation := schema.NewProgram(program.Disassembled, program.Comments)
+	request.Instructions = representation.Instructions
+	request.Comments = representation.Comments
+	return request, nil
+}
+
+// Render program with the external exporter at path
+func renderExternal(ctx context.Context, path, format string, w io.Writer, program Program, options Options) error {
+	request, err := NewExternalRequest(ctx, format, program, options)
+	if err != nil {
+		return err
+	}
+	input, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if options.Logger != nil {
+		options.Logger.Debug("running external exporter", "format", format, "path", path)
+	}
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("exporter %s: %w: %s", ExternalExporterPrefix+format, err, message)
+		}
+		return fmt.Errorf("exporter %s: %w", ExternalExporterPrefix+format, err)
+	}
+	return nil
+}
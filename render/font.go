@@ -0,0 +1,309 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A font used for the text of rendered programs. A font is either a
+// system font, which is referred to by family name and whose text widths
+// are approximated, or a font loaded from a TrueType, OpenType or WOFF file
+// whose widths are measured and which can be embedded in the output
+type Font struct {
+	Family string
+	// The font file, if loaded with LoadFont or ParseFont
+	data   []byte
+	format string
+	// The advance width of the printable ASCII characters, as a fraction of
+	// the font size; nil if unknown
+	advances map[rune]float64
+	// The approximate average advance width, as a fraction of the font size
+	average float64
+}
+
+// The font used by default, as used by the game
+var DefaultFont = SystemFont("Arial Black")
+
+// The approximate average advance widths of some common font families
+var systemFontAdvances = map[string]float64{
+	"arial black":     0.67,
+	"arial":           0.52,
+	"helvetica":       0.52,
+	"verdana":         0.58,
+	"dejavu sans":     0.56,
+	"liberation sans": 0.52,
+	"monospace":       0.6,
+	"courier":         0.6,
+	"courier new":     0.6,
+	"sans-serif":      0.55,
+}
+
+// Return a font referring to an installed font family. Text widths are
+// approximated
+func SystemFont(family string) Font {
+	average, found := systemFontAdvances[strings.ToLower(family)]
+	if !found {
+		average = 0.6
+	}
+	return Font{Family: family, average: average}
+}
+
+// Load a TrueType, OpenType or WOFF font from path. If family is empty the
+// file name (without extension) is used as the family name
+func LoadFont(path, family string) (Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Font{}, err
+	}
+	if family == "" {
+		family = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return ParseFont(family, data)
+}
+
+// Parse a TrueType, OpenType or WOFF font. The advance widths of WOFF2
+// fonts are not measured (they are approximated), but the font can still be
+// embedded
+func ParseFont(family string, data []byte) (Font, error) {
+	font := SystemFont(family)
+	font.data = data
+	if len(data) < 4 {
+		return font, errors.New("font file too short")
+	}
+	var tables func(tag string) ([]byte, error)
+	switch magic := string(data[:4]); magic {
+	case "\x00\x01\x00\x00", "true":
+		font.format, tables = "truetype", sfntTables(data)
+	case "OTTO":
+		font.format, tables = "opentype", sfntTables(data)
+	case "wOFF":
+		font.format, tables = "woff", woffTables(data)
+	case "wOF2":
+		font.format = "woff2"
+		return font, nil
+	default:
+		return font, fmt.Errorf("unknown font format (magic %q)", magic)
+	}
+	advances, err := measureFont(tables)
+	if err != nil {
+		return font, fmt.Errorf("reading font metrics: %w", err)
+	}
+	font.advances = advances
+	return font, nil
+}
+
+// Return the width of s rendered in the font at size
+func (f Font) TextWidth(s string, size float64) float64 {
+	width := 0.0
+	for _, ch := range s {
+		if advance, found := f.advances[ch]; found {
+			width += advance * size
+		} else {
+			width += f.average * size
+		}
+	}
+	return width
+}
+
+// Report whether the font can be embedded in the output
+func (f Font) Embeddable() bool {
+	return f.data != nil
+}
+
+// Return the CSS font-family value for the font
+func (f Font) cssFamily() string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(f.Family, "'", ""))
+}
+
+// Return a CSS @font-face rule embedding the font
+func (f Font) fontFace() string {
+	mime := map[string]string{
+		"truetype": "font/ttf",
+		"opentype": "font/otf",
+		"woff":     "font/woff",
+		"woff2":    "font/woff2",
+	}[f.format]
+	return fmt.Sprintf(
+		"@font-face { font-family: '%s'; src: url(data:%s;base64,%s) format('%s'); }",
+		strings.ReplaceAll(f.Family, "'", ""), mime, base64.StdEncoding.EncodeToString(f.data), f.format)
+}
+
+// Return a function looking up the tables of a TrueType/OpenType font
+func sfntTables(data []byte) func(string) ([]byte, error) {
+	return func(tag string) ([]byte, error) {
+		if len(data) < 12 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		numTables := int(binary.BigEndian.Uint16(data[4:]))
+		for i := 0; i < numTables; i++ {
+			record := 12 + i*16
+			if record+16 > len(data) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if string(data[record:record+4]) != tag {
+				continue
+			}
+			offset := int(binary.BigEndian.Uint32(data[record+8:]))
+			length := int(binary.BigEndian.Uint32(data[record+12:]))
+			if offset+length > len(data) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return data[offset : offset+length], nil
+		}
+		return nil, fmt.Errorf("missing %q table", tag)
+	}
+}
+
+// Return a function looking up (and decompressing) the tables of a WOFF
+// font
+func woffTables(data []byte) func(string) ([]byte, error) {
+	return func(tag string) ([]byte, error) {
+		if len(data) < 44 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		numTables := int(binary.BigEndian.Uint16(data[12:]))
+		for i := 0; i < numTables; i++ {
+			record := 44 + i*20
+			if record+20 > len(data) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if string(data[record:record+4]) != tag {
+				continue
+			}
+			offset := int(binary.BigEndian.Uint32(data[record+4:]))
+			compLength := int(binary.BigEndian.Uint32(data[record+8:]))
+			origLength := int(binary.BigEndian.Uint32(data[record+12:]))
+			if offset+compLength > len(data) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			table := data[offset : offset+compLength]
+			if compLength == origLength {
+				return table, nil
+			}
+			reader, err := zlib.NewReader(bytes.NewReader(table))
+			if err != nil {
+				return nil, err
+			}
+			defer reader.Close()
+			decompressed := make([]byte, origLength)
+			_, err = io.ReadFull(reader, decompressed)
+			return decompressed, err
+		}
+		return nil, fmt.Errorf("missing %q table", tag)
+	}
+}
+
+// Read the advance widths of the printable ASCII characters from the head,
+// hhea, hmtx and cmap tables
+func measureFont(tables func(string) ([]byte, error)) (map[rune]float64, error) {
+	head, err := tables("head")
+	if err != nil {
+		return nil, err
+	}
+	hhea, err := tables("hhea")
+	if err != nil {
+		return nil, err
+	}
+	hmtx, err := tables("hmtx")
+	if err != nil {
+		return nil, err
+	}
+	cmap, err := tables("cmap")
+	if err != nil {
+		return nil, err
+	}
+	if len(head) < 20 || len(hhea) < 36 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	unitsPerEm := float64(binary.BigEndian.Uint16(head[18:]))
+	numberOfHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if unitsPerEm == 0 || numberOfHMetrics == 0 || len(hmtx) < numberOfHMetrics*4 {
+		return nil, errors.New("invalid horizontal metrics")
+	}
+	glyphs, err := cmapFormat4(cmap)
+	if err != nil {
+		return nil, err
+	}
+	advances := make(map[rune]float64)
+	for ch := rune(0x20); ch < 0x7f; ch++ {
+		glyph, found := glyphs[ch]
+		if !found {
+			continue
+		}
+		if glyph >= numberOfHMetrics {
+			glyph = numberOfHMetrics - 1
+		}
+		advances[ch] = float64(binary.BigEndian.Uint16(hmtx[glyph*4:])) / unitsPerEm
+	}
+	return advances, nil
+}
+
+// Map the printable ASCII characters to glyph indexes using the Unicode
+// BMP (format 4) subtable of the cmap table
+func cmapFormat4(cmap []byte) (map[rune]int, error) {
+	if len(cmap) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	numTables := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < numTables; i++ {
+		record := 4 + i*8
+		if record+8 > len(cmap) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		platform := binary.BigEndian.Uint16(cmap[record:])
+		encoding := binary.BigEndian.Uint16(cmap[record+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if !(platform == 3 && encoding == 1) && platform != 0 {
+			continue
+		}
+		if offset+14 > len(cmap) || binary.BigEndian.Uint16(cmap[offset:]) != 4 {
+			continue
+		}
+		sub := cmap[offset:]
+		segCount := int(binary.BigEndian.Uint16(sub[6:])) / 2
+		endCodes := 14
+		startCodes := endCodes + segCount*2 + 2
+		idDeltas := startCodes + segCount*2
+		idRangeOffsets := idDeltas + segCount*2
+		if idRangeOffsets+segCount*2 > len(sub) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		glyphs := make(map[rune]int)
+		for ch := rune(0x20); ch < 0x7f; ch++ {
+			for seg := 0; seg < segCount; seg++ {
+				end := rune(binary.BigEndian.Uint16(sub[endCodes+seg*2:]))
+				start := rune(binary.BigEndian.Uint16(sub[startCodes+seg*2:]))
+				if ch > end || ch < start {
+					continue
+				}
+				delta := int(binary.BigEndian.Uint16(sub[idDeltas+seg*2:]))
+				rangeOffset := int(binary.BigEndian.Uint16(sub[idRangeOffsets+seg*2:]))
+				glyph := 0
+				if rangeOffset == 0 {
+					glyph = (int(ch) + delta) & 0xffff
+				} else {
+					index := idRangeOffsets + seg*2 + rangeOffset + int(ch-start)*2
+					if index+2 <= len(sub) {
+						if glyph = int(binary.BigEndian.Uint16(sub[index:])); glyph != 0 {
+							glyph = (glyph + delta) & 0xffff
+						}
+					}
+				}
+				if glyph != 0 {
+					glyphs[ch] = glyph
+				}
+				break
+			}
+		}
+		return glyphs, nil
+	}
+	return nil, errors.New("no Unicode BMP (format 4) cmap subtable")
+}
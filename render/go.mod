@@ -3,6 +3,15 @@ module github.com/clj/hrm-profile-tool/render
 require (
 	github.com/ajstarks/svgo v0.0.0-20180830174826-7338bd80e790
 	github.com/clj/hrm-profile-tool/instructions v0.0.0
+	github.com/clj/hrm-profile-tool/schema v0.0.0
+	github.com/clj/hrm-profile-tool/utils/text v0.0.0
+	github.com/clj/hrm-profile-tool/utils/logging v0.0.0
 )
 
-replace github.com/clj/hrm-profile-tool/instructions => ../utils
+replace github.com/clj/hrm-profile-tool/instructions => ../instructions
+
+replace github.com/clj/hrm-profile-tool/utils/text => ../utils/text
+
+replace github.com/clj/hrm-profile-tool/utils/logging => ../utils/logging
+
+replace github.com/clj/hrm-profile-tool/schema => ../schema
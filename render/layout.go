@@ -0,0 +1,244 @@
+package render
+
+import (
+	"math"
+	"sort"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+// The geometry of the game style rendering of a program
+type geometry struct {
+	lineNumberColumnWidth int
+	instXOffset           int
+	instYOffset           int
+	instYStep             int
+	instHeight            int
+	commentYStep          int
+	commentHeight         int
+	commentWidth          int
+	canvasWidth           int
+	targetLabelWidth      int
+	argumentWidth         int
+	argumentGap           int
+}
+
+var defaultGeometry = geometry{
+	lineNumberColumnWidth: 35,
+	instXOffset:           10,
+	instYOffset:           10,
+	instYStep:             30,
+	instHeight:            25,
+	commentYStep:          45,
+	commentHeight:         40,
+	commentWidth:          120,
+	canvasWidth:           300,
+	targetLabelWidth:      75,
+	argumentWidth:         50,
+	argumentGap:           10,
+}
+
+// The layout of a program, shared by the renderers that emulate the game's
+// display of instructions (SVG, EPS, ...)
+type programLayout struct {
+	geometry
+	canvasHeight int
+	// The number of comments preceding the i'th instruction
+	commentCount []int
+	// The width of the instruction boxes, if widened to fit a font
+	mnemonicWidths map[instructions.OpCode]int
+	// The x coordinate of the control points of the jump arc of the i'th
+	// instruction, if the jumps are routed (see routeJumps)
+	jumpExtents map[int]int
+}
+
+// A jump arc drawn as a cubic bezier curve from the jump instruction (s)
+// to the jump target (e) with control points c and p
+type jumpArc struct {
+	sx, sy, cx, cy, px, py, ex, ey int
+}
+
+// The point of the arc furthest from the instructions
+func (a jumpArc) apex() (int, int) {
+	return (a.sx + 3*a.cx + 3*a.px + a.ex) / 8, (a.sy + 3*a.cy + 3*a.py + a.ey) / 8
+}
+
+// The horizontal distance between the control points of nested jump arcs
+// when the jumps are routed
+const jumpRouteStep = 20
+
+func newProgramLayout(disassembled instructions.Disassembled, comments instructions.Comments) programLayout {
+	l := programLayout{geometry: defaultGeometry}
+	l.canvasHeight = len(disassembled)*l.instYStep + l.instYOffset*2 + len(comments)*(l.commentYStep-l.instYStep)
+
+	numComments := 0
+	l.commentCount = make([]int, len(disassembled))
+	for i, diss := range disassembled {
+		l.commentCount[i] = numComments
+		switch diss.(type) {
+		case instructions.DisassembleComment:
+			numComments++
+		}
+	}
+	return l
+}
+
+// Size the canvas to the comments of disassembled, rather than to all the
+// comments of the program, when rendering an excerpt of it
+func (l *programLayout) fitComments(disassembled instructions.Disassembled) {
+	numComments := 0
+	for _, diss := range disassembled {
+		if _, ok := diss.(instructions.DisassembleComment); ok {
+			numComments++
+		}
+	}
+	l.canvasHeight = len(disassembled)*l.instYStep + l.instYOffset*2 + numComments*(l.commentYStep-l.instYStep)
+}
+
+// The padding either side of the text of an instruction box
+const mnemonicPadding = 10
+
+// Return the offset of the condition of a conditional jump ("if zero")
+// from the start of the jump mnemonic, fitting the mnemonic in font
+func jumpConditionGap(font Font, mnemonic string) int {
+	gap := 45
+	if width := int(math.Ceil(font.TextWidth(mnemonic, 16))) + 2; width > gap {
+		gap = width
+	}
+	return gap
+}
+
+// Widen the instruction boxes where required to fit their text, as
+// returned by label, in font
+func (l *programLayout) fitFont(font Font, label func(instructions.OpCode) svgLabel, conditionGap int) {
+	l.mnemonicWidths = make(map[instructions.OpCode]int)
+	for op, mnemonic := range svgInstrunctionMnemonics {
+		text := label(op)
+		width := int(math.Ceil(font.TextWidth(text.mnemonic, 16))) + mnemonicPadding*2
+		if text.conditionIf != "" {
+			width = 15 + conditionGap + int(math.Ceil(math.Max(font.TextWidth(text.conditionIf, 10), font.TextWidth(text.condition, 10)))) + 5
+		}
+		if width < mnemonic.Width {
+			width = mnemonic.Width
+		}
+		l.mnemonicWidths[op] = width
+	}
+}
+
+// The width of the instruction box of op
+func (l programLayout) mnemonicWidth(op instructions.OpCode) int {
+	if width, found := l.mnemonicWidths[op]; found {
+		return width
+	}
+	return svgInstrunctionMnemonics[op].Width
+}
+
+// The x coordinate of all instruction boxes
+func (l programLayout) instX() int {
+	return l.lineNumberColumnWidth + l.instXOffset
+}
+
+// The y coordinate of the i'th instruction box
+func (l programLayout) instY(i int) int {
+	return l.instYOffset + i*l.instYStep + l.commentCount[i]*(l.commentYStep-l.instYStep)
+}
+
+// The x coordinate of the argument box of an instruction
+func (l programLayout) argumentX(op instructions.OpCode) int {
+	return l.instX() + l.mnemonicWidth(op) + l.argumentGap
+}
+
+// The jump arc of the i'th instruction, which must be a jump. Reports
+// false if the jump has no valid target
+func (l programLayout) jumpArc(i int, jump instructions.DisassembleJumpInstruction) (jumpArc, bool) {
+	if jump.Target < 0 || jump.Target >= len(l.commentCount) {
+		return jumpArc{}, false
+	}
+	sy := l.instY(i) + l.instHeight/2
+	ey := l.instY(jump.Target) + l.instHeight/2
+	extent := l.canvasWidth
+	if x, found := l.jumpExtents[i]; found {
+		extent = x
+	}
+	return jumpArc{
+		sx: l.instX() + l.mnemonicWidth(jump.Op),
+		sy: sy,
+		cx: extent,
+		cy: sy,
+		px: extent,
+		py: ey,
+		ex: l.instX() + l.targetLabelWidth + 10,
+		ey: ey,
+	}, true
+}
+
+// The arc of the i'th instruction, a jump to a target outside of an
+// excerpt of the program, running off the top (direction -1) or bottom
+// (direction 1) of the canvas
+func (l programLayout) jumpExit(i int, jump instructions.DisassembleJumpInstruction, direction int) jumpArc {
+	sx := l.instX() + l.mnemonicWidth(jump.Op)
+	sy := l.instY(i) + l.instHeight/2
+	x := (sx + 3*l.canvasWidth) / 4
+	ey := 0
+	if direction > 0 {
+		ey = l.canvasHeight
+	}
+	return jumpArc{sx: sx, sy: sy, cx: x, cy: sy, px: x, py: sy, ex: x, ey: ey}
+}
+
+// Give the jump arcs of disassembled distinct horizontal extents, so that
+// they do not overlap. As in the game, arcs are nested by the lines they
+// span: an arc is drawn outside every shorter arc it overlaps, the
+// outermost arcs reaching the edge of the canvas
+func (l *programLayout) routeJumps(disassembled instructions.Disassembled) {
+	type span struct {
+		index, top, bottom int
+	}
+	var spans []span
+	// The control points are kept right of the start of every arc
+	innerX := 0
+	for i, diss := range disassembled {
+		jump, ok := diss.(instructions.DisassembleJumpInstruction)
+		if !ok || jump.Target < 0 || jump.Target >= len(l.commentCount) {
+			continue
+		}
+		top, bottom := i, jump.Target
+		if top > bottom {
+			top, bottom = bottom, top
+		}
+		spans = append(spans, span{i, top, bottom})
+		if x := l.instX() + l.mnemonicWidth(jump.Op); x > innerX {
+			innerX = x
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].bottom-spans[i].top < spans[j].bottom-spans[j].top
+	})
+
+	levels := make([]int, len(spans))
+	maxLevel := 0
+	for i, s := range spans {
+		levels[i] = 1
+		for j := 0; j < i; j++ {
+			if spans[j].top <= s.bottom && s.top <= spans[j].bottom && levels[j] >= levels[i] {
+				levels[i] = levels[j] + 1
+			}
+		}
+		if levels[i] > maxLevel {
+			maxLevel = levels[i]
+		}
+	}
+
+	// Squeeze the arcs together if they do not fit the canvas
+	step := jumpRouteStep
+	if maxLevel > 0 && (l.canvasWidth-innerX)/maxLevel < step {
+		step = (l.canvasWidth - innerX) / maxLevel
+	}
+	if step < 0 {
+		step = 0
+	}
+	l.jumpExtents = make(map[int]int, len(spans))
+	for i, s := range spans {
+		l.jumpExtents[s.index] = l.canvasWidth - (maxLevel-levels[i])*step
+	}
+}
@@ -0,0 +1,101 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+// Render the result of a three-way merge as text. Without conflicts the
+// output is a program which can be pasted into the game; conflicts are
+// rendered between the conflict markers used by git, labelled oursName
+// and theirsName. Labels and comments are renumbered. Jumps whose target
+// was removed by the merge jump to the label "?".
+//
+// Returns the text and the number of conflicts and jumps without a target.
+// Only the UseMnemonics, RenderMnemonicsIn, HideCommentDefinitions,
+// CommentCompressionLevel, GameIdenticalComments and CommentWrap options
+// apply
+func RenderMergeText(merged instructions.Merged, oursName, theirsName string, opts ...RenderInstructionsTextOption) (string, int) {
+	var options renderInstructionsTextOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	mnemonic := options.mnemonic
+
+	// Name the targets of the jumps in order of appearance
+	var refs []instructions.MergeRef
+	for _, chunk := range merged.Chunks {
+		refs = append(append(refs, chunk.Ours...), chunk.Theirs...)
+	}
+	targets := make(map[int]bool)
+	for _, ref := range refs {
+		if line, found := merged.Target(ref); found {
+			targets[line] = true
+		}
+	}
+	labels := make(map[int]string)
+	label := "a"
+	for line := range refs {
+		if targets[line] {
+			labels[line] = label
+			label = instructions.NextLabel(label)
+		}
+	}
+
+	unresolved := merged.Conflicts()
+	var comments instructions.RawComments
+	var builder strings.Builder
+	line := 0
+	write := func(refs []instructions.MergeRef) {
+		for _, ref := range refs {
+			switch diss := merged.Instruction(ref).(type) {
+			case instructions.DisassembleComment:
+				var comment instructions.RawComment
+				if program := merged.Programs[ref.Source]; int(diss.Index) < len(program.Comments) {
+					comment = program.Comments[diss.Index]
+				}
+				fmt.Fprintf(&builder, "COMMENT %d\n", len(comments))
+				comments = append(comments, comment)
+			case instructions.DisassembleJumpTarget:
+				if name, found := labels[line]; found {
+					fmt.Fprintf(&builder, "%s:\n", name)
+				}
+			case instructions.DisassembleJumpInstruction:
+				target, found := merged.Target(ref)
+				if !found {
+					unresolved++
+					fmt.Fprintf(&builder, "%s ?\n", mnemonic(diss.Op))
+					break
+				}
+				fmt.Fprintf(&builder, "%s %s\n", mnemonic(diss.Op), labels[target])
+			case instructions.DisassembleArgInstruction:
+				if diss.Indirect {
+					fmt.Fprintf(&builder, "%s [%d]\n", mnemonic(diss.Op), diss.Arg)
+				} else {
+					fmt.Fprintf(&builder, "%s %d\n", mnemonic(diss.Op), diss.Arg)
+				}
+			case instructions.DisassembleInstruction:
+				fmt.Fprintf(&builder, "%s\n", mnemonic(diss.Op))
+			}
+			line++
+		}
+	}
+	for _, chunk := range merged.Chunks {
+		if !chunk.Conflict {
+			write(chunk.Ours)
+			continue
+		}
+		fmt.Fprintf(&builder, "<<<<<<< %s\n", oursName)
+		write(chunk.Ours)
+		builder.WriteString("=======\n")
+		write(chunk.Theirs)
+		fmt.Fprintf(&builder, ">>>>>>> %s\n", theirsName)
+	}
+
+	if !options.hideCommentDefs && len(comments) > 0 {
+		builder.WriteString("\n" + options.wrapComments(RenderCommentsText(comments, opts...)))
+	}
+	return builder.String(), unresolved
+}
@@ -0,0 +1,192 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+const mergeBase = `a:
+INBOX
+COPYTO 0
+ADD 0
+OUTBOX
+JUMP a
+`
+
+var mergeTests = []struct {
+	name       string
+	base       string
+	ours       string
+	theirs     string
+	merged     string
+	unresolved int
+}{
+	{"clean merge", mergeBase, `a:
+INBOX
+COPYTO 1
+ADD 0
+OUTBOX
+JUMP a
+`, `a:
+INBOX
+COPYTO 0
+ADD 0
+OUTBOX
+BUMPDN 0
+JUMP a
+`, `a:
+INBOX
+COPYTO 1
+ADD 0
+OUTBOX
+BUMPDN 0
+JUMP a
+`, 0},
+	{"conflict", mergeBase, `a:
+INBOX
+COPYTO 1
+ADD 0
+OUTBOX
+JUMP a
+`, `a:
+INBOX
+COPYTO 2
+ADD 0
+OUTBOX
+JUMP a
+`, `a:
+INBOX
+<<<<<<< ours
+COPYTO 1
+=======
+COPYTO 2
+>>>>>>> theirs
+ADD 0
+OUTBOX
+JUMP a
+`, 1},
+	{"identical change on both sides", mergeBase, `a:
+INBOX
+COPYTO 1
+ADD 1
+OUTBOX
+JUMP a
+`, `a:
+INBOX
+COPYTO 1
+ADD 1
+OUTBOX
+JUMP a
+`, `a:
+INBOX
+COPYTO 1
+ADD 1
+OUTBOX
+JUMP a
+`, 0},
+	{"one-sided delete", mergeBase, mergeBase, `a:
+INBOX
+COPYTO 0
+OUTBOX
+JUMP a
+`, `a:
+INBOX
+COPYTO 0
+OUTBOX
+JUMP a
+`, 0},
+	{"delete and change", mergeBase, `a:
+INBOX
+COPYTO 0
+SUB 0
+OUTBOX
+JUMP a
+`, `a:
+INBOX
+COPYTO 0
+OUTBOX
+JUMP a
+`, `a:
+INBOX
+COPYTO 0
+<<<<<<< ours
+SUB 0
+=======
+>>>>>>> theirs
+OUTBOX
+JUMP a
+`, 1},
+	{"relabeled", mergeBase, `INBOX
+COPYTO 0
+ADD 0
+OUTBOX
+b:
+JUMP b
+`, `z:
+INBOX
+COPYTO 0
+ADD 0
+OUTBOX
+JUMP z
+`, `INBOX
+COPYTO 0
+ADD 0
+OUTBOX
+a:
+JUMP a
+`, 0},
+	{"jump to a deleted label", `a:
+INBOX
+OUTBOX
+JUMP a
+`, `INBOX
+OUTBOX
+`, `a:
+INBOX
+JUMPZ a
+OUTBOX
+JUMP a
+`, `INBOX
+JUMPZ ?
+OUTBOX
+`, 1},
+}
+
+func mergeProgram(t *testing.T, text string) instructions.MergeProgram {
+	t.Helper()
+	_, disassembled := assembled(t, text)
+	return instructions.MergeProgram{Disassembled: disassembled}
+}
+
+func TestRenderMergeText(t *testing.T) {
+	for _, test := range mergeTests {
+		t.Run(test.name, func(t *testing.T) {
+			merged := instructions.MergePrograms(mergeProgram(t, test.base), mergeProgram(t, test.ours), mergeProgram(t, test.theirs))
+			text, unresolved := RenderMergeText(merged, "ours", "theirs")
+			if text != test.merged {
+				t.Errorf("got\n%s\nexpected\n%s", text, test.merged)
+			}
+			if unresolved != test.unresolved {
+				t.Errorf("got %d unresolved, expected %d", unresolved, test.unresolved)
+			}
+		})
+	}
+}
+
+func TestRenderMergeTextComments(t *testing.T) {
+	base := mergeProgram(t, "INBOX\nCOMMENT 0\nOUTBOX\n")
+	base.Comments = instructions.RawComments{{{1, 0, 2, 0}}}
+	ours := mergeProgram(t, "INBOX\nCOMMENT 0\nOUTBOX\nCOMMENT 1\n")
+	ours.Comments = instructions.RawComments{{{1, 0, 2, 0}}, {{3, 0, 4, 0}}}
+	// Theirs numbers the same drawing differently
+	theirs := mergeProgram(t, "COMMENT 1\nINBOX\nCOMMENT 0\nOUTBOX\n")
+	theirs.Comments = instructions.RawComments{{{1, 0, 2, 0}}, {{5, 0, 6, 0}}}
+
+	merged := instructions.MergePrograms(base, ours, theirs)
+	text, unresolved := RenderMergeText(merged, "ours", "theirs", HideCommentDefinitions())
+	expected := "COMMENT 0\nINBOX\nCOMMENT 1\nOUTBOX\nCOMMENT 2\n"
+	if text != expected || unresolved != 0 {
+		t.Errorf("got %d unresolved and\n%s\nexpected\n%s", unresolved, text, expected)
+	}
+}
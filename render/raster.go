@@ -0,0 +1,233 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// Return the colour as an opaque color.RGBA
+func (c Colour) rgba() color.RGBA {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(string(c), "rgb(%d, %d, %d)", &r, &g, &b); err != nil {
+		return color.RGBA{0, 0, 0, 0xff}
+	}
+	return color.RGBA{r, g, b, 0xff}
+}
+
+// A minimal raster canvas used for the image (PNG) renderers. The standard
+// library has no vector drawing or fonts, so shapes are rasterised directly
+// and text uses the built in bitmap font below.
+type raster struct {
+	*image.RGBA
+}
+
+func newRaster(width, height int) raster {
+	return raster{image.NewRGBA(image.Rect(0, 0, width, height))}
+}
+
+func (r raster) fillRect(x, y, w, h int, c color.Color) {
+	draw.Draw(r.RGBA, image.Rect(x, y, x+w, y+h), image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// Fill a rectangle with rounded corners of radius rad
+func (r raster) fillRoundRect(x, y, w, h, rad int, c color.Color) {
+	for py := y; py < y+h; py++ {
+		for px := x; px < x+w; px++ {
+			dx, dy := 0, 0
+			if px < x+rad {
+				dx = x + rad - px
+			} else if px >= x+w-rad {
+				dx = px - (x + w - rad - 1)
+			}
+			if py < y+rad {
+				dy = y + rad - py
+			} else if py >= y+h-rad {
+				dy = py - (y + h - rad - 1)
+			}
+			if dx*dx+dy*dy <= rad*rad {
+				r.Set(px, py, c)
+			}
+		}
+	}
+}
+
+func (r raster) fillCircle(cx, cy, rad float64, c color.Color) {
+	for py := int(cy - rad); py <= int(cy+rad); py++ {
+		for px := int(cx - rad); px <= int(cx+rad); px++ {
+			dx, dy := float64(px)-cx, float64(py)-cy
+			if dx*dx+dy*dy <= rad*rad {
+				r.Set(px, py, c)
+			}
+		}
+	}
+}
+
+// Draw a line of width w with round caps, clipped to clip
+func (r raster) line(x0, y0, x1, y1, w float64, c color.Color, clip image.Rectangle) {
+	sub := raster{r.SubImage(clip).(*image.RGBA)}
+	steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		sub.fillCircle(x0+(x1-x0)*t, y0+(y1-y0)*t, w/2, c)
+	}
+}
+
+// Draw a cubic bezier curve of width w
+func (r raster) bezier(arc jumpArc, w float64, c color.Color) {
+	point := func(t float64) (float64, float64) {
+		u := 1 - t
+		x := u*u*u*float64(arc.sx) + 3*u*u*t*float64(arc.cx) + 3*u*t*t*float64(arc.px) + t*t*t*float64(arc.ex)
+		y := u*u*u*float64(arc.sy) + 3*u*u*t*float64(arc.cy) + 3*u*t*t*float64(arc.py) + t*t*t*float64(arc.ey)
+		return x, y
+	}
+	const segments = 32
+	x0, y0 := point(0)
+	for i := 1; i <= segments; i++ {
+		x1, y1 := point(float64(i) / segments)
+		r.line(x0, y0, x1, y1, w, c, r.Bounds())
+		x0, y0 = x1, y1
+	}
+}
+
+// Blend the rectangle towards c, by alpha (0-1)
+func (r raster) blend(rect image.Rectangle, c color.RGBA, alpha float64) {
+	rect = rect.Intersect(r.Bounds())
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a)*(1-alpha) + float64(b)*alpha)
+	}
+	for py := rect.Min.Y; py < rect.Max.Y; py++ {
+		for px := rect.Min.X; px < rect.Max.X; px++ {
+			o := r.RGBAAt(px, py)
+			r.SetRGBA(px, py, color.RGBA{mix(o.R, c.R), mix(o.G, c.G), mix(o.B, c.B), 0xff})
+		}
+	}
+}
+
+// Return the image scaled to width x height, averaging the source pixels
+// covered by each destination pixel
+func (r raster) scale(width, height int) *image.RGBA {
+	src := r.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		sy0 := src.Min.Y + y*src.Dy()/height
+		sy1 := src.Min.Y + (y+1)*src.Dy()/height
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for x := 0; x < width; x++ {
+			sx0 := src.Min.X + x*src.Dx()/width
+			sx1 := src.Min.X + (x+1)*src.Dx()/width
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+			var rs, gs, bs, as, n int
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					c := r.RGBAAt(sx, sy)
+					rs, gs, bs, as, n = rs+int(c.R), gs+int(c.G), bs+int(c.B), as+int(c.A), n+1
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(rs / n), uint8(gs / n), uint8(bs / n), uint8(as / n)})
+		}
+	}
+	return dst
+}
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+// A 5x7 bitmap font covering the characters used by the renderers. Unknown
+// characters are drawn as blanks.
+var glyphs = map[rune][glyphHeight]string{
+	'a': {"     ", "     ", " ### ", "    #", " ####", "#   #", " ####"},
+	'b': {"#    ", "#    ", "#### ", "#   #", "#   #", "#   #", "#### "},
+	'c': {"     ", "     ", " ####", "#    ", "#    ", "#    ", " ####"},
+	'd': {"    #", "    #", " ####", "#   #", "#   #", "#   #", " ####"},
+	'e': {"     ", "     ", " ### ", "#   #", "#####", "#    ", " ####"},
+	'f': {"  ## ", " #   ", "#### ", " #   ", " #   ", " #   ", " #   "},
+	'g': {"     ", " ####", "#   #", "#   #", " ####", "    #", " ### "},
+	'h': {"#    ", "#    ", "#### ", "#   #", "#   #", "#   #", "#   #"},
+	'i': {"  #  ", "     ", " ##  ", "  #  ", "  #  ", "  #  ", " ### "},
+	'j': {"   # ", "     ", "  ## ", "   # ", "   # ", "#  # ", " ##  "},
+	'k': {"#    ", "#    ", "#  # ", "# #  ", "##   ", "# #  ", "#  # "},
+	'l': {" ##  ", "  #  ", "  #  ", "  #  ", "  #  ", "  #  ", " ### "},
+	'm': {"     ", "     ", "## # ", "# # #", "# # #", "# # #", "# # #"},
+	'n': {"     ", "     ", "#### ", "#   #", "#   #", "#   #", "#   #"},
+	'o': {"     ", "     ", " ### ", "#   #", "#   #", "#   #", " ### "},
+	'p': {"     ", "#### ", "#   #", "#   #", "#### ", "#    ", "#    "},
+	'q': {"     ", " ####", "#   #", "#   #", " ####", "    #", "    #"},
+	'r': {"     ", "     ", "# ## ", "##   ", "#    ", "#    ", "#    "},
+	's': {"     ", "     ", " ####", "#    ", " ### ", "    #", "#### "},
+	't': {" #   ", " #   ", "#### ", " #   ", " #   ", " #   ", "  ## "},
+	'u': {"     ", "     ", "#   #", "#   #", "#   #", "#   #", " ####"},
+	'v': {"     ", "     ", "#   #", "#   #", "#   #", " # # ", "  #  "},
+	'w': {"     ", "     ", "#   #", "# # #", "# # #", "# # #", " # # "},
+	'x': {"     ", "     ", "#   #", " # # ", "  #  ", " # # ", "#   #"},
+	'y': {"     ", "#   #", "#   #", "#   #", " ####", "    #", " ### "},
+	'z': {"     ", "     ", "#####", "   # ", "  #  ", " #   ", "#####"},
+	'0': {" ### ", "#   #", "#  ##", "# # #", "##  #", "#   #", " ### "},
+	'1': {"  #  ", " ##  ", "  #  ", "  #  ", "  #  ", "  #  ", " ### "},
+	'2': {" ### ", "#   #", "    #", "   # ", "  #  ", " #   ", "#####"},
+	'3': {" ### ", "#   #", "    #", "  ## ", "    #", "#   #", " ### "},
+	'4': {"   # ", "  ## ", " # # ", "#  # ", "#####", "   # ", "   # "},
+	'5': {"#####", "#    ", "#### ", "    #", "    #", "#   #", " ### "},
+	'6': {" ### ", "#    ", "#### ", "#   #", "#   #", "#   #", " ### "},
+	'7': {"#####", "    #", "   # ", "  #  ", " #   ", " #   ", " #   "},
+	'8': {" ### ", "#   #", "#   #", " ### ", "#   #", "#   #", " ### "},
+	'9': {" ### ", "#   #", "#   #", " ####", "    #", "    #", " ### "},
+	'[': {" ### ", " #   ", " #   ", " #   ", " #   ", " #   ", " ### "},
+	']': {" ### ", "   # ", "   # ", "   # ", "   # ", "   # ", " ### "},
+	'+': {"     ", "  #  ", "  #  ", "#####", "  #  ", "  #  ", "     "},
+	'-': {"     ", "     ", "     ", "#####", "     ", "     ", "     "},
+	'/': {"    #", "    #", "   # ", "  #  ", " #   ", "#    ", "#    "},
+	':': {"     ", "  #  ", "  #  ", "     ", "  #  ", "  #  ", "     "},
+	'.': {"     ", "     ", "     ", "     ", "     ", "  #  ", "  #  "},
+	'%': {"##   ", "##  #", "   # ", "  #  ", " #   ", "#  ##", "   ##"},
+	'#': {" # # ", " # # ", "#####", " # # ", "#####", " # # ", " # # "},
+	'!': {"  #  ", "  #  ", "  #  ", "  #  ", "  #  ", "     ", "  #  "},
+	'?': {" ### ", "#   #", "    #", "   # ", "  #  ", "     ", "  #  "},
+	'✓': {"     ", "    #", "    #", "   # ", "#  # ", " ##  ", "  #  "},
+}
+
+// Return the width in pixels of s drawn at scale
+func textWidth(s string, scale int) int {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+	return (n*(glyphWidth+1) - 1) * scale
+}
+
+// Draw s with its top left corner at x, y. Upper case letters are drawn
+// using the lower case glyphs
+func (r raster) text(x, y, scale int, c color.Color, s string) {
+	for _, ch := range s {
+		if ch >= 'A' && ch <= 'Z' {
+			ch += 'a' - 'A'
+		}
+		glyph := glyphs[ch]
+		for gy, row := range glyph {
+			for gx, bit := range row {
+				if bit != ' ' {
+					r.fillRect(x+gx*scale, y+gy*scale, scale, scale, c)
+				}
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
+
+// Draw s centered on x, y
+func (r raster) centeredText(x, y, scale int, c color.Color, s string) {
+	r.text(x-textWidth(s, scale)/2, y-glyphHeight*scale/2, scale, c, s)
+}
+
+// Draw s left aligned at x and vertically centered on y
+func (r raster) leftText(x, y, scale int, c color.Color, s string) {
+	r.text(x, y-glyphHeight*scale/2, scale, c, s)
+}
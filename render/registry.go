@@ -0,0 +1,253 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/schema"
+)
+
+// A decoded program, i.e. the contents of a single tab, ready for rendering
+type Program struct {
+	Instructions instructions.Instructions
+	Disassembled instructions.Disassembled
+	RawComments  instructions.RawComments
+	Comments     instructions.Comments
+}
+
+// Decode a program from the given reader. The reader must be correctly
+// positioned over the instruction count of the program
+func DecodeProgram(reader io.ReadSeeker, opts ...instructions.DecodeOption) (Program, error) {
+	return DecodeProgramContext(context.Background(), reader, opts...)
+}
+
+// Like DecodeProgram, but stops decoding and returns the context's error
+// if ctx is cancelled
+func DecodeProgramContext(ctx context.Context, reader io.ReadSeeker, opts ...instructions.DecodeOption) (Program, error) {
+	var program Program
+	start, err := reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return program, err
+	}
+	if program.Instructions, err = instructions.DecodeInstructionsContext(ctx, reader, opts...); err != nil {
+		return program, err
+	}
+	if program.Disassembled, err = instructions.Disassemble(program.Instructions); err != nil {
+		return program, err
+	}
+
+	if _, err := reader.Seek(start+instructions.INSTRUCTIONS_BLOCK_SIZE, io.SeekStart); err != nil {
+		return program, err
+	}
+	if program.RawComments, err = instructions.DecodeRawCommentsContext(ctx, reader, opts...); err != nil {
+		return program, err
+	}
+	if program.Comments, err = instructions.DecodeComments(program.RawComments, opts...); err != nil {
+		return program, err
+	}
+	return program, nil
+}
+
+// Decode a program from r at offset, which must be the offset of the
+// instruction count of the program. All data is read at explicit offsets,
+// so r may be shared by concurrent callers
+func DecodeProgramAt(ctx context.Context, r io.ReaderAt, offset int64, opts ...instructions.DecodeOption) (Program, error) {
+	var program Program
+	var err error
+	if program.Instructions, err = instructions.DecodeInstructionsAt(ctx, r, offset, opts...); err != nil {
+		return program, err
+	}
+	if program.Disassembled, err = instructions.Disassemble(program.Instructions); err != nil {
+		return program, err
+	}
+	commentsOffset := offset + instructions.INSTRUCTIONS_BLOCK_SIZE
+	if program.RawComments, err = instructions.DecodeRawCommentsAt(ctx, r, commentsOffset, opts...); err != nil {
+		return program, err
+	}
+	if program.Comments, err = instructions.DecodeComments(program.RawComments, opts...); err != nil {
+		return program, err
+	}
+	return program, nil
+}
+
+// Options passed to a Format's render function. Formats ignore options
+// that do not apply to them
+type Options struct {
+	Text   []RenderInstructionsTextOption
+	SVG    []RenderSVGOption
+	Logger *slog.Logger
+}
+
+// An output format
+type Format struct {
+	// The name used to select the format, e.g. with --format
+	Name string
+	// File name extensions (including the dot) implying this format
+	Extensions []string
+	// Whether the output is unsuitable for display on a terminal
+	Binary bool
+	// Render the program to w, returning early with the context's error
+	// if ctx is cancelled
+	Render func(ctx context.Context, w io.Writer, program Program, options Options) error
+}
+
+var formats = make(map[string]Format)
+
+// Register an output format. Registering a format with the name of an
+// existing format replaces it
+func Register(format Format) {
+	formats[format.Name] = format
+}
+
+// Return the format registered under name
+func Lookup(name string) (Format, bool) {
+	format, found := formats[name]
+	return format, found
+}
+
+// Return the format implied by the extension of the file name
+func LookupFileName(fileName string) (Format, bool) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for _, format := range Formats() {
+		for _, formatExt := range format.Extensions {
+			if ext == formatExt {
+				return format, true
+			}
+		}
+	}
+	return Format{}, false
+}
+
+// Return all registered formats ordered by name
+func Formats() []Format {
+	list := make([]Format, 0, len(formats))
+	for _, format := range formats {
+		list = append(list, format)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Return the names of all registered formats
+func FormatNames() []string {
+	var names []string
+	for _, format := range Formats() {
+		names = append(names, format.Name)
+	}
+	return names
+}
+
+// Render a program as text, i.e. the instructions followed by the
+// comment definitions wrapped at 80 columns (see CommentWrap), optionally
+// preceded by a banner (see TextBanner)
+func RenderText(program Program, opts ...RenderInstructionsTextOption) string {
+	str, _ := RenderTextContext(context.Background(), program, opts...)
+	return str
+}
+
+// Like RenderText, but returns the context's error if ctx is cancelled
+func RenderTextContext(ctx context.Context, program Program, opts ...RenderInstructionsTextOption) (string, error) {
+	opts = append(opts, RawInstructions(program.Instructions))
+	assembly, err := RenderInstructionsTextContext(ctx, program.Disassembled, opts...)
+	if err != nil {
+		return "", err
+	}
+	var options renderInstructionsTextOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.banner != nil {
+		if banner := options.banner.Text(); banner != "" {
+			assembly = banner + "\n" + assembly
+		}
+	}
+	if !options.hideCommentDefs {
+		comments := RenderCommentsText(program.RawComments, opts...)
+		if comments != "" {
+			assembly += "\n" + options.wrapComments(comments)
+		}
+	}
+	if options.commentPreview != "" && len(program.Comments) > 0 {
+		assembly += "\n" + renderCommentPreviews(program.Comments, options.commentPreview)
+	}
+	return assembly, nil
+}
+
+func init() {
+	Register(Format{
+		Name:       "text",
+		Extensions: []string{".txt", ".asm", ".hrm"},
+		Render: func(ctx context.Context, w io.Writer, program Program, options Options) error {
+			opts := append([]RenderInstructionsTextOption{TextLogger(options.Logger)}, options.Text...)
+			str, err := RenderTextContext(ctx, program, opts...)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, str)
+			return err
+		},
+	})
+	Register(Format{
+		Name:       "svg",
+		Extensions: []string{".svg", ".svgz"},
+		Binary:     true,
+		Render: func(ctx context.Context, w io.Writer, program Program, options Options) error {
+			opts := append([]RenderSVGOption{SVGLogger(options.Logger)}, options.SVG...)
+			str, err := RenderSVGContext(ctx, program.Disassembled, program.Comments, opts...)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, str)
+			return err
+		},
+	})
+	Register(Format{
+		Name:       "json",
+		Extensions: []string{".json"},
+		Render: func(ctx context.Context, w io.Writer, program Program, options Options) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(schema.NewExport(program.Disassembled, program.Comments))
+		},
+	})
+	Register(Format{
+		Name:       "csv",
+		Extensions: []string{".csv"},
+		Render: func(ctx context.Context, w io.Writer, program Program, options Options) error {
+			str, err := RenderCSVContext(ctx, program)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, str)
+			return err
+		},
+	})
+	Register(Format{
+		Name:       "eps",
+		Extensions: []string{".eps", ".ps"},
+		Binary:     true,
+		Render: func(ctx context.Context, w io.Writer, program Program, options Options) error {
+			str, err := RenderEPSContext(ctx, program.Disassembled, program.Comments, EPSLogger(options.Logger))
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, str)
+			return err
+		},
+	})
+}
+
+// Return an error describing an unknown format name
+func UnknownFormatError(name string) error {
+	return fmt.Errorf("unknown format %q (available: %s, or install an %s%s exporter on PATH)", name, strings.Join(FormatNames(), ", "), ExternalExporterPrefix, name)
+}
@@ -0,0 +1,77 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	svg "github.com/ajstarks/svgo"
+)
+
+// Badge colours, as used by shields.io
+var (
+	BadgeGreen       = Colour("rgb(68, 204, 17)")
+	BadgeYellowGreen = Colour("rgb(164, 166, 28)")
+	BadgeYellow      = Colour("rgb(223, 177, 23)")
+	BadgeOrange      = Colour("rgb(254, 125, 55)")
+	BadgeRed         = Colour("rgb(224, 93, 68)")
+	badgeLabelColour = Colour("rgb(85, 85, 85)")
+)
+
+// Return the badge colour for a fraction (0-1) of progress
+func BadgeColour(fraction float64) Colour {
+	switch {
+	case fraction >= 1:
+		return BadgeGreen
+	case fraction >= 0.75:
+		return BadgeYellowGreen
+	case fraction >= 0.5:
+		return BadgeYellow
+	case fraction >= 0.25:
+		return BadgeOrange
+	default:
+		return BadgeRed
+	}
+}
+
+// Approximate the width of s in the badge font (11px Verdana)
+func badgeTextWidth(s string) int {
+	return len([]rune(s))*7 + 10
+}
+
+// Render a shields.io style status badge, e.g. "completion | 30/36"
+func RenderBadge(label, value string, colour Colour) string {
+	var builder strings.Builder
+	canvas := svg.New(&builder)
+
+	labelWidth, valueWidth := badgeTextWidth(label), badgeTextWidth(value)
+	width, height := labelWidth+valueWidth, 20
+	canvas.Start(width, height, fmt.Sprintf(`role="img" aria-label="%s: %s"`, label, value))
+	canvas.Title(label + ": " + value)
+	canvas.Def()
+	canvas.LinearGradient("badgeShine", 0, 0, 0, 100, []svg.Offcolor{
+		{Offset: 0, Color: "rgb(187,187,187)", Opacity: 0.1},
+		{Offset: 100, Color: "rgb(0,0,0)", Opacity: 0.1}})
+	canvas.ClipPath(`id="badgeRound"`)
+	canvas.Roundrect(0, 0, width, height, 3, 3, "fill:white")
+	canvas.ClipEnd()
+	canvas.DefEnd()
+
+	canvas.Group(`clip-path="url(#badgeRound)"`)
+	canvas.Rect(0, 0, labelWidth, height, badgeLabelColour.fill())
+	canvas.Rect(labelWidth, 0, valueWidth, height, colour.fill())
+	canvas.Rect(0, 0, width, height, "fill:url(#badgeShine)")
+	canvas.Gend()
+
+	canvas.Group(`fill="white" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11"`)
+	for _, t := range []struct {
+		x    int
+		text string
+	}{{labelWidth / 2, label}, {labelWidth + valueWidth/2, value}} {
+		canvas.Text(t.x, 15, t.text, `fill="rgb(1,1,1)" fill-opacity=".3"`)
+		canvas.Text(t.x, 14, t.text)
+	}
+	canvas.Gend()
+	canvas.End()
+
+	return builder.String()
+}
@@ -0,0 +1,140 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/utils/logging"
+)
+
+// The dimensions of a social card, as recommended by the common social
+// media sites
+const (
+	CardWidth  = 1200
+	CardHeight = 630
+)
+
+// A result shown on a social card, e.g. the size of a program and the
+// size challenge goal
+type CardResult struct {
+	Label string
+	Value int
+	Goal  int
+	Met   bool
+}
+
+// The text of a social card
+type Card struct {
+	Title    string
+	Subtitle string
+	Results  []CardResult
+}
+
+type cardOptions struct {
+	logger *slog.Logger
+}
+
+// A RenderCard option
+type CardOption func(*cardOptions)
+
+// Log render warnings and timing to logger. By default nothing is logged
+func CardLogger(logger *slog.Logger) CardOption {
+	return func(o *cardOptions) {
+		o.logger = logger
+	}
+}
+
+// Render a social card (CardWidth x CardHeight) showing the program, in the
+// style of RenderSVG, next to the card's title and results
+func RenderCard(card Card, disassembled instructions.Disassembled, comments instructions.Comments, opts ...CardOption) image.Image {
+	img, _ := RenderCardContext(context.Background(), card, disassembled, comments, opts...)
+	return img
+}
+
+// Like RenderCard, but returns the context's error if ctx is cancelled
+func RenderCardContext(ctx context.Context, card Card, disassembled instructions.Disassembled, comments instructions.Comments, opts ...CardOption) (image.Image, error) {
+	var options cardOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	logger := logging.OrDiscard(options.logger)
+	start := time.Now()
+
+	r := newRaster(CardWidth, CardHeight)
+	r.fillRect(0, 0, CardWidth, CardHeight, commentColour.rgba())
+
+	// the program is drawn at the layout's natural scale, cropped to the
+	// height of the card, and then scaled up to fill the left of the card
+	const programScale = 2
+	l := newProgramLayout(disassembled, comments)
+	program := newRaster(l.canvasWidth, CardHeight/programScale)
+	if err := rasterProgram(ctx, program, l, disassembled, comments, logger); err != nil {
+		return nil, err
+	}
+	if l.canvasHeight > CardHeight/programScale {
+		fadeStart := CardHeight / programScale * 4 / 5
+		for y := fadeStart; y < CardHeight/programScale; y++ {
+			alpha := float64(y-fadeStart) / float64(CardHeight/programScale-fadeStart)
+			program.blend(image.Rect(0, y, l.canvasWidth, y+1), canvasColour.rgba(), alpha)
+		}
+	}
+	scaled := program.scale(l.canvasWidth*programScale, CardHeight)
+	draw.Draw(r.RGBA, scaled.Bounds(), scaled, image.Point{}, draw.Src)
+
+	x := l.canvasWidth*programScale + 40
+	y := 50
+	for _, line := range wrapCardText(card.Title, (CardWidth-x-40)/((glyphWidth+1)*6)) {
+		r.text(x, y, 6, textColour.rgba(), line)
+		y += (glyphHeight + 3) * 6
+	}
+	if card.Subtitle != "" {
+		r.text(x, y, 3, lineNoColour.rgba(), card.Subtitle)
+		y += (glyphHeight + 3) * 3
+	}
+	y += 40
+	for _, result := range card.Results {
+		mark, markColour := "-", lineNoColour.rgba()
+		if result.Met {
+			mark, markColour = "✓", ioColour.rgba()
+		}
+		r.fillRoundRect(x, y, 60, 60, 6, markColour)
+		r.centeredText(x+30, y+30, 6, color.White, mark)
+		text := fmt.Sprintf("%s %d", result.Label, result.Value)
+		if result.Goal > 0 {
+			text += fmt.Sprintf(" / %d", result.Goal)
+		}
+		r.leftText(x+80, y+30, 4, textColour.rgba(), text)
+		y += 80
+	}
+
+	logger.Debug("rendered card", "instructions", len(disassembled), "duration", time.Since(start))
+	return r.RGBA, nil
+}
+
+// Split s into lines of at most width characters, breaking at spaces
+func wrapCardText(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
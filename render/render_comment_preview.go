@@ -0,0 +1,162 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+// A style of comment preview, see CommentPreview
+type CommentPreviewStyle string
+
+const (
+	// Plain ASCII, one character per dot
+	CommentPreviewASCII = CommentPreviewStyle("ascii")
+	// Unicode half blocks, two dots per character
+	CommentPreviewBlocks = CommentPreviewStyle("blocks")
+	// Unicode Braille patterns, eight (2x4) dots per character
+	CommentPreviewBraille = CommentPreviewStyle("braille")
+)
+
+// The size of the comment preview in characters. Comments are three times
+// as wide as they are high, and characters are roughly twice as high as
+// they are wide
+const (
+	commentPreviewColumns = 48
+	commentPreviewRows    = 8
+)
+
+// Return the names of the comment preview styles
+func CommentPreviewStyles() []string {
+	return []string{string(CommentPreviewASCII), string(CommentPreviewBlocks), string(CommentPreviewBraille)}
+}
+
+// Report whether style is a known comment preview style
+func (style CommentPreviewStyle) Valid() bool {
+	for _, s := range CommentPreviewStyles() {
+		if string(style) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// The number of dots in each character cell of the style
+func (style CommentPreviewStyle) cell() (int, int) {
+	switch style {
+	case CommentPreviewBlocks:
+		return 1, 2
+	case CommentPreviewBraille:
+		return 2, 4
+	default:
+		return 1, 1
+	}
+}
+
+// The bit of each dot of a Braille pattern cell, indexed by [y][x]
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Rasterize the strokes of a comment into a width x height grid of dots
+func rasterizeComment(comment instructions.Comment, width, height int) [][]bool {
+	grid := make([][]bool, height)
+	for y := range grid {
+		grid[y] = make([]bool, width)
+	}
+	scale := func(p instructions.CommentPoint) (float64, float64) {
+		return float64(p.X) * float64(width-1) / math.MaxUint16, float64(p.Y) * float64(height-1) / math.MaxUint16
+	}
+	plot := func(x, y float64) {
+		ix, iy := int(math.Round(x)), int(math.Round(y))
+		if ix >= 0 && ix < width && iy >= 0 && iy < height {
+			grid[iy][ix] = true
+		}
+	}
+	for _, line := range comment {
+		for i, point := range line {
+			x1, y1 := scale(point)
+			if i == 0 {
+				plot(x1, y1)
+				continue
+			}
+			x0, y0 := scale(line[i-1])
+			steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+			for s := 0; s <= steps; s++ {
+				t := float64(s) / float64(steps)
+				plot(x0+(x1-x0)*t, y0+(y1-y0)*t)
+			}
+		}
+	}
+	return grid
+}
+
+// Render a rough preview of a comment's drawing as lines of text
+func RenderCommentPreview(comment instructions.Comment, style CommentPreviewStyle) []string {
+	cellWidth, cellHeight := style.cell()
+	grid := rasterizeComment(comment, commentPreviewColumns*cellWidth, commentPreviewRows*cellHeight)
+	lines := make([]string, commentPreviewRows)
+	for row := range lines {
+		var builder strings.Builder
+		for column := 0; column < commentPreviewColumns; column++ {
+			x, y := column*cellWidth, row*cellHeight
+			switch style {
+			case CommentPreviewBlocks:
+				top, bottom := grid[y][x], grid[y+1][x]
+				switch {
+				case top && bottom:
+					builder.WriteRune('█')
+				case top:
+					builder.WriteRune('▀')
+				case bottom:
+					builder.WriteRune('▄')
+				default:
+					builder.WriteRune(' ')
+				}
+			case CommentPreviewBraille:
+				pattern := rune(0)
+				for dy := range brailleDots {
+					for dx, dot := range brailleDots[dy] {
+						if grid[y+dy][x+dx] {
+							pattern |= dot
+						}
+					}
+				}
+				if pattern == 0 {
+					builder.WriteRune(' ')
+				} else {
+					builder.WriteRune(0x2800 + pattern)
+				}
+			default:
+				if grid[y][x] {
+					builder.WriteRune('#')
+				} else {
+					builder.WriteRune(' ')
+				}
+			}
+		}
+		lines[row] = strings.TrimRight(builder.String(), " ")
+	}
+	return lines
+}
+
+// Render previews of all comments, as "--" prefixed lines which the game
+// ignores when the text is pasted
+func renderCommentPreviews(comments instructions.Comments, style CommentPreviewStyle) string {
+	var builder strings.Builder
+	for i, comment := range comments {
+		fmt.Fprintf(&builder, "-- COMMENT %d\n", i)
+		fmt.Fprintf(&builder, "-- +%s+\n", strings.Repeat("-", commentPreviewColumns))
+		for _, line := range RenderCommentPreview(comment, style) {
+			padding := commentPreviewColumns - len([]rune(line))
+			fmt.Fprintf(&builder, "-- |%s%s|\n", line, strings.Repeat(" ", padding))
+		}
+		fmt.Fprintf(&builder, "-- +%s+\n", strings.Repeat("-", commentPreviewColumns))
+	}
+	return builder.String()
+}
@@ -0,0 +1,70 @@
+package render
+
+import (
+	"context"
+	"encoding/csv"
+	"strconv"
+	"strings"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+// The header row of RenderCSV
+var csvHeader = []string{"index", "line", "opcode", "mnemonic", "arg", "indirect", "label", "target", "comment"}
+
+// Render a program as CSV, one row per entry of the instruction block: the
+// index of the entry, the line number shown in the game, the binary opcode,
+// the mnemonic (or label or comment), the argument and whether it is
+// indirect, the name of a label, the label targeted by a jump and the
+// index of the drawing of a comment. Empty cells are not applicable
+func RenderCSV(program Program) string {
+	str, _ := RenderCSVContext(context.Background(), program)
+	return str
+}
+
+// Like RenderCSV, but returns the context's error if ctx is cancelled
+func RenderCSVContext(ctx context.Context, program Program) (string, error) {
+	var builder strings.Builder
+	w := csv.NewWriter(&builder)
+	w.Write(csvHeader)
+	for i, item := range program.Disassembled {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		row := make([]string, len(csvHeader))
+		row[0] = strconv.Itoa(i)
+		switch item := item.(type) {
+		case instructions.DisassembleComment:
+			row[3] = "comment"
+			row[8] = strconv.Itoa(int(item.Index))
+		case instructions.DisassembleJumpTarget:
+			row[2] = strconv.Itoa(instructions.OP_JUMP_TGT)
+			row[3] = "label"
+			row[6] = item.Label
+		case instructions.DisassembleJumpInstruction:
+			row[1] = strconv.Itoa(item.LineNumber)
+			row[2] = strconv.Itoa(int(item.Op))
+			row[3] = item.Op.String()
+			row[7] = item.TargetLabel
+		case instructions.DisassembleArgInstruction:
+			row[1] = strconv.Itoa(item.LineNumber)
+			row[2] = strconv.Itoa(int(item.Op))
+			row[3] = item.Op.String()
+			row[4] = strconv.Itoa(int(item.Arg))
+			row[5] = strconv.FormatBool(item.Indirect)
+		case instructions.DisassembleInstruction:
+			row[1] = strconv.Itoa(item.LineNumber)
+			row[2] = strconv.Itoa(int(item.Op))
+			row[3] = item.Op.String()
+		default:
+			// Not disassembled (e.g. an unknown opcode, or a label no jump
+			// targets); the opcode is known if the program was decoded
+			if i < len(program.Instructions) && program.Instructions[i].Comment == 0 {
+				row[2] = strconv.Itoa(int(program.Instructions[i].Op))
+			}
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	return builder.String(), w.Error()
+}
@@ -0,0 +1,38 @@
+package render
+
+import (
+	"image"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+type drawingOptions struct {
+	width int
+}
+
+// A RenderDrawing option
+type DrawingOption func(*drawingOptions)
+
+// Set the width of the image in pixels (default 384). The height is a
+// third of the width, the aspect ratio of comments in the game
+func DrawingWidth(width int) DrawingOption {
+	return func(o *drawingOptions) {
+		o.width = width
+	}
+}
+
+// Render the drawing of a comment on its own, as shown in the game
+func RenderDrawing(comment instructions.Comment, opts ...DrawingOption) image.Image {
+	options := drawingOptions{width: 384}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	width := options.width
+	height := width / 3
+	if height < 1 {
+		height = 1
+	}
+	r := newRaster(width, height)
+	rasterComment(r, 0, 0, width, height, comment)
+	return r.RGBA
+}
@@ -0,0 +1,209 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/utils/logging"
+)
+
+type renderEPSOptions struct {
+	logger *slog.Logger
+}
+
+// A RenderEPS option
+type RenderEPSOption func(*renderEPSOptions)
+
+// Log render warnings and timing to logger. By default nothing is logged
+func EPSLogger(logger *slog.Logger) RenderEPSOption {
+	return func(o *renderEPSOptions) {
+		o.logger = logger
+	}
+}
+
+var lineNumberColumnColour = Colour("rgb(172, 146, 127)")
+
+// Return the colour as a PostScript setrgbcolor operation
+func (c Colour) setrgbcolor() string {
+	rgba := c.rgba()
+	return fmt.Sprintf("%.3f %.3f %.3f setrgbcolor", float64(rgba.R)/255, float64(rgba.G)/255, float64(rgba.B)/255)
+}
+
+// Escape a string for use as a PostScript string literal
+func psString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+	return "(" + r.Replace(s) + ")"
+}
+
+// The prologue defines procedures used by the body. The page is flipped so
+// that the layout can use the same top-left origin as the SVG renderer; text
+// is flipped back when shown.
+const epsPrologue = `/rrect { % x y w h r -> path
+  /r exch def /h exch def /w exch def /y exch def /x exch def
+  newpath
+  x r add y moveto
+  x w add y x w add y h add r arcto 4 {pop} repeat
+  x w add y h add x y h add r arcto 4 {pop} repeat
+  x y h add x y r arcto 4 {pop} repeat
+  x y x w add y r arcto 4 {pop} repeat
+  closepath
+} bind def
+/ctext { % x y size string -> (centered)
+  /s exch def /size exch def
+  gsave translate 1 -1 scale
+  /Helvetica-Bold findfont size scalefont setfont
+  s stringwidth pop 2 div neg size 0.35 mul neg moveto s show
+  grestore
+} bind def
+/ltext { % x y size string -> (left aligned)
+  /s exch def /size exch def
+  gsave translate 1 -1 scale
+  /Helvetica-Bold findfont size scalefont setfont
+  0 size 0.35 mul neg moveto s show
+  grestore
+} bind def
+`
+
+func epsBox(w io.Writer, x, y, width, height int, colour Colour) {
+	fmt.Fprintf(w, "%s %d %d %d %d 2 rrect fill\n", colour.setrgbcolor(), x, y, width, height)
+}
+
+func epsCenteredText(w io.Writer, x, y, size int, colour Colour, s string) {
+	fmt.Fprintf(w, "%s %d %d %d %s ctext\n", colour.setrgbcolor(), x, y, size, psString(s))
+}
+
+func epsText(w io.Writer, x, y, size int, colour Colour, s string) {
+	fmt.Fprintf(w, "%s %d %d %d %s ltext\n", colour.setrgbcolor(), x, y, size, psString(s))
+}
+
+func epsComment(w io.Writer, x, y, width, height int, comment instructions.Comment) {
+	epsBox(w, x, y, width, height, commentColour)
+	fmt.Fprintf(w, "gsave %d %d %d %d 2 rrect clip\n", x, y, width, height)
+	fmt.Fprintf(w, "0 setgray 3 setlinewidth 1 setlinecap 1 setlinejoin\n")
+	scaleX := (float64(width) / math.MaxUint16)
+	scaleY := (float64(height) / math.MaxUint16)
+	for _, line := range comment {
+		if len(line) == 0 {
+			continue
+		}
+		px := func(p instructions.CommentPoint) float64 { return float64(x) + float64(p.X)*scaleX }
+		py := func(p instructions.CommentPoint) float64 { return float64(y) + float64(p.Y)*scaleY }
+		if len(line) == 1 {
+			fmt.Fprintf(w, "newpath %.2f %.2f 2 0 360 arc fill\n", px(line[0]), py(line[0]))
+			continue
+		}
+		fmt.Fprintf(w, "newpath %.2f %.2f moveto\n", px(line[0]), py(line[0]))
+		for _, point := range line[1:] {
+			fmt.Fprintf(w, "%.2f %.2f lineto\n", px(point), py(point))
+		}
+		fmt.Fprintf(w, "stroke\n")
+	}
+	fmt.Fprintf(w, "grestore\n")
+}
+
+// Render a sequence of disassembled instructions and comments into an
+// Encapsulated PostScript figure, using the same layout as RenderSVG.
+func RenderEPS(disassembled instructions.Disassembled, comments instructions.Comments, opts ...RenderEPSOption) string {
+	str, _ := RenderEPSContext(context.Background(), disassembled, comments, opts...)
+	return str
+}
+
+// Like RenderEPS, but returns the context's error if ctx is cancelled
+func RenderEPSContext(ctx context.Context, disassembled instructions.Disassembled, comments instructions.Comments, opts ...RenderEPSOption) (string, error) {
+	var options renderEPSOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	logger := logging.OrDiscard(options.logger)
+	start := time.Now()
+
+	var builder strings.Builder
+	l := newProgramLayout(disassembled, comments)
+
+	fmt.Fprintf(&builder, "%%!PS-Adobe-3.0 EPSF-3.0\n")
+	fmt.Fprintf(&builder, "%%%%BoundingBox: 0 0 %d %d\n", l.canvasWidth, l.canvasHeight)
+	fmt.Fprintf(&builder, "%%%%Creator: hrm-profile-tool\n")
+	fmt.Fprintf(&builder, "%%%%EndComments\n")
+	builder.WriteString(epsPrologue)
+	fmt.Fprintf(&builder, "gsave\n0 %d translate 1 -1 scale\n", l.canvasHeight)
+
+	fmt.Fprintf(&builder, "%s 0 0 %d %d rectfill\n", canvasColour.setrgbcolor(), l.canvasWidth, l.canvasHeight)
+	fmt.Fprintf(&builder, "%s 0 0 %d %d rectfill\n", lineNumberColumnColour.setrgbcolor(), l.lineNumberColumnWidth, l.canvasHeight)
+
+	// draw jump lines first, which should be under instructions
+	for i, diss := range disassembled {
+		switch diss := diss.(type) {
+		case instructions.DisassembleJumpInstruction:
+			arc, ok := l.jumpArc(i, diss)
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(&builder, "%s 3 setlinewidth\n", jumpColour.setrgbcolor())
+			fmt.Fprintf(
+				&builder, "newpath %d %d moveto %d %d %d %d %d %d curveto stroke\n",
+				arc.sx, arc.sy, arc.cx, arc.cy, arc.px, arc.py, arc.ex, arc.ey)
+			fmt.Fprintf(
+				&builder, "newpath %d %d moveto %d %d lineto %d %d lineto closepath fill\n",
+				arc.ex-2, arc.ey-3, arc.ex-2, arc.ey+3, arc.ex-11, arc.ey)
+		}
+	}
+	// draw instructions
+	for i, diss := range disassembled {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		instX, instY := l.instX(), l.instY(i)
+		lineNumberX, lineNumberY := l.lineNumberColumnWidth/2, instY+l.instHeight/2
+		switch diss := diss.(type) {
+		case instructions.DisassembleComment:
+			if int(diss.Index) >= len(comments) {
+				logger.Warn("comment not defined", "comment", diss.Index, "defined", len(comments))
+				epsBox(&builder, instX, instY, l.commentWidth, l.commentHeight, commentColour)
+				continue
+			}
+			epsComment(&builder, instX, instY, l.commentWidth, l.commentHeight, comments[diss.Index])
+		case instructions.DisassembleJumpTarget:
+			epsBox(&builder, instX, instY, l.targetLabelWidth, l.instHeight, jumpColour)
+		case instructions.DisassembleJumpInstruction:
+			mnemonic := svgInstrunctionMnemonics[diss.Op]
+			epsCenteredText(&builder, lineNumberX, lineNumberY, 16, lineNoColour, fmt.Sprintf("%02d", diss.Line()))
+			epsBox(&builder, instX, instY, l.mnemonicWidth(diss.Op), l.instHeight, mnemonic.Colour)
+			if condition := svgJumpConditions[diss.Op]; condition != "" {
+				epsText(&builder, instX+15, instY+l.instHeight/2, 16, textColour, mnemonic.Mnemonic)
+				epsText(&builder, instX+15+45, instY+l.instHeight/3, 10, textColour, "if")
+				epsText(&builder, instX+15+45, instY+(l.instHeight/3)*2, 10, textColour, condition)
+			} else {
+				epsCenteredText(&builder, instX+l.mnemonicWidth(diss.Op)/2, instY+l.instHeight/2, 16, textColour, mnemonic.Mnemonic)
+			}
+		case instructions.DisassembleArgInstruction:
+			mnemonic := svgInstrunctionMnemonics[diss.Op]
+			epsCenteredText(&builder, lineNumberX, lineNumberY, 16, lineNoColour, fmt.Sprintf("%02d", diss.Line()))
+			epsBox(&builder, instX, instY, l.mnemonicWidth(diss.Op), l.instHeight, mnemonic.Colour)
+			epsCenteredText(&builder, instX+l.mnemonicWidth(diss.Op)/2, instY+l.instHeight/2, 16, textColour, mnemonic.Mnemonic)
+			argX := l.argumentX(diss.Op)
+			epsBox(&builder, argX, instY, l.argumentWidth, l.instHeight, mnemonic.Colour)
+			var strArg string
+			if diss.Indirect {
+				strArg = fmt.Sprintf("[%d]", diss.Arg)
+			} else {
+				strArg = fmt.Sprintf("%d", diss.Arg)
+			}
+			epsCenteredText(&builder, argX+l.argumentWidth/2, instY+l.instHeight/2, 22, textColour, strArg)
+		case instructions.DisassembleInstruction:
+			mnemonic := svgInstrunctionMnemonics[diss.Op]
+			epsCenteredText(&builder, lineNumberX, lineNumberY, 16, lineNoColour, fmt.Sprintf("%02d", diss.Line()))
+			epsBox(&builder, instX, instY, l.mnemonicWidth(diss.Op), l.instHeight, mnemonic.Colour)
+			epsCenteredText(&builder, instX+l.mnemonicWidth(diss.Op)/2, instY+l.instHeight/2, 16, textColour, mnemonic.Mnemonic)
+		}
+	}
+	fmt.Fprintf(&builder, "grestore\nshowpage\n%%%%EOF\n")
+	logger.Debug("rendered eps", "instructions", len(disassembled), "bytes", builder.Len(), "duration", time.Since(start))
+
+	return builder.String(), nil
+}
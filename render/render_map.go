@@ -0,0 +1,148 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	svg "github.com/ajstarks/svgo"
+)
+
+// A node of a progression map, e.g. a floor of the game
+type MapNode struct {
+	ID     int
+	Label  string
+	Solved bool
+	// The IDs of the nodes unlocked by this node
+	Next []int
+}
+
+var (
+	mapSolvedColour   = ioColour
+	mapUnsolvedColour = commentColour
+)
+
+// Render a progression map in the Graphviz DOT language
+func RenderMapDOT(nodes []MapNode) string {
+	var builder strings.Builder
+	builder.WriteString("digraph progression {\n")
+	builder.WriteString("\trankdir=BT;\n")
+	builder.WriteString("\tnode [shape=box, style=\"rounded,filled\", fontname=\"Arial Black\"];\n")
+	for _, node := range nodes {
+		colour := mapUnsolvedColour
+		if node.Solved {
+			colour = mapSolvedColour
+		}
+		rgba := colour.rgba()
+		fmt.Fprintf(
+			&builder, "\tn%d [label=%q, fillcolor=\"#%02x%02x%02x\"];\n",
+			node.ID, node.Label, rgba.R, rgba.G, rgba.B)
+	}
+	for _, node := range nodes {
+		for _, next := range node.Next {
+			fmt.Fprintf(&builder, "\tn%d -> n%d;\n", node.ID, next)
+		}
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// Assign each node a rank, the length of the longest path to it from a node
+// which is not unlocked by another node
+func mapRanks(nodes []MapNode) map[int]int {
+	byID := make(map[int]MapNode)
+	incoming := make(map[int]int)
+	for _, node := range nodes {
+		byID[node.ID] = node
+		for _, next := range node.Next {
+			incoming[next]++
+		}
+	}
+	ranks := make(map[int]int)
+	var queue []int
+	for _, node := range nodes {
+		if incoming[node.ID] == 0 {
+			queue = append(queue, node.ID)
+		}
+	}
+	// Kahn's algorithm, nodes on cycles are left at rank 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range byID[id].Next {
+			if ranks[id]+1 > ranks[next] {
+				ranks[next] = ranks[id] + 1
+			}
+			if incoming[next]--; incoming[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+	return ranks
+}
+
+// Render a progression map as an SVG. The map is drawn as a tower, with
+// the first nodes at the bottom and the nodes they unlock above them
+func RenderMapSVG(nodes []MapNode) string {
+	const nodeWidth, nodeHeight, xStep, yStep, margin = 220, 26, 240, 40, 10
+
+	ranks := mapRanks(nodes)
+	columns := make(map[int][]int)
+	maxRank, maxColumns := 0, 0
+	for _, node := range nodes {
+		rank := ranks[node.ID]
+		columns[rank] = append(columns[rank], node.ID)
+		if rank > maxRank {
+			maxRank = rank
+		}
+		if len(columns[rank]) > maxColumns {
+			maxColumns = len(columns[rank])
+		}
+	}
+	width := maxColumns*xStep - (xStep - nodeWidth) + margin*2
+	height := (maxRank+1)*yStep - (yStep - nodeHeight) + margin*2
+
+	type position struct{ x, y int }
+	positions := make(map[int]position)
+	for rank, ids := range columns {
+		sort.Ints(ids)
+		for column, id := range ids {
+			positions[id] = position{margin + column*xStep, height - margin - nodeHeight - rank*yStep}
+		}
+	}
+
+	var builder strings.Builder
+	canvas := svg.New(&builder)
+	canvas.Start(width, height)
+	canvas.Def()
+	canvas.Marker("mapArrow", 9, 3, 10, 6, `orient="auto"`)
+	canvas.Path("M0 0 10 3 0 6z", lineNoColour.fill())
+	canvas.MarkerEnd()
+	canvas.DefEnd()
+	canvas.Rect(0, 0, width, height, canvasColour.fill())
+	for _, node := range nodes {
+		from := positions[node.ID]
+		for _, next := range node.Next {
+			to, found := positions[next]
+			if !found {
+				continue
+			}
+			canvas.Line(
+				from.x+nodeWidth/2, from.y, to.x+nodeWidth/2, to.y+nodeHeight,
+				`stroke="`+string(lineNoColour)+`" stroke-width="2" marker-end="url(#mapArrow)"`)
+		}
+	}
+	for _, node := range nodes {
+		p := positions[node.ID]
+		colour := mapUnsolvedColour
+		if node.Solved {
+			colour = mapSolvedColour
+		}
+		canvas.Roundrect(p.x, p.y, nodeWidth, nodeHeight, 2, 2, colour.fill())
+		canvas.Text(
+			p.x+nodeWidth/2, p.y+nodeHeight/2, node.Label, instTextStyle.Render("12px"),
+			`alignment-baseline="central" text-anchor="middle"`)
+	}
+	canvas.End()
+	return builder.String()
+}
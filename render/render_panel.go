@@ -0,0 +1,246 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/utils/logging"
+)
+
+var (
+	woodColour      = Colour("rgb(133, 94, 62)")
+	tabButtonColour = Colour("rgb(150, 122, 101)")
+	handColour      = Colour("rgb(238, 196, 158)")
+)
+
+// The geometry of the panel around the program
+const (
+	panelMargin       = 16
+	panelTabWidth     = 60
+	panelTabHeight    = 28
+	panelHintHeight   = 56
+	panelShadowHeight = 14
+	woodPlankHeight   = 48
+)
+
+type panelOptions struct {
+	logger *slog.Logger
+	height int
+	tab    int
+	scroll int
+	scale  int
+	hint   string
+}
+
+// A RenderPanel option
+type PanelOption func(*panelOptions)
+
+// Log render warnings and timing to logger. By default nothing is logged
+func PanelLogger(logger *slog.Logger) PanelOption {
+	return func(o *panelOptions) {
+		o.logger = logger
+	}
+}
+
+// Set the height of the panel in pixels, before scaling (default 600)
+func PanelHeight(height int) PanelOption {
+	return func(o *panelOptions) {
+		o.height = height
+	}
+}
+
+// Show tab number (starting at 1) as the selected tab (default 1)
+func PanelTab(tab int) PanelOption {
+	return func(o *panelOptions) {
+		o.tab = tab
+	}
+}
+
+// Scroll the program so that the instruction with line number line is at
+// the top, as if scrolled to in the game. By default the program is shown
+// from the start
+func PanelScroll(line int) PanelOption {
+	return func(o *panelOptions) {
+		o.scroll = line
+	}
+}
+
+// Scale the panel up by factor, e.g. 2 for the resolution of a screenshot
+// on a high density display (default 1)
+func PanelScale(factor int) PanelOption {
+	return func(o *panelOptions) {
+		o.scale = factor
+	}
+}
+
+// Show text next to the hand in the hint area below the program
+func PanelHint(text string) PanelOption {
+	return func(o *panelOptions) {
+		o.hint = text
+	}
+}
+
+// Return the colour of the wooden background at x, y: planks with a grain
+// running along them, each plank shaded slightly differently
+func woodPixel(x, y int) color.RGBA {
+	plank := y / woodPlankHeight
+	if y%woodPlankHeight == 0 {
+		return shade(woodColour.rgba(), 0.7)
+	}
+	phase := float64(plank * 7919 % 97)
+	fx, fy := float64(x), float64(y%woodPlankHeight)
+	grain := math.Sin((fy + 4*math.Sin(fx/53+phase) + 1.5*math.Sin(fx/13+phase*2)) * 0.8)
+	return shade(woodColour.rgba(), 1+0.06*grain+0.05*math.Sin(phase))
+}
+
+// Return c with its channels multiplied by factor
+func shade(c color.RGBA, factor float64) color.RGBA {
+	channel := func(v uint8) uint8 {
+		return uint8(math.Max(0, math.Min(255, float64(v)*factor)))
+	}
+	return color.RGBA{channel(c.R), channel(c.G), channel(c.B), c.A}
+}
+
+// Draw the worker's hand, pointing up, with the palm centered on x, y
+func rasterHand(r raster, x, y int) {
+	c := handColour.rgba()
+	outline := shade(c, 0.7)
+	for i, length := range []int{14, 18, 17, 13} {
+		fx := x - 11 + i*6
+		r.fillRoundRect(fx-1, y-6-length-1, 7, length+8, 3, outline)
+		r.fillRoundRect(fx, y-6-length, 5, length+6, 2, c)
+	}
+	r.fillRoundRect(x-13, y-7, 27, 21, 6, outline)
+	r.fillRoundRect(x-12, y-6, 25, 19, 5, c)
+	r.fillRoundRect(x+11, y-3, 11, 7, 3, outline)
+	r.fillRoundRect(x+11, y-2, 10, 5, 2, c)
+}
+
+// Render a program as it appears in the right hand panel of the game: on
+// a wooden background, below the tab buttons and above the hint area with
+// the worker's hand, with shadows where the program scrolls out of view.
+// The program is drawn in the style of RenderSVG
+func RenderPanel(disassembled instructions.Disassembled, comments instructions.Comments, opts ...PanelOption) image.Image {
+	img, _ := RenderPanelContext(context.Background(), disassembled, comments, opts...)
+	return img
+}
+
+// Like RenderPanel, but returns the context's error if ctx is cancelled
+func RenderPanelContext(ctx context.Context, disassembled instructions.Disassembled, comments instructions.Comments, opts ...PanelOption) (image.Image, error) {
+	options := panelOptions{height: 600, tab: 1, scale: 1}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	logger := logging.OrDiscard(options.logger)
+	start := time.Now()
+
+	l := newProgramLayout(disassembled, comments)
+	width := l.canvasWidth + panelMargin*2
+	areaX, areaY := panelMargin, panelMargin+panelTabHeight
+	areaHeight := options.height - areaY - panelHintHeight - panelMargin
+	if areaHeight < l.instYStep {
+		return nil, fmt.Errorf("panel height %d leaves no room for the program", options.height)
+	}
+	r := newRaster(width, options.height)
+	for y := 0; y < options.height; y++ {
+		for x := 0; x < width; x++ {
+			r.SetRGBA(x, y, woodPixel(x, y))
+		}
+	}
+
+	// The tab buttons, the selected one joining the program area
+	for tab := 1; tab <= 3; tab++ {
+		x := areaX + 4 + (tab-1)*(panelTabWidth+6)
+		c, y, h := tabButtonColour.rgba(), panelMargin+4, panelTabHeight-4
+		if tab == options.tab {
+			c, y, h = canvasColour.rgba(), panelMargin, panelTabHeight+4
+		}
+		r.fillRoundRect(x-1, y-1, panelTabWidth+2, h+2, 5, shade(woodColour.rgba(), 0.6))
+		r.fillRoundRect(x, y, panelTabWidth, h, 4, c)
+		r.centeredText(x+panelTabWidth/2, panelMargin+panelTabHeight/2+1, 2, lineNoColour.rgba(), fmt.Sprint(tab))
+	}
+
+	// The program, scrolled to the requested line
+	scrollY := 0
+	if options.scroll > 0 {
+		if start, end := disassembled.LineRange(options.scroll, 0); start < end {
+			scrollY = l.instY(start) - (l.instYStep - l.instHeight)
+		}
+	}
+	if scrollY > l.canvasHeight-areaHeight {
+		scrollY = l.canvasHeight - areaHeight
+	}
+	if scrollY < 0 {
+		scrollY = 0
+	}
+	program := newRaster(l.canvasWidth, l.canvasHeight)
+	if err := rasterProgram(ctx, program, l, disassembled, comments, logger); err != nil {
+		return nil, err
+	}
+	r.fillRoundRect(areaX-2, areaY-2, l.canvasWidth+4, areaHeight+4, 6, shade(woodColour.rgba(), 0.6))
+	area := newRaster(l.canvasWidth, areaHeight)
+	area.fillRect(0, 0, l.canvasWidth, areaHeight, canvasColour.rgba())
+	draw.Draw(area.RGBA, area.Bounds(), program.RGBA, image.Pt(0, scrollY), draw.Src)
+
+	// Shadows where the program continues out of view, and a scroll bar
+	black := color.RGBA{0, 0, 0, 0xff}
+	for i := 0; i < panelShadowHeight; i++ {
+		alpha := 0.35 * float64(panelShadowHeight-i) / panelShadowHeight
+		if scrollY > 0 {
+			area.blend(image.Rect(0, i, l.canvasWidth, i+1), black, alpha)
+		}
+		if scrollY+areaHeight < l.canvasHeight {
+			area.blend(image.Rect(0, areaHeight-1-i, l.canvasWidth, areaHeight-i), black, alpha)
+		}
+	}
+	if l.canvasHeight > areaHeight {
+		thumbHeight := areaHeight * areaHeight / l.canvasHeight
+		if thumbHeight < 20 {
+			thumbHeight = 20
+		}
+		thumbY := scrollY * (areaHeight - thumbHeight) / (l.canvasHeight - areaHeight)
+		area.fillRoundRect(l.canvasWidth-8, 2, 6, areaHeight-4, 3, shade(canvasColour.rgba(), 0.85))
+		area.fillRoundRect(l.canvasWidth-8, thumbY+2, 6, thumbHeight-4, 3, lineNoColour.rgba())
+	}
+	for y := 0; y < areaHeight; y++ {
+		for x := 0; x < l.canvasWidth; x++ {
+			// Round the corners of the program area
+			dx, dy := 0, 0
+			if x < 4 {
+				dx = 4 - x
+			} else if x >= l.canvasWidth-4 {
+				dx = x - (l.canvasWidth - 5)
+			}
+			if y < 4 {
+				dy = 4 - y
+			} else if y >= areaHeight-4 {
+				dy = y - (areaHeight - 5)
+			}
+			if dx*dx+dy*dy <= 16 {
+				r.SetRGBA(areaX+x, areaY+y, area.RGBAAt(x, y))
+			}
+		}
+	}
+
+	// The hint area, with the worker's hand
+	hintY := options.height - panelMargin - panelHintHeight + 8
+	r.fillRoundRect(areaX, hintY, l.canvasWidth, panelHintHeight-8, 6, shade(woodColour.rgba(), 0.75))
+	rasterHand(r, areaX+30, hintY+(panelHintHeight-8)/2+6)
+	if options.hint != "" {
+		r.leftText(areaX+62, hintY+(panelHintHeight-8)/2, 2, commentColour.rgba(), options.hint)
+	}
+
+	img := r.RGBA
+	if options.scale > 1 {
+		img = r.scale(width*options.scale, options.height*options.scale)
+	}
+	logger.Debug("rendered panel", "instructions", len(disassembled), "scroll", scrollY, "duration", time.Since(start))
+	return img, nil
+}
@@ -0,0 +1,105 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"log/slog"
+	"math"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+// Draw a program onto r using the same layout as RenderSVG. Instructions
+// starting below the bottom of r are not drawn
+func rasterProgram(ctx context.Context, r raster, l programLayout, disassembled instructions.Disassembled, comments instructions.Comments, logger *slog.Logger) error {
+	height := r.Bounds().Dy()
+	r.fillRect(0, 0, r.Bounds().Dx(), height, canvasColour.rgba())
+	r.fillRect(0, 0, l.lineNumberColumnWidth, height, lineNumberColumnColour.rgba())
+
+	// draw jump lines first, which should be under instructions
+	for i, diss := range disassembled {
+		switch diss := diss.(type) {
+		case instructions.DisassembleJumpInstruction:
+			arc, ok := l.jumpArc(i, diss)
+			if !ok {
+				continue
+			}
+			r.bezier(arc, 3, jumpColour.rgba())
+			for d := 0; d < 9; d++ {
+				r.fillRect(arc.ex-11+d, arc.ey-d/3, 1, 2*(d/3)+1, jumpColour.rgba())
+			}
+		}
+	}
+	// draw instructions
+	for i, diss := range disassembled {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		instX, instY := l.instX(), l.instY(i)
+		if instY > height {
+			break
+		}
+		lineNumberX, lineNumberY := l.lineNumberColumnWidth/2, instY+l.instHeight/2
+		switch diss := diss.(type) {
+		case instructions.DisassembleComment:
+			if int(diss.Index) >= len(comments) {
+				logger.Warn("comment not defined", "comment", diss.Index, "defined", len(comments))
+				r.fillRoundRect(instX, instY, l.commentWidth, l.commentHeight, 2, commentColour.rgba())
+				continue
+			}
+			rasterComment(r, instX, instY, l.commentWidth, l.commentHeight, comments[diss.Index])
+		case instructions.DisassembleJumpTarget:
+			r.fillRoundRect(instX, instY, l.targetLabelWidth, l.instHeight, 2, jumpColour.rgba())
+		case instructions.DisassembleJumpInstruction:
+			mnemonic := svgInstrunctionMnemonics[diss.Op]
+			r.centeredText(lineNumberX, lineNumberY, 2, lineNoColour.rgba(), fmt.Sprintf("%02d", diss.Line()))
+			r.fillRoundRect(instX, instY, l.mnemonicWidth(diss.Op), l.instHeight, 2, mnemonic.Colour.rgba())
+			if condition := svgJumpConditions[diss.Op]; condition != "" {
+				r.leftText(instX+8, instY+l.instHeight/2, 2, textColour.rgba(), mnemonic.Mnemonic)
+				r.leftText(instX+8+50, instY+l.instHeight/3, 1, textColour.rgba(), "if")
+				r.leftText(instX+8+50, instY+(l.instHeight/3)*2, 1, textColour.rgba(), condition)
+			} else {
+				r.centeredText(instX+l.mnemonicWidth(diss.Op)/2, instY+l.instHeight/2, 2, textColour.rgba(), mnemonic.Mnemonic)
+			}
+		case instructions.DisassembleArgInstruction:
+			mnemonic := svgInstrunctionMnemonics[diss.Op]
+			r.centeredText(lineNumberX, lineNumberY, 2, lineNoColour.rgba(), fmt.Sprintf("%02d", diss.Line()))
+			r.fillRoundRect(instX, instY, l.mnemonicWidth(diss.Op), l.instHeight, 2, mnemonic.Colour.rgba())
+			r.centeredText(instX+l.mnemonicWidth(diss.Op)/2, instY+l.instHeight/2, 2, textColour.rgba(), mnemonic.Mnemonic)
+			argX := l.argumentX(diss.Op)
+			r.fillRoundRect(argX, instY, l.argumentWidth, l.instHeight, 2, mnemonic.Colour.rgba())
+			var strArg string
+			if diss.Indirect {
+				strArg = fmt.Sprintf("[%d]", diss.Arg)
+			} else {
+				strArg = fmt.Sprintf("%d", diss.Arg)
+			}
+			r.centeredText(argX+l.argumentWidth/2, instY+l.instHeight/2, 2, textColour.rgba(), strArg)
+		case instructions.DisassembleInstruction:
+			mnemonic := svgInstrunctionMnemonics[diss.Op]
+			r.centeredText(lineNumberX, lineNumberY, 2, lineNoColour.rgba(), fmt.Sprintf("%02d", diss.Line()))
+			r.fillRoundRect(instX, instY, l.mnemonicWidth(diss.Op), l.instHeight, 2, mnemonic.Colour.rgba())
+			r.centeredText(instX+l.mnemonicWidth(diss.Op)/2, instY+l.instHeight/2, 2, textColour.rgba(), mnemonic.Mnemonic)
+		}
+	}
+	return nil
+}
+
+func rasterComment(r raster, x, y, w, h int, comment instructions.Comment) {
+	r.fillRoundRect(x, y, w, h, 2, commentColour.rgba())
+	clip := image.Rect(x, y, x+w, y+h)
+	scaleX := (float64(w) / math.MaxUint16)
+	scaleY := (float64(h) / math.MaxUint16)
+	px := func(p instructions.CommentPoint) float64 { return float64(x) + float64(p.X)*scaleX }
+	py := func(p instructions.CommentPoint) float64 { return float64(y) + float64(p.Y)*scaleY }
+	for _, line := range comment {
+		if len(line) == 1 {
+			r.line(px(line[0]), py(line[0]), px(line[0]), py(line[0]), 4, color.Black, clip)
+		}
+		for i := 1; i < len(line); i++ {
+			r.line(px(line[i-1]), py(line[i-1]), px(line[i]), py(line[i]), 3, color.Black, clip)
+		}
+	}
+}
@@ -1,16 +1,273 @@
 package render
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	svg "github.com/ajstarks/svgo"
 	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/utils/logging"
 )
 
+type renderSVGOptions struct {
+	logger    *slog.Logger
+	font      Font
+	embedFont bool
+	mnemonics *instructions.Mnemonics
+	labels    map[instructions.OpCode]string
+	// conditions of conditional jumps, e.g. "if zero"
+	conditions map[instructions.OpCode]string
+	// crop comments to the bounding box of their strokes
+	cropComments bool
+	cropPadding  int
+	// give the jump arcs distinct extents, and label them
+	routeJumps bool
+	labelJumps bool
+	// render only the lines fromLine to toLine
+	excerpt          bool
+	fromLine, toLine int
+	quality          SVGQuality
+}
+
+// A RenderSVG option
+type RenderSVGOption func(*renderSVGOptions)
+
+// Log render warnings and timing to logger. By default nothing is logged
+func SVGLogger(logger *slog.Logger) RenderSVGOption {
+	return func(o *renderSVGOptions) {
+		o.logger = logger
+	}
+}
+
+// Render text in font (DefaultFont by default). The instruction boxes are
+// widened if required to fit the text
+func SVGFont(font Font) RenderSVGOption {
+	return func(o *renderSVGOptions) {
+		o.font = font
+	}
+}
+
+// Embed the font file in the SVG using @font-face, so it renders the same
+// on systems without the font installed. The font must be loaded from a
+// file (see LoadFont)
+func SVGEmbedFont() RenderSVGOption {
+	return func(o *renderSVGOptions) {
+		o.embedFont = true
+	}
+}
+
+// Label instructions using mnemonics instead of the game's mnemonics. Jump
+// conditions are part of the mnemonic, the instruction boxes are widened to
+// fit
+func SVGMnemonicSet(mnemonics *instructions.Mnemonics) RenderSVGOption {
+	return func(o *renderSVGOptions) {
+		o.mnemonics = mnemonics
+	}
+}
+
+// Label instructions with translated text, e.g. "entrada" for INBOX.
+// Conditions replace the conditions of conditional jumps, the first word
+// ("if") is drawn above the rest ("zero"). Opcodes missing from labels or
+// conditions keep the game's text, the instruction boxes are widened to fit
+func SVGLabels(labels, conditions map[instructions.OpCode]string) RenderSVGOption {
+	return func(o *renderSVGOptions) {
+		o.labels = labels
+		o.conditions = conditions
+	}
+}
+
+// Fit the bounding box of the strokes of each comment into the comment
+// box, leaving padding pixels around it, instead of scaling the whole
+// comment canvas into the box. Small drawings are enlarged to fill the
+// box, as the game displays them. The proportions of the drawing are kept
+func SVGCropComments(padding int) RenderSVGOption {
+	return func(o *renderSVGOptions) {
+		o.cropComments = true
+		o.cropPadding = padding
+	}
+}
+
+// Route the jump arcs so that they do not overlap, nesting them by the
+// lines they span as the game does. By default every arc reaches the edge
+// of the canvas
+func SVGRouteJumps() RenderSVGOption {
+	return func(o *renderSVGOptions) {
+		o.routeJumps = true
+	}
+}
+
+// Label each jump arc, and the jump target it points to, with the letter
+// of the target (as in the text format, e.g. "a")
+func SVGLabelJumps() RenderSVGOption {
+	return func(o *renderSVGOptions) {
+		o.labelJumps = true
+	}
+}
+
+// The quality of an SVG render, i.e. which filters are applied, see
+// SVGRenderQuality
+type SVGQuality string
+
+const (
+	// No filters, for the smallest files which render the fastest (e.g.
+	// for batch exports)
+	SVGQualityFlat = SVGQuality("flat")
+	// The drop shadows of the game
+	SVGQualitySoft = SVGQuality("soft")
+	// Deeper, softer shadows and a paper texture (e.g. for posters)
+	SVGQualityFull = SVGQuality("full")
+)
+
+// Return the names of the SVG qualities
+func SVGQualities() []string {
+	return []string{string(SVGQualityFlat), string(SVGQualitySoft), string(SVGQualityFull)}
+}
+
+// Report whether q is a known SVG quality
+func (q SVGQuality) Valid() bool {
+	for _, name := range SVGQualities() {
+		if string(q) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Render with the filters of quality. By default SVGQualitySoft is used
+func SVGRenderQuality(quality SVGQuality) RenderSVGOption {
+	return func(o *renderSVGOptions) {
+		o.quality = quality
+	}
+}
+
+// Return the filter attribute of the elements casting a shadow, none if
+// the render has no filters
+func (o renderSVGOptions) shadow() string {
+	if o.quality == SVGQualityFlat {
+		return ""
+	}
+	return `filter="url(#dropShadow)"`
+}
+
+// Define the filters of the quality of the render
+func (o renderSVGOptions) defineFilters(canvas *svg.SVG) {
+	if o.quality == SVGQualityFlat {
+		return
+	}
+	offset, opacity, blur := 1, 0.5, 1.0
+	if o.quality == SVGQualityFull {
+		offset, opacity, blur = 2, 0.6, 2
+	}
+	canvas.Filter("dropShadow", `width="200%" height="200%"`)
+	canvas.FeOffset(svg.Filterspec{In: "SourceAlpha", Result: "offOut"}, offset, offset)
+	canvas.FeColorMatrix(
+		svg.Filterspec{In: "offOut", Result: "matrixOut"},
+		[...]float64{0.2, 0, 0, 0, 0, 0, 0.2, 0, 0, 0, 0, 0, 0.2, 0, 0, 0, 0, 0, opacity, 0}, `mode="normal"`)
+	canvas.FeGaussianBlur(svg.Filterspec{In: "matrixOut", Result: "blurOut"}, blur, blur)
+	canvas.FeBlend(svg.Filterspec{In: "SourceGraphic", In2: "blurOut"}, `mode="normal"`)
+	canvas.Fend()
+	if o.quality != SVGQualityFull {
+		return
+	}
+	// Light a noise surface from the top left and multiply the canvas by
+	// it, as the game's paper texture
+	canvas.Filter("paper", `x="0" y="0" width="100%" height="100%"`)
+	canvas.FeTurbulence(svg.Filterspec{Result: "noise"}, "fractalNoise", 0.04, 0.04, 5, 0, false)
+	canvas.FeDiffuseLighting(svg.Filterspec{In: "noise", Result: "light"}, 2, 1, `lighting-color="white"`)
+	canvas.FeDistantLight(svg.Filterspec{}, 45, 60)
+	canvas.FeDiffEnd()
+	canvas.FeComposite(svg.Filterspec{In: "light", In2: "SourceGraphic"}, "arithmetic", 1, 0, 0, 0)
+	canvas.Fend()
+}
+
+// Return the filter attribute of the canvas background, the paper
+// texture of SVGQualityFull
+func (o renderSVGOptions) paper() string {
+	if o.quality == SVGQualityFull {
+		return `filter="url(#paper)"`
+	}
+	return ""
+}
+
+// Render only the instructions with the line numbers from to to
+// (inclusive, a to of 0 is the last line), with the comments and jump
+// targets preceding them. Jump arcs leaving the excerpt are dashed and run
+// off the top or bottom of the canvas, labelled with their target
+func SVGLines(from, to int) RenderSVGOption {
+	return func(o *renderSVGOptions) {
+		o.excerpt = true
+		o.fromLine, o.toLine = from, to
+	}
+}
+
+// Return the instructions of disassembled with the line numbers from to
+// to (see instructions.Disassembled.LineRange), with jump targets indexed
+// from the start of the excerpt. Jumps leaving the excerpt have no
+// target, the direction they leave in (-1 up, 1 down) is returned keyed
+// by their index in the excerpt
+func excerpt(disassembled instructions.Disassembled, from, to int) (instructions.Disassembled, map[int]int) {
+	start, end := disassembled.LineRange(from, to)
+	lines := make(instructions.Disassembled, end-start)
+	leaving := make(map[int]int)
+	for i, diss := range disassembled[start:end] {
+		switch d := diss.(type) {
+		case instructions.DisassembleJumpInstruction:
+			switch {
+			case d.Target < 0:
+			case d.Target < start:
+				leaving[i], d.Target = -1, -1
+			case d.Target >= end:
+				leaving[i], d.Target = 1, -1
+			default:
+				d.Target -= start
+			}
+			diss = d
+		case instructions.DisassembleJumpTarget:
+			if d.Jumpee >= 0 {
+				d.Jumpee -= start
+			}
+			diss = d
+		}
+		lines[i] = diss
+	}
+	return lines, leaving
+}
+
+// The text of an instruction box: the mnemonic and, for conditional
+// jumps, the two lines of the condition ("if", "zero")
+type svgLabel struct {
+	mnemonic    string
+	conditionIf string
+	condition   string
+}
+
+// Return the text of the instruction box of op
+func (o renderSVGOptions) label(op instructions.OpCode) svgLabel {
+	if o.mnemonics != nil {
+		return svgLabel{mnemonic: o.mnemonics.Name(op)}
+	}
+	label := svgLabel{mnemonic: svgInstrunctionMnemonics[op].Mnemonic}
+	if mnemonic, found := o.labels[op]; found {
+		label.mnemonic = mnemonic
+	}
+	if condition, found := o.conditions[op]; found {
+		words := strings.SplitN(condition, " ", 2)
+		label.conditionIf = words[0]
+		if len(words) == 2 {
+			label.condition = words[1]
+		}
+	} else if condition := svgJumpConditions[op]; condition != "" {
+		label.conditionIf, label.condition = "if", condition
+	}
+	return label
+}
+
 type Colour string
 
 var (
@@ -30,8 +287,28 @@ func (c Colour) fill() string {
 
 type TextStyle string
 
-var instTextStyle = TextStyle("font-family:'Arial Black';font-size:%s;" + textColour.fill())
-var lineNoTextStyle = TextStyle("font-family:'Arial Black';font-size:%s;" + lineNoColour.fill())
+var instTextStyle = newTextStyle(DefaultFont, textColour)
+
+// Return a text style using font
+func newTextStyle(font Font, colour Colour) TextStyle {
+	return TextStyle("font-family:" + strings.ReplaceAll(font.cssFamily(), "%", "%%") + ";font-size:%s;" + colour.fill())
+}
+
+// The text styles of an SVG render
+type svgText struct {
+	font   Font
+	inst   TextStyle
+	lineNo TextStyle
+	// The offset of the condition of a conditional jump from the jump
+	// mnemonic
+	conditionGap int
+}
+
+func newSVGText(font Font) svgText {
+	return svgText{
+		font, newTextStyle(font, textColour), newTextStyle(font, lineNoColour),
+		jumpConditionGap(font, svgInstrunctionMnemonics[instructions.OP_JUMP].Mnemonic)}
+}
 
 func (t TextStyle) Render(fontSize string) string {
 	return fmt.Sprintf(string(t), fontSize)
@@ -64,50 +341,76 @@ var svgJumpConditions = map[instructions.OpCode]string{
 }
 
 func absInt(n int) int {
-	y := n >> strconv.IntSize
+	y := n >> (strconv.IntSize - 1)
 	return (n ^ y) - y
 }
 
-func instruction(canvas *svg.SVG, x, y, w, h int, style, op string) {
-	canvas.Gtransform(fmt.Sprintf("translate(%d, %d)", x, y))
-	canvas.Roundrect(0, 0, w, h, 2, 2, style, `filter="url(#dropShadow)"`)
+// Symbol ids for the reusable elements defined once and instantiated
+// with <use>
+const (
+	jumpTargetSymbol     = "jump-target"
+	missingCommentSymbol = "comment-missing"
+)
+
+func instructionSymbol(op instructions.OpCode) string {
+	return "inst-" + strings.ToLower(op.String())
+}
+
+func argumentSymbol(op instructions.OpCode) string {
+	return "arg-" + strings.ToLower(op.String())
+}
+
+func commentSymbol(index uint32) string {
+	return fmt.Sprintf("comment-%d", index)
+}
+
+// Define an instruction box symbol, drawn at the origin
+func defineInstruction(canvas *svg.SVG, text svgText, id string, w, h int, style, shadow, op string) {
+	canvas.Gid(id)
+	canvas.Roundrect(0, 0, w, h, 2, 2, style, shadow)
 	if op != "" {
 		fmt.Fprintf(canvas.Writer, `<svg width="%d" height="%d">`+"\n", w, h)
 		canvas.Text(
-			w/2, h/2, op, instTextStyle.Render("16px"),
+			w/2, h/2, op, text.inst.Render("16px"),
 			`alignment-baseline="central" text-anchor="middle"`)
 		canvas.End()
 	}
 	canvas.Gend()
 }
 
-func jumpInstruction(canvas *svg.SVG, x, y, w, h int, style, op, condition string) {
-	canvas.Gtransform(fmt.Sprintf("translate(%d, %d)", x, y))
-	canvas.Roundrect(0, 0, w, h, 2, 2, style, `filter="url(#dropShadow)"`)
+// Define a jump instruction box symbol, drawn at the origin
+func defineJumpInstruction(canvas *svg.SVG, text svgText, id string, w, h int, style, shadow string, label svgLabel) {
+	canvas.Gid(id)
+	canvas.Roundrect(0, 0, w, h, 2, 2, style, shadow)
 	fmt.Fprintf(canvas.Writer, `<svg width="%d" height="%d">`+"\n", w, h)
-	if condition != "" {
+	if label.conditionIf != "" {
 		canvas.Text(
-			15, h/2, op,
-			instTextStyle.Render("16px"), `alignment-baseline="central" text-anchor="left"`)
+			15, h/2, label.mnemonic,
+			text.inst.Render("16px"), `alignment-baseline="central" text-anchor="left"`)
 		canvas.Text(
-			15+45, h/3, "if",
-			instTextStyle.Render("10px"), `alignment-baseline="central" text-anchor="left"`)
+			15+text.conditionGap, h/3, label.conditionIf,
+			text.inst.Render("10px"), `alignment-baseline="central" text-anchor="left"`)
 		canvas.Text(
-			15+45, (h/3)*2, condition,
-			instTextStyle.Render("10px"), `alignment-baseline="central" text-anchor="left"`)
+			15+text.conditionGap, (h/3)*2, label.condition,
+			text.inst.Render("10px"), `alignment-baseline="central" text-anchor="left"`)
 	} else {
 		canvas.Text(
-			w/2, h/2, op,
-			instTextStyle.Render("16px"), `alignment-baseline="central" text-anchor="middle"`)
+			w/2, h/2, label.mnemonic,
+			text.inst.Render("16px"), `alignment-baseline="central" text-anchor="middle"`)
 	}
 	canvas.End()
 	canvas.Gend()
 }
 
-func argument(canvas *svg.SVG, x, y, w, h int, style string, arg uint32, indirect bool) {
-	canvas.Gtransform(fmt.Sprintf("translate(%d, %d)", x, y))
-	canvas.Roundrect(0, 0, w, h, 2, 2, style, `filter="url(#dropShadow)"`)
-	fmt.Fprintf(canvas.Writer, `<svg width="%d" height="%d">`+"\n", w, h)
+// Define an (empty) argument box symbol, drawn at the origin
+func defineArgument(canvas *svg.SVG, id string, w, h int, style, shadow string) {
+	canvas.Gid(id)
+	canvas.Roundrect(0, 0, w, h, 2, 2, style, shadow)
+	canvas.Gend()
+}
+
+func argument(canvas *svg.SVG, text svgText, x, y, w, h int, id string, arg uint32, indirect bool) {
+	canvas.Use(x, y, "#"+id)
 	// XXX: Deal with defined label
 	var strArg string
 	if indirect {
@@ -116,41 +419,91 @@ func argument(canvas *svg.SVG, x, y, w, h int, style string, arg uint32, indirec
 		strArg = fmt.Sprintf("%d", arg)
 	}
 	canvas.Text(
-		w/2, h/2, strArg,
-		instTextStyle.Render("22px"), `alignment-baseline="central" text-anchor="middle"`)
-	canvas.End()
-	canvas.Gend()
+		x+w/2, y+h/2, strArg,
+		text.inst.Render("22px"), `alignment-baseline="central" text-anchor="middle"`)
 }
 
-func lineNumber(canvas *svg.SVG, x, y, width, height, lineNumber int) {
+// Draw the label of a jump arc centered on x, y
+func jumpLabel(canvas *svg.SVG, text svgText, x, y int, label string) {
+	canvas.Circle(x, y, 9, canvasColour.fill(), `stroke="rgb(141, 141, 193)" stroke-width="2"`)
+	canvas.Text(
+		x, y, label,
+		text.inst.Render("12px"), `alignment-baseline="central" text-anchor="middle"`)
+}
+
+func lineNumber(canvas *svg.SVG, text svgText, x, y, width, height, lineNumber int) {
 	canvas.Text(
 		(x+width)/2, y+height/2, fmt.Sprintf("%02d", lineNumber),
-		lineNoTextStyle.Render("16px"), `alignment-baseline="central" text-anchor="middle"`)
+		text.lineNo.Render("16px"), `alignment-baseline="central" text-anchor="middle"`)
 }
 
-func comment(canvas *svg.SVG, x, y, w, h int, comment instructions.Comment) {
+// Return the function placing the points of a comment in a w x h box. The
+// comment canvas is scaled into the box, or, if crop is set, the bounding
+// box of the comment's points is fitted into the box with padding around
+// it and centered
+func commentProjection(comment instructions.Comment, w, h int, crop bool, padding int) func(instructions.CommentPoint) (float64, float64) {
+	scaleX := (float64(w) / math.MaxUint16)
+	scaleY := (float64(h) / math.MaxUint16)
+	project := func(p instructions.CommentPoint) (float64, float64) {
+		return float64(p.X) * scaleX, float64(p.Y) * scaleY
+	}
+	availableWidth, availableHeight := float64(w-2*padding), float64(h-2*padding)
+	if !crop || availableWidth <= 0 || availableHeight <= 0 {
+		return project
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, line := range comment {
+		for _, point := range line {
+			x, y := project(point)
+			minX, minY = math.Min(minX, x), math.Min(minY, y)
+			maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+		}
+	}
+	if math.IsInf(minX, 1) {
+		return project
+	}
+	scale := 1.0
+	switch width, height := maxX-minX, maxY-minY; {
+	case width > 0 && height > 0:
+		scale = math.Min(availableWidth/width, availableHeight/height)
+	case width > 0:
+		scale = availableWidth / width
+	case height > 0:
+		scale = availableHeight / height
+	}
+	offsetX := (float64(w)-(maxX-minX)*scale)/2 - minX*scale
+	offsetY := (float64(h)-(maxY-minY)*scale)/2 - minY*scale
+	return func(p instructions.CommentPoint) (float64, float64) {
+		x, y := project(p)
+		return x*scale + offsetX, y*scale + offsetY
+	}
+}
+
+// Define a comment symbol, drawn at the origin
+func defineComment(canvas *svg.SVG, id string, w, h int, comment instructions.Comment, options renderSVGOptions) {
 	style := commentColour.fill()
-	canvas.Gtransform(fmt.Sprintf("translate(%d, %d)", x, y))
-	canvas.Roundrect(0, 0, w, h, 2, 2, style, `filter="url(#dropShadow)"`)
+	clipID := id + "-clip"
+	canvas.Gid(id)
+	canvas.Roundrect(0, 0, w, h, 2, 2, style, options.shadow())
 	fmt.Fprintf(canvas.Writer, `<svg width="%d" height="%d">`+"\n", w, h)
-	canvas.Def()
-	canvas.ClipPath(`id="clipping-rect"`)
+	canvas.ClipPath(fmt.Sprintf(`id="%s"`, clipID))
 	canvas.Roundrect(0, 0, w, h, 2, 2)
 	canvas.ClipEnd()
-	canvas.DefEnd()
-	scaleX := (float64(w) / math.MaxUint16)
-	scaleY := (float64(h) / math.MaxUint16)
+	clip := fmt.Sprintf(`clip-path="url(#%s)"`, clipID)
+	project := commentProjection(comment, w, h, options.cropComments, options.cropPadding)
 	for _, line := range comment {
 		if len(line) == 1 {
-			point := line[0]
-			canvas.Circle(int(float64(point.X)*scaleX), int(float64(point.Y)*scaleY), 2, `clip-path="url(#clipping-rect)"`)
+			x, y := project(line[0])
+			canvas.Circle(int(x), int(y), 2, clip)
 		} else {
 			xs := make([]int, len(line))
 			ys := make([]int, len(line))
 			for i, point := range line {
-				xs[i], ys[i] = int(float64(point.X)*scaleX), int(float64(point.Y)*scaleY)
+				x, y := project(point)
+				xs[i], ys[i] = int(x), int(y)
 			}
-			canvas.Polyline(xs, ys, `fill="none" stroke="black" stroke-width="3" stroke-linecap="round" stroke-linejoin="round" clip-path="url(#clipping-rect)"`)
+			canvas.Polyline(xs, ys, `fill="none" stroke="black" stroke-width="3" stroke-linecap="round" stroke-linejoin="round" `+clip)
 		}
 	}
 	canvas.End()
@@ -161,51 +514,61 @@ func comment(canvas *svg.SVG, x, y, w, h int, comment instructions.Comment) {
 // positioned instruction count of program.
 //
 // See: RenderSVG
-func RenderSVGFromReader(reader io.ReadSeeker) (string, error) {
-	start, _ := reader.Seek(0, io.SeekCurrent)
-	instructionList, err := instructions.DecodeInstructions(reader)
+func RenderSVGFromReader(reader io.ReadSeeker, opts ...RenderSVGOption) (string, error) {
+	program, err := DecodeProgram(reader)
 	if err != nil {
 		return "", err
 	}
-	disassembled := instructions.Disassemble(instructionList)
 
-	reader.Seek(start+4100, io.SeekStart)
-	rawComments, err := instructions.DecodeRawComments(reader)
-	if err != nil {
-		return "", err
-	}
-	comments, err := instructions.DecodeComments(rawComments)
-	if err != nil {
-		return "", err
-	}
-
-	return RenderSVG(disassembled, comments), nil
+	return RenderSVG(program.Disassembled, program.Comments, opts...), nil
 }
 
 // Render a sequence of disassembled instructions and comments into an SVG. The rendered
 // SVG emulates the style of the game's display of instructions.
-func RenderSVG(disassembled instructions.Disassembled, comments instructions.Comments) string {
+func RenderSVG(disassembled instructions.Disassembled, comments instructions.Comments, opts ...RenderSVGOption) string {
+	str, _ := RenderSVGContext(context.Background(), disassembled, comments, opts...)
+	return str
+}
+
+// Like RenderSVG, but returns the context's error if ctx is cancelled
+func RenderSVGContext(ctx context.Context, disassembled instructions.Disassembled, comments instructions.Comments, opts ...RenderSVGOption) (string, error) {
+	options := renderSVGOptions{font: DefaultFont, quality: SVGQualitySoft}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	logger := logging.OrDiscard(options.logger)
+	start := time.Now()
 	var builder strings.Builder
 
 	canvas := svg.New(&builder)
 
-	lineNumberColumnWidth := 35
-	instXOffset, instYOffset, instYStep, instHeight := 10, 10, 30, 25
-	commentYStep, commentHeight, commentWidth := 45, 40, 120
-	canvasWidth := 300
-	canvasHeight := len(disassembled)*instYStep + instYOffset*2 + len(comments)*(commentYStep-instYStep)
-	targetLabelWidth := 75
-	canvas.Start(canvasWidth, canvasHeight)
+	var leaving map[int]int
+	if options.excerpt {
+		disassembled, leaving = excerpt(disassembled, options.fromLine, options.toLine)
+	}
+	l := newProgramLayout(disassembled, comments)
+	if options.excerpt {
+		l.fitComments(disassembled)
+	}
+	text := newSVGText(options.font)
+	text.conditionGap = jumpConditionGap(options.font, options.label(instructions.OP_JUMP_ZERO).mnemonic)
+	if options.font.Family != DefaultFont.Family || options.mnemonics != nil || options.labels != nil || options.conditions != nil {
+		l.fitFont(options.font, options.label, text.conditionGap)
+	}
+	if options.routeJumps {
+		l.routeJumps(disassembled)
+	}
+	canvas.Start(l.canvasWidth, l.canvasHeight)
 
 	canvas.Def()
-	canvas.Filter("dropShadow", `width="200%" height="200%"`)
-	canvas.FeOffset(svg.Filterspec{In: "SourceAlpha", Result: "offOut"}, 1, 1)
-	canvas.FeColorMatrix(
-		svg.Filterspec{In: "offOut", Result: "matrixOut"},
-		[...]float64{0.2, 0, 0, 0, 0, 0, 0.2, 0, 0, 0, 0, 0, 0.2, 0, 0, 0, 0, 0, 0.5, 0}, `mode="normal"`)
-	canvas.FeGaussianBlur(svg.Filterspec{In: "matrixOut", Result: "blurOut"}, 1, 1)
-	canvas.FeBlend(svg.Filterspec{In: "SourceGraphic", In2: "blurOut"}, `mode="normal"`)
-	canvas.Fend()
+	if options.embedFont {
+		if options.font.Embeddable() {
+			canvas.Style("text/css", options.font.fontFace())
+		} else {
+			logger.Warn("font cannot be embedded, it was not loaded from a file", "font", options.font.Family)
+		}
+	}
+	options.defineFilters(canvas)
 	canvas.Marker("arrow", 3, 3, 10, 10)
 	canvas.Path("M10 0 10 6 1 3z", jumpColour.fill())
 	canvas.MarkerEnd()
@@ -213,77 +576,131 @@ func RenderSVG(disassembled instructions.Disassembled, comments instructions.Com
 		{0, "rgb(140,119,104)", 1.0},
 		{40, "rgb(172,146,127)", 1.0},
 		{100, "rgb(172,146,127)", 1.0}})
-	canvas.DefEnd()
-
-	canvas.Rect(0, 0, canvasWidth, canvasHeight, canvasColour.fill())
-	canvas.Rect(0, 0, lineNumberColumnWidth, canvasHeight, "fill:url(#lineNumberColumn)")
-
-	// calculate comments up to the i'th instruction
-	numComments := 0
-	commentCount := make([]int, len(disassembled))
-	for i, diss := range disassembled {
-		commentCount[i] = numComments
-		switch diss.(type) {
+	// define every distinct instruction box and comment once, they are
+	// instantiated with <use> below
+	defined := make(map[string]bool)
+	define := func(id string, fn func()) {
+		if !defined[id] {
+			defined[id] = true
+			fn()
+		}
+	}
+	for _, diss := range disassembled {
+		switch diss := diss.(type) {
 		case instructions.DisassembleComment:
-			numComments++
+			if int(diss.Index) >= len(comments) {
+				define(missingCommentSymbol, func() {
+					defineInstruction(canvas, text, missingCommentSymbol, l.commentWidth, l.commentHeight, commentColour.fill(), options.shadow(), "")
+				})
+				continue
+			}
+			define(commentSymbol(diss.Index), func() {
+				defineComment(canvas, commentSymbol(diss.Index), l.commentWidth, l.commentHeight, comments[diss.Index], options)
+			})
+		case instructions.DisassembleJumpTarget:
+			define(jumpTargetSymbol, func() {
+				defineInstruction(canvas, text, jumpTargetSymbol, l.targetLabelWidth, l.instHeight, jumpColour.fill(), options.shadow(), "")
+			})
+		case instructions.DisassembleJumpInstruction:
+			mnemonic := svgInstrunctionMnemonics[diss.Op]
+			define(instructionSymbol(diss.Op), func() {
+				defineJumpInstruction(
+					canvas, text, instructionSymbol(diss.Op), l.mnemonicWidth(diss.Op), l.instHeight,
+					mnemonic.Colour.fill(), options.shadow(), options.label(diss.Op))
+			})
+		case instructions.DisassembleArgInstruction:
+			mnemonic := svgInstrunctionMnemonics[diss.Op]
+			define(instructionSymbol(diss.Op), func() {
+				defineInstruction(
+					canvas, text, instructionSymbol(diss.Op), l.mnemonicWidth(diss.Op), l.instHeight,
+					mnemonic.Colour.fill(), options.shadow(), options.label(diss.Op).mnemonic)
+			})
+			define(argumentSymbol(diss.Op), func() {
+				defineArgument(canvas, argumentSymbol(diss.Op), l.argumentWidth, l.instHeight, mnemonic.Colour.fill(), options.shadow())
+			})
+		case instructions.DisassembleInstruction:
+			mnemonic := svgInstrunctionMnemonics[diss.Op]
+			define(instructionSymbol(diss.Op), func() {
+				defineInstruction(
+					canvas, text, instructionSymbol(diss.Op), l.mnemonicWidth(diss.Op), l.instHeight,
+					mnemonic.Colour.fill(), options.shadow(), options.label(diss.Op).mnemonic)
+			})
 		}
 	}
+	canvas.DefEnd()
+
+	canvas.Rect(0, 0, l.canvasWidth, l.canvasHeight, canvasColour.fill(), options.paper())
+	canvas.Rect(0, 0, l.lineNumberColumnWidth, l.canvasHeight, "fill:url(#lineNumberColumn)")
 
 	// draw jump lines first, which should be under instructions
 	for i, diss := range disassembled {
 		switch diss := diss.(type) {
 		case instructions.DisassembleJumpInstruction:
-			mnemonic := svgInstrunctionMnemonics[diss.Op]
-			targetCommentOffset := commentCount[diss.Target] * (commentYStep - instYStep)
-			currentCommentOffset := commentCount[i] * (commentYStep - instYStep)
-			sx := lineNumberColumnWidth + instXOffset + mnemonic.Width
-			sy := instYOffset + i*instYStep + currentCommentOffset + instHeight/2
-			cx := canvasWidth
-			cy := sy
-			ex := lineNumberColumnWidth + instXOffset + targetLabelWidth + 10
-			ey := instYOffset + diss.Target*instYStep + targetCommentOffset + instHeight/2
-			px := canvasWidth
-			py := ey
+			arc, ok := l.jumpArc(i, diss)
+			if !ok {
+				if direction := leaving[i]; direction != 0 {
+					arc = l.jumpExit(i, diss, direction)
+					canvas.Bezier(
+						arc.sx, arc.sy, arc.cx, arc.cy, arc.px, arc.py, arc.ex, arc.ey,
+						`fill="none" stroke="rgb(141, 141, 193)" stroke-width="3" stroke-dasharray="6 4"`,
+						options.shadow())
+				}
+				continue
+			}
 			canvas.Bezier(
-				sx, sy, cx, cy, px, py, ex, ey,
-				`fill="none" stroke="rgb(141, 141, 193)" stroke-width="3"`+
-					` marker-end="url(#arrow)" filter="url(#dropShadow)"`)
+				arc.sx, arc.sy, arc.cx, arc.cy, arc.px, arc.py, arc.ex, arc.ey,
+				`fill="none" stroke="rgb(141, 141, 193)" stroke-width="3" marker-end="url(#arrow)"`,
+				options.shadow())
+		}
+	}
+	for i, diss := range disassembled {
+		if diss, ok := diss.(instructions.DisassembleJumpInstruction); ok {
+			if arc, ok := l.jumpArc(i, diss); ok && options.labelJumps {
+				x, y := arc.apex()
+				jumpLabel(canvas, text, x, y, diss.TargetLabel)
+			} else if direction := leaving[i]; direction != 0 {
+				arc := l.jumpExit(i, diss, direction)
+				jumpLabel(canvas, text, arc.ex, arc.ey-direction*12, diss.TargetLabel)
+			}
 		}
 	}
 	// draw instructions
 	for i, diss := range disassembled {
-		instX := lineNumberColumnWidth + instXOffset
-		instY := instYOffset + i*instYStep + commentCount[i]*(commentYStep-instYStep)
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		instX, instY := l.instX(), l.instY(i)
 		switch diss := diss.(type) {
 		case instructions.DisassembleComment:
-			comment(canvas, instX, instY, commentWidth, commentHeight, comments[diss.Index])
+			if int(diss.Index) >= len(comments) {
+				logger.Warn("comment not defined", "comment", diss.Index, "defined", len(comments))
+				canvas.Use(instX, instY, "#"+missingCommentSymbol)
+				continue
+			}
+			canvas.Use(instX, instY, "#"+commentSymbol(diss.Index))
 		case instructions.DisassembleJumpTarget:
-			instruction(canvas, instX, instY, targetLabelWidth, instHeight, jumpColour.fill(), "")
+			canvas.Use(instX, instY, "#"+jumpTargetSymbol)
+			if options.labelJumps {
+				canvas.Text(
+					instX+l.targetLabelWidth/2, instY+l.instHeight/2, diss.Label,
+					text.inst.Render("16px"), `alignment-baseline="central" text-anchor="middle"`)
+			}
 		case instructions.DisassembleJumpInstruction:
-			lineNumber(canvas, 0, instY, lineNumberColumnWidth, instHeight, diss.Line)
-			mnemonic := svgInstrunctionMnemonics[diss.Op]
-			condition := svgJumpConditions[diss.Op]
-			jumpInstruction(
-				canvas, instX, instY, mnemonic.Width, instHeight,
-				mnemonic.Colour.fill(), mnemonic.Mnemonic, condition)
+			lineNumber(canvas, text, 0, instY, l.lineNumberColumnWidth, l.instHeight, diss.Line())
+			canvas.Use(instX, instY, "#"+instructionSymbol(diss.Op))
 		case instructions.DisassembleArgInstruction:
-			lineNumber(canvas, 0, instY, lineNumberColumnWidth, instHeight, diss.Line)
-			mnemonic := svgInstrunctionMnemonics[diss.Op]
-			instruction(
-				canvas, instX, instY, mnemonic.Width, instHeight,
-				mnemonic.Colour.fill(), mnemonic.Mnemonic)
+			lineNumber(canvas, text, 0, instY, l.lineNumberColumnWidth, l.instHeight, diss.Line())
+			canvas.Use(instX, instY, "#"+instructionSymbol(diss.Op))
 			argument(
-				canvas, instX+mnemonic.Width+10, instY, 50, instHeight,
-				mnemonic.Colour.fill(), diss.Arg, diss.Indirect)
+				canvas, text, l.argumentX(diss.Op), instY, l.argumentWidth, l.instHeight,
+				argumentSymbol(diss.Op), diss.Arg, diss.Indirect)
 		case instructions.DisassembleInstruction:
-			lineNumber(canvas, 0, instY, lineNumberColumnWidth, instHeight, diss.Line)
-			mnemonic := svgInstrunctionMnemonics[diss.Op]
-			instruction(
-				canvas, instX, instY, mnemonic.Width, instHeight,
-				mnemonic.Colour.fill(), mnemonic.Mnemonic)
+			lineNumber(canvas, text, 0, instY, l.lineNumberColumnWidth, l.instHeight, diss.Line())
+			canvas.Use(instX, instY, "#"+instructionSymbol(diss.Op))
 		}
 	}
 	canvas.End()
+	logger.Debug("rendered svg", "instructions", len(disassembled), "bytes", builder.Len(), "duration", time.Since(start))
 
-	return builder.String()
+	return builder.String(), nil
 }
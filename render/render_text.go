@@ -3,22 +3,39 @@ package render
 import (
 	"bytes"
 	"compress/zlib"
+	"context"
 	"encoding/base64"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
-	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/utils/logging"
+	"github.com/clj/hrm-profile-tool/utils/text"
 )
 
 type renderInstructionsTextOptions struct {
 	showInstructionNumber bool
 	showLineNumber        bool
 	showRawInstruction    bool
+	hideCommentDefs       bool
+	commentPreview        CommentPreviewStyle
+	mnemonics             *instructions.Mnemonics
 	instructions          instructions.Instructions
+	logger                *slog.Logger
+	commentLevel          *int
+	gameComments          bool
+	commentWrap           *int
+	mnemonicCase          MnemonicCase
+	banner                *Banner
+	color                 bool
+	// render only the lines fromLine to toLine
+	excerpt          bool
+	fromLine, toLine int
 }
 
 // A RenderInstructionsText option
@@ -47,6 +64,113 @@ func ShowRawInstructions() RenderInstructionsTextOption {
 	}
 }
 
+// Leave out the comment definitions (DEFINE COMMENT n) when rendering a
+// program with RenderText. The COMMENT n markers are still rendered
+func HideCommentDefinitions() RenderInstructionsTextOption {
+	return func(o *renderInstructionsTextOptions) {
+		o.hideCommentDefs = true
+	}
+}
+
+// Append a preview of the comment drawings in style to the output of
+// RenderText
+func CommentPreview(style CommentPreviewStyle) RenderInstructionsTextOption {
+	return func(o *renderInstructionsTextOptions) {
+		o.commentPreview = style
+	}
+}
+
+// Render only the instructions with the line numbers from to to
+// (inclusive, a to of 0 is the last line), with the comments and jump
+// targets preceding them. Jumps to targets outside of the excerpt are
+// followed by a line comment naming the target
+func TextLines(from, to int) RenderInstructionsTextOption {
+	return func(o *renderInstructionsTextOptions) {
+		o.excerpt = true
+		o.fromLine, o.toLine = from, to
+	}
+}
+
+// Render instructions using mnemonics instead of the game's mnemonics
+func UseMnemonics(mnemonics *instructions.Mnemonics) RenderInstructionsTextOption {
+	return func(o *renderInstructionsTextOptions) {
+		o.mnemonics = mnemonics
+	}
+}
+
+// The letter case of rendered mnemonics, see RenderMnemonicsIn
+type MnemonicCase string
+
+const (
+	// Lowercase, as shown on the game's instruction tiles (e.g. copyfrom)
+	MnemonicCaseLower = MnemonicCase("lower")
+	// Uppercase, as in programs copied from the game (e.g. COPYFROM)
+	MnemonicCaseUpper = MnemonicCase("upper")
+)
+
+// Return the names of the mnemonic cases
+func MnemonicCases() []string {
+	return []string{string(MnemonicCaseLower), string(MnemonicCaseUpper)}
+}
+
+// Report whether c is a known mnemonic case
+func (c MnemonicCase) Valid() bool {
+	for _, name := range MnemonicCases() {
+		if string(c) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Render mnemonics (including those set by UseMnemonics) in the letter
+// case c. The assembler accepts mnemonics in any case
+func RenderMnemonicsIn(c MnemonicCase) RenderInstructionsTextOption {
+	return func(o *renderInstructionsTextOptions) {
+		o.mnemonicCase = c
+	}
+}
+
+// Color the instructions with ANSI escape sequences in the colors of the
+// game's instruction tiles, for display on a terminal. The output can no
+// longer be pasted into the game. The comment definitions are not colored
+func ColorText() RenderInstructionsTextOption {
+	return func(o *renderInstructionsTextOptions) {
+		o.color = true
+	}
+}
+
+// ANSI escape sequences used by ColorText
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+)
+
+// Return the color of the game's instruction tile for op
+func opColor(op instructions.OpCode) string {
+	switch op {
+	case instructions.OP_INBOX, instructions.OP_OUTBOX:
+		return ansiGreen
+	case instructions.OP_COPY_FROM, instructions.OP_COPY_TO:
+		return ansiRed
+	case instructions.OP_JUMP, instructions.OP_JUMP_ZERO, instructions.OP_JUMP_NEG:
+		return ansiBlue
+	}
+	return ansiYellow
+}
+
+// Return str in color if the ColorText option is used
+func (o renderInstructionsTextOptions) paint(color, str string) string {
+	if !o.color {
+		return str
+	}
+	return color + str + ansiReset
+}
+
 // Raw instruction data for use with ShowRawInstructions. Using this option
 // does *not* imply that the data will be shown. To show the data use
 // ShowRawInstructions
@@ -56,11 +180,83 @@ func RawInstructions(instructions instructions.Instructions) RenderInstructionsT
 	}
 }
 
+// Compress comment definitions with zlib at level (zlib.HuffmanOnly to
+// zlib.BestCompression) instead of the default level 6
+func CommentCompressionLevel(level int) RenderInstructionsTextOption {
+	return func(o *renderInstructionsTextOptions) {
+		o.commentLevel = &level
+	}
+}
+
+// Compress comment definitions exactly as the game does, so that they are
+// byte-equal to comments copied in the game. Takes precedence over
+// CommentCompressionLevel
+func GameIdenticalComments() RenderInstructionsTextOption {
+	return func(o *renderInstructionsTextOptions) {
+		o.gameComments = true
+	}
+}
+
+// Wrap the comment definitions at width columns instead of 80 columns. A
+// width of 0 or less disables wrapping
+func CommentWrap(width int) RenderInstructionsTextOption {
+	return func(o *renderInstructionsTextOptions) {
+		o.commentWrap = &width
+	}
+}
+
+// Log render warnings to logger. By default nothing is logged
+func TextLogger(logger *slog.Logger) RenderInstructionsTextOption {
+	return func(o *renderInstructionsTextOptions) {
+		o.logger = logger
+	}
+}
+
 // Validate the options and panic if something is wrong
 func (o renderInstructionsTextOptions) validate() {
 	if o.showRawInstruction && o.instructions == nil {
 		panic("RawInstructions(instructions) must be passed if ShowRawInstructions is used")
 	}
+	o.validateComments()
+}
+
+// Return the mnemonic of op as set by the UseMnemonics and
+// RenderMnemonicsIn options
+func (o renderInstructionsTextOptions) mnemonic(op instructions.OpCode) string {
+	name := op.String()
+	if o.mnemonics != nil {
+		name = o.mnemonics.Name(op)
+	}
+	switch o.mnemonicCase {
+	case MnemonicCaseLower:
+		return strings.ToLower(name)
+	case MnemonicCaseUpper:
+		return strings.ToUpper(name)
+	}
+	return name
+}
+
+// The default width at which comment definitions are wrapped
+const commentWrapWidth = 80
+
+// Wrap rendered comment definitions as set by the CommentWrap option
+func (o renderInstructionsTextOptions) wrapComments(comments string) string {
+	width := commentWrapWidth
+	if o.commentWrap != nil {
+		width = *o.commentWrap
+	}
+	if width <= 0 {
+		return comments
+	}
+	return text.Wrap(comments, width)
+}
+
+// Validate the options used by RenderCommentsText and panic if something
+// is wrong
+func (o renderInstructionsTextOptions) validateComments() {
+	if o.commentLevel != nil && (*o.commentLevel < zlib.HuffmanOnly || *o.commentLevel > zlib.BestCompression) {
+		panic(fmt.Sprintf("invalid comment compression level %d", *o.commentLevel))
+	}
 }
 
 // Render a textual representation of a program from a given reader.
@@ -73,7 +269,10 @@ func RenderInstructionsTextFromReader(reader io.Reader, opts ...RenderInstructio
 	if err != nil {
 		return "", err
 	}
-	disassembled := instructions.Disassemble(instructionList)
+	disassembled, err := instructions.Disassemble(instructionList)
+	if err != nil {
+		return "", err
+	}
 
 	opts = append(opts, RawInstructions(instructionList))
 
@@ -88,57 +287,80 @@ func RenderInstructionsTextFromReader(reader io.Reader, opts ...RenderInstructio
 // compatible with Human Resource Machine (i.e. it can be pasted
 // into the game)
 func RenderInstructionsText(disassembled instructions.Disassembled, opts ...RenderInstructionsTextOption) string {
+	str, _ := RenderInstructionsTextContext(context.Background(), disassembled, opts...)
+	return str
+}
+
+// Like RenderInstructionsText, but returns the context's error if ctx is
+// cancelled
+func RenderInstructionsTextContext(ctx context.Context, disassembled instructions.Disassembled, opts ...RenderInstructionsTextOption) (string, error) {
 	var builder strings.Builder
 	var options renderInstructionsTextOptions
 	for _, opt := range opts {
 		opt(&options)
 	}
 	options.validate()
+	logger := logging.OrDiscard(options.logger)
+	mnemonic := options.mnemonic
 
 	instNumPadding := int(math.Log10(float64(len(disassembled)))) + 1
 
-	for i, diss := range disassembled {
+	start, end := 0, len(disassembled)
+	if options.excerpt {
+		start, end = disassembled.LineRange(options.fromLine, options.toLine)
+	}
+	for i := start; i < end; i++ {
+		diss := disassembled[i]
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		if options.showInstructionNumber {
 			// print instruction number
-			fmt.Fprintf(&builder, "%*d ", instNumPadding, i)
+			builder.WriteString(options.paint(ansiDim, fmt.Sprintf("%*d", instNumPadding, i)) + " ")
 		}
 		if options.showLineNumber {
 			// print "line" number
-			switch diss := diss.(type) {
-			case instructions.DisassembleJumpTarget:
+			if diss, ok := diss.(instructions.LineNumbered); ok {
+				builder.WriteString(options.paint(ansiDim, fmt.Sprintf("%*d", instNumPadding, diss.Line())) + " ")
+			} else {
 				fmt.Fprintf(&builder, "%*s ", instNumPadding, "")
-			case instructions.DisassembleComment:
-				fmt.Fprintf(&builder, "%*s ", instNumPadding, "")
-			default:
-				line := reflect.ValueOf(diss).FieldByName("Line").Int()
-				fmt.Fprintf(&builder, "%*d ", instNumPadding, line)
 			}
 		}
 		if options.showRawInstruction {
 			inst := options.instructions[i]
-			fmt.Fprintf(&builder, "%08X %08X %08X %08X ", inst.Comment, inst.Op, inst.Mode, inst.Arg)
+			builder.WriteString(options.paint(ansiDim, fmt.Sprintf("%08X %08X %08X %08X", inst.Comment, inst.Op, inst.Mode, inst.Arg)) + " ")
 		}
 		// print label or opcode
 		switch diss := diss.(type) {
 		case instructions.DisassembleComment:
-			fmt.Fprintf(&builder, "COMMENT %d", diss.Index)
+			builder.WriteString(options.paint(ansiDim, fmt.Sprintf("COMMENT %d", diss.Index)))
 		case instructions.DisassembleJumpTarget:
-			fmt.Fprintf(&builder, "%s:", diss.Label)
+			builder.WriteString(options.paint(ansiBlue, diss.Label+":"))
 		case instructions.DisassembleJumpInstruction:
-			fmt.Fprintf(&builder, "%s %s", diss.Op.String(), diss.TargetLabel)
+			fmt.Fprintf(&builder, "%s %s", options.paint(opColor(diss.Op), mnemonic(diss.Op)), diss.TargetLabel)
 		case instructions.DisassembleArgInstruction:
 			openBracket, closeBracket := "", ""
 			if diss.Indirect {
 				openBracket, closeBracket = "[", "]"
 			}
-			fmt.Fprintf(&builder, "%s %s%d%s", diss.Op.String(), openBracket, diss.Arg, closeBracket)
+			fmt.Fprintf(&builder, "%s %s%d%s", options.paint(opColor(diss.Op), mnemonic(diss.Op)), openBracket, diss.Arg, closeBracket)
 		case instructions.DisassembleInstruction:
-			fmt.Fprint(&builder, diss.Op.String())
+			builder.WriteString(options.paint(opColor(diss.Op), mnemonic(diss.Op)))
+		case nil:
+			logger.Warn("instruction was not disassembled", "index", i)
 		}
 		fmt.Fprintf(&builder, "\n")
+		if jump, ok := diss.(instructions.DisassembleJumpInstruction); ok && jump.TargetLabel != "" {
+			switch {
+			case jump.Target < start:
+				builder.WriteString(options.paint(ansiDim, fmt.Sprintf("-- %s: above line %d", jump.TargetLabel, options.fromLine)) + "\n")
+			case jump.Target >= end:
+				builder.WriteString(options.paint(ansiDim, fmt.Sprintf("-- %s: below line %d", jump.TargetLabel, options.toLine)) + "\n")
+			}
+		}
 	}
 
-	return builder.String()
+	return builder.String(), nil
 }
 
 // Render a textual representation of a program's comments
@@ -160,30 +382,89 @@ func RenderCommentsTextFromReader(reader io.ReadSeeker) (string, error) {
 // can be pasted into the game) and should be appended to the rendered
 // instructions. The returned text can be wrapped arbitrarily as long
 // as the "DEFINE COMMENT xxx" text is not wrapped.
-func RenderCommentsText(rawComments instructions.RawComments) string {
+//
+// Only the CommentCompressionLevel and GameIdenticalComments options apply
+func RenderCommentsText(rawComments instructions.RawComments, opts ...RenderInstructionsTextOption) string {
+	var options renderInstructionsTextOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.validateComments()
+
 	var builder strings.Builder
+	// Each comment is roughly "DEFINE COMMENT n" followed by ~100 bytes of base64
+	builder.Grow(len(rawComments) * 128)
 
+	compressed := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(compressed)
 	for commentIdx, comment := range rawComments {
-		var b bytes.Buffer
-		w, _ := zlib.NewWriterLevel(&b, 6)
-		var i int
-		var data [4]byte
-		var dataBuffer bytes.Buffer
-		binary.Write(&dataBuffer, binary.LittleEndian, uint32(len(comment)))
-		w.Write(dataBuffer.Bytes())
-		for i, data = range comment {
-			w.Write(data[:])
-		}
-		for j := i; j < 1024/4-1; j++ {
-			w.Write([]byte{0, 0, 0, 0})
-		}
-		w.Close()
+		compressed.Reset()
+		encodeComment(compressed, comment, options)
 
 		fmt.Fprintf(&builder, "DEFINE COMMENT %d\n", commentIdx)
-		encodedComment := base64.StdEncoding.EncodeToString(b.Bytes())
+		encodedComment := base64.StdEncoding.EncodeToString(compressed.Bytes())
 		builder.WriteString(strings.TrimRight(encodedComment, "="))
 		builder.WriteString(";\n\n")
 	}
 
 	return builder.String()
 }
+
+// The size of a comment record: the point count followed by up to
+// MAX_COMMENT_POINTS points
+const commentRecordSize = instructions.COMMENT_RECORD_SIZE
+
+// The zlib compression level used when encoding comments
+const commentCompressionLevel = 6
+
+// Pools of buffers and zlib writers, reused when encoding comments in
+// order to avoid allocating per comment during batch exports
+var (
+	bufferPool = sync.Pool{
+		New: func() interface{} {
+			return bytes.NewBuffer(make([]byte, 0, commentRecordSize))
+		},
+	}
+	zlibWriterPool = sync.Pool{
+		New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(nil, commentCompressionLevel)
+			return w
+		},
+	}
+)
+
+// Encode a raw comment as a zlib compressed comment record, as found in the
+// "DEFINE COMMENT" section of programs copied from the game
+func encodeComment(w io.Writer, comment instructions.RawComment, options renderInstructionsTextOptions) {
+	record := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(record)
+	record.Reset()
+
+	var word [4]byte
+	binary.LittleEndian.PutUint32(word[:], uint32(len(comment)))
+	record.Write(word[:])
+	for _, data := range comment {
+		record.Write(data[:])
+	}
+	// Pad to a whole number of records, comments of more than
+	// MAX_COMMENT_POINTS points continue into the following records
+	size := instructions.CommentRecords(len(comment)) * commentRecordSize
+	for record.Len() < size {
+		record.Write([]byte{0, 0, 0, 0})
+	}
+
+	switch {
+	case options.gameComments:
+		w.Write(zlibCompress(record.Bytes()))
+	case options.commentLevel != nil && *options.commentLevel != commentCompressionLevel:
+		zw, _ := zlib.NewWriterLevel(w, *options.commentLevel)
+		zw.Write(record.Bytes())
+		zw.Close()
+	default:
+		zw := zlibWriterPool.Get().(*zlib.Writer)
+		defer zlibWriterPool.Put(zw)
+		zw.Reset(w)
+		zw.Write(record.Bytes())
+		zw.Close()
+	}
+}
@@ -0,0 +1,85 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+const assembleProgram = `a:
+INBOX
+COMMENT 0
+COPYTO [3]
+JUMPZ c
+BUMPUP 3
+b:
+COPYFROM 3
+JUMPN b
+OUTBOX
+JUMP a
+c:
+COMMENT 1
+SUB [0]
+`
+
+// Assemble and disassemble a program, failing the test on errors
+func assembled(t *testing.T, text string, opts ...instructions.AssembleOption) (instructions.Instructions, instructions.Disassembled) {
+	t.Helper()
+	program, _, err := instructions.Assemble(text, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	disassembled, err := instructions.Disassemble(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return program, disassembled
+}
+
+func TestAssembleRenderRoundTrip(t *testing.T) {
+	program, disassembled := assembled(t, assembleProgram)
+	text := RenderInstructionsText(disassembled)
+	if text != assembleProgram {
+		t.Errorf("got\n%s\nexpected\n%s", text, assembleProgram)
+	}
+	reassembled, _ := assembled(t, text)
+	if !reflect.DeepEqual(reassembled, program) {
+		t.Errorf("reassembled %v, expected %v", reassembled, program)
+	}
+}
+
+func TestAssembleRenderMnemonics(t *testing.T) {
+	mnemonics, err := instructions.ParseMnemonics(strings.NewReader("JUMPN = JLZ\nBUMPUP = INC\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, disassembled := assembled(t, assembleProgram)
+	text := RenderInstructionsText(disassembled, UseMnemonics(mnemonics), RenderMnemonicsIn(MnemonicCaseLower))
+	for _, expected := range []string{"\njlz b\n", "\ninc 3\n", "\ncopyto [3]\n"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("%q is missing from\n%s", expected, text)
+		}
+	}
+	reassembled, _ := assembled(t, text, instructions.AssembleMnemonics(mnemonics))
+	if !reflect.DeepEqual(reassembled, program) {
+		t.Errorf("reassembled %v, expected %v", reassembled, program)
+	}
+}
+
+func TestAssembleRenderComments(t *testing.T) {
+	comments := instructions.RawComments{
+		{{0x10, 0, 0x20, 0}, {0, 0, 0, 0}, {0xff, 0x03, 0x7f, 0x02}},
+		{{0x01, 0x02, 0x03, 0x04}},
+	}
+	_, disassembled := assembled(t, assembleProgram)
+	text := RenderInstructionsText(disassembled) + "\n\n" + RenderCommentsText(comments)
+	_, reassembled, err := instructions.Assemble(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reassembled, comments) {
+		t.Errorf("reassembled comments %v, expected %v", reassembled, comments)
+	}
+}
@@ -0,0 +1,97 @@
+package render
+
+import (
+	"context"
+	"image"
+	"log/slog"
+	"time"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/utils/logging"
+)
+
+type thumbnailOptions struct {
+	logger *slog.Logger
+	size   int
+	badge  []string
+}
+
+// A RenderThumbnail option
+type ThumbnailOption func(*thumbnailOptions)
+
+// Log render warnings and timing to logger. By default nothing is logged
+func ThumbnailLogger(logger *slog.Logger) ThumbnailOption {
+	return func(o *thumbnailOptions) {
+		o.logger = logger
+	}
+}
+
+// Set the width and height of the thumbnail in pixels (default 256)
+func ThumbnailSize(size int) ThumbnailOption {
+	return func(o *thumbnailOptions) {
+		o.size = size
+	}
+}
+
+// Show lines (e.g. the size and steps results) in a badge in the top right
+// corner of the thumbnail
+func ThumbnailBadge(lines ...string) ThumbnailOption {
+	return func(o *thumbnailOptions) {
+		o.badge = lines
+	}
+}
+
+// Render a compact square thumbnail of a program: the first instructions in
+// the style of RenderSVG, fading out towards the bottom
+func RenderThumbnail(disassembled instructions.Disassembled, comments instructions.Comments, opts ...ThumbnailOption) image.Image {
+	img, _ := RenderThumbnailContext(context.Background(), disassembled, comments, opts...)
+	return img
+}
+
+// Like RenderThumbnail, but returns the context's error if ctx is cancelled
+func RenderThumbnailContext(ctx context.Context, disassembled instructions.Disassembled, comments instructions.Comments, opts ...ThumbnailOption) (image.Image, error) {
+	options := thumbnailOptions{size: 256}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	logger := logging.OrDiscard(options.logger)
+	start := time.Now()
+
+	l := newProgramLayout(disassembled, comments)
+	// the thumbnail is drawn at the layout's natural scale and then resized
+	side := l.canvasWidth
+	r := newRaster(side, side)
+	if err := rasterProgram(ctx, r, l, disassembled, comments, logger); err != nil {
+		return nil, err
+	}
+
+	if l.canvasHeight > side {
+		fadeStart := side * 3 / 5
+		for y := fadeStart; y < side; y++ {
+			alpha := float64(y-fadeStart) / float64(side-fadeStart)
+			r.blend(image.Rect(0, y, side, y+1), canvasColour.rgba(), alpha)
+		}
+	}
+
+	if len(options.badge) > 0 {
+		const scale, padding = 2, 6
+		width := 0
+		for _, line := range options.badge {
+			if w := textWidth(line, scale); w > width {
+				width = w
+			}
+		}
+		lineHeight := (glyphHeight + 3) * scale
+		width += padding * 2
+		height := len(options.badge)*lineHeight - 3*scale + padding*2
+		x, y := side-width-padding, padding
+		r.fillRoundRect(x, y, width, height, 4, textColour.rgba())
+		for i, line := range options.badge {
+			r.text(x+padding, y+padding+i*lineHeight, scale, commentColour.rgba(), line)
+		}
+	}
+
+	img := r.scale(options.size, options.size)
+	logger.Debug("rendered thumbnail", "instructions", len(disassembled), "size", options.size, "duration", time.Since(start))
+	return img, nil
+}
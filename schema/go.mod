@@ -0,0 +1,10 @@
+module github.com/clj/hrm-profile-tool/schema
+
+require (
+	github.com/clj/hrm-profile-tool/instructions v0.0.0
+	github.com/clj/hrm-profile-tool/utils/logging v0.0.0 // indirect
+)
+
+replace github.com/clj/hrm-profile-tool/instructions => ../instructions
+
+replace github.com/clj/hrm-profile-tool/utils/logging => ../utils/logging
@@ -0,0 +1,126 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// The JSON Schema dialect written by WriteJSONSchema
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// A JSON Schema, or a part of one
+type jsonSchema map[string]interface{}
+
+// Generates a JSON Schema from the types of the representation, collecting
+// the schemas of nested structs as definitions
+type jsonSchemaGenerator struct {
+	defs map[string]jsonSchema
+}
+
+// Write a JSON Schema describing the JSON representation of v, e.g.
+// Profile{} or Export{}. The schema is generated from the struct
+// definitions, so it always matches what is produced
+func WriteJSONSchema(w io.Writer, v interface{}, generator string) error {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot generate a JSON Schema for %T", v)
+	}
+	g := jsonSchemaGenerator{defs: make(map[string]jsonSchema)}
+	root := g.object(t)
+	root["$schema"] = JSONSchemaDialect
+	root["$comment"] = fmt.Sprintf("Generated by %s, do not edit", generator)
+	root["title"] = fmt.Sprintf("Human Resource Machine %s (schema version %d)", strings.ToLower(t.Name()), Version)
+	if len(g.defs) > 0 {
+		root["$defs"] = g.defs
+	}
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Return the schema of a value of type t
+func (g *jsonSchemaGenerator) schema(t reflect.Type) jsonSchema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := g.schema(t.Elem())
+		if typ, ok := schema["type"].(string); ok {
+			schema["type"] = []string{typ, "null"}
+			return schema
+		}
+		return jsonSchema{"anyOf": []jsonSchema{schema, {"type": "null"}}}
+	case reflect.Slice, reflect.Array:
+		return jsonSchema{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Struct:
+		if _, found := g.defs[t.Name()]; !found {
+			g.defs[t.Name()] = nil // Defined while generating the definition
+			g.defs[t.Name()] = g.object(t)
+		}
+		return jsonSchema{"$ref": "#/$defs/" + t.Name()}
+	case reflect.String:
+		return jsonSchema{"type": "string"}
+	case reflect.Bool:
+		return jsonSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonSchema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return jsonSchema{"type": "number"}
+	}
+	return jsonSchema{}
+}
+
+// Return the schema of the struct type t. Fields which are not omitted
+// when empty are required, and the fields of embedded structs are
+// properties of t, as encoded by encoding/json. Additional properties are
+// allowed, as fields may be added without changing the version
+func (g *jsonSchemaGenerator) object(t reflect.Type) jsonSchema {
+	properties := make(map[string]jsonSchema)
+	required := []string{}
+	g.fields(t, properties, &required)
+	return jsonSchema{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+func (g *jsonSchemaGenerator) fields(t reflect.Type, properties map[string]jsonSchema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, flags := tag, ""
+		if comma := strings.Index(tag, ","); comma >= 0 {
+			name, flags = tag[:comma], tag[comma:]
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.fields(field.Type, properties, required)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema := g.schema(field.Type)
+		if doc := field.Tag.Get("doc"); doc != "" {
+			schema["description"] = doc
+		}
+		if name == "schema_version" {
+			schema["const"] = Version
+		}
+		properties[name] = schema
+		if !strings.Contains(flags, ",omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
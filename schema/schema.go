@@ -0,0 +1,117 @@
+// Package schema defines the JSON representation of Human Resource Machine
+// profiles and programs shared by every JSON feature of hrm-profile-tool
+// (the json output format, the playground API, external exporters and
+// queries), so that each feature produces and accepts the same shapes.
+//
+// The representation is versioned: within a version fields are only ever
+// added. Renaming or removing a field, or changing its meaning, increments
+// Version. A JSON Schema of the representation is generated by
+// WriteJSONSchema
+package schema
+
+import (
+	"github.com/clj/hrm-profile-tool/instructions"
+)
+
+// The version of the JSON representation
+const Version = 1
+
+// A save slot of a profile
+type Profile struct {
+	SchemaVersion int     `json:"schema_version" doc:"The version of the schema"`
+	Slot          int     `json:"slot" doc:"The save slot, starting at 1"`
+	Floors        []Floor `json:"floors" doc:"The floors of the game, by floor number"`
+}
+
+// A floor of a save slot
+type Floor struct {
+	Number      int    `json:"number" doc:"The floor number shown in the game"`
+	Name        string `json:"name" doc:"The name of the level"`
+	Completed   bool   `json:"completed" doc:"Whether the floor is completed"`
+	Size        *int   `json:"size" doc:"The best size, null until completed"`
+	Speed       *int   `json:"speed" doc:"The best speed in steps, null until completed"`
+	SizeTarget  int    `json:"size_target" doc:"The size challenge of the level"`
+	SpeedTarget int    `json:"speed_target" doc:"The speed challenge of the level"`
+	Tabs        []Tab  `json:"tabs" doc:"The three program tabs of the floor"`
+}
+
+// A program tab of a floor
+type Tab struct {
+	Tab int `json:"tab" doc:"The tab number, starting at 1"`
+	Program
+}
+
+// A program
+type Program struct {
+	Size         int           `json:"size" doc:"The size of the program as counted by the game"`
+	Hash         string        `json:"hash" doc:"The canonical hash of the program, ignoring comments and label names"`
+	Instructions []Instruction `json:"instructions" doc:"The instructions, labels and comments of the program"`
+	Comments     [][][]Point   `json:"comments" doc:"The comment drawings: lists of lines of points, indexed by the comment instructions"`
+}
+
+// A program exported on its own, e.g. by the json output format
+type Export struct {
+	SchemaVersion int `json:"schema_version" doc:"The version of the schema"`
+	Program
+}
+
+// An instruction, label or comment of a program
+type Instruction struct {
+	Line     int    `json:"line,omitempty" doc:"The line number shown in the game, absent for labels and comments"`
+	Op       string `json:"op" doc:"The instruction mnemonic, or label or comment"`
+	Arg      *int   `json:"arg,omitempty" doc:"The tile argument of instructions taking one"`
+	Indirect bool   `json:"indirect,omitempty" doc:"Whether the argument is indirect ([n])"`
+	Label    string `json:"label,omitempty" doc:"The name of a label"`
+	Target   string `json:"target,omitempty" doc:"The label targeted by a jump"`
+	Comment  *int   `json:"comment,omitempty" doc:"The index of the drawing of a comment"`
+}
+
+// A point of a comment drawing. A point with both coordinates 0 ends a line
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Return the representation of a program
+func NewProgram(code instructions.Disassembled, comments instructions.Comments) Program {
+	program := Program{
+		Size:         code.Size(),
+		Hash:         code.Hash(),
+		Instructions: []Instruction{},
+		Comments:     [][][]Point{},
+	}
+	for _, item := range code {
+		switch item := item.(type) {
+		case instructions.DisassembleComment:
+			comment := int(item.Index)
+			program.Instructions = append(program.Instructions, Instruction{Op: "comment", Comment: &comment})
+		case instructions.DisassembleJumpTarget:
+			program.Instructions = append(program.Instructions, Instruction{Op: "label", Label: item.Label})
+		case instructions.DisassembleJumpInstruction:
+			program.Instructions = append(program.Instructions, Instruction{Line: item.LineNumber, Op: item.Op.String(), Target: item.TargetLabel})
+		case instructions.DisassembleArgInstruction:
+			arg := int(item.Arg)
+			program.Instructions = append(program.Instructions, Instruction{Line: item.LineNumber, Op: item.Op.String(), Arg: &arg, Indirect: item.Indirect})
+		case instructions.DisassembleInstruction:
+			program.Instructions = append(program.Instructions, Instruction{Line: item.LineNumber, Op: item.Op.String()})
+		}
+	}
+
+	for _, comment := range comments {
+		lines := [][]Point{}
+		for _, line := range comment {
+			points := make([]Point, 0, len(line))
+			for _, point := range line {
+				points = append(points, Point{int(point.X), int(point.Y)})
+			}
+			lines = append(lines, points)
+		}
+		program.Comments = append(program.Comments, lines)
+	}
+	return program
+}
+
+// Return a program exported on its own
+func NewExport(code instructions.Disassembled, comments instructions.Comments) Export {
+	return Export{SchemaVersion: Version, Program: NewProgram(code, comments)}
+}
@@ -0,0 +1,27 @@
+// Package logging provides helpers for packages accepting an optional
+// *slog.Logger, so they never write to the global logger
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// A handler discarding all records
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// A logger discarding everything logged to it
+var Discard = slog.New(discardHandler{})
+
+// Return logger, or Discard if logger is nil
+func OrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return Discard
+	}
+	return logger
+}
@@ -0,0 +1,302 @@
+// Package vm executes Human Resource Machine programs the way the game
+// does: a worker takes values from the inbox, holds one value at a time,
+// uses the floor tiles as memory and puts values in the outbox
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/levels"
+)
+
+// The range of numbers the game allows
+const (
+	MIN_NUMBER = -999
+	MAX_NUMBER = 999
+)
+
+// The default limit on the number of steps executed by Run
+const DEFAULT_MAX_STEPS = 100000
+
+// ErrStepLimit is returned by Run when the program executes more than the
+// step limit, e.g. when it loops forever
+var ErrStepLimit = errors.New("step limit exceeded")
+
+// An error executing an instruction, as reported by the boss in the game
+type RuntimeError struct {
+	Index int // The index of the instruction in the program
+	Line  int // The line number of the instruction, as shown in the game
+	Op    instructions.OpCode
+	Err   string
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("line %d (%s): %s", e.Line, e.Op, e.Err)
+}
+
+// A running program
+type Machine struct {
+	Program instructions.Disassembled
+	Inbox   []levels.Value
+	Outbox  []levels.Value
+	Floor   []levels.Tile
+	// The value held by the worker, if Holding
+	Hand    levels.Value
+	Holding bool
+	// The index of the next instruction to execute
+	PC int
+	// The number of instructions executed, as counted by the speed
+	// challenge
+	Steps  int
+	Halted bool
+}
+
+// Return a machine ready to execute program, with values in the inbox and
+// the initial floor tiles (e.g. from levels.Level.Tiles)
+func New(program instructions.Disassembled, inbox []levels.Value, floor []levels.Tile) *Machine {
+	return &Machine{
+		Program: program,
+		Inbox:   append([]levels.Value(nil), inbox...),
+		Floor:   append([]levels.Tile(nil), floor...),
+	}
+}
+
+// Return a machine for the level, with its initial floor tiles
+func NewForLevel(program instructions.Disassembled, inbox []levels.Value, level levels.Level) *Machine {
+	return New(program, inbox, level.Tiles())
+}
+
+// Execute instructions until the next instruction the worker performs
+// (skipping comments and jump targets), or until the program ends. The
+// program ends when it runs past its last instruction or takes from an
+// empty inbox
+func (m *Machine) Step() error {
+	for !m.Halted {
+		if m.PC >= len(m.Program) {
+			m.Halted = true
+			return nil
+		}
+		switch inst := m.Program[m.PC].(type) {
+		case instructions.DisassembleJumpInstruction:
+			return m.jump(inst)
+		case instructions.DisassembleArgInstruction:
+			return m.execArg(inst)
+		case instructions.DisassembleInstruction:
+			return m.exec(inst)
+		default:
+			m.PC++
+		}
+	}
+	return nil
+}
+
+// Run the program until it ends, the step limit (DEFAULT_MAX_STEPS if 0)
+// is reached, or ctx is cancelled
+func (m *Machine) Run(ctx context.Context, maxSteps int) error {
+	if maxSteps == 0 {
+		maxSteps = DEFAULT_MAX_STEPS
+	}
+	for !m.Halted {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if m.Steps >= maxSteps {
+			return ErrStepLimit
+		}
+		if err := m.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Machine) fail(inst instructions.DisassembleInstruction, format string, args ...interface{}) error {
+	m.Halted = true
+	return &RuntimeError{m.PC, inst.LineNumber, inst.Op, fmt.Sprintf(format, args...)}
+}
+
+func (m *Machine) exec(inst instructions.DisassembleInstruction) error {
+	switch inst.Op {
+	case instructions.OP_INBOX:
+		if len(m.Inbox) == 0 {
+			m.Halted = true
+			return nil
+		}
+		m.Hand, m.Holding = m.Inbox[0], true
+		m.Inbox = m.Inbox[1:]
+	case instructions.OP_OUTBOX:
+		if !m.Holding {
+			return m.fail(inst, "empty hands, nothing to put in the outbox")
+		}
+		m.Outbox = append(m.Outbox, m.Hand)
+		m.Holding = false
+	default:
+		return m.fail(inst, "unknown instruction")
+	}
+	m.Steps++
+	m.PC++
+	return nil
+}
+
+// Return the tile addressed by an instruction's argument
+func (m *Machine) address(inst instructions.DisassembleArgInstruction) (int, error) {
+	address := int(inst.Arg)
+	if address >= len(m.Floor) {
+		return 0, m.fail(inst.DisassembleInstruction, "there is no tile %d", address)
+	}
+	if !inst.Indirect {
+		return address, nil
+	}
+	tile := m.Floor[address]
+	switch {
+	case !tile.Filled:
+		return 0, m.fail(inst.DisassembleInstruction, "tile %d is empty, it cannot be used as an address", address)
+	case tile.Value.IsLetter():
+		return 0, m.fail(inst.DisassembleInstruction, "tile %d holds a letter, it cannot be used as an address", address)
+	case tile.Value.Number < 0 || tile.Value.Number >= len(m.Floor):
+		return 0, m.fail(inst.DisassembleInstruction, "there is no tile %d", tile.Value.Number)
+	}
+	return tile.Value.Number, nil
+}
+
+func (m *Machine) execArg(inst instructions.DisassembleArgInstruction) error {
+	address, err := m.address(inst)
+	if err != nil {
+		return err
+	}
+	tile := &m.Floor[address]
+	needTile := func() error {
+		if !tile.Filled {
+			return m.fail(inst.DisassembleInstruction, "tile %d is empty", address)
+		}
+		return nil
+	}
+	needHand := func() error {
+		if !m.Holding {
+			return m.fail(inst.DisassembleInstruction, "empty hands")
+		}
+		return nil
+	}
+	number := func(n int) error {
+		if n < MIN_NUMBER || n > MAX_NUMBER {
+			return m.fail(inst.DisassembleInstruction, "overflow, %d is outside %d to %d", n, MIN_NUMBER, MAX_NUMBER)
+		}
+		return nil
+	}
+
+	switch inst.Op {
+	case instructions.OP_COPY_FROM:
+		if err := needTile(); err != nil {
+			return err
+		}
+		m.Hand, m.Holding = tile.Value, true
+	case instructions.OP_COPY_TO:
+		if err := needHand(); err != nil {
+			return err
+		}
+		*tile = levels.Tile{Filled: true, Value: m.Hand}
+	case instructions.OP_ADD:
+		if err := needHand(); err != nil {
+			return err
+		}
+		if err := needTile(); err != nil {
+			return err
+		}
+		if m.Hand.IsLetter() || tile.Value.IsLetter() {
+			return m.fail(inst.DisassembleInstruction, "letters cannot be added")
+		}
+		sum := m.Hand.Number + tile.Value.Number
+		if err := number(sum); err != nil {
+			return err
+		}
+		m.Hand = levels.NumberValue(sum)
+	case instructions.OP_SUB:
+		if err := needHand(); err != nil {
+			return err
+		}
+		if err := needTile(); err != nil {
+			return err
+		}
+		var difference int
+		switch {
+		case m.Hand.IsLetter() && tile.Value.IsLetter():
+			difference = int(m.Hand.Letter) - int(tile.Value.Letter)
+		case m.Hand.IsLetter() || tile.Value.IsLetter():
+			return m.fail(inst.DisassembleInstruction, "a letter and a number cannot be subtracted")
+		default:
+			difference = m.Hand.Number - tile.Value.Number
+		}
+		if err := number(difference); err != nil {
+			return err
+		}
+		m.Hand = levels.NumberValue(difference)
+	case instructions.OP_BUMP_PLUS, instructions.OP_BUMP_MINUS:
+		if err := needTile(); err != nil {
+			return err
+		}
+		if tile.Value.IsLetter() {
+			return m.fail(inst.DisassembleInstruction, "letters cannot be bumped")
+		}
+		bumped := tile.Value.Number + 1
+		if inst.Op == instructions.OP_BUMP_MINUS {
+			bumped = tile.Value.Number - 1
+		}
+		if err := number(bumped); err != nil {
+			return err
+		}
+		tile.Value = levels.NumberValue(bumped)
+		m.Hand, m.Holding = tile.Value, true
+	default:
+		return m.fail(inst.DisassembleInstruction, "unknown instruction")
+	}
+	m.Steps++
+	m.PC++
+	return nil
+}
+
+func (m *Machine) jump(inst instructions.DisassembleJumpInstruction) error {
+	taken := true
+	switch inst.Op {
+	case instructions.OP_JUMP_ZERO, instructions.OP_JUMP_NEG:
+		if !m.Holding {
+			return m.fail(inst.DisassembleInstruction, "empty hands, nothing to compare")
+		}
+		if inst.Op == instructions.OP_JUMP_ZERO {
+			taken = !m.Hand.IsLetter() && m.Hand.Number == 0
+		} else {
+			taken = !m.Hand.IsLetter() && m.Hand.Number < 0
+		}
+	}
+	m.Steps++
+	if !taken {
+		m.PC++
+		return nil
+	}
+	if inst.Target < 0 || inst.Target >= len(m.Program) {
+		return m.fail(inst.DisassembleInstruction, "jump to an invalid target")
+	}
+	m.PC = inst.Target
+	return nil
+}
+
+// Parse a value as shown in the game: a number or a single letter
+func ParseValue(s string) (levels.Value, error) {
+	if len(s) == 1 && s[0] >= 'A' && s[0] <= 'Z' {
+		return levels.LetterValue(s[0]), nil
+	}
+	if len(s) == 1 && s[0] >= 'a' && s[0] <= 'z' {
+		return levels.LetterValue(s[0] - 'a' + 'A'), nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return levels.Value{}, fmt.Errorf("invalid value %q, expected a number or a letter", s)
+	}
+	if n < MIN_NUMBER || n > MAX_NUMBER {
+		return levels.Value{}, fmt.Errorf("invalid value %d, numbers range from %d to %d", n, MIN_NUMBER, MAX_NUMBER)
+	}
+	return levels.NumberValue(n), nil
+}
@@ -0,0 +1,290 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/clj/hrm-profile-tool/instructions"
+	"github.com/clj/hrm-profile-tool/levels"
+)
+
+func disassembled(t *testing.T, text string) instructions.Disassembled {
+	t.Helper()
+	program, _, err := instructions.Assemble(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := instructions.Disassemble(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+var (
+	n = levels.NumberValue
+	l = levels.LetterValue
+)
+
+// Return a floor of size tiles, filled with the given values
+func floor(size int, filled map[int]levels.Value) []levels.Tile {
+	tiles := make([]levels.Tile, size)
+	for i, value := range filled {
+		tiles[i] = levels.Tile{Filled: true, Value: value}
+	}
+	return tiles
+}
+
+var runTests = []struct {
+	name    string
+	program string
+	inbox   []levels.Value
+	floor   []levels.Tile
+	outbox  []levels.Value
+	steps   int
+	failure string // The error reported by the boss, if any
+	line    int    // The line of the failing instruction
+}{
+	{"inbox exhaustion halts", `
+a:
+    INBOX
+    OUTBOX
+    JUMP     a
+`, []levels.Value{n(1), l('B'), n(-3)}, nil, []levels.Value{n(1), l('B'), n(-3)}, 9, "", 0},
+	{"empty inbox", `
+    INBOX
+    OUTBOX
+`, nil, nil, nil, 0, "", 0},
+	{"running past the end halts", `
+    INBOX
+    OUTBOX
+`, []levels.Value{n(1), n(2)}, nil, []levels.Value{n(1)}, 2, "", 0},
+	{"jump to a label at the end", `
+    INBOX
+    JUMP     a
+    OUTBOX
+a:
+`, []levels.Value{n(1)}, nil, nil, 2, "", 0},
+	{"outbox with empty hands", `
+    OUTBOX
+`, nil, nil, nil, 0, "empty hands, nothing to put in the outbox", 1},
+	{"add", `
+    INBOX
+    ADD      0
+    OUTBOX
+`, []levels.Value{n(998)}, floor(1, map[int]levels.Value{0: n(1)}), []levels.Value{n(999)}, 3, "", 0},
+	{"add overflow", `
+    INBOX
+    ADD      0
+    OUTBOX
+`, []levels.Value{n(1)}, floor(1, map[int]levels.Value{0: n(999)}), nil, 1, "overflow, 1000 is outside -999 to 999", 2},
+	{"add underflow", `
+    INBOX
+    ADD      0
+`, []levels.Value{n(-1)}, floor(1, map[int]levels.Value{0: n(-999)}), nil, 1, "overflow, -1000 is outside -999 to 999", 2},
+	{"add letters", `
+    INBOX
+    ADD      0
+`, []levels.Value{l('A')}, floor(1, map[int]levels.Value{0: n(1)}), nil, 1, "letters cannot be added", 2},
+	{"sub", `
+    INBOX
+    SUB      0
+    OUTBOX
+`, []levels.Value{n(0)}, floor(1, map[int]levels.Value{0: n(999)}), []levels.Value{n(-999)}, 3, "", 0},
+	{"sub underflow", `
+    INBOX
+    SUB      0
+`, []levels.Value{n(-1)}, floor(1, map[int]levels.Value{0: n(999)}), nil, 1, "overflow, -1000 is outside -999 to 999", 2},
+	{"sub overflow", `
+    INBOX
+    SUB      0
+`, []levels.Value{n(1)}, floor(1, map[int]levels.Value{0: n(-999)}), nil, 1, "overflow, 1000 is outside -999 to 999", 2},
+	{"sub letters", `
+    INBOX
+    SUB      0
+    OUTBOX
+    INBOX
+    SUB      0
+    OUTBOX
+`, []levels.Value{l('E'), l('A')}, floor(1, map[int]levels.Value{0: l('B')}), []levels.Value{n(3), n(-1)}, 6, "", 0},
+	{"sub a letter and a number", `
+    INBOX
+    SUB      0
+`, []levels.Value{l('E')}, floor(1, map[int]levels.Value{0: n(1)}), nil, 1, "a letter and a number cannot be subtracted", 2},
+	{"sub from an empty tile", `
+    INBOX
+    SUB      0
+`, []levels.Value{n(1)}, floor(1, nil), nil, 1, "tile 0 is empty", 2},
+	{"copyfrom an empty tile", `
+    COPYFROM 0
+`, nil, floor(1, nil), nil, 0, "tile 0 is empty", 1},
+	{"copyfrom a missing tile", `
+    COPYFROM 3
+`, nil, floor(3, nil), nil, 0, "there is no tile 3", 1},
+	{"indirect addressing", `
+    INBOX
+    COPYTO   [0]
+    COPYFROM [1]
+    OUTBOX
+`, []levels.Value{l('X')}, floor(3, map[int]levels.Value{0: n(2), 1: n(2)}), []levels.Value{l('X')}, 4, "", 0},
+	{"indirect through an empty tile", `
+    COPYFROM [0]
+`, nil, floor(3, nil), nil, 0, "tile 0 is empty, it cannot be used as an address", 1},
+	{"indirect through a letter", `
+    COPYFROM [0]
+`, nil, floor(3, map[int]levels.Value{0: l('A')}), nil, 0, "tile 0 holds a letter, it cannot be used as an address", 1},
+	{"indirect to a missing tile", `
+    BUMPUP   [0]
+`, nil, floor(3, map[int]levels.Value{0: n(3)}), nil, 0, "there is no tile 3", 1},
+	{"indirect to a negative tile", `
+    ADD      [0]
+`, nil, floor(3, map[int]levels.Value{0: n(-1)}), nil, 0, "there is no tile -1", 1},
+	{"bump", `
+    BUMPUP   0
+    OUTBOX
+    BUMPDN   1
+    OUTBOX
+`, nil, floor(2, map[int]levels.Value{0: n(998), 1: n(-998)}), []levels.Value{n(999), n(-999)}, 4, "", 0},
+	{"bump a letter", `
+    BUMPUP   0
+`, nil, floor(1, map[int]levels.Value{0: l('A')}), nil, 0, "letters cannot be bumped", 1},
+	{"bump overflow", `
+    BUMPUP   0
+`, nil, floor(1, map[int]levels.Value{0: n(999)}), nil, 0, "overflow, 1000 is outside -999 to 999", 1},
+	{"bump underflow", `
+    BUMPDN   0
+`, nil, floor(1, map[int]levels.Value{0: n(-999)}), nil, 0, "overflow, -1000 is outside -999 to 999", 1},
+	{"bump an empty tile", `
+    BUMPDN   0
+`, nil, floor(1, nil), nil, 0, "tile 0 is empty", 1},
+	{"conditional jumps", `
+a:
+    INBOX
+    JUMPZ    a
+    JUMPN    a
+    OUTBOX
+    JUMP     a
+`, []levels.Value{n(0), n(-2), l('A'), n(3)}, nil, []levels.Value{l('A'), n(3)}, 15, "", 0},
+	{"conditional jump with empty hands", `
+a:
+    JUMPZ    a
+`, nil, nil, nil, 0, "empty hands, nothing to compare", 1},
+}
+
+func TestRun(t *testing.T) {
+	for _, test := range runTests {
+		t.Run(test.name, func(t *testing.T) {
+			m := New(disassembled(t, test.program), test.inbox, test.floor)
+			err := m.Run(context.Background(), 0)
+			if test.failure == "" {
+				if err != nil {
+					t.Fatalf("got error %v", err)
+				}
+			} else {
+				var runtimeErr *RuntimeError
+				if !errors.As(err, &runtimeErr) {
+					t.Fatalf("got error %v, expected %q", err, test.failure)
+				}
+				if runtimeErr.Err != test.failure || runtimeErr.Line != test.line {
+					t.Errorf("got %q on line %d, expected %q on line %d", runtimeErr.Err, runtimeErr.Line, test.failure, test.line)
+				}
+			}
+			if !m.Halted {
+				t.Error("the machine has not halted")
+			}
+			if len(m.Outbox) != len(test.outbox) {
+				t.Fatalf("got outbox %v, expected %v", m.Outbox, test.outbox)
+			}
+			for i := range m.Outbox {
+				if m.Outbox[i] != test.outbox[i] {
+					t.Errorf("got outbox %v, expected %v", m.Outbox, test.outbox)
+					break
+				}
+			}
+			if m.Steps != test.steps {
+				t.Errorf("got %d steps, expected %d", m.Steps, test.steps)
+			}
+		})
+	}
+}
+
+func TestRunKeepsInitialFloor(t *testing.T) {
+	tiles := floor(1, map[int]levels.Value{0: n(5)})
+	m := New(disassembled(t, "BUMPUP 0\n"), nil, tiles)
+	if err := m.Run(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if m.Floor[0].Value != n(6) || tiles[0].Value != n(5) {
+		t.Errorf("got machine tile %v and initial tile %v, expected 6 and 5", m.Floor[0].Value, tiles[0].Value)
+	}
+}
+
+func TestJumpTargetBounds(t *testing.T) {
+	for _, target := range []int{-1, 2, 10} {
+		program := instructions.Disassembled{
+			instructions.DisassembleInstruction{LineNumber: 1, Op: instructions.OP_INBOX},
+			instructions.DisassembleJumpInstruction{
+				DisassembleInstruction: instructions.DisassembleInstruction{LineNumber: 2, Op: instructions.OP_JUMP},
+				Target:                 target,
+			},
+		}
+		m := New(program, []levels.Value{n(1)}, nil)
+		err := m.Run(context.Background(), 0)
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) || runtimeErr.Err != "jump to an invalid target" || runtimeErr.Index != 1 {
+			t.Errorf("jump to %d: got error %v, expected an invalid target at index 1", target, err)
+		}
+	}
+}
+
+func TestStepLimit(t *testing.T) {
+	program := disassembled(t, "a:\n    BUMPUP 0\n    JUMP a\n")
+	for _, limit := range []int{1, 10, 101} {
+		m := New(program, nil, floor(1, map[int]levels.Value{0: n(0)}))
+		if err := m.Run(context.Background(), limit); err != ErrStepLimit {
+			t.Errorf("limit %d: got error %v, expected ErrStepLimit", limit, err)
+		}
+		if m.Steps != limit || m.Halted {
+			t.Errorf("limit %d: stopped after %d steps (halted %v)", limit, m.Steps, m.Halted)
+		}
+	}
+
+	m := New(program, nil, floor(1, map[int]levels.Value{0: n(0)}))
+	if err := m.Run(context.Background(), 0); err == nil {
+		t.Error("the default step limit did not stop an endless loop")
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m := New(disassembled(t, "a:\n    JUMP a\n"), nil, nil)
+	if err := m.Run(ctx, 0); err != context.Canceled {
+		t.Errorf("got error %v, expected context.Canceled", err)
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		text  string
+		value levels.Value
+		valid bool
+	}{
+		{"0", n(0), true},
+		{"-999", n(-999), true},
+		{"999", n(999), true},
+		{"1000", levels.Value{}, false},
+		{"-1000", levels.Value{}, false},
+		{"A", l('A'), true},
+		{"z", l('Z'), true},
+		{"AB", levels.Value{}, false},
+		{"", levels.Value{}, false},
+	}
+	for _, test := range tests {
+		value, err := ParseValue(test.text)
+		if (err == nil) != test.valid || value != test.value {
+			t.Errorf("ParseValue(%q) = %v, %v, expected %v (valid %v)", test.text, value, err, test.value, test.valid)
+		}
+	}
+}